@@ -0,0 +1,387 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// thresholds are the --check limits; zero values disable a check.
+type thresholds struct {
+	maxFDPct    float64
+	minDiskFree diskFree
+	mount       string // only check this mountpoint
+	maxMemPct   float64
+	diskUsed    []diskUsed
+}
+
+// diskUsed is a disk:<mountpoint>:<pct>% check: the mount's used_percent
+// must not exceed maxPct. An empty mountpoint checks every mount.
+type diskUsed struct {
+	mount  string
+	maxPct float64
+}
+
+// checkSpecs collects "kind:target:threshold" checks, the compact form of
+// the threshold flags:
+//
+//	disk:/data:90%   used_percent of /data at most 90 (disk::90% for every mount)
+//	fd:90%           same as --max-fd-pct 90
+//	mem:95%          same as --max-mem-pct 95
+type checkSpecs []checkSpec
+
+type checkSpec struct {
+	kind, target string
+	pct          float64
+}
+
+func (c *checkSpecs) String() string {
+	var specs []string
+	for _, s := range *c {
+		specs = append(specs, fmt.Sprintf("%s:%s:%g%%", s.kind, s.target, s.pct))
+	}
+	return strings.Join(specs, ",")
+}
+
+func (c *checkSpecs) Set(v string) error {
+	spec, err := parseCheckSpec(v)
+	if err == nil {
+		*c = append(*c, spec)
+	}
+	return err
+}
+
+// parseCheckSpec splits at the first and the last colon, so a mountpoint
+// may itself contain colons.
+func parseCheckSpec(v string) (checkSpec, error) {
+	kind, rest, ok := strings.Cut(v, ":")
+	if !ok {
+		return checkSpec{}, fmt.Errorf("%q: want kind:target:threshold, e.g. disk:/:90%%", v)
+	}
+	target, threshold := "", rest
+	if i := strings.LastIndex(rest, ":"); i >= 0 {
+		target, threshold = rest[:i], rest[i+1:]
+	}
+	spec := checkSpec{kind: kind, target: target}
+	switch kind {
+	case "disk":
+		if !strings.Contains(rest, ":") {
+			return checkSpec{}, fmt.Errorf("%q: want disk:<mountpoint>:<pct>%%, or disk::<pct>%% for every mount", v)
+		}
+	case "fd", "mem":
+		if target != "" {
+			return checkSpec{}, fmt.Errorf("%q: %s takes no target (want %s:<pct>%%)", v, kind, kind)
+		}
+	default:
+		return checkSpec{}, fmt.Errorf("%q: unknown kind %q (want disk, fd or mem)", v, kind)
+	}
+	num, isPct := strings.CutSuffix(threshold, "%")
+	pct, err := strconv.ParseFloat(num, 64)
+	if !isPct || err != nil || pct <= 0 || pct > 100 {
+		return checkSpec{}, fmt.Errorf("%q: invalid threshold %q (want a percentage such as 90%%)", v, threshold)
+	}
+	spec.pct = pct
+	return spec, nil
+}
+
+// checkSwitch is the main command's --check: bare it turns the checks on,
+// and --check=kind:target:threshold also adds one to specs.
+type checkSwitch struct {
+	on    bool
+	specs *checkSpecs
+}
+
+func (c *checkSwitch) IsBoolFlag() bool { return true }
+
+func (c *checkSwitch) String() string {
+	if c == nil || !c.on {
+		return "false"
+	}
+	return "true"
+}
+
+func (c *checkSwitch) Set(v string) error {
+	if on, err := strconv.ParseBool(v); err == nil {
+		c.on = on
+		return nil
+	}
+	c.on = true
+	return c.specs.Set(v)
+}
+
+// diskFree is a --min-disk-free value: a percentage of the filesystem
+// size or an absolute number of bytes.
+type diskFree struct {
+	pct   float64
+	bytes uint64
+	set   bool
+}
+
+func (d diskFree) String() string {
+	if d.pct > 0 {
+		return strconv.FormatFloat(d.pct, 'g', -1, 64) + "%"
+	}
+	return formatSize(d.bytes)
+}
+
+var sizeSuffixes = map[string]uint64{
+	"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40,
+}
+
+// parseDiskFree accepts a percentage ("5%") or a size for parseSize.
+func parseDiskFree(s string) (diskFree, error) {
+	if num, ok := strings.CutSuffix(s, "%"); ok {
+		pct, err := strconv.ParseFloat(num, 64)
+		if err != nil || pct <= 0 || pct > 100 {
+			return diskFree{}, fmt.Errorf("invalid percentage %q", s)
+		}
+		return diskFree{pct: pct, set: true}, nil
+	}
+	size, err := parseSize(s)
+	if err != nil || size == 0 {
+		return diskFree{}, fmt.Errorf("invalid size %q (want e.g. 5%%, 10G or 512MiB)", s)
+	}
+	return diskFree{bytes: size, set: true}, nil
+}
+
+// parseSize accepts "10G", "512MiB", "100MB", "1.5T" or plain bytes, all
+// suffixes powers of 1024.
+func parseSize(s string) (uint64, error) {
+	num := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(s), "B"), "I")
+	suffix := ""
+	if n := len(num); n > 0 && num[n-1] >= 'A' && num[n-1] <= 'Z' {
+		num, suffix = num[:n-1], num[n-1:]
+	}
+	mult, ok := sizeSuffixes[suffix]
+	v, err := strconv.ParseFloat(num, 64)
+	if !ok || err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q (want e.g. 10G or 512MiB)", s)
+	}
+	return uint64(v * float64(mult)), nil
+}
+
+// thresholdFlags defines the --check limits on fs and returns a function
+// that parses them, together with specs, once fs has been parsed.
+func thresholdFlags(fs *flag.FlagSet, specs *checkSpecs) func() (thresholds, error) {
+	maxFDPct := fs.Float64("max-fd-pct", 0, "--check: highest allowed share of the soft RLIMIT_NOFILE in use, in percent")
+	minDiskFree := fs.String("min-disk-free", "", "--check: least available space per mount, as a percentage (5%) or a size (10G)")
+	mount := fs.String("mount", "", "--check: apply --min-disk-free only to this mountpoint")
+	maxMemPct := fs.Float64("max-mem-pct", 0, "--check: highest allowed memory use against the cgroup limit (host memory if unlimited), in percent")
+	return func() (thresholds, error) {
+		t := thresholds{maxFDPct: *maxFDPct, mount: *mount, maxMemPct: *maxMemPct}
+		if *minDiskFree != "" {
+			var err error
+			if t.minDiskFree, err = parseDiskFree(*minDiskFree); err != nil {
+				return t, fmt.Errorf("--min-disk-free: %w", err)
+			}
+		}
+		for _, spec := range *specs {
+			switch spec.kind {
+			case "disk":
+				t.diskUsed = append(t.diskUsed, diskUsed{mount: spec.target, maxPct: spec.pct})
+			case "fd":
+				t.maxFDPct = spec.pct
+			case "mem":
+				t.maxMemPct = spec.pct
+			}
+		}
+		if t.maxFDPct <= 0 && !t.minDiskFree.set && t.maxMemPct <= 0 && len(t.diskUsed) == 0 {
+			return t, errors.New("--check needs at least one of --max-fd-pct, --min-disk-free, --max-mem-pct or a kind:target:threshold check")
+		}
+		return t, nil
+	}
+}
+
+// checkFields are the report keys the checks in t read.
+func (t thresholds) checkFields() []string {
+	var fields []string
+	if t.maxFDPct > 0 {
+		fields = append(fields, "fd_count", "rlimits")
+	}
+	if t.minDiskFree.set || len(t.diskUsed) > 0 {
+		fields = append(fields, "mounts")
+	}
+	if t.maxMemPct > 0 {
+		fields = append(fields, "memory", "cgroup_v1", "cgroup_v2")
+	}
+	return fields
+}
+
+type checkResult struct {
+	name   string
+	ok     bool
+	detail string
+}
+
+// errCheckData reports that a check could not run because the data it
+// needs failed to collect; the caller exits 2 for it.
+type errCheckData struct {
+	check, collector, reason string
+}
+
+func (e *errCheckData) Error() string {
+	return fmt.Sprintf("%s: %s unavailable: %s", e.check, e.collector, e.reason)
+}
+
+// evaluateChecks applies t to info. Limits are inclusive: a value equal to
+// a maximum or minimum passes.
+func evaluateChecks(info *sysinfo.SysInfo, t thresholds) ([]checkResult, error) {
+	var results []checkResult
+	missing := func(check string, collectors ...string) error {
+		for _, c := range collectors {
+			if reason, failed := info.Errors[c]; failed {
+				return &errCheckData{check, c, reason}
+			}
+		}
+		return nil
+	}
+
+	if t.maxFDPct > 0 {
+		if err := missing("fd", "fd_count", "rlimits"); err != nil {
+			return nil, err
+		}
+		r := checkResult{name: "fd", ok: true, detail: fmt.Sprintf("%d fds, no soft RLIMIT_NOFILE", info.FDCount)}
+		if l := info.Rlimits["nofile"]; l.Soft != nil && *l.Soft > 0 {
+			pct := float64(info.FDCount) / float64(*l.Soft) * 100
+			r.ok = comparePct(uint64(info.FDCount), *l.Soft, t.maxFDPct) <= 0
+			r.detail = fmt.Sprintf("%d of %d fds (%.1f%%, max %g%%)", info.FDCount, *l.Soft, pct, t.maxFDPct)
+		}
+		results = append(results, r)
+	}
+
+	if t.minDiskFree.set {
+		if err := missing("disk", "mounts"); err != nil {
+			return nil, err
+		}
+		found := false
+		for _, d := range info.Mounts {
+			if t.mount != "" && d.Mountpoint != t.mount {
+				continue
+			}
+			found = true
+			name := "disk " + d.Mountpoint
+			if d.Error != "" {
+				// Without --mount an unresponsive mount is reported by
+				// its own finding; a check aimed at it must fail.
+				if t.mount != "" {
+					results = append(results, checkResult{name: name, detail: d.Error})
+				}
+				continue
+			}
+			ok := d.Avail >= t.minDiskFree.bytes
+			if t.minDiskFree.pct > 0 {
+				ok = d.Total == 0 || comparePct(d.Avail, d.Total, t.minDiskFree.pct) >= 0
+			}
+			results = append(results, checkResult{name: name, ok: ok,
+				detail: fmt.Sprintf("%s of %s available (min %s)", formatSize(d.Avail), formatSize(d.Total), t.minDiskFree)})
+		}
+		if t.mount != "" && !found {
+			results = append(results, checkResult{name: "disk " + t.mount, detail: "not mounted or filtered out"})
+		}
+	}
+
+	for _, du := range t.diskUsed {
+		if err := missing("disk", "mounts"); err != nil {
+			return nil, err
+		}
+		found := false
+		for _, d := range info.Mounts {
+			if du.mount != "" && d.Mountpoint != du.mount {
+				continue
+			}
+			found = true
+			name := "disk " + d.Mountpoint
+			if d.Error != "" {
+				if du.mount != "" {
+					results = append(results, checkResult{name: name, detail: d.Error})
+				}
+				continue
+			}
+			// UsedPercent is rounded to a tenth for display; compare the
+			// block counts so that 90.04% used fails a 90% limit.
+			ok := d.Total == 0 || d.Avail > d.Total || comparePct(d.Total-d.Avail, d.Total, du.maxPct) <= 0
+			results = append(results, checkResult{name: name, ok: ok,
+				detail: fmt.Sprintf("%.1f%% used of %s (max %g%%)", d.UsedPercent, formatSize(d.Total), du.maxPct)})
+		}
+		if du.mount != "" && !found {
+			results = append(results, checkResult{name: "disk " + du.mount, detail: "not mounted or filtered out"})
+		}
+	}
+
+	if t.maxMemPct > 0 {
+		used, limit, source := memoryAgainstLimit(info)
+		if limit == 0 {
+			if err := missing("memory", "memory"); err != nil {
+				return nil, err
+			}
+			return nil, &errCheckData{"memory", "memory", "no data"}
+		}
+		pct := float64(used) / float64(limit) * 100
+		results = append(results, checkResult{name: "memory", ok: comparePct(used, limit, t.maxMemPct) <= 0,
+			detail: fmt.Sprintf("%s of %s %s (%.1f%%, max %g%%)", formatSize(used), formatSize(limit), source, pct, t.maxMemPct)})
+	}
+	return results, nil
+}
+
+// comparePct compares part as a percentage of whole with pct. It compares
+// part*100 with pct*whole rather than the quotient, which would put 57 of
+// 100 at 56.99999999999999% and fail a limit of exactly 57%.
+func comparePct(part, whole uint64, pct float64) int {
+	return cmp.Compare(float64(part)*100, pct*float64(whole))
+}
+
+// memoryAgainstLimit picks the tightest known memory bound: the cgroup v2
+// memory.max, the v1 limit, or else the host's MemTotal.
+func memoryAgainstLimit(info *sysinfo.SysInfo) (used, limit uint64, source string) {
+	if cg := info.CgroupV2; cg != nil && cg.MemoryMaxBytes != nil && cg.MemoryCurrentBytes != nil {
+		return *cg.MemoryCurrentBytes, *cg.MemoryMaxBytes, "cgroup v2 memory.max"
+	}
+	if cg := info.CgroupV1; cg != nil && cg.MemoryLimitBytes != nil && cg.MemoryUsageBytes != nil {
+		return *cg.MemoryUsageBytes, *cg.MemoryLimitBytes, "cgroup v1 limit"
+	}
+	if m := info.Memory; m != nil {
+		return m.UsedBytes, m.TotalBytes, "host memory"
+	}
+	return 0, 0, ""
+}
+
+// runCheck collects what t needs and exits 0 when every check passes, 1
+// when one fails and 2 when the data for a check could not be collected.
+func runCheck(opts sysinfo.Options, t thresholds, verbose bool) int {
+	opts.Fields = t.checkFields()
+	if t.mount != "" || slices.ContainsFunc(t.diskUsed, func(du diskUsed) bool { return du.mount != "" }) {
+		// Deduplication could hide the mountpoint behind another one on
+		// the same device.
+		opts.Mounts.All = true
+	}
+	info, _ := sysinfo.CollectWith(opts)
+	results, err := evaluateChecks(info, t)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sysinfo: check", err)
+		return 2
+	}
+	return printChecks(os.Stdout, results, verbose)
+}
+
+// printChecks writes failed checks, and with verbose the passing ones too,
+// and returns the exit code: 1 if any check failed.
+func printChecks(w io.Writer, results []checkResult, verbose bool) int {
+	code := 0
+	for _, r := range results {
+		if !r.ok {
+			code = 1
+			fmt.Fprintf(w, "FAIL %s: %s\n", r.name, r.detail)
+		} else if verbose {
+			fmt.Fprintf(w, "ok   %s: %s\n", r.name, r.detail)
+		}
+	}
+	return code
+}
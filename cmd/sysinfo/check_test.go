@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"math"
+	"testing"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+func TestParseCheckSpec(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    checkSpec
+		wantErr bool
+	}{
+		{in: "disk:/:90%", want: checkSpec{kind: "disk", target: "/", pct: 90}},
+		{in: "disk:/data:12.5%", want: checkSpec{kind: "disk", target: "/data", pct: 12.5}},
+		{in: "disk::90%", want: checkSpec{kind: "disk", pct: 90}},
+		{in: "disk:/mnt/a:b:80%", want: checkSpec{kind: "disk", target: "/mnt/a:b", pct: 80}},
+		{in: "fd:90%", want: checkSpec{kind: "fd", pct: 90}},
+		{in: "fd::90%", want: checkSpec{kind: "fd", pct: 90}},
+		{in: "mem:100%", want: checkSpec{kind: "mem", pct: 100}},
+		{in: "disk:90%", wantErr: true},
+		{in: "disk:/:90", wantErr: true},
+		{in: "disk:/:0%", wantErr: true},
+		{in: "disk:/:101%", wantErr: true},
+		{in: "fd:/:90%", wantErr: true},
+		{in: "cpu:90%", wantErr: true},
+		{in: "disk", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCheckSpec(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: err = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSpecsMapOntoThresholds(t *testing.T) {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	var specs checkSpecs
+	fs.Var(&specs, "check", "")
+	parse := thresholdFlags(fs, &specs)
+	if err := fs.Parse([]string{"--check", "disk:/:90%", "--check", "fd:80%", "--check", "mem:95%"}); err != nil {
+		t.Fatal(err)
+	}
+	th, err := parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.maxFDPct != 80 || th.maxMemPct != 95 || len(th.diskUsed) != 1 || th.diskUsed[0] != (diskUsed{"/", 90}) {
+		t.Errorf("thresholds = %+v", th)
+	}
+}
+
+func TestCheckSwitch(t *testing.T) {
+	for _, tt := range []struct {
+		args  []string
+		on    bool
+		specs int
+	}{
+		{nil, false, 0},
+		{[]string{"--check"}, true, 0},
+		{[]string{"--check=false"}, false, 0},
+		{[]string{"--check=disk:/:90%", "--check=mem:95%"}, true, 2},
+	} {
+		fs := flag.NewFlagSet("sysinfo", flag.ContinueOnError)
+		var specs checkSpecs
+		check := checkSwitch{specs: &specs}
+		fs.Var(&check, "check", "")
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if check.on != tt.on || len(specs) != tt.specs {
+			t.Errorf("%q: on %v with %d specs, want %v with %d", tt.args, check.on, len(specs), tt.on, tt.specs)
+		}
+	}
+}
+
+func TestEvaluateDiskUsed(t *testing.T) {
+	info := &sysinfo.SysInfo{Mounts: []sysinfo.DiskInfo{
+		{Mountpoint: "/", Total: 1000, Avail: 100, UsedPercent: 90},
+		{Mountpoint: "/data", Total: 1000, Avail: 99, UsedPercent: 90.1},
+		{Mountpoint: "/nfs", Error: "stat timed out after 2s"},
+	}}
+	tests := []struct {
+		check diskUsed
+		want  map[string]bool
+	}{
+		{diskUsed{"/", 90}, map[string]bool{"disk /": true}},
+		{diskUsed{"/data", 90}, map[string]bool{"disk /data": false}},
+		{diskUsed{"/nfs", 90}, map[string]bool{"disk /nfs": false}},
+		{diskUsed{"/missing", 90}, map[string]bool{"disk /missing": false}},
+		// Every mount: the unresponsive one is left to its finding.
+		{diskUsed{"", 90}, map[string]bool{"disk /": true, "disk /data": false}},
+	}
+	for _, tt := range tests {
+		results, err := evaluateChecks(info, thresholds{diskUsed: []diskUsed{tt.check}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]bool)
+		for _, r := range results {
+			got[r.name] = r.ok
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%+v: results %v, want %v", tt.check, got, tt.want)
+			continue
+		}
+		for name, ok := range tt.want {
+			if g, found := got[name]; !found || g != ok {
+				t.Errorf("%+v: %s ok = %v, want %v", tt.check, name, g, ok)
+			}
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// checkOK runs evaluateChecks and returns whether each named check passed.
+func checkOK(t *testing.T, info *sysinfo.SysInfo, th thresholds) map[string]bool {
+	t.Helper()
+	results, err := evaluateChecks(info, th)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for _, r := range results {
+		got[r.name] = r.ok
+	}
+	return got
+}
+
+func TestEvaluateFDBoundaries(t *testing.T) {
+	soft := uint64(1000)
+	for _, tt := range []struct {
+		fds    int
+		maxPct float64
+		ok     bool
+	}{
+		{899, 90, true},
+		{900, 90, true},
+		{901, 90, false},
+		{570, 57, true}, // 57.0%, not 56.99999999999999%
+		{571, 57, false},
+		{1000, 100, true},
+		{1001, 100, false},
+	} {
+		info := &sysinfo.SysInfo{FDCount: tt.fds, Rlimits: map[string]sysinfo.Rlimit{"nofile": {Soft: &soft}}}
+		if got := checkOK(t, info, thresholds{maxFDPct: tt.maxPct}); got["fd"] != tt.ok {
+			t.Errorf("%d of %d fds, max %g%%: ok = %v, want %v", tt.fds, soft, tt.maxPct, got["fd"], tt.ok)
+		}
+	}
+	// Without a soft limit there is nothing to exceed.
+	info := &sysinfo.SysInfo{FDCount: 1 << 20, Rlimits: map[string]sysinfo.Rlimit{"nofile": {}}}
+	if got := checkOK(t, info, thresholds{maxFDPct: 1}); !got["fd"] {
+		t.Error("fd check failed without a soft limit")
+	}
+}
+
+func TestEvaluateDiskFreeBoundaries(t *testing.T) {
+	tests := []struct {
+		avail, total uint64
+		min          string
+		ok           bool
+	}{
+		{4, 100, "5%", false},
+		{5, 100, "5%", true},
+		{6, 100, "5%", true},
+		{56, 100, "57%", false},
+		{57, 100, "57%", true},
+		{29 << 30, 100 << 30, "29%", true},
+		{100, 100, "100%", true},
+		{99, 100, "100%", false},
+		{10<<30 - 1, 50 << 30, "10G", false},
+		{10 << 30, 50 << 30, "10G", true},
+		{10<<30 + 1, 50 << 30, "10G", true},
+		{0, 0, "5%", true}, // nothing to fill, e.g. an empty tmpfs quota
+		{0, 0, "1K", false},
+	}
+	for _, tt := range tests {
+		min, err := parseDiskFree(tt.min)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := &sysinfo.SysInfo{Mounts: []sysinfo.DiskInfo{{Mountpoint: "/data", Avail: tt.avail, Total: tt.total}}}
+		if got := checkOK(t, info, thresholds{minDiskFree: min}); got["disk /data"] != tt.ok {
+			t.Errorf("%d of %d available, min %s: ok = %v, want %v", tt.avail, tt.total, tt.min, got["disk /data"], tt.ok)
+		}
+	}
+}
+
+func TestEvaluateDiskFreeSkippedMount(t *testing.T) {
+	min, _ := parseDiskFree("5%")
+	info := &sysinfo.SysInfo{Mounts: []sysinfo.DiskInfo{
+		{Mountpoint: "/", Avail: 50, Total: 100},
+		{Mountpoint: "/nfs", Error: "stat timed out after 2s"},
+		{Mountpoint: "/gone", Error: "statfs: no such file or directory"},
+	}}
+	tests := []struct {
+		mount string
+		want  map[string]bool
+	}{
+		{"", map[string]bool{"disk /": true}},
+		{"/", map[string]bool{"disk /": true}},
+		{"/nfs", map[string]bool{"disk /nfs": false}},
+		{"/gone", map[string]bool{"disk /gone": false}},
+		{"/missing", map[string]bool{"disk /missing": false}},
+	}
+	for _, tt := range tests {
+		got := checkOK(t, info, thresholds{minDiskFree: min, mount: tt.mount})
+		if len(got) != len(tt.want) {
+			t.Errorf("--mount %q: results %v, want %v", tt.mount, got, tt.want)
+			continue
+		}
+		for name, ok := range tt.want {
+			if g, found := got[name]; !found || g != ok {
+				t.Errorf("--mount %q: %s ok = %v, want %v", tt.mount, name, g, ok)
+			}
+		}
+	}
+}
+
+func TestEvaluateDiskUsedBoundaries(t *testing.T) {
+	for _, tt := range []struct {
+		avail, total uint64
+		max          float64
+		ok           bool
+	}{
+		{1001, 10000, 90, true},
+		{1000, 10000, 90, true},
+		{999, 10000, 90, false},
+		{9996, 100000, 90, false}, // 90.004% shows as 90.0% but is over
+		{43, 100, 57, true},       // 57.0%, not 56.99999999999999%
+		{42, 100, 57, false},
+		{0, 100, 100, true},
+		{100, 100, 0.1, true},
+		{0, 0, 1, true},     // nothing to fill
+		{200, 100, 1, true}, // more available than total: nothing used
+	} {
+		// UsedPercent as usedPercent would round it, to show it is not
+		// what the check compares.
+		used := 0.0
+		if tt.total > 0 && tt.avail <= tt.total {
+			used = math.Round(float64(tt.total-tt.avail)/float64(tt.total)*1000) / 10
+		}
+		info := &sysinfo.SysInfo{Mounts: []sysinfo.DiskInfo{{Mountpoint: "/", Total: tt.total, Avail: tt.avail, UsedPercent: used}}}
+		if got := checkOK(t, info, thresholds{diskUsed: []diskUsed{{"/", tt.max}}}); got["disk /"] != tt.ok {
+			t.Errorf("%d of %d available, max %g%% used: ok = %v, want %v", tt.avail, tt.total, tt.max, got["disk /"], tt.ok)
+		}
+	}
+}
+
+func TestEvaluateMemoryBoundaries(t *testing.T) {
+	const limit = 1000
+	v2 := func(current uint64) *sysinfo.SysInfo {
+		return &sysinfo.SysInfo{CgroupV2: &sysinfo.CgroupV2{MemoryMaxBytes: ptr[uint64](limit), MemoryCurrentBytes: &current}}
+	}
+	v1 := func(usage uint64) *sysinfo.SysInfo {
+		return &sysinfo.SysInfo{CgroupV1: &sysinfo.CgroupV1{MemoryLimitBytes: ptr[uint64](limit), MemoryUsageBytes: &usage}}
+	}
+	host := func(used uint64) *sysinfo.SysInfo {
+		return &sysinfo.SysInfo{Memory: &sysinfo.MemInfo{TotalBytes: limit, UsedBytes: used}}
+	}
+	for name, info := range map[string]func(uint64) *sysinfo.SysInfo{"cgroup v2": v2, "cgroup v1": v1, "host": host} {
+		for _, tt := range []struct {
+			used   uint64
+			maxPct float64
+			ok     bool
+		}{
+			{949, 95, true},
+			{950, 95, true},
+			{951, 95, false},
+			{570, 57, true},
+			{1000, 100, true},
+		} {
+			if got := checkOK(t, info(tt.used), thresholds{maxMemPct: tt.maxPct}); got["memory"] != tt.ok {
+				t.Errorf("%s: %d of %d, max %g%%: ok = %v, want %v", name, tt.used, limit, tt.maxPct, got["memory"], tt.ok)
+			}
+		}
+	}
+	// A cgroup reporting usage but no limit falls back to host memory.
+	info := host(500)
+	info.CgroupV2 = &sysinfo.CgroupV2{MemoryCurrentBytes: ptr[uint64](2000)}
+	if got := checkOK(t, info, thresholds{maxMemPct: 50}); !got["memory"] {
+		t.Error("memory.current without memory.max checked against nothing")
+	}
+}
+
+func TestEvaluateChecksMissingData(t *testing.T) {
+	min, _ := parseDiskFree("5%")
+	tests := []struct {
+		name string
+		info *sysinfo.SysInfo
+		th   thresholds
+	}{
+		{"fd count", &sysinfo.SysInfo{Errors: map[string]string{"fd_count": "permission denied"}}, thresholds{maxFDPct: 90}},
+		{"rlimits", &sysinfo.SysInfo{Errors: map[string]string{"rlimits": "permission denied"}}, thresholds{maxFDPct: 90}},
+		{"mounts", &sysinfo.SysInfo{Errors: map[string]string{"mounts": "no /proc/mounts"}}, thresholds{minDiskFree: min}},
+		{"mounts for disk used", &sysinfo.SysInfo{Errors: map[string]string{"mounts": "no /proc/mounts"}}, thresholds{diskUsed: []diskUsed{{"", 90}}}},
+		{"memory", &sysinfo.SysInfo{Errors: map[string]string{"memory": "malformed"}}, thresholds{maxMemPct: 90}},
+		{"no memory at all", &sysinfo.SysInfo{}, thresholds{maxMemPct: 90}},
+	}
+	for _, tt := range tests {
+		var dataErr *errCheckData
+		if _, err := evaluateChecks(tt.info, tt.th); !errors.As(err, &dataErr) {
+			t.Errorf("%s: err = %v, want the data to be missing", tt.name, err)
+		}
+	}
+}
+
+func TestPrintChecks(t *testing.T) {
+	results := []checkResult{{name: "fd", ok: true, detail: "1 of 10"}, {name: "disk /", detail: "1% free"}}
+	for _, tt := range []struct {
+		results []checkResult
+		verbose bool
+		code    int
+		out     string
+	}{
+		{results[:1], false, 0, ""},
+		{results[:1], true, 0, "ok   fd: 1 of 10\n"},
+		{results, false, 1, "FAIL disk /: 1% free\n"},
+		{results, true, 1, "ok   fd: 1 of 10\nFAIL disk /: 1% free\n"},
+		{nil, false, 0, ""},
+	} {
+		var buf bytes.Buffer
+		if code := printChecks(&buf, tt.results, tt.verbose); code != tt.code || buf.String() != tt.out {
+			t.Errorf("verbose %v: exit %d %q, want %d %q", tt.verbose, code, buf.String(), tt.code, tt.out)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// column is one scalar picked out of the JSON report for --format
+// csv-stream.
+type column struct {
+	name  string
+	path  string // dotted path into the report, e.g. "loadavg.load1"
+	mount string // set for disk:<mountpoint>
+}
+
+// columnShorthands expand to several dotted paths.
+var columnShorthands = map[string][]string{
+	"mem":  {"memory.used_bytes", "memory.available_bytes"},
+	"load": {"loadavg.load1", "loadavg.load5", "loadavg.load15"},
+	"fd":   {"fd_count"},
+}
+
+// parseColumns expands --only: shorthands (mem, load, fd), disk:<mountpoint>
+// for that mount's available bytes, or any dotted path such as
+// memory.cached_bytes or mounts.0.Avail. It also returns the top-level
+// keys the columns need, so only their collectors run.
+func parseColumns(spec string) ([]column, []string, error) {
+	var cols []column
+	var keys []string
+	addKey := func(k string) {
+		if !slices.Contains(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+			continue
+		case strings.HasPrefix(item, "disk:"):
+			mount := strings.TrimPrefix(item, "disk:")
+			if mount == "" {
+				return nil, nil, fmt.Errorf("column %q: missing mountpoint", item)
+			}
+			cols = append(cols, column{name: item + ".avail_bytes", mount: mount})
+			addKey("mounts")
+		case columnShorthands[item] != nil:
+			for _, p := range columnShorthands[item] {
+				cols = append(cols, column{name: p, path: p})
+				addKey(strings.SplitN(p, ".", 2)[0])
+			}
+		default:
+			top := strings.SplitN(item, ".", 2)[0]
+			if err := sysinfo.CheckFields([]string{top}); err != nil {
+				return nil, nil, fmt.Errorf("column %q: %w", item, err)
+			}
+			cols = append(cols, column{name: item, path: item})
+			addKey(top)
+		}
+	}
+	if len(cols) == 0 {
+		return nil, nil, fmt.Errorf("no columns selected")
+	}
+	return cols, keys, nil
+}
+
+// lookupPath resolves a dotted path in a decoded JSON document; numeric
+// segments index arrays. A missing key yields nil (the section was not
+// collected). Objects and arrays are rejected: columns must be scalars.
+func lookupPath(doc any, path string) (any, error) {
+	v := doc
+	for _, seg := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[seg]
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %q is not an array index", errBadColumn, path, seg)
+			}
+			if i < 0 || i >= len(node) {
+				return nil, nil
+			}
+			v = node[i]
+		case nil:
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("%w: %s: %q is below a scalar", errBadColumn, path, seg)
+		}
+	}
+	switch v.(type) {
+	case map[string]any:
+		return nil, fmt.Errorf("%w: %s is an object; name one of its fields", errBadColumn, path)
+	case []any:
+		return nil, fmt.Errorf("%w: %s is an array; name an element, e.g. %s.0", errBadColumn, path, path)
+	}
+	return v, nil
+}
+
+// errBadColumn marks --only paths that name an object or array, which
+// only shows once there is a report to resolve them against.
+var errBadColumn = errors.New("bad column")
+
+// csvStream is the csv-stream renderer: the header once, then one row per
+// report. A report without a timestamp is stamped with clk.
+type csvStream struct {
+	w    *csv.Writer
+	cols []column
+	clk  clock.Clock
+}
+
+func (s *csvStream) render(w io.Writer, info, _ *sysinfo.SysInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	// UseNumber keeps byte counters above 2^53 exact.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	ts := s.clk.Now()
+	if info.Timestamp != nil {
+		ts = *info.Timestamp
+	}
+	record := []string{ts.Format(time.RFC3339)}
+	for _, c := range s.cols {
+		if c.mount != "" {
+			record = append(record, mountAvail(info, c.mount))
+			continue
+		}
+		v, err := lookupPath(doc, c.path)
+		if err != nil {
+			return err
+		}
+		record = append(record, formatCell(v))
+	}
+	if s.w == nil {
+		s.w = csv.NewWriter(w)
+		header := []string{"timestamp"}
+		for _, c := range s.cols {
+			header = append(header, c.name)
+		}
+		s.w.Write(header)
+	}
+	s.w.Write(record)
+	s.w.Flush()
+	return s.w.Error()
+}
+
+func mountAvail(info *sysinfo.SysInfo, mountpoint string) string {
+	for _, d := range info.Mounts {
+		if d.Mountpoint == mountpoint && d.Error == "" {
+			return strconv.FormatUint(d.Avail, 10)
+		}
+	}
+	return ""
+}
+
+func formatCell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case json.Number:
+		return v.String()
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// config is the optional configuration file, JSON so that a typo in a key
+// is an error rather than silently ignored:
+//
+//	{"section_titles": {"mounts": "== Storage ==", "cgroup": "Limits"}}
+type config struct {
+	SectionTitles map[string]string `json:"section_titles"`
+}
+
+// configPath is the file given with --config; configTitles are the
+// section titles it set. A --section-title flag overrides them.
+var (
+	configPath   string
+	configTitles map[string]string
+)
+
+// defaultConfigPath is $XDG_CONFIG_HOME/sysinfo/config.json, or "" when
+// there is no home directory to find it in.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "sysinfo", "config.json")
+}
+
+// loadConfig reads --config, or the default file when it exists.
+func loadConfig() error {
+	path := configPath
+	if path == "" {
+		if path = defaultConfigPath(); path == "" {
+			return nil
+		}
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	configTitles = cfg.SectionTitles
+	return nil
+}
+
+func parseConfig(data []byte) (*config, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var cfg config
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	for name := range cfg.SectionTitles {
+		if !isSection(name) {
+			return nil, fmt.Errorf("section_titles: unknown section %q (want one of %s)", name, sectionNames())
+		}
+	}
+	return &cfg, nil
+}
+
+// sectionTitle returns the heading of a text section, from --section-title
+// or else from the config file.
+func sectionTitle(name string) (string, bool) {
+	if title, ok := sectionTitles[name]; ok {
+		return title, true
+	}
+	title, ok := configTitles[name]
+	return title, ok
+}
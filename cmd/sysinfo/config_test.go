@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		data    string
+		titles  map[string]string
+		wantErr string
+	}{
+		{`{}`, nil, ""},
+		{`{"section_titles": {"mounts": "== Storage ==", "cgroup": ""}}`, map[string]string{"mounts": "== Storage ==", "cgroup": ""}, ""},
+		{`{"section_title": {"mounts": "Storage"}}`, nil, "unknown field"},
+		{`{"section_titles": {"disks": "Storage"}}`, nil, `unknown section "disks"`},
+		{`section_titles: {}`, nil, "invalid character"},
+	}
+	for _, tt := range tests {
+		cfg, err := parseConfig([]byte(tt.data))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseConfig(%s) error = %v, want %q", tt.data, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseConfig(%s): %v", tt.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(cfg.SectionTitles, tt.titles) {
+			t.Errorf("parseConfig(%s) titles = %v, want %v", tt.data, cfg.SectionTitles, tt.titles)
+		}
+	}
+}
+
+func TestConfigSectionTitles(t *testing.T) {
+	withFields(t, "host")
+	savedPath, savedTitles, savedFlags := configPath, configTitles, sectionTitles
+	t.Cleanup(func() { configPath, configTitles, sectionTitles = savedPath, savedTitles, savedFlags })
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	writeConfig := func(path, data string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info := &sysinfo.SysInfo{Host: &sysinfo.Host{Hostname: "db1", KernelRelease: "6.1.0", Arch: "x86_64"}}
+	host := "Host:     db1\nKernel:   6.1.0 x86_64\n"
+	render := func() string {
+		var buf bytes.Buffer
+		printText(&buf, info, nil)
+		return buf.String()
+	}
+
+	// No file in the default place is no configuration.
+	configPath, configTitles, sectionTitles = "", nil, map[string]string{}
+	if err := loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if got := render(); got != host {
+		t.Errorf("without a config:\n%s\nwant:\n%s", got, host)
+	}
+
+	writeConfig(filepath.Join(home, "sysinfo", "config.json"), `{"section_titles": {"host": "== Machine =="}}`)
+	if err := loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := render(), "== Machine ==\n"+host; got != want {
+		t.Errorf("default config:\n%s\nwant:\n%s", got, want)
+	}
+
+	// --config replaces the default file and --section-title wins over both.
+	configPath = filepath.Join(t.TempDir(), "runbook.json")
+	writeConfig(configPath, `{"section_titles": {"host": "Node"}}`)
+	if err := loadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := render(), "Node\n"+host; got != want {
+		t.Errorf("--config:\n%s\nwant:\n%s", got, want)
+	}
+	sectionTitles = map[string]string{"host": "Server"}
+	if got, want := render(), "Server\n"+host; got != want {
+		t.Errorf("--section-title over --config:\n%s\nwant:\n%s", got, want)
+	}
+
+	// A file given explicitly must exist; a broken one names itself.
+	configPath = filepath.Join(t.TempDir(), "missing.json")
+	if err := loadConfig(); err == nil {
+		t.Error("missing --config file accepted")
+	}
+	configPath = filepath.Join(home, "sysinfo", "config.json")
+	writeConfig(configPath, `{"section_titles": {"host": 1}}`)
+	if err := loadConfig(); err == nil || !strings.Contains(err.Error(), configPath) {
+		t.Errorf("broken config error = %v, want it to name %s", err, configPath)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// reportChange is one difference between two reports. Path is a dotted
+// JSON path; array elements are addressed by their identifying field
+// (mountpoint, name, path, ...) in brackets, or by index without one.
+type reportChange struct {
+	Path  string   `json:"path"`
+	Kind  string   `json:"kind"` // "added", "removed" or "changed"
+	Old   any      `json:"old,omitempty"`
+	New   any      `json:"new,omitempty"`
+	Delta *float64 `json:"delta,omitempty"`
+}
+
+// elementKeys identify the elements of report arrays, in order of
+// preference.
+var elementKeys = []string{"Mountpoint", "name", "path", "device", "pid", "processor", "code", "chip"}
+
+// runDiff implements "sysinfo diff before.json after.json". It exits 0
+// when the reports match, 1 when they differ and 2 when one cannot be
+// read.
+func runDiff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	jsonOutput := flags.Bool("json", false, "output the changes as a JSON array")
+	ignore := flags.String("ignore", "", "comma-separated JSON paths (and everything under them) to leave out, e.g. uptime_seconds,loadavg")
+	flags.StringVar(&units, "units", units, "size units for mount free space: auto, bytes, si, iec")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "usage: sysinfo diff [--json] [--ignore paths] before.json after.json")
+		return 2
+	}
+	if !validUnits(units) {
+		fmt.Fprintf(os.Stderr, "unknown --units %q (want auto, bytes, si or iec)\n", units)
+		return 2
+	}
+	var reports [2]*sysinfo.SysInfo
+	for i, path := range flags.Args() {
+		snap, err := loadSnapshot(path, time.Time{})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "diff:", err)
+			return 2
+		}
+		reports[i] = snap.info
+	}
+	var ignored []string
+	if *ignore != "" {
+		ignored = strings.Split(*ignore, ",")
+	}
+	changes, err := diffReports(reports[0], reports[1], ignored)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "diff:", err)
+		return 2
+	}
+
+	if *jsonOutput {
+		if changes == nil {
+			changes = []reportChange{}
+		}
+		out, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "diff:", err)
+			return 2
+		}
+		fmt.Println(string(out))
+	} else {
+		printChanges(os.Stdout, changes)
+	}
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// diffReports compares every value of the two reports but the timestamp.
+// Mounts are compared by mountpoint, with the change of free space as the
+// delta.
+func diffReports(before, after *sysinfo.SysInfo, ignored []string) ([]reportChange, error) {
+	var flat [2]map[string]any
+	for i, info := range []*sysinfo.SysInfo{before, after} {
+		data, err := json.Marshal(info)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var v map[string]any
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		delete(v, "timestamp")
+		delete(v, "mounts")
+		flat[i] = make(map[string]any)
+		flattenJSON("", v, flat[i])
+	}
+
+	skip := func(path string) bool {
+		for _, p := range ignored {
+			p = strings.TrimSpace(p)
+			if path == p || strings.HasPrefix(path, p+".") || strings.HasPrefix(path, p+"[") {
+				return true
+			}
+		}
+		return false
+	}
+	var changes []reportChange
+	paths := slices.Sorted(maps.Keys(flat[0]))
+	for p := range flat[1] {
+		if _, ok := flat[0][p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(paths)
+	for _, p := range paths {
+		if skip(p) {
+			continue
+		}
+		old, inOld := flat[0][p]
+		cur, inNew := flat[1][p]
+		switch {
+		case !inOld:
+			changes = append(changes, reportChange{Path: p, Kind: "added", New: cur})
+		case !inNew:
+			changes = append(changes, reportChange{Path: p, Kind: "removed", Old: old})
+		case !reflect.DeepEqual(old, cur):
+			c := reportChange{Path: p, Kind: "changed", Old: old, New: cur}
+			if o, ok := old.(json.Number); ok {
+				if n, ok := cur.(json.Number); ok {
+					of, err1 := o.Float64()
+					nf, err2 := n.Float64()
+					if err1 == nil && err2 == nil {
+						d := nf - of
+						c.Delta = &d
+					}
+				}
+			}
+			changes = append(changes, c)
+		}
+	}
+	if !skip("mounts") {
+		changes = append(changes, diffMounts(before.Mounts, after.Mounts)...)
+	}
+	return changes, nil
+}
+
+func diffMounts(before, after []sysinfo.DiskInfo) []reportChange {
+	var changes []reportChange
+	old := make(map[string]sysinfo.DiskInfo)
+	for _, d := range before {
+		old[d.Mountpoint] = d
+	}
+	seen := make(map[string]bool)
+	for _, d := range after {
+		seen[d.Mountpoint] = true
+		path := "mounts[" + d.Mountpoint + "]"
+		o, ok := old[d.Mountpoint]
+		switch {
+		case !ok:
+			changes = append(changes, reportChange{Path: path, Kind: "added", New: d})
+		case o.Avail != d.Avail:
+			delta := float64(d.Avail) - float64(o.Avail)
+			changes = append(changes, reportChange{Path: path + ".Avail", Kind: "changed", Old: o.Avail, New: d.Avail, Delta: &delta})
+		}
+	}
+	for _, d := range before {
+		if !seen[d.Mountpoint] {
+			changes = append(changes, reportChange{Path: "mounts[" + d.Mountpoint + "]", Kind: "removed", Old: d})
+		}
+	}
+	return changes
+}
+
+// flattenJSON stores the scalar leaves of v under their paths. Arrays of
+// scalars are leaves as a whole.
+func flattenJSON(prefix string, v any, out map[string]any) {
+	join := func(k string) string {
+		if prefix == "" {
+			return k
+		}
+		return prefix + "." + k
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		for k, e := range t {
+			flattenJSON(join(k), e, out)
+		}
+	case []any:
+		if !slices.ContainsFunc(t, func(e any) bool { _, ok := e.(map[string]any); return ok }) {
+			out[prefix] = t
+			return
+		}
+		for i, e := range t {
+			key := fmt.Sprint(i)
+			if m, ok := e.(map[string]any); ok {
+				for _, k := range elementKeys {
+					if id, ok := m[k]; ok {
+						key = fmt.Sprint(id)
+						break
+					}
+				}
+			}
+			flattenJSON(prefix+"["+key+"]", e, out)
+		}
+	default:
+		out[prefix] = t
+	}
+}
+
+func printChanges(w io.Writer, changes []reportChange) {
+	for _, c := range changes {
+		switch {
+		case c.Kind == "added" && strings.HasPrefix(c.Path, "mounts["):
+			d := c.New.(sysinfo.DiskInfo)
+			fmt.Fprintf(w, "+ mount %s (%s, %s free of %s)\n", d.Mountpoint, d.FSType, formatSize(d.Avail), formatSize(d.Total))
+		case c.Kind == "removed" && strings.HasPrefix(c.Path, "mounts["):
+			fmt.Fprintf(w, "- mount %s\n", c.Old.(sysinfo.DiskInfo).Mountpoint)
+		case strings.HasPrefix(c.Path, "mounts["):
+			mountpoint := strings.TrimSuffix(strings.TrimPrefix(c.Path, "mounts["), "].Avail")
+			fmt.Fprintf(w, "~ mount %s free: %s -> %s (%s)\n", mountpoint, formatSize(c.Old.(uint64)),
+				formatSize(c.New.(uint64)), formatSignedSize(int64(*c.Delta)))
+		case c.Kind == "added":
+			fmt.Fprintf(w, "+ %s: %s\n", c.Path, formatJSONValue(c.New))
+		case c.Kind == "removed":
+			fmt.Fprintf(w, "- %s: %s\n", c.Path, formatJSONValue(c.Old))
+		default:
+			fmt.Fprintf(w, "~ %s: %s -> %s\n", c.Path, formatJSONValue(c.Old), formatJSONValue(c.New))
+		}
+	}
+}
+
+func formatJSONValue(v any) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	out, _ := json.Marshal(v)
+	return string(out)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// diffReport returns the path of a report under testdata/diff. before and
+// after differ in fd_count, uptime_seconds and the free space of /, and
+// /data was replaced by /backup; old_version is before as an older sysinfo
+// wrote it, with keys this one no longer knows.
+func diffReport(name string) string {
+	return filepath.Join("testdata", "diff", name+".json")
+}
+
+// runDiffOutput runs "sysinfo diff args" and returns its exit code and
+// standard output. --units is put back afterwards.
+func runDiffOutput(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	defer func(saved string) { units = saved }(units)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- data
+	}()
+	code := runDiff(args)
+	os.Stdout = stdout
+	w.Close()
+	return code, string(<-out)
+}
+
+func TestRunDiff(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{diffReport("before"), diffReport("before")}, 0, ""},
+		{[]string{diffReport("old_version"), diffReport("before")}, 0, ""},
+		{
+			[]string{diffReport("before"), diffReport("after")}, 1,
+			"~ fd_count: 120 -> 150\n" +
+				"~ uptime_seconds: 3600 -> 7200\n" +
+				"~ mount / free: 40 GiB -> 35 GiB (-5 GiB)\n" +
+				"+ mount /backup (nfs4, 1 TiB free of 2 TiB)\n" +
+				"- mount /data\n",
+		},
+		{
+			[]string{"--ignore", "uptime_seconds,mounts", diffReport("before"), diffReport("after")}, 1,
+			"~ fd_count: 120 -> 150\n",
+		},
+		{[]string{"--ignore", "fd_count,uptime_seconds,mounts", diffReport("before"), diffReport("after")}, 0, ""},
+		{[]string{"--json", diffReport("before"), diffReport("before")}, 0, "[]\n"},
+		{[]string{diffReport("before"), diffReport("broken")}, 2, ""},
+		{[]string{diffReport("before"), diffReport("missing")}, 2, ""},
+		{[]string{diffReport("before")}, 2, ""},
+		{[]string{"--units", "furlongs", diffReport("before"), diffReport("after")}, 2, ""},
+	}
+	for _, tt := range tests {
+		code, out := runDiffOutput(t, tt.args...)
+		if code != tt.code || out != tt.want {
+			t.Errorf("diff %q exited %d with\n%s\nwant %d with\n%s", tt.args, code, out, tt.code, tt.want)
+		}
+	}
+}
+
+func TestRunDiffJSON(t *testing.T) {
+	code, out := runDiffOutput(t, "--json", diffReport("before"), diffReport("after"))
+	if code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	var changes []struct {
+		Path  string          `json:"path"`
+		Kind  string          `json:"kind"`
+		Old   json.RawMessage `json:"old"`
+		New   json.RawMessage `json:"new"`
+		Delta *float64        `json:"delta"`
+	}
+	if err := json.Unmarshal([]byte(out), &changes); err != nil {
+		t.Fatalf("%v in\n%s", err, out)
+	}
+	type change struct {
+		path, kind string
+		delta      float64
+	}
+	var got []change
+	for _, c := range changes {
+		var d float64
+		if c.Delta != nil {
+			d = *c.Delta
+		}
+		got = append(got, change{c.Path, c.Kind, d})
+	}
+	want := []change{
+		{"fd_count", "changed", 30},
+		{"uptime_seconds", "changed", 3600},
+		{"mounts[/].Avail", "changed", -5 << 30},
+		{"mounts[/backup]", "added", 0},
+		{"mounts[/data]", "removed", 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %+v, want %+v", got, want)
+	}
+	// Added and removed mounts carry the whole mount, not just its name.
+	var added sysinfo.DiskInfo
+	if err := json.Unmarshal(changes[3].New, &added); err != nil || added.Device != "nas:/backup" || changes[3].Old != nil {
+		t.Errorf("added mount = %s, old %s (%v)", changes[3].New, changes[3].Old, err)
+	}
+	var removed sysinfo.DiskInfo
+	if err := json.Unmarshal(changes[4].Old, &removed); err != nil || removed.FSType != "xfs" || changes[4].New != nil {
+		t.Errorf("removed mount = %s, new %s (%v)", changes[4].Old, changes[4].New, err)
+	}
+}
+
+func TestDiffReports(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after *sysinfo.SysInfo
+		ignored       []string
+		want          []reportChange
+	}{
+		{"equal", &sysinfo.SysInfo{CPUCores: 4}, &sysinfo.SysInfo{CPUCores: 4}, nil, nil},
+		{
+			"numbers get a delta, strings do not",
+			&sysinfo.SysInfo{CPUCores: 4, Hostname: "db1"},
+			&sysinfo.SysInfo{CPUCores: 2, Hostname: "db2"},
+			nil,
+			[]reportChange{
+				{Path: "cpu_cores", Kind: "changed", Old: json.Number("4"), New: json.Number("2"), Delta: ptr[float64](-2)},
+				{Path: "hostname", Kind: "changed", Old: "db1", New: "db2"},
+			},
+		},
+		{
+			"sections come and go",
+			&sysinfo.SysInfo{LoadAvg: &sysinfo.LoadAvg{One: 1}},
+			&sysinfo.SysInfo{Kernel: "6.1.0"},
+			[]string{"loadavg.load5", "loadavg.load15", "loadavg.running_procs", "loadavg.total_procs"},
+			[]reportChange{
+				{Path: "kernel", Kind: "added", New: "6.1.0"},
+				{Path: "loadavg.load1", Kind: "removed", Old: json.Number("1")},
+			},
+		},
+		{
+			"array elements by their key",
+			&sysinfo.SysInfo{Network: &sysinfo.Network{Interfaces: []sysinfo.NetInterface{{Name: "eth0", MTU: 1500}}}},
+			&sysinfo.SysInfo{Network: &sysinfo.Network{Interfaces: []sysinfo.NetInterface{{Name: "eth0", MTU: 9000}}}},
+			nil,
+			[]reportChange{
+				{Path: "network.interfaces[eth0].mtu", Kind: "changed", Old: json.Number("1500"), New: json.Number("9000"), Delta: ptr[float64](7500)},
+			},
+		},
+		{
+			"the timestamp is never a change",
+			&sysinfo.SysInfo{Timestamp: ptr(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))},
+			&sysinfo.SysInfo{Timestamp: ptr(time.Date(2026, 3, 1, 13, 0, 0, 0, time.UTC))},
+			nil,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		got, err := diffReports(tt.before, tt.after, tt.ignored)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: changes\n%+v\nwant\n%+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiffMounts(t *testing.T) {
+	root := sysinfo.DiskInfo{Mountpoint: "/", Total: 100, Avail: 40}
+	data := sysinfo.DiskInfo{Mountpoint: "/data", Total: 500, Avail: 10}
+	fuller := root
+	fuller.Avail, fuller.UsedPercent = 25, 75
+	relabelled := data
+	relabelled.Device, relabelled.Options = "/dev/sdc1", []string{"ro"}
+	tests := []struct {
+		name          string
+		before, after []sysinfo.DiskInfo
+		want          []reportChange
+	}{
+		{"unchanged", []sysinfo.DiskInfo{root, data}, []sysinfo.DiskInfo{data, root}, nil},
+		// Only the available space is compared.
+		{"other fields", []sysinfo.DiskInfo{data}, []sysinfo.DiskInfo{relabelled}, nil},
+		{"less free", []sysinfo.DiskInfo{root}, []sysinfo.DiskInfo{fuller}, []reportChange{
+			{Path: "mounts[/].Avail", Kind: "changed", Old: uint64(40), New: uint64(25), Delta: ptr[float64](-15)},
+		}},
+		{"more free", []sysinfo.DiskInfo{fuller}, []sysinfo.DiskInfo{root}, []reportChange{
+			{Path: "mounts[/].Avail", Kind: "changed", Old: uint64(25), New: uint64(40), Delta: ptr[float64](15)},
+		}},
+		{"added", nil, []sysinfo.DiskInfo{data}, []reportChange{{Path: "mounts[/data]", Kind: "added", New: data}}},
+		{"removed", []sysinfo.DiskInfo{root, data}, []sysinfo.DiskInfo{root}, []reportChange{{Path: "mounts[/data]", Kind: "removed", Old: data}}},
+	}
+	for _, tt := range tests {
+		if got := diffMounts(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: changes\n%+v\nwant\n%+v", tt.name, got, tt.want)
+		}
+	}
+}
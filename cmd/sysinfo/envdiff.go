@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// runEnvDiff implements "sysinfo env-diff": it compares the environment of
+// a running process against the current one or an --expected file.
+func runEnvDiff(args []string) int {
+	flags := flag.NewFlagSet("env-diff", flag.ExitOnError)
+	pid := flags.Int("pid", 0, "process whose environment is inspected (required)")
+	expectedFile := flags.String("expected", "", "file with expected KEY=value lines (default: current environment)")
+	showEnv := flags.Bool("show-env", false, "print values without redaction")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "env-diff: unexpected arguments %q\n", flags.Args())
+		return 2
+	}
+	// Without --pid the environment of pid 0, which does not exist, would
+	// be read: an error that does not say what is missing.
+	if *pid <= 0 {
+		fmt.Fprintln(os.Stderr, "env-diff: --pid is required")
+		return 2
+	}
+
+	actual, err := sysinfo.ReadEnviron("", *pid)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "env-diff:", err)
+		return 1
+	}
+	expected := sysinfo.ParseEnv(os.Environ())
+	if *expectedFile != "" {
+		expected, err = sysinfo.ReadEnvFile(*expectedFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "env-diff:", err)
+			return 1
+		}
+	}
+
+	changes := sysinfo.DiffEnv(actual, expected, *showEnv)
+	if *jsonOutput {
+		if changes == nil {
+			changes = []sysinfo.EnvChange{}
+		}
+		out, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "JSON marshal error:", err)
+			return 1
+		}
+		fmt.Println(string(out))
+		return 0
+	}
+	for _, c := range changes {
+		switch c.Kind {
+		case "added":
+			fmt.Printf("+ %s=%s\n", c.Key, c.New)
+		case "removed":
+			fmt.Printf("- %s=%s\n", c.Key, c.Old)
+		case "changed":
+			fmt.Printf("~ %s: %s -> %s\n", c.Key, c.Old, c.New)
+		}
+	}
+	return 0
+}
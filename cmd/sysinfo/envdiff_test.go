@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestRunEnvDiffUsage(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"--pid", "0"},
+		{"--pid", "-1"},
+		{"--expected", "expected.env"},
+		{"--pid", "1", "extra"},
+	} {
+		if code := runEnvDiff(args); code != 2 {
+			t.Errorf("env-diff %q exited %d, want 2", args, code)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// snapshot is a report read back from a JSON file; keys are the top-level
+// JSON keys it has, so renderers and checks can tell a section that was
+// never collected from an empty one.
+type snapshot struct {
+	info *sysinfo.SysInfo
+	keys map[string]bool
+}
+
+// loadSnapshot reads a single JSON report or an NDJSON history ("-" for
+// stdin) and returns the report whose timestamp is closest to at, or the
+// last one when at is zero. Findings are derived again, so a history is
+// judged by the rules of this version.
+func loadSnapshot(path string, at time.Time) (*snapshot, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var best *snapshot
+	var bestDist time.Duration
+	for n := 1; ; n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: report %d: %w", path, n, err)
+		}
+		s := &snapshot{info: &sysinfo.SysInfo{}}
+		var keys map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &keys); err != nil {
+			return nil, fmt.Errorf("%s: report %d: %w", path, n, err)
+		}
+		if err := json.Unmarshal(raw, s.info); err != nil {
+			return nil, fmt.Errorf("%s: report %d: %w", path, n, err)
+		}
+		s.keys = make(map[string]bool, len(keys))
+		for k := range keys {
+			s.keys[k] = true
+		}
+		if at.IsZero() {
+			best = s
+			continue
+		}
+		if s.info.Timestamp == nil {
+			continue
+		}
+		dist := s.info.Timestamp.Sub(at).Abs()
+		if best == nil || dist < bestDist {
+			best, bestDist = s, dist
+		}
+	}
+	if best == nil {
+		if at.IsZero() {
+			return nil, fmt.Errorf("%s: no reports", path)
+		}
+		return nil, fmt.Errorf("%s: no report has a timestamp (only --watch reports do)", path)
+	}
+	best.info.Findings = sysinfo.Analyze(best.info)
+	best.keys["findings"] = true
+	return best, nil
+}
+
+// inputFlags defines --input and --at on fs and returns a loader for the
+// selected snapshot.
+func inputFlags(fs *flag.FlagSet) func() (*snapshot, error) {
+	input := fs.String("input", "", "JSON report or NDJSON history to read instead of collecting (- for stdin)")
+	at := fs.String("at", "", "with --input, use the report closest to this RFC 3339 time instead of the last one")
+	return func() (*snapshot, error) {
+		if *input == "" {
+			if *at != "" {
+				return nil, errors.New("--at requires --input")
+			}
+			return nil, nil
+		}
+		var t time.Time
+		if *at != "" {
+			var err error
+			if t, err = time.Parse(time.RFC3339, *at); err != nil {
+				return nil, fmt.Errorf("--at: %w", err)
+			}
+		}
+		return loadSnapshot(*input, t)
+	}
+}
+
+// runRender re-renders a stored report:
+//
+//	sysinfo render --input history.ndjson --at 2024-06-01T12:00:00Z
+func runRender(args []string) int {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	load := inputFlags(flags)
+	format := flags.String("format", "text", "output format: "+formatNames()+" or motd")
+	plain := flags.Bool("plain", false, "motd: no ANSI colors")
+	noColor := flags.Bool("no-color", false, "text: no ANSI colors")
+	flags.StringVar(&units, "units", units, "size units in text mode: auto, bytes, si, iec")
+	flags.BoolVar(&longTable, "long", false, "text: add the mount options to the mounts table")
+	flags.BoolVar(&explainOutput, "explain", false, "text: end with how the page cache and memory peak figures were derived")
+	sectionFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "render: unexpected arguments %q\n", flags.Args())
+		return 2
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "render:", err)
+		return 2
+	}
+	if !validUnits(units) {
+		fmt.Fprintf(os.Stderr, "unknown --units %q (want auto, bytes, si or iec)\n", units)
+		return 2
+	}
+	if *format != "motd" && formats[*format] == nil {
+		fmt.Fprintf(os.Stderr, "render: unknown --format %q (want %s or motd)\n", *format, formatNames())
+		return 2
+	}
+	snap, err := load()
+	if err == nil && snap == nil {
+		err = errors.New("--input is required")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "render:", err)
+		return 2
+	}
+
+	if *format == "motd" {
+		printMotd(os.Stdout, snap.info, *plain)
+		return 0
+	}
+	// Only the sections the report has are shown, as if it had been
+	// collected with --fields.
+	selectedFields = snap.keys
+	colorOutput = useColor(os.Stdout, *noColor)
+	if err := formats[*format](nil, false).render(os.Stdout, snap.info, nil); err != nil {
+		fmt.Fprintln(os.Stderr, "render:", err)
+		return 1
+	}
+	return 0
+}
+
+// runCheckCommand evaluates the --check thresholds against a stored report
+// with --input, or against a fresh collection without it:
+//
+//	sysinfo check --input snapshot.json --min-disk-free 10% --mount /
+//	sysinfo check --input snapshot.json --check disk:/:90%
+func runCheckCommand(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	load := inputFlags(flags)
+	var specs checkSpecs
+	flags.Var(&specs, "check", "a kind:target:threshold check (repeatable): disk:/:90% (used_percent), fd:90% or mem:95%")
+	parseThresholds := thresholdFlags(flags, &specs)
+	verbose := flags.Bool("verbose", false, "also print the checks that passed")
+	flags.Parse(args)
+	t, err := parseThresholds()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "check:", err)
+		return 2
+	}
+	snap, err := load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "check:", err)
+		return 2
+	}
+	if snap == nil {
+		return runCheck(sysinfo.Options{}, t, *verbose)
+	}
+	for _, key := range t.checkFields() {
+		// Cgroup sections are optional: memory falls back to the host.
+		if !snap.keys[key] && key != "cgroup_v1" && key != "cgroup_v2" {
+			fmt.Fprintln(os.Stderr, "sysinfo: check", &errCheckData{"snapshot", key, "not in the report"})
+			return 2
+		}
+	}
+	results, err := evaluateChecks(snap.info, t)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sysinfo: check", err)
+		return 2
+	}
+	return printChecks(os.Stdout, results, *verbose)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// runInventory implements "sysinfo inventory": the static hardware, OS,
+// storage and network identity of the machine, for asset management.
+// Serials are left out unless --show-serials is given; --anonymize hashes
+// the identifiers that remain, serials included.
+func runInventory(args []string) int {
+	flags := flag.NewFlagSet("inventory", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text, json or yaml")
+	jsonOutput := flags.Bool("json", false, "same as --format json")
+	showSerials := flags.Bool("show-serials", false, "include serial numbers, UUIDs and WWNs (DMI ones need root)")
+	anonymize := flags.Bool("anonymize", false, "replace host name, machine-id, MACs and shown serials with hashes salted by the machine-id")
+	flags.StringVar(&units, "units", units, "size units in text mode: auto, bytes, si, iec")
+	flags.Parse(args)
+	if *jsonOutput {
+		*format = "json"
+	}
+	if *format != "text" && *format != "json" && *format != "yaml" {
+		fmt.Fprintf(os.Stderr, "inventory: unknown --format %q (want text, json or yaml)\n", *format)
+		return 2
+	}
+	if !validUnits(units) {
+		fmt.Fprintf(os.Stderr, "unknown --units %q (want auto, bytes, si or iec)\n", units)
+		return 2
+	}
+
+	inv, collectErr := sysinfo.CollectInventory(context.Background(), "")
+	if !*showSerials {
+		inv.HideSerials()
+	}
+	if *anonymize {
+		inv.Anonymize()
+	}
+	if *format == "text" {
+		printInventory(os.Stdout, inv)
+	} else {
+		out, err := json.MarshalIndent(inv, "", "  ")
+		if err == nil && *format == "yaml" {
+			out, err = jsonToYAML(out)
+		} else {
+			out = append(out, '\n')
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "inventory:", err)
+			return 1
+		}
+		os.Stdout.Write(out)
+	}
+	if collectErr != nil {
+		fmt.Fprintln(os.Stderr, "inventory:", collectErr)
+		return 1
+	}
+	return 0
+}
+
+func printInventory(out io.Writer, inv *sysinfo.Inventory) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	if h := inv.Host; h != nil {
+		fmt.Fprintln(w, "Hostname:\t", h.Hostname)
+		if h.OS != nil {
+			fmt.Fprintln(w, "OS:\t", h.OS.PrettyName)
+		}
+		fmt.Fprintln(w, "Kernel:\t", strings.TrimSpace(h.KernelRelease+" "+h.Arch))
+	}
+	if inv.MachineID != "" {
+		fmt.Fprintln(w, "Machine ID:\t", inv.MachineID)
+	}
+	if d := inv.Hardware; d != nil {
+		fmt.Fprintln(w, "System:\t", strings.TrimSpace(d.SysVendor+" "+d.ProductName+" "+d.ProductVersion))
+		fmt.Fprintln(w, "Board:\t", strings.TrimSpace(d.BoardVendor+" "+d.BoardName))
+		if d.ChassisType != "" {
+			fmt.Fprintln(w, "Chassis:\t", d.ChassisType)
+		}
+		fmt.Fprintln(w, "BIOS:\t", strings.TrimSpace(d.BIOSVendor+" "+d.BIOSVersion+" "+d.BIOSDate))
+		for _, s := range []struct{ label, value string }{
+			{"System serial", d.ProductSerial}, {"System UUID", d.ProductUUID},
+			{"Board serial", d.BoardSerial}, {"Chassis serial", d.ChassisSerial},
+		} {
+			if s.value != "" {
+				fmt.Fprintln(w, s.label+":\t", s.value)
+			}
+		}
+	}
+	if c := inv.CPU; c != nil {
+		fmt.Fprintf(w, "CPU:\t %s (%d sockets, %d cores, %d threads)\n", c.Model, c.Sockets, c.PhysicalCores, c.LogicalCores)
+	}
+	if m := inv.Memory; m != nil {
+		fmt.Fprintln(w, "Memory:\t", formatSize(m.TotalBytes))
+		if m.Slots > 0 {
+			fmt.Fprintf(w, "DIMMs:\t %d of %d slots, %s installed\n", len(m.DIMMs), m.Slots, formatSize(m.InstalledBytes))
+		}
+		for _, d := range m.DIMMs {
+			desc := strings.Join(strings.Fields(fmt.Sprintf("%s %s %s", formatSize(d.SizeBytes), d.Type, d.Manufacturer+" "+d.PartNumber)), " ")
+			if d.SpeedMTs > 0 {
+				desc += fmt.Sprintf(", %d MT/s", d.SpeedMTs)
+			}
+			if d.Serial != "" {
+				desc += ", serial " + d.Serial
+			}
+			fmt.Fprintf(w, "  %s:\t %s\n", d.Locator, desc)
+		}
+	}
+	if len(inv.BlockDevices) > 0 {
+		fmt.Fprintln(w, "Disks:")
+		for _, d := range inv.BlockDevices {
+			kind := "ssd"
+			if d.Rotational {
+				kind = "hdd"
+			}
+			if d.Removable {
+				kind += ", removable"
+			}
+			desc := strings.Join(strings.Fields(d.Vendor+" "+d.Model), " ")
+			fmt.Fprintf(w, "  %s:\t %s %s (%s)", d.Name, formatSize(d.SizeBytes), desc, kind)
+			if d.Serial != "" {
+				fmt.Fprint(w, " serial ", d.Serial)
+			}
+			if d.WWN != "" {
+				fmt.Fprint(w, " wwn ", d.WWN)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if len(inv.Interfaces) > 0 {
+		fmt.Fprintln(w, "NICs:")
+		for _, ni := range inv.Interfaces {
+			fmt.Fprintf(w, "  %s:\t %s %s\n", ni.Name, ni.MAC, ni.Driver)
+		}
+	}
+	for _, field := range slices.Sorted(maps.Keys(inv.Errors)) {
+		fmt.Fprintf(w, "%s:\t unavailable (%s)\n", field, inv.Errors[field])
+	}
+}
@@ -0,0 +1,545 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "motd":
+			os.Exit(runMotd(os.Args[2:]))
+		case "env-diff":
+			os.Exit(runEnvDiff(os.Args[2:]))
+		case "render":
+			os.Exit(runRender(os.Args[2:]))
+		case "check":
+			os.Exit(runCheckCommand(os.Args[2:]))
+		case "inventory":
+			os.Exit(runInventory(os.Args[2:]))
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
+		case "ps":
+			os.Exit(runPs(os.Args[2:]))
+		}
+	}
+
+	var jsonOutput = flag.Bool("json", false, "deprecated: use --format json")
+	var yamlOutput = flag.Bool("yaml", false, "same as --format yaml")
+	var promOutput = flag.Bool("prometheus", false, "same as --format prometheus (for the node_exporter textfile collector)")
+	var streamOutput = flag.Bool("stream", false, "stream JSON output section by section")
+	var schema = flag.Bool("schema", false, "print a JSON Schema of the JSON report and exit")
+	var showVersion = flag.Bool("version", false, "print the version, commit, build date and Go version and exit")
+	var irqDetail = flag.Bool("irq-detail", false, "include per-CPU softirq counts and the busiest IRQs")
+	var pid = flag.Int("pid", 0, "inspect this process instead of self")
+	var rssSample = flag.Duration("rss-sample", 0, "sample VmRSS over this interval and report its growth rate")
+	flag.Bool("strict", false, "deprecated: any collection failure already exits non-zero")
+	var cpuTime = flag.Bool("cpu-time", false, "report user/system CPU time of the process and its children")
+	var cpuSample = flag.Duration("cpu-sample", 200*time.Millisecond, "sample /proc/stat twice this far apart for the CPU usage percentage; 0 skips it")
+	var threads = flag.Bool("threads", false, "group the threads of the process by name, with CPU time and the nproc and pids.max limits (every thread in JSON)")
+	var children = flag.Bool("children", false, "also report the process tree under --pid: per-process state, RSS, threads and fds plus totals")
+	var fds = flag.Bool("fds", false, "list open file descriptors with their type and target (sockets resolved to endpoints)")
+	flag.BoolVar(fds, "list-fds", false, "alias for --fds")
+	var configFiles = flag.Bool("config-files", false, "report size, mtime, sha256 prefix and symlink target of resolv.conf, hosts, nsswitch.conf, fstab and os-release")
+	var sched = flag.Bool("sched", false, "report voluntary/nonvoluntary context switches of the process")
+	var delta = flag.Duration("delta", 0, "sample counters twice this far apart and report per-second rates")
+	var schedFeatures = flag.Bool("schedfeatures", false, "report scheduler features from debugfs (requires root)")
+	var rawCounters = flag.Bool("raw-counters", false, "include the raw cumulative counters (*_total) behind computed values in JSON")
+	var fieldList = flag.String("fields", "", "comma-separated JSON keys or aliases (cgroup, mem, fd, disk, load, net) to collect and output")
+	var memoryDetail = flag.Bool("memory-detail", false, "PSS, shared/private and swapped memory of the process from /proc/<pid>/smaps_rollup")
+	var agents = flag.Bool("agents", false, "list running observability agents (node_exporter, telegraf, ...) with their versions")
+	var containers = flag.Bool("containers", false, "attribute CPU time to containers by cgroup over the --sample window")
+	var sample = flag.Duration("sample", time.Second, "sampling window for --containers; when given, also for the cgroup CPU quota utilization, CPU frequency throttling and --disk-health latency")
+	var sensors = flag.Bool("sensors", false, "report hwmon temperatures with their max and crit thresholds (also enabled by --fields sensors)")
+	var diskIOSample = flag.Duration("disk-io-sample", 0, "read /proc/diskstats twice this far apart for per-second disk I/O rates; 0 reports only the counters")
+	var diskIOAll = flag.Bool("disk-io-all", false, "report I/O of partitions, loop and ram devices too, not only whole disks")
+	var diskHealth = flag.Bool("disk-health", false, "heuristic per-disk health from kernel error counters (not SMART); with --sample also the average I/O latency")
+	var listen = flag.String("listen", "", "serve the report over HTTP on this address (e.g. :9100): /sysinfo, /metrics, /healthz")
+	flag.StringVar(listen, "serve", "", "alias for --listen")
+	var timeout = flag.Duration("timeout", 10*time.Second, "per-request collection timeout for --listen")
+	var security = flag.Bool("security", false, "also list setuid/setgid binaries and files with capabilities under --security-dirs")
+	var securityDirs = flag.String("security-dirs", strings.Join(sysinfo.DefaultPrivilegedDirs, ":"), "colon-separated directories scanned by --security")
+	var isolation = flag.Bool("isolation", false, "show per-CPU isolation (isolcpus, nohz_full, rcu_nocbs, process cpuset, IRQ affinity)")
+	var allMounts = flag.Bool("all-mounts", false, "list every mount, including pseudo filesystems and duplicate bind mounts")
+	var fsTypes = flag.String("fstype", "", "comma-separated filesystem types to list (e.g. ext4,xfs)")
+	var mountTimeout = flag.Duration("mount-timeout", 2*time.Second, "give up on a mount whose stat/statfs takes longer (0 waits forever)")
+	var skipFSTypes = flag.String("skip-fstype", "", "comma-separated filesystem types to skip besides the pseudo filesystems skipped by default (a trailing * matches a prefix)")
+	var skipNetworkFS = flag.Bool("skip-network-fs", false, "do not stat network filesystems (nfs, cifs, ceph, fuse.sshfs, ...)")
+	var format = flag.String("format", "", "output format: text, json, yaml, prometheus or csv-stream (a header, then one row per --watch interval)")
+	var only = flag.String("only", "", "columns for --format csv-stream: mem, load, fd, disk:<mountpoint> or dotted JSON paths (e.g. memory.cached_bytes)")
+	flag.StringVar(&outputPath, "output", "", "write the report to this file, replacing it atomically, instead of stdout")
+	flag.StringVar(&outputPath, "o", "", "shorthand for --output")
+	var checkSpecs checkSpecs
+	check := checkSwitch{specs: &checkSpecs}
+	flag.Var(&check, "check", "evaluate the --max-*/--min-* thresholds and exit 1 if one is violated (2 if its data could not be collected); "+
+		"--check=kind:target:threshold (repeatable) adds a check: disk:/:90%, fd:90% or mem:95%")
+	parseThresholds := thresholdFlags(flag.CommandLine, &checkSpecs)
+	var verbose = flag.Bool("verbose", false, "--check: also print the checks that passed")
+	var watchInterval = flag.Duration("watch", 0, "re-collect and reprint the report at this interval (e.g. 2s)")
+	flag.StringVar(&units, "units", units, "size units in text mode: auto, bytes, si, iec")
+	flag.StringVar(&mountSort, "sort", mountSort, "order of the mounts, also in JSON and YAML: mountpoint, total, free, used or used_percent (sizes largest first); prefix - for descending or + for ascending (default: as in /proc/mounts)")
+	flag.IntVar(&mountTop, "top", 0, "show only the first N mounts after --sort (in JSON and YAML too when given)")
+	flag.BoolVar(&longTable, "long", false, "add the mount options (ro, noexec, nosuid, ...) to the mounts table")
+	flag.BoolVar(&explainOutput, "explain", false, "end the text report with how the page cache and memory peak figures were derived")
+	flag.BoolVar(&noLoopback, "no-loopback", false, "hide loopback interfaces in the text table")
+	sectionFlags(flag.CommandLine)
+	var noColor = flag.Bool("no-color", false, "no ANSI colors in the text report (also with NO_COLOR set, or when stdout is not a terminal)")
+	flag.BoolVar(&allInterfaces, "all-interfaces", false, "also list interfaces that are down in the text table")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [pid]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	colorOutput = outputPath == "" && useColor(os.Stdout, *noColor)
+	if *showVersion {
+		printVersion(os.Stdout)
+		return
+	}
+	if *schema {
+		out, err := sysinfo.JSONSchema()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "sysinfo:", err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(append(out, '\n'))
+		return
+	}
+	if flag.NArg() > 0 {
+		n, err := strconv.Atoi(flag.Arg(0))
+		if err != nil || n <= 0 || flag.NArg() > 1 {
+			fmt.Fprintf(os.Stderr, "unexpected arguments %q (want a single pid)\n", flag.Args())
+			os.Exit(2)
+		}
+		if *pid != 0 && *pid != n {
+			fmt.Fprintln(os.Stderr, "both --pid and a positional pid given")
+			os.Exit(2)
+		}
+		*pid = n
+	}
+	if mountSortFunc(mountSort) == nil {
+		fmt.Fprintf(os.Stderr, "unknown --sort %q (want %s, optionally prefixed with - or +)\n", mountSort, mountSortNames())
+		os.Exit(2)
+	}
+	if !validUnits(units) {
+		fmt.Fprintf(os.Stderr, "unknown --units %q (want auto, bytes, si or iec)\n", units)
+		os.Exit(2)
+	}
+	if err := loadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "sysinfo:", err)
+		os.Exit(2)
+	}
+	opts := sysinfo.Options{
+		PID:            *pid,
+		RSSSample:      *rssSample,
+		IRQDetail:      *irqDetail,
+		CPUTime:        *cpuTime,
+		Sched:          *sched,
+		Delta:          *delta,
+		SchedFeatures:  *schedFeatures,
+		RawCounters:    *rawCounters,
+		Isolation:      *isolation,
+		FDs:            *fds,
+		ConfigFiles:    *configFiles,
+		DiskHealth:     *diskHealth,
+		DiskIOSample:   *diskIOSample,
+		DiskIOAll:      *diskIOAll,
+		Children:       *children,
+		Threads:        *threads,
+		CPUSample:      *cpuSample,
+		Sensors:        *sensors,
+		Agents:         *agents,
+		MemoryDetail:   *memoryDetail,
+		PrivilegedScan: *security,
+		PrivilegedDirs: filepath.SplitList(*securityDirs),
+		Mounts: sysinfo.MountOptions{
+			All:           *allMounts,
+			SkipNetworkFS: *skipNetworkFS,
+			Timeout:       *mountTimeout,
+		},
+	}
+	if *fsTypes != "" {
+		opts.Mounts.FSTypes = strings.Split(*fsTypes, ",")
+	}
+	if *skipFSTypes != "" {
+		opts.Mounts.SkipFSTypes = append(slices.Clone(sysinfo.DefaultSkipFSTypes), strings.Split(*skipFSTypes, ",")...)
+	}
+	if *containers {
+		opts.ContainerCPU = *sample
+	}
+	flag.Visit(func(f *flag.Flag) {
+		// The default window would slow every run down.
+		if f.Name == "sample" {
+			opts.CgroupCPU = *sample
+			opts.ThrottleSample = *sample
+			if *diskHealth {
+				opts.DiskLatency = *sample
+			}
+		}
+	})
+	if *fieldList != "" {
+		var err error
+		if opts.Fields, err = sysinfo.ExpandFields(strings.Split(*fieldList, ",")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		selectedFields = make(map[string]bool)
+		for _, f := range opts.Fields {
+			selectedFields[f] = true
+		}
+	}
+	formatName := *format
+	var shorthands []string
+	for name, set := range map[string]bool{"json": *jsonOutput, "yaml": *yamlOutput, "prometheus": *promOutput} {
+		if set {
+			shorthands = append(shorthands, name)
+		}
+	}
+	switch {
+	case len(shorthands) > 1:
+		fmt.Fprintln(os.Stderr, "--json, --yaml and --prometheus are mutually exclusive")
+		os.Exit(2)
+	case len(shorthands) == 1 && (formatName == "" || formatName == shorthands[0]):
+		formatName = shorthands[0]
+	case len(shorthands) == 1:
+		fmt.Fprintf(os.Stderr, "--%s cannot be combined with --format %s\n", shorthands[0], formatName)
+		os.Exit(2)
+	case formatName == "":
+		formatName = "text"
+	}
+	if *streamOutput {
+		// The stream is JSON, with the mounts written in /proc/mounts
+		// order as they are statted: sorting them would mean holding them
+		// all.
+		switch {
+		case *format != "" && formatName != "json" || len(shorthands) == 1 && shorthands[0] != "json":
+			fmt.Fprintf(os.Stderr, "--stream writes JSON; it cannot be combined with --format %s\n", formatName)
+			os.Exit(2)
+		case mountSort != "" || mountTop > 0:
+			fmt.Fprintln(os.Stderr, "--stream writes mounts as they are statted; it cannot be combined with --sort or --top")
+			os.Exit(2)
+		}
+		formatName = "json"
+	}
+	if outputPath != "" && formatName == "csv-stream" {
+		fmt.Fprintln(os.Stderr, "--output cannot be combined with --format csv-stream; redirect stdout instead")
+		os.Exit(2)
+	}
+	var columns []column
+	newRenderer := func(watching bool) renderer {
+		if formatName == "csv-stream" {
+			return &csvStream{cols: columns, clk: clock.Real}
+		}
+		// With --output each --watch report replaces the file, so there
+		// is no screen to clear.
+		return formats[formatName](opts.Fields, watching && outputPath == "")
+	}
+	if formatName == "csv-stream" {
+		var keys []string
+		var err error
+		if columns, keys, err = parseColumns(*only); err != nil {
+			fmt.Fprintln(os.Stderr, "--only:", err)
+			os.Exit(2)
+		}
+		if opts.Fields == nil {
+			opts.Fields = keys
+		}
+	} else if formats[formatName] == nil {
+		fmt.Fprintf(os.Stderr, "unknown --format %q (want %s)\n", formatName, formatNames())
+		os.Exit(2)
+	} else if *only != "" {
+		fmt.Fprintln(os.Stderr, "--only requires --format csv-stream")
+		os.Exit(2)
+	}
+	if check.on {
+		t, err := parseThresholds()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		os.Exit(runCheck(opts, t, *verbose))
+	}
+	if *listen != "" {
+		if err := serve(*listen, opts, *timeout, *streamOutput); err != nil {
+			fmt.Fprintln(os.Stderr, "sysinfo:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *watchInterval != 0 {
+		if *watchInterval < minWatchInterval {
+			fmt.Fprintln(os.Stderr, "--watch interval must be at least", minWatchInterval)
+			os.Exit(2)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		opts.Clock = clock.Real
+		c, err := sysinfo.New(opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "sysinfo:", err)
+			os.Exit(2)
+		}
+		defer c.Close()
+		exitOnRenderError(watch(ctx, clock.Real, c, *watchInterval, newRenderer(true), os.Stdout, os.Stderr))
+		return
+	}
+	if *streamOutput {
+		opts.Indent = "  "
+		var collectErr error
+		write := func(w io.Writer) error {
+			collectErr = sysinfo.EncodeJSON(context.Background(), w, opts)
+			for _, e := range splitErrors(collectErr) {
+				var fe *sysinfo.FieldError
+				if !errors.As(e, &fe) {
+					return e
+				}
+			}
+			return nil
+		}
+		// With --output the report goes straight into the temporary file
+		// that replaces the old one.
+		var err error
+		if outputPath != "" {
+			err = writeAtomic(outputPath, write)
+		} else {
+			err = write(os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "JSON stream error:", err)
+			os.Exit(1)
+		}
+		os.Exit(reportErrors(collectErr))
+	}
+
+	info, collectErr := sysinfo.CollectWith(opts)
+
+	renderOrExit(newRenderer(false), info, nil)
+	os.Exit(reportErrors(collectErr))
+}
+
+const minWatchInterval = 100 * time.Millisecond
+
+// watch reprints the report every interval until ctx is cancelled, passing
+// r the new report and the previous one. Text mode clears the screen
+// first and shows changes since the previous sample; JSON mode emits one
+// timestamped object per line and csv-stream one row. A SIGINT is only
+// observed between iterations, so output is never cut off halfway through a
+// flush. When the session ends a summary of RSS growth by type goes to
+// errOut. clk must be the clock c was created with. A failure to write a
+// report ends the loop and is returned.
+func watch(ctx context.Context, clk clock.Clock, c *sysinfo.Collector, interval time.Duration, r renderer, out, errOut io.Writer) error {
+	ticker := clk.NewTicker(interval)
+	defer ticker.Stop()
+	var first, prev *sysinfo.RSSBreakdown
+	var last *sysinfo.SysInfo
+	var samples int
+	for {
+		// Not ctx: the iteration in progress always completes.
+		info, _ := c.Collect(context.Background())
+		now := clk.Now()
+		info.Timestamp = &now
+		if info.RSS != nil {
+			if prev != nil {
+				delta := info.RSS.Sub(prev)
+				info.RSS.Delta = &delta
+			} else {
+				first = info.RSS
+			}
+			prev = info.RSS
+			samples++
+		}
+		if err := writeReport(r, out, info, last); err != nil {
+			return err
+		}
+		last = info
+		select {
+		case <-ctx.Done():
+			printRSSSummary(errOut, first, prev, samples)
+			return nil
+		case <-ticker.C():
+		}
+	}
+}
+
+// writeReport renders one report to out, or with --output replaces that
+// file with it.
+func writeReport(r renderer, out io.Writer, info, prev *sysinfo.SysInfo) error {
+	if outputPath == "" {
+		return r.render(out, info, prev)
+	}
+	var buf bytes.Buffer
+	if err := r.render(&buf, info, prev); err != nil {
+		return err
+	}
+	return writeFileAtomic(outputPath, buf.Bytes())
+}
+
+func renderOrExit(r renderer, info, prev *sysinfo.SysInfo) {
+	exitOnRenderError(writeReport(r, os.Stdout, info, prev))
+}
+
+// exitOnRenderError exits 2 for a csv-stream column that turned out not to
+// be a scalar and 1 for any other output failure.
+func exitOnRenderError(err error) {
+	switch {
+	case errors.Is(err, errBadColumn):
+		fmt.Fprintln(os.Stderr, "--only:", err)
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "output error:", err)
+		os.Exit(1)
+	}
+}
+
+func printRSSSummary(out io.Writer, first, last *sysinfo.RSSBreakdown, samples int) {
+	if first == nil || samples < 2 {
+		return
+	}
+	d := last.Sub(first)
+	if !last.Split {
+		fmt.Fprintf(out, "RSS over %d samples: total %+d B (%s)\n", samples, d.TotalBytes, last.Note)
+		return
+	}
+	fmt.Fprintf(out, "RSS over %d samples: total %+d B, anon %+d B, file %+d B, shmem %+d B\n",
+		samples, d.TotalBytes, d.AnonBytes, d.FileBytes, d.ShmemBytes)
+	switch d.Growing() {
+	case "anon":
+		fmt.Fprintln(out, "Growth is in anonymous memory (heap/stacks).")
+	case "file":
+		fmt.Fprintln(out, "Growth is in file-backed mappings (page cache, mapped files).")
+	case "shmem":
+		fmt.Fprintln(out, "Growth is in shared memory (shmem/tmpfs mappings).")
+	default:
+		fmt.Fprintln(out, "No RSS component grew.")
+	}
+}
+
+// reportErrors prints each collection failure to stderr and returns the
+// exit status: 1 if anything could not be collected, so scripts and
+// health checks notice, even though the rest of the report was printed.
+func reportErrors(err error) int {
+	failed := splitErrors(err)
+	for _, e := range failed {
+		fmt.Fprintln(os.Stderr, "sysinfo:", e)
+	}
+	if len(failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// usedOfLimit formats "used / limit (percent)", or just the usage when
+// there is no limit.
+func usedOfLimit(used uint64, limit *uint64) string {
+	if limit == nil || *limit == 0 {
+		return formatSize(used) + " (no limit)"
+	}
+	return fmt.Sprintf("%s / %s (%.1f%%)", formatSize(used), formatSize(*limit), float64(used)/float64(*limit)*100)
+}
+
+func formatRlimit(v *uint64) string {
+	if v == nil {
+		return "unlimited"
+	}
+	return strconv.FormatUint(*v, 10)
+}
+
+// formatFDTypes renders "socket: 42, file: 10", most frequent first.
+func formatFDTypes(counts map[string]int) string {
+	types := slices.Sorted(maps.Keys(counts))
+	slices.SortStableFunc(types, func(a, b string) int { return counts[b] - counts[a] })
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = fmt.Sprintf("%s: %d", t, counts[t])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func printThrottling(w io.Writer, label string, t *sysinfo.CgroupThrottling) {
+	if t == nil || t.Periods == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\t %d of %d periods (%.1f%%), %.1fs total\n", label,
+		t.Throttled, t.Periods, float64(t.Throttled)/float64(t.Periods)*100, t.ThrottledSeconds)
+}
+
+// printPressure writes one line per resource: the some and full stall
+// percentages averaged over 10s, 60s and 300s.
+func printPressure(w io.Writer, label string, p sysinfo.Pressure) {
+	avgs := func(psi *sysinfo.PSI) string {
+		return fmt.Sprintf("%.2f %.2f %.2f", psi.Avg10, psi.Avg60, psi.Avg300)
+	}
+	for _, res := range []string{"cpu", "memory", "io"} {
+		rp, ok := p[res]
+		if !ok {
+			continue
+		}
+		line := "some " + avgs(rp.Some)
+		if rp.Full != nil {
+			line += ", full " + avgs(rp.Full)
+		}
+		fmt.Fprintf(w, "%s %s:\t %s\n", label, res, line)
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// formatUptime renders seconds as its three most significant units,
+// dropping leading zero ones: "3d 4h 12m", "1m 30s", "42s".
+func formatUptime(seconds float64) string {
+	total := int64(seconds)
+	values := []int64{total / 86400, total % 86400 / 3600, total % 3600 / 60, total % 60}
+	suffixes := []string{"d", "h", "m", "s"}
+	i := 0
+	for i < len(values)-1 && values[i] == 0 {
+		i++
+	}
+	var parts []string
+	for j := i; j < len(values) && j < i+3; j++ {
+		parts = append(parts, strconv.FormatInt(values[j], 10)+suffixes[j])
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatSchedFeatures(features map[string]bool) string {
+	names := make([]string, 0, len(features))
+	for name, enabled := range features {
+		if !enabled {
+			name = "NO_" + name
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
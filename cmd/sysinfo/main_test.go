@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+func TestFormatUptime(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "0s"},
+		{42.9, "42s"},
+		{90, "1m 30s"},
+		{3600, "1h 0m 0s"},
+		{3*86400 + 4*3600 + 12*60 + 59, "3d 4h 12m"},
+		{86400, "1d 0h 0m"},
+		{400 * 86400, "400d 0h 0m"},
+	}
+	for _, tt := range tests {
+		if got := formatUptime(tt.seconds); got != tt.want {
+			t.Errorf("formatUptime(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+// reportWriter hands each report watch writes to the test, so the test
+// knows the loop is waiting for the next tick.
+type reportWriter chan []byte
+
+func (w reportWriter) Write(p []byte) (int, error) {
+	w <- bytes.Clone(p)
+	return len(p), nil
+}
+
+// writeStatus writes a /proc/self/status under root whose anonymous RSS is
+// anonKB.
+func writeStatus(t *testing.T, root string, anonKB int) {
+	t.Helper()
+	path := filepath.Join(root, "proc", "self", "status")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	status := fmt.Sprintf("Name:\tsysinfo\nPid:\t42\nThreads:\t1\nVmRSS:\t%d kB\nRssAnon:\t%d kB\nRssFile:\t300 kB\nRssShmem:\t100 kB\n",
+		anonKB+400, anonKB)
+	if err := os.WriteFile(path, []byte(status), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	const ticks = 3
+	const interval = 250 * time.Millisecond
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	root := t.TempDir()
+	writeStatus(t, root, 1000)
+	clk := clock.NewManual(start)
+	c, err := sysinfo.New(sysinfo.Options{Root: root, Fields: []string{"rss"}, Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(reportWriter)
+	var errOut bytes.Buffer
+	done := make(chan error)
+	go func() {
+		done <- watch(ctx, clk, c, interval, formats["json"](nil, true), out, &errOut)
+	}()
+	for i := range ticks {
+		line := <-out
+		var report struct {
+			Timestamp time.Time
+			RSS       sysinfo.RSSBreakdown
+		}
+		if err := json.Unmarshal(line, &report); err != nil {
+			t.Fatalf("report %d: %v in %q", i, err, line)
+		}
+		if n := bytes.Count(line, []byte("\n")); n != 1 || line[len(line)-1] != '\n' {
+			t.Errorf("report %d spans %d lines, want one NDJSON line", i, n)
+		}
+		if want := start.Add(time.Duration(i) * interval); !report.Timestamp.Equal(want) {
+			t.Errorf("report %d timestamp = %v, want %v", i, report.Timestamp, want)
+		}
+		switch d := report.RSS.Delta; {
+		case i == 0 && d != nil:
+			t.Errorf("report 0 has an RSS delta %+v", *d)
+		case i > 0 && (d == nil || d.AnonBytes != 1024<<10 || d.FileBytes != 0):
+			t.Errorf("report %d RSS delta = %+v, want 1 MiB of anon", i, d)
+		}
+		if i < ticks-1 {
+			writeStatus(t, root, 1000+1024*(i+1))
+			clk.Advance(interval)
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	want := "RSS over 3 samples: total +2097152 B, anon +2097152 B, file +0 B, shmem +0 B\n" +
+		"Growth is in anonymous memory (heap/stacks).\n"
+	if got := errOut.String(); got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
+
+// failingWriter rejects every write, like a closed pipe.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestWatchWriteError(t *testing.T) {
+	// A report that cannot be written ends the loop instead of waiting for
+	// the next tick, which this clock would never deliver.
+	root := t.TempDir()
+	writeStatus(t, root, 1000)
+	clk := clock.NewManual(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	c, err := sysinfo.New(sysinfo.Options{Root: root, Fields: []string{"rss"}, Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	err = watch(context.Background(), clk, c, time.Second, formats["json"](nil, true), failingWriter{}, new(bytes.Buffer))
+	if err == nil || err.Error() != "broken pipe" {
+		t.Errorf("watch = %v, want the write error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// motdBudget bounds collection so a hung statfs cannot stall a login.
+const motdBudget = 150 * time.Millisecond
+
+// motdFields are the only sections the banner needs; everything else
+// (cpu flags, irq, hwmon, bind files, ...) is skipped.
+var motdFields = []string{"uptime_seconds", "loadavg", "memory", "mounts", "cgroup_v1", "network"}
+
+// runMotd implements "sysinfo motd": a short login banner for
+// /etc/update-motd.d. Errors go to stderr only, so a failing run leaves the
+// banner empty rather than garbled.
+func runMotd(args []string) int {
+	flags := flag.NewFlagSet("motd", flag.ExitOnError)
+	plain := flags.Bool("plain", false, "no ANSI colors")
+	flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), motdBudget)
+	defer cancel()
+	info, err := sysinfo.CollectContext(ctx, sysinfo.Options{
+		Fields: motdFields,
+		Mounts: sysinfo.MountOptions{Timeout: motdBudget / 2},
+	})
+	if info == nil {
+		fmt.Fprintln(os.Stderr, "motd:", err)
+		return 1
+	}
+	printMotd(os.Stdout, info, *plain)
+	return 0
+}
+
+// printMotd renders at most eight lines with the labels padded to a fixed
+// width. Sections that could not be collected are left out.
+func printMotd(w io.Writer, info *sysinfo.SysInfo, plain bool) {
+	line := func(label, format string, v ...any) {
+		fmt.Fprintf(w, "  %-10s %s\n", label+":", fmt.Sprintf(format, v...))
+	}
+	if _, failed := info.Errors["loadavg"]; !failed && info.LoadAvg != nil {
+		l := info.LoadAvg
+		line("Load", "%.2f %.2f %.2f (%d/%d procs)", l.One, l.Five, l.Fifteen, l.RunnableProcs, l.TotalProcs)
+	}
+	if _, failed := info.Errors["uptime"]; !failed && info.UptimeSeconds > 0 {
+		line("Uptime", "%s", formatUptime(info.UptimeSeconds))
+	}
+	if _, failed := info.Errors["memory"]; !failed && info.Memory != nil && info.Memory.TotalBytes > 0 {
+		m := info.Memory
+		pct := float64(m.UsedBytes) / float64(m.TotalBytes) * 100
+		line("Memory", "%s %5.1f%% of %s", motdBar(pct, plain), pct, formatSize(m.TotalBytes))
+		if m.SwapTotalBytes > 0 {
+			swap := float64(m.SwapTotalBytes-m.SwapFreeBytes) / float64(m.SwapTotalBytes) * 100
+			line("Swap", "%s %5.1f%% of %s", motdBar(swap, plain), swap, formatSize(m.SwapTotalBytes))
+		}
+	}
+	for _, d := range info.Mounts {
+		if d.Mountpoint == "/" && d.Error == "" {
+			line("Disk /", "%s %5.1f%% of %s", motdBar(d.UsedPercent, plain), d.UsedPercent, formatSize(d.Total))
+		}
+	}
+	if ip, dev := defaultIP(info.Network); ip != "" {
+		line("IP", "%s (%s)", ip, dev)
+	}
+	if n := len(info.Findings); n > 0 {
+		msg := fmt.Sprintf("%d, run sysinfo for details", n)
+		if !plain {
+			msg = "\033[33m" + msg + "\033[0m"
+		}
+		line("Findings", "%s", msg)
+	} else {
+		line("Findings", "none")
+	}
+}
+
+// motdBar draws a 20-cell usage bar, colored by threshold unless plain.
+func motdBar(pct float64, plain bool) string {
+	const width = 20
+	filled := int(pct/100*width + 0.5)
+	filled = max(0, min(width, filled))
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", width-filled)
+	if plain {
+		return "[" + bar + "]"
+	}
+	color := "32"
+	switch {
+	case pct >= 90:
+		color = "31"
+	case pct >= 70:
+		color = "33"
+	}
+	return "[\033[" + color + "m" + bar + "\033[0m]"
+}
+
+// defaultIP returns the first IPv4 address, without the prefix length, of
+// the interface holding the default route.
+func defaultIP(n *sysinfo.Network) (ip, dev string) {
+	if n == nil || n.DefaultRoute == nil {
+		return "", ""
+	}
+	dev = n.DefaultRoute.Device
+	for _, iface := range n.Interfaces {
+		if iface.Name != dev {
+			continue
+		}
+		for _, addr := range iface.Addresses {
+			if a, _, _ := strings.Cut(addr, "/"); !strings.Contains(a, ":") {
+				return a, dev
+			}
+		}
+	}
+	return "", ""
+}
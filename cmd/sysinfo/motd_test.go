@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// motdInfo is a host with every banner section filled in: a busy root disk,
+// swap in use and one pending finding.
+func motdInfo() *sysinfo.SysInfo {
+	return &sysinfo.SysInfo{
+		UptimeSeconds: 3*86400 + 4*3600 + 12*60 + 7,
+		LoadAvg:       &sysinfo.LoadAvg{One: 0.52, Five: 0.48, Fifteen: 0.4, RunnableProcs: 2, TotalProcs: 431},
+		Memory: &sysinfo.MemInfo{
+			TotalBytes: 16 << 30, UsedBytes: 12 << 30, AvailableBytes: 4 << 30,
+			SwapTotalBytes: 2 << 30, SwapFreeBytes: 1 << 30,
+		},
+		Mounts: []sysinfo.DiskInfo{
+			{Mountpoint: "/boot", Total: 1 << 30, UsedPercent: 40},
+			{Mountpoint: "/", Total: 100 << 30, UsedPercent: 93.4},
+		},
+		Network: &sysinfo.Network{
+			Interfaces: []sysinfo.NetInterface{
+				{Name: "lo", Addresses: []string{"127.0.0.1/8", "::1/128"}},
+				{Name: "eth0", Addresses: []string{"fe80::1/64", "192.0.2.10/24"}},
+			},
+			DefaultRoute: &sysinfo.DefaultRoute{Gateway: "192.0.2.1", Device: "eth0"},
+		},
+		Findings: []sysinfo.Finding{{Code: "memory_limit_unset_no_swap", Severity: "info"}},
+	}
+}
+
+func TestPrintMotdGolden(t *testing.T) {
+	saved := units
+	t.Cleanup(func() { units = saved })
+	units = "auto"
+
+	failed := motdInfo()
+	// Sections that failed may still hold partial values next to their
+	// error; none of them may reach the banner.
+	failed.Errors = map[string]string{"loadavg": "permission denied", "uptime": "permission denied", "memory": "malformed /proc/meminfo"}
+	failed.Network, failed.Findings = nil, nil
+	failed.Mounts = []sysinfo.DiskInfo{{Mountpoint: "/", Error: "stat timed out after 75ms"}}
+
+	tests := []struct {
+		golden string
+		info   *sysinfo.SysInfo
+		plain  bool
+	}{
+		{"full.plain", motdInfo(), true},
+		{"full.ansi", motdInfo(), false},
+		{"failed.plain", failed, true},
+		{"failed.ansi", failed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			var buf bytes.Buffer
+			printMotd(&buf, tt.info, tt.plain)
+			path := filepath.Join("testdata", "motd", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			golden, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run go test -run TestPrintMotdGolden -update)", err)
+			}
+			if buf.String() != string(golden) {
+				t.Errorf("banner differs from %s:\n%s\nwant:\n%s", path, buf.String(), golden)
+			}
+			if n := strings.Count(buf.String(), "\n"); n > 8 {
+				t.Errorf("%d lines, want at most 8", n)
+			}
+			if tt.plain && strings.Contains(buf.String(), "\033") {
+				t.Error("--plain output has ANSI escapes")
+			}
+		})
+	}
+}
+
+func TestMotdBar(t *testing.T) {
+	tests := []struct {
+		pct   float64
+		plain string
+		color string
+	}{
+		{0, "[--------------------]", "32"},
+		{2.4, "[--------------------]", "32"},
+		{2.5, "[#-------------------]", "32"},
+		{69.9, "[##############------]", "32"},
+		{70, "[##############------]", "33"},
+		{90, "[##################--]", "31"},
+		{100, "[####################]", "31"},
+		{120, "[####################]", "31"},
+	}
+	for _, tt := range tests {
+		if got := motdBar(tt.pct, true); got != tt.plain {
+			t.Errorf("motdBar(%v, plain) = %q, want %q", tt.pct, got, tt.plain)
+		}
+		want := "[\033[" + tt.color + "m" + strings.Trim(tt.plain, "[]") + "\033[0m]"
+		if got := motdBar(tt.pct, false); got != want {
+			t.Errorf("motdBar(%v) = %q, want %q", tt.pct, got, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// mountSort and mountTop are --sort and --top for the mounts table. An
+// empty mountSort keeps the /proc/mounts order, in the table as in JSON.
+var (
+	mountSort string
+	mountTop  int
+)
+
+// mountSortKeys compare two mounts for --sort. Size-based keys put the
+// largest first.
+var mountSortKeys = map[string]func(a, b sysinfo.DiskInfo) int{
+	"mountpoint":   func(a, b sysinfo.DiskInfo) int { return strings.Compare(a.Mountpoint, b.Mountpoint) },
+	"total":        func(a, b sysinfo.DiskInfo) int { return cmp.Compare(b.Total, a.Total) },
+	"free":         func(a, b sysinfo.DiskInfo) int { return cmp.Compare(b.Avail, a.Avail) },
+	"used":         func(a, b sysinfo.DiskInfo) int { return cmp.Compare(b.Total-b.Free, a.Total-a.Free) },
+	"used-pct":     func(a, b sysinfo.DiskInfo) int { return cmp.Compare(b.UsedPercent, a.UsedPercent) },
+	"used_percent": func(a, b sysinfo.DiskInfo) int { return cmp.Compare(b.UsedPercent, a.UsedPercent) },
+}
+
+// descendingFirst are the keys whose unprefixed order is already
+// descending.
+var descendingFirst = []string{"total", "free", "used", "used-pct", "used_percent"}
+
+func mountSortNames() string {
+	return strings.Join(slices.Sorted(maps.Keys(mountSortKeys)), ", ")
+}
+
+// mountSortFunc resolves a --sort value: a key, optionally prefixed with
+// one "-" for descending or "+" for ascending order. It returns nil for an
+// unknown key, and for an empty spec a comparison that keeps every mount
+// where it is.
+func mountSortFunc(spec string) func(a, b sysinfo.DiskInfo) int {
+	if spec == "" {
+		return func(a, b sysinfo.DiskInfo) int { return 0 }
+	}
+	key, sign := spec, ""
+	if spec[0] == '-' || spec[0] == '+' {
+		key, sign = spec[1:], spec[:1]
+	}
+	compare := mountSortKeys[key]
+	if compare == nil {
+		return nil
+	}
+	descending := slices.Contains(descendingFirst, key)
+	switch sign {
+	case "-":
+		descending = true
+	case "+":
+		descending = false
+	}
+	if descending == slices.Contains(descendingFirst, key) {
+		return compare
+	}
+	return func(a, b sysinfo.DiskInfo) int { return compare(b, a) }
+}
+
+// orderMounts returns a sorted copy of mounts, cut to --top rows when set.
+// The sort is stable, so ties keep their /proc/mounts order.
+func orderMounts(mounts []sysinfo.DiskInfo) []sysinfo.DiskInfo {
+	sorted := slices.Clone(mounts)
+	slices.SortStableFunc(sorted, mountSortFunc(mountSort))
+	if mountTop > 0 && len(sorted) > mountTop {
+		sorted = sorted[:mountTop]
+	}
+	return sorted
+}
+
+// withMountOrder applies --sort and --top to the mounts of a JSON or YAML
+// report. Those formats keep every mount in collection order unless one
+// of them is given.
+func withMountOrder(info *sysinfo.SysInfo) *sysinfo.SysInfo {
+	if mountTop <= 0 && mountSort == "" {
+		return info
+	}
+	view := *info
+	view.Mounts = orderMounts(info.Mounts)
+	return &view
+}
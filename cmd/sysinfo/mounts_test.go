@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// withMountFlags sets --sort and --top for the duration of the test.
+func withMountFlags(t *testing.T, sort string, top int) {
+	t.Helper()
+	savedSort, savedTop := mountSort, mountTop
+	t.Cleanup(func() { mountSort, mountTop = savedSort, savedTop })
+	mountSort, mountTop = sort, top
+}
+
+func mountpoints(mounts []sysinfo.DiskInfo) string {
+	var points []string
+	for _, d := range mounts {
+		points = append(points, d.Mountpoint)
+	}
+	return strings.Join(points, " ")
+}
+
+// As /proc/mounts lists them: not sorted by anything.
+var testMounts = []sysinfo.DiskInfo{
+	{Mountpoint: "/", Total: 100, Free: 50, Avail: 40, UsedPercent: 55.6},
+	{Mountpoint: "/var", Total: 300, Free: 30, Avail: 30, UsedPercent: 90},
+	{Mountpoint: "/boot", Total: 10, Free: 8, Avail: 8, UsedPercent: 20},
+}
+
+func TestOrderMounts(t *testing.T) {
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"", "/ /var /boot"},
+		{"mountpoint", "/ /boot /var"},
+		{"-mountpoint", "/var /boot /"},
+		{"+mountpoint", "/ /boot /var"},
+		{"total", "/var / /boot"},
+		{"+total", "/boot / /var"},
+		{"-total", "/var / /boot"},
+		{"used_percent", "/var / /boot"},
+		{"+used-pct", "/boot / /var"},
+	}
+	for _, tt := range tests {
+		withMountFlags(t, tt.sort, 0)
+		if got := mountpoints(orderMounts(testMounts)); got != tt.want {
+			t.Errorf("--sort %q: %s, want %s", tt.sort, got, tt.want)
+		}
+	}
+}
+
+func TestMountSortFuncPrefix(t *testing.T) {
+	for _, spec := range []string{"--total", "+-total", "-+free", "++mountpoint", "-", "size"} {
+		if mountSortFunc(spec) != nil {
+			t.Errorf("--sort %q accepted, want it rejected", spec)
+		}
+	}
+}
+
+func TestWithMountOrderDefault(t *testing.T) {
+	withMountFlags(t, "", 0)
+	info := &sysinfo.SysInfo{Mounts: testMounts}
+	if withMountOrder(info) != info {
+		t.Error("JSON mounts reordered without --sort or --top")
+	}
+	withMountFlags(t, "", 2)
+	if got := mountpoints(withMountOrder(info).Mounts); got != "/ /var" {
+		t.Errorf("--top 2: %s, want the first two in /proc/mounts order", got)
+	}
+}
+
+func TestOrderMountsTies(t *testing.T) {
+	// /a, /c and /d tie on total and used%; /b and /d tie on free.
+	mounts := []sysinfo.DiskInfo{
+		{Mountpoint: "/c", Total: 100, Free: 50, Avail: 50, UsedPercent: 50},
+		{Mountpoint: "/b", Total: 200, Free: 20, Avail: 20, UsedPercent: 90},
+		{Mountpoint: "/a", Total: 100, Free: 50, Avail: 50, UsedPercent: 50},
+		{Mountpoint: "/d", Total: 100, Free: 20, Avail: 20, UsedPercent: 50},
+	}
+	tests := []struct {
+		sort string
+		top  int
+		want string
+	}{
+		{"total", 0, "/b /c /a /d"},
+		{"+total", 0, "/c /a /d /b"},
+		{"-total", 0, "/b /c /a /d"},
+		{"free", 0, "/c /a /b /d"},
+		{"+free", 0, "/b /d /c /a"},
+		{"used", 0, "/b /d /c /a"},
+		{"used-pct", 0, "/b /c /a /d"},
+		{"+used-pct", 0, "/c /a /d /b"},
+		// The cut falls inside a tie: the earlier mounts win.
+		{"total", 2, "/b /c"},
+		{"+used-pct", 2, "/c /a"},
+		{"", 3, "/c /b /a"},
+	}
+	for _, tt := range tests {
+		withMountFlags(t, tt.sort, tt.top)
+		if got := mountpoints(orderMounts(mounts)); got != tt.want {
+			t.Errorf("--sort %q --top %d: %s, want %s", tt.sort, tt.top, got, tt.want)
+		}
+	}
+}
+
+func TestOrderMountsZeroTotal(t *testing.T) {
+	// Leftovers with nothing to measure: a pseudo filesystem that slipped
+	// past the filter, and a mount whose statfs failed.
+	mounts := []sysinfo.DiskInfo{
+		{Mountpoint: "/proc/fs/nfsd"},
+		{Mountpoint: "/", Total: 100, Free: 40, Avail: 30, UsedPercent: 70},
+		{Mountpoint: "/gone", Error: "statfs: no such file or directory"},
+		{Mountpoint: "/boot", Total: 10, Free: 9, Avail: 9, UsedPercent: 10},
+	}
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"total", "/ /boot /proc/fs/nfsd /gone"},
+		{"+total", "/proc/fs/nfsd /gone /boot /"},
+		{"free", "/ /boot /proc/fs/nfsd /gone"},
+		{"used", "/ /boot /proc/fs/nfsd /gone"},
+		{"used-pct", "/ /boot /proc/fs/nfsd /gone"},
+		{"+used_percent", "/proc/fs/nfsd /gone /boot /"},
+		{"mountpoint", "/ /boot /gone /proc/fs/nfsd"},
+	}
+	for _, tt := range tests {
+		withMountFlags(t, tt.sort, 0)
+		if got := mountpoints(orderMounts(mounts)); got != tt.want {
+			t.Errorf("--sort %q: %s, want %s", tt.sort, got, tt.want)
+		}
+	}
+	withMountFlags(t, "used-pct", 2)
+	if got := mountpoints(orderMounts(mounts)); got != "/ /boot" {
+		t.Errorf("--sort used-pct --top 2: %s, want the two measured mounts", got)
+	}
+}
+
+func TestOrderMountsKeepsInput(t *testing.T) {
+	withMountFlags(t, "total", 1)
+	before := mountpoints(testMounts)
+	orderMounts(testMounts)
+	if got := mountpoints(testMounts); got != before {
+		t.Errorf("orderMounts reordered its input: %s, was %s", got, before)
+	}
+}
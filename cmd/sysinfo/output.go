@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// outputPath is the --output file; empty means stdout.
+var outputPath string
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory and a rename, so readers see either the old or the new
+// report, never a truncated one.
+func writeFileAtomic(path string, data []byte) error {
+	return writeAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeAtomic is writeFileAtomic for output that write produces as it
+// goes. When write fails path is left as it was.
+func writeAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// CreateTemp uses 0600; reports are not secret.
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
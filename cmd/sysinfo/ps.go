@@ -0,0 +1,287 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// runPs implements "sysinfo ps": the processes matching every --match
+// expression, listed from a single walk of /proc.
+//
+//	sysinfo ps --match comm=nginx --match 'rss>100MB' --match 'fds>500'
+//
+// It exits 1 when nothing matches, like pgrep.
+func runPs(args []string) int {
+	flags := flag.NewFlagSet("ps", flag.ExitOnError)
+	var matches []psMatch
+	flags.Func("match", "keep processes matching this expression (repeatable, all must match): "+
+		"comm or cmdline with = (substring) or ~ (regexp); rss, vsz, fds, threads or age with =, <, <=, >, >=; uid or user with =", func(s string) error {
+		m, err := parsePsMatch(s)
+		if err == nil {
+			matches = append(matches, m)
+		}
+		return err
+	})
+	format := flags.String("format", "text", "output format: text or json")
+	jsonOutput := flags.Bool("json", false, "same as --format json")
+	flags.StringVar(&units, "units", units, "size units in text mode: auto, bytes, si, iec")
+	flags.Parse(args)
+	if *jsonOutput {
+		*format = "json"
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "ps: unexpected arguments %q\n", flags.Args())
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "ps: unknown --format %q (want text or json)\n", *format)
+		return 2
+	}
+	if !validUnits(units) {
+		fmt.Fprintf(os.Stderr, "unknown --units %q (want auto, bytes, si or iec)\n", units)
+		return 2
+	}
+
+	needFDs := slices.ContainsFunc(matches, func(m psMatch) bool { return m.key == "fds" })
+	procs, err := sysinfo.ListProcesses(context.Background(), "", needFDs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ps:", err)
+		return 1
+	}
+	// The command line of this process contains the expressions, so a
+	// cmdline match would always find it.
+	self := os.Getpid()
+	found := []sysinfo.ProcessSummary{}
+	for _, p := range procs {
+		if p.PID != self && slices.IndexFunc(matches, func(m psMatch) bool { return !m.test(p) }) < 0 {
+			found = append(found, p)
+		}
+	}
+
+	if *format == "json" {
+		out, err := json.MarshalIndent(found, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ps:", err)
+			return 1
+		}
+		os.Stdout.Write(append(out, '\n'))
+	} else {
+		printPs(os.Stdout, found, matches)
+	}
+	if len(found) == 0 {
+		return 1
+	}
+	return 0
+}
+
+// psMatch is one --match expression: key, operator and the value parsed
+// for the key's kind.
+type psMatch struct {
+	expr, key, op string
+	text          string
+	re            *regexp.Regexp
+	num           float64
+}
+
+// psKeys maps the match keys to the operators they accept.
+var psKeys = map[string][]string{
+	"comm":    {"=", "~"},
+	"cmdline": {"=", "~"},
+	"rss":     {"=", "<", "<=", ">", ">="},
+	"vsz":     {"=", "<", "<=", ">", ">="},
+	"fds":     {"=", "<", "<=", ">", ">="},
+	"threads": {"=", "<", "<=", ">", ">="},
+	"age":     {"=", "<", "<=", ">", ">="},
+	"uid":     {"="},
+	"user":    {"="},
+}
+
+// parsePsMatch parses "<key><op><value>": sizes take K/M/G/T suffixes
+// (powers of 1024, "100MB" is 100 MiB), ages are Go durations ("90m",
+// "2h30m"). Every error names the expression.
+func parsePsMatch(expr string) (psMatch, error) {
+	i := strings.IndexAny(expr, "=<>~")
+	if i <= 0 {
+		return psMatch{}, fmt.Errorf("%q: want <key><op><value>, e.g. comm=nginx or rss>100MB", expr)
+	}
+	m := psMatch{expr: expr, key: expr[:i], op: expr[i : i+1]}
+	if (m.op == "<" || m.op == ">") && strings.HasPrefix(expr[i+1:], "=") {
+		m.op += "="
+	}
+	value := expr[i+len(m.op):]
+	ops, ok := psKeys[m.key]
+	if !ok {
+		return psMatch{}, fmt.Errorf("%q: unknown key %q (want comm, cmdline, rss, vsz, fds, threads, age, uid or user)", expr, m.key)
+	}
+	if !slices.Contains(ops, m.op) {
+		return psMatch{}, fmt.Errorf("%q: %s does not take %s (want %s)", expr, m.key, m.op, strings.Join(ops, ", "))
+	}
+	if value == "" {
+		return psMatch{}, fmt.Errorf("%q: missing value", expr)
+	}
+
+	var err error
+	switch m.key {
+	case "comm", "cmdline", "user":
+		m.text = value
+		if m.op == "~" {
+			if m.re, err = regexp.Compile(value); err != nil {
+				return psMatch{}, fmt.Errorf("%q: %w", expr, err)
+			}
+		}
+	case "rss", "vsz":
+		var size uint64
+		if size, err = parseSize(value); err != nil {
+			return psMatch{}, fmt.Errorf("%q: %w", expr, err)
+		}
+		m.num = float64(size)
+	case "age":
+		var d time.Duration
+		if d, err = time.ParseDuration(value); err != nil || d < 0 {
+			return psMatch{}, fmt.Errorf("%q: invalid age %q (want a duration such as 90m or 2h)", expr, value)
+		}
+		m.num = d.Seconds()
+	case "fds", "threads", "uid":
+		var n int
+		if n, err = strconv.Atoi(value); err != nil || n < 0 {
+			return psMatch{}, fmt.Errorf("%q: invalid %s %q (want a non-negative integer)", expr, m.key, value)
+		}
+		m.num = float64(n)
+	}
+	return m, nil
+}
+
+func (m psMatch) test(p sysinfo.ProcessSummary) bool {
+	switch m.key {
+	case "comm", "cmdline":
+		s := p.Comm
+		if m.key == "cmdline" {
+			s = p.Cmdline
+		}
+		if m.re != nil {
+			return m.re.MatchString(s)
+		}
+		return strings.Contains(s, m.text)
+	case "user":
+		return p.User == m.text
+	case "fds":
+		// Unreadable fds (another user's process without root) never
+		// match, rather than counting as zero.
+		return p.FDs != nil && compareNum(float64(*p.FDs), m.op, m.num)
+	}
+	return compareNum(psValue(p, m.key), m.op, m.num)
+}
+
+func psValue(p sysinfo.ProcessSummary, key string) float64 {
+	switch key {
+	case "rss":
+		return float64(p.RSSBytes)
+	case "vsz":
+		return float64(p.VSZBytes)
+	case "threads":
+		return float64(p.Threads)
+	case "age":
+		return p.AgeSeconds
+	case "uid":
+		return float64(p.UID)
+	}
+	return 0
+}
+
+func compareNum(v float64, op string, want float64) bool {
+	switch op {
+	case "=":
+		return v == want
+	case "<":
+		return v < want
+	case "<=":
+		return v <= want
+	case ">":
+		return v > want
+	case ">=":
+		return v >= want
+	}
+	return false
+}
+
+// printPs prints pid, user and age, then a column per other key the
+// matches use, in the order first given. The command line, being the
+// widest, comes last; without it or comm among the keys the comm is
+// added to name the process.
+func printPs(out io.Writer, procs []sysinfo.ProcessSummary, matches []psMatch) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	var keys []string
+	for _, m := range matches {
+		if m.key != "user" && m.key != "age" && m.key != "cmdline" && !slices.Contains(keys, m.key) {
+			keys = append(keys, m.key)
+		}
+	}
+	switch {
+	case slices.ContainsFunc(matches, func(m psMatch) bool { return m.key == "cmdline" }):
+		keys = append(keys, "cmdline")
+	case !slices.Contains(keys, "comm"):
+		keys = append(keys, "comm")
+	}
+	header := []string{"PID:", "User:", "Age:"}
+	for _, k := range keys {
+		header = append(header, psHeaders[k])
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, p := range procs {
+		row := []string{strconv.Itoa(p.PID), cmp.Or(p.User, strconv.Itoa(p.UID)), formatUptime(p.AgeSeconds)}
+		for _, k := range keys {
+			row = append(row, psCell(p, k))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+}
+
+const psCmdlineWidth = 120
+
+var psHeaders = map[string]string{
+	"comm": "Comm:", "cmdline": "Command:", "rss": "RSS:", "vsz": "VSZ:",
+	"fds": "FDs:", "threads": "Threads:", "uid": "UID:",
+}
+
+func psCell(p sysinfo.ProcessSummary, key string) string {
+	switch key {
+	case "comm":
+		return p.Comm
+	case "cmdline":
+		// Arguments may hold newlines (scripts passed with -c); one line
+		// per process keeps the table readable, JSON has them verbatim.
+		cmdline := strings.Join(strings.Fields(p.Cmdline), " ")
+		if r := []rune(cmdline); len(r) > psCmdlineWidth {
+			cmdline = string(r[:psCmdlineWidth]) + "..."
+		}
+		return cmp.Or(cmdline, "["+p.Comm+"]")
+	case "rss":
+		return formatSize(p.RSSBytes)
+	case "vsz":
+		return formatSize(p.VSZBytes)
+	case "fds":
+		if p.FDs == nil {
+			return "?"
+		}
+		return strconv.Itoa(*p.FDs)
+	case "threads":
+		return strconv.Itoa(p.Threads)
+	case "uid":
+		return strconv.Itoa(p.UID)
+	}
+	return ""
+}
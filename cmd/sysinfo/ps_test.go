@@ -0,0 +1,146 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+func TestParsePsMatchKeysAndOps(t *testing.T) {
+	values := map[string]string{
+		"comm": "nginx", "cmdline": "--port", "user": "root", "uid": "0",
+		"rss": "100MB", "vsz": "1G", "fds": "500", "threads": "8", "age": "90m",
+	}
+	for key, ops := range psKeys {
+		for _, op := range []string{"=", "~", "<", "<=", ">", ">="} {
+			expr := key + op + values[key]
+			m, err := parsePsMatch(expr)
+			if !slices.Contains(ops, op) {
+				if err == nil || !strings.Contains(err.Error(), "does not take "+op) {
+					t.Errorf("parsePsMatch(%q) = %v, want an error that %s does not take %s", expr, err, key, op)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("parsePsMatch(%q): %v", expr, err)
+				continue
+			}
+			if m.key != key || m.op != op || m.expr != expr {
+				t.Errorf("parsePsMatch(%q) = key %q op %q expr %q", expr, m.key, m.op, m.expr)
+			}
+		}
+	}
+}
+
+func TestParsePsMatch(t *testing.T) {
+	tests := []struct {
+		expr    string
+		op      string
+		num     float64
+		text    string
+		regexp  bool
+		wantErr string
+	}{
+		{expr: "comm=nginx", op: "=", text: "nginx"},
+		{expr: "comm~^ngi", op: "~", text: "^ngi", regexp: true},
+		{expr: "cmdline=a=b", op: "=", text: "a=b"},
+		{expr: "user=root", op: "=", text: "root"},
+		{expr: "rss>100MB", op: ">", num: 100 << 20},
+		{expr: "rss<=100M", op: "<=", num: 100 << 20},
+		{expr: "rss>=1G", op: ">=", num: 1 << 30},
+		{expr: "vsz<2GiB", op: "<", num: 2 << 30},
+		{expr: "rss=1.5K", op: "=", num: 1536},
+		{expr: "rss>4096", op: ">", num: 4096},
+		{expr: "vsz>1T", op: ">", num: 1 << 40},
+		{expr: "fds>=500", op: ">=", num: 500},
+		{expr: "threads<=1", op: "<=", num: 1},
+		{expr: "uid=0", op: "=", num: 0},
+		{expr: "age>90m", op: ">", num: 5400},
+		{expr: "age<2h30m", op: "<", num: 9000},
+
+		{expr: "rss>-1M", wantErr: `invalid size "-1M"`},
+		{expr: "fds>-1", wantErr: "want a non-negative integer"},
+		{expr: "uid=-5", wantErr: "want a non-negative integer"},
+		{expr: "age>-5m", wantErr: "invalid age"},
+		{expr: "rss>10X", wantErr: `invalid size "10X"`},
+		{expr: "threads>2.5", wantErr: "want a non-negative integer"},
+		{expr: "age>90", wantErr: "invalid age"},
+		{expr: "comm=", wantErr: "missing value"},
+		{expr: "rss>=", wantErr: "missing value"},
+		{expr: "rss=<5", wantErr: `invalid size "<5"`},
+		{expr: "pid=1", wantErr: `unknown key "pid"`},
+		{expr: "=nginx", wantErr: "want <key><op><value>"},
+		{expr: "nginx", wantErr: "want <key><op><value>"},
+		{expr: "comm~[", wantErr: "missing closing ]"},
+		{expr: "uid>0", wantErr: "uid does not take >"},
+		{expr: "comm<x", wantErr: "comm does not take <"},
+	}
+	for _, tt := range tests {
+		m, err := parsePsMatch(tt.expr)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parsePsMatch(%q) error = %v, want it to contain %q", tt.expr, err, tt.wantErr)
+			} else if !strings.Contains(err.Error(), `"`+tt.expr+`"`) {
+				t.Errorf("parsePsMatch(%q) error %q does not name the expression", tt.expr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePsMatch(%q): %v", tt.expr, err)
+			continue
+		}
+		if m.op != tt.op || m.num != tt.num || m.text != tt.text || (m.re != nil) != tt.regexp {
+			t.Errorf("parsePsMatch(%q) = op %q num %v text %q regexp %v, want op %q num %v text %q regexp %v",
+				tt.expr, m.op, m.num, m.text, m.re != nil, tt.op, tt.num, tt.text, tt.regexp)
+		}
+	}
+}
+
+func TestPsMatchTest(t *testing.T) {
+	fds := 500
+	p := sysinfo.ProcessSummary{
+		PID: 7, Comm: "nginx", Cmdline: "nginx -g daemon off;", UID: 33, User: "www-data",
+		RSSBytes: 100 << 20, VSZBytes: 1 << 30, Threads: 4, FDs: &fds, AgeSeconds: 3600,
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"comm=ngi", true},
+		{"comm=apache", false},
+		{"comm~^nginx$", true},
+		{"cmdline~daemon (on|off)", true},
+		{"user=www-data", true},
+		{"user=www", false},
+		{"uid=33", true},
+		{"rss=100M", true},
+		{"rss<=100M", true},
+		{"rss<100M", false},
+		{"rss>=100M", true},
+		{"rss>100M", false},
+		{"vsz>=1G", true},
+		{"fds>=500", true},
+		{"fds>500", false},
+		{"threads<=4", true},
+		{"threads<4", false},
+		{"age>=1h", true},
+		{"age>1h", false},
+	}
+	for _, tt := range tests {
+		m, err := parsePsMatch(tt.expr)
+		if err != nil {
+			t.Fatalf("parsePsMatch(%q): %v", tt.expr, err)
+		}
+		if got := m.test(p); got != tt.want {
+			t.Errorf("%q matches = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	// Unreadable fds match nothing, not even fds<1.
+	p.FDs = nil
+	if m, _ := parsePsMatch("fds<1"); m.test(p) {
+		t.Error("fds<1 matched a process whose fds could not be counted")
+	}
+}
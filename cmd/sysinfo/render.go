@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// renderer writes one report. prev is the previous report in --watch mode
+// and nil otherwise.
+type renderer interface {
+	render(w io.Writer, info, prev *sysinfo.SysInfo) error
+}
+
+// formats lists the --format values; csv-stream is built separately since
+// it needs --only.
+var formats = map[string]func(fields []string, watching bool) renderer{
+	"text": func(_ []string, watching bool) renderer { return textRenderer{clear: watching} },
+	"json": func(fields []string, watching bool) renderer {
+		// One object per line when watching, so the output is NDJSON.
+		if watching {
+			return jsonRenderer{fields: fields}
+		}
+		return jsonRenderer{fields: fields, indent: "  "}
+	},
+	"yaml":       func(fields []string, _ bool) renderer { return yamlRenderer{fields: fields} },
+	"prometheus": func(fields []string, _ bool) renderer { return prometheusRenderer{fields: fields} },
+}
+
+func formatNames() string {
+	names := []string{"csv-stream"}
+	for name := range formats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+type textRenderer struct {
+	clear bool // clear the screen first, for --watch
+}
+
+func (r textRenderer) render(w io.Writer, info, prev *sysinfo.SysInfo) error {
+	if r.clear {
+		fmt.Fprint(w, "\033[H\033[2J")
+	}
+	printText(w, info, prev)
+	return nil
+}
+
+type jsonRenderer struct {
+	fields []string
+	indent string
+}
+
+func (r jsonRenderer) render(w io.Writer, info, _ *sysinfo.SysInfo) error {
+	out, err := sysinfo.MarshalReport(withMountOrder(info), r.fields, r.indent)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+type yamlRenderer struct {
+	fields []string
+}
+
+func (r yamlRenderer) render(w io.Writer, info, _ *sysinfo.SysInfo) error {
+	out, err := sysinfo.MarshalReport(withMountOrder(info), r.fields, "")
+	if err != nil {
+		return err
+	}
+	out, err = jsonToYAML(out)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+type prometheusRenderer struct {
+	fields []string
+}
+
+func (r prometheusRenderer) render(w io.Writer, info, _ *sysinfo.SysInfo) error {
+	return sysinfo.WritePrometheus(w, info, r.fields)
+}
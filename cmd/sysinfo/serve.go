@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+const shutdownTimeout = 30 * time.Second
+
+// serve mounts sysinfo's HTTP handler at the root, so GET /json (also under
+// its old name /sysinfo), /metrics and /healthz are answered until SIGTERM
+// or SIGINT, then lets in-flight requests finish. Every request collects
+// within timeout. With stream the report is always sent as /json?stream
+// sends it, section by section.
+func serve(addr string, opts sysinfo.Options, timeout time.Duration, stream bool) error {
+	c, err := sysinfo.New(opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	h := c.Handler()
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+	mux.HandleFunc("/sysinfo", func(w http.ResponseWriter, r *http.Request) {
+		r = r.Clone(r.Context())
+		r.URL.Path = "/json"
+		h.ServeHTTP(w, r)
+	})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.Clone(ctx)
+		if stream {
+			q := r.URL.Query()
+			q.Set("stream", "1")
+			r.URL.RawQuery = q.Encode()
+		}
+		mux.ServeHTTP(w, r)
+	})
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "sysinfo: listening on", ln.Addr())
+
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
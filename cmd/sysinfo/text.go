@@ -0,0 +1,1101 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+	"golang.org/x/term"
+)
+
+// noLoopback hides loopback interfaces from the text table; JSON always
+// lists them, flagged with "loopback". Likewise interfaces that are down
+// are only in the table with allInterfaces.
+var (
+	noLoopback    bool
+	allInterfaces bool
+)
+
+// longTable adds the mount options column to the mounts table (--long).
+var longTable bool
+
+// explainOutput appends how the approximate figures were derived
+// (--explain).
+var explainOutput bool
+
+// selectedFields limits the text report to these JSON keys (--fields).
+var selectedFields map[string]bool
+
+// sectionOrder lists the sections --section-order moves to the front;
+// sectionTitles are the headings given with --section-title.
+var (
+	sectionOrder  []string
+	sectionTitles = make(map[string]string)
+)
+
+// colorOutput enables ANSI colors in the text report (see useColor).
+var colorOutput bool
+
+// SGR codes for paint. They are all two digits, so every painted cell
+// carries the same nine invisible bytes: tabwriter counts them as width,
+// and a column stays aligned as long as all of its cells, the header
+// included, are painted.
+const (
+	colorRed     = "31"
+	colorGreen   = "32"
+	colorYellow  = "33"
+	colorDefault = "39"
+	colorDim     = "02"
+)
+
+// useColor reports whether text written to f may be colored: f is a
+// terminal and neither --no-color nor NO_COLOR (https://no-color.org) is
+// set.
+func useColor(f *os.File, noColor bool) bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(f.Fd()))
+}
+
+func paint(code, s string) string {
+	if !colorOutput {
+		return s
+	}
+	return "\033[" + code + "m" + s + "\033[0m"
+}
+
+// usageColor matches the motd bars: green below 70%, yellow below 90%,
+// red from there.
+func usageColor(pct float64) string {
+	switch {
+	case pct >= 90:
+		return colorRed
+	case pct >= 70:
+		return colorYellow
+	}
+	return colorGreen
+}
+
+// textReport is the state the text sections share. Each section writes
+// tab-separated rows into w, its own buffer; printText then runs all of
+// them through one tabwriter so the columns line up across sections.
+type textReport struct {
+	w          io.Writer
+	info, prev *sysinfo.SysInfo
+}
+
+type textSection struct {
+	name   string
+	render func(*textReport)
+}
+
+// textSections are the sections of the text report in default order.
+var textSections = []textSection{
+	{"host", (*textReport).host},
+	{"process", (*textReport).process},
+	{"cpu", (*textReport).cpu},
+	{"load", (*textReport).load},
+	{"memory", (*textReport).memory},
+	{"numa", (*textReport).numa},
+	{"sysctl", (*textReport).sysctl},
+	{"cgroup", (*textReport).cgroup},
+	{"mounts", (*textReport).mounts},
+	{"disk_health", (*textReport).diskHealth},
+	{"disk_io", (*textReport).diskIO},
+	{"bind_files", (*textReport).bindFiles},
+	{"config_files", (*textReport).configFiles},
+	{"network", (*textReport).network},
+	{"containers", (*textReport).containers},
+	{"agents", (*textReport).agents},
+	{"irq", (*textReport).irq},
+	{"isolation", (*textReport).isolation},
+	{"hwmon", (*textReport).hwmon},
+	{"sensors", (*textReport).sensors},
+	{"security", (*textReport).security},
+	{"findings", (*textReport).findings},
+	{"explain", (*textReport).explain},
+}
+
+// printText renders the table report. With prev set (watch mode), FD
+// count, VmRSS and per-mount free space also show the change since prev.
+func printText(out io.Writer, info, prev *sysinfo.SysInfo) {
+	r := &textReport{info: info, prev: prev}
+	written := false
+	for _, s := range orderedSections() {
+		var buf bytes.Buffer
+		r.w = &buf
+		s.render(r)
+		// Sections are separated by one blank line wherever --section-order
+		// puts them, and each is aligned on its own: the blank lines they
+		// were written with are dropped and the tabwriter is flushed after
+		// every section, so a wide table does not widen its neighbours.
+		section := bytes.Trim(buf.Bytes(), "\n")
+		if len(bytes.TrimSpace(section)) == 0 {
+			continue
+		}
+		if written {
+			fmt.Fprintln(out)
+		}
+		if title, ok := sectionTitle(s.name); ok {
+			fmt.Fprintln(out, title)
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		w.Write(section)
+		fmt.Fprintln(w)
+		w.Flush()
+		written = true
+	}
+}
+
+// orderedSections returns textSections with those in sectionOrder first,
+// in that order.
+func orderedSections() []textSection {
+	sections := make([]textSection, 0, len(textSections))
+	for _, name := range sectionOrder {
+		i := slices.IndexFunc(textSections, func(s textSection) bool { return s.name == name })
+		sections = append(sections, textSections[i])
+	}
+	for _, s := range textSections {
+		if !slices.Contains(sectionOrder, s.name) {
+			sections = append(sections, s)
+		}
+	}
+	return sections
+}
+
+func sectionNames() string {
+	names := make([]string, len(textSections))
+	for i, s := range textSections {
+		names[i] = s.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// sectionFlags defines --section-order, --section-title and --config on
+// fs.
+func sectionFlags(fs *flag.FlagSet) {
+	fs.Func("section-order", "comma-separated text sections to print first, in this order (others follow as usual): "+sectionNames(), func(list string) error {
+		sectionOrder = nil
+		for _, name := range strings.Split(list, ",") {
+			name = strings.TrimSpace(name)
+			if !isSection(name) {
+				return fmt.Errorf("unknown section %q", name)
+			}
+			if !slices.Contains(sectionOrder, name) {
+				sectionOrder = append(sectionOrder, name)
+			}
+		}
+		return nil
+	})
+	fs.Func("section-title", "print a heading above a text section, as section=Title (repeatable)", func(v string) error {
+		name, title, ok := strings.Cut(v, "=")
+		if !ok || !isSection(name) {
+			return fmt.Errorf("want section=Title, with a section of %s", sectionNames())
+		}
+		sectionTitles[name] = title
+		return nil
+	})
+	fs.StringVar(&configPath, "config", "", "read the section titles from this JSON file (default $XDG_CONFIG_HOME/sysinfo/config.json, when it exists)")
+}
+
+func isSection(name string) bool {
+	return slices.ContainsFunc(textSections, func(s textSection) bool { return s.name == name })
+}
+
+func (r *textReport) unavailable(label, field string) bool {
+	reason, ok := r.info.Errors[field]
+	if ok {
+		fmt.Fprintln(r.w, label+":\t", "unavailable ("+reason+")")
+	}
+	return ok
+}
+
+func (r *textReport) row(label, field string, v ...any) {
+	if !r.unavailable(label, field) {
+		fmt.Fprintln(r.w, append([]any{label + ":\t"}, v...)...)
+	}
+}
+
+func (r *textReport) show(keys ...string) bool {
+	if len(selectedFields) == 0 {
+		return true
+	}
+	for _, k := range keys {
+		if selectedFields[k] {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *textReport) host() {
+	w, info := r.w, r.info
+	if h := info.Host; r.show("host") && !r.unavailable("Host", "host") && h != nil {
+		fmt.Fprintln(w, "Host:\t", h.Hostname)
+		if h.OS != nil {
+			fmt.Fprintln(w, "OS:\t", cmp.Or(h.OS.PrettyName, strings.TrimSpace(h.OS.ID+" "+h.OS.VersionID)))
+		}
+		fmt.Fprintln(w, "Kernel:\t", strings.TrimSpace(h.KernelRelease+" "+h.Arch))
+		if h.BootTime != "" {
+			fmt.Fprintln(w, "Booted:\t", h.BootTime)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func (r *textReport) process() {
+	w, info, prev := r.w, r.info, r.prev
+	if r.show("pid", "comm") && !r.unavailable("PID", "pid") {
+		fmt.Fprintf(w, "PID:\t %d (%s)\n", info.PID, info.Comm)
+	}
+	fdCount := strconv.Itoa(info.FDCount)
+	if l := info.Rlimits["nofile"]; l.Soft != nil && *l.Soft > 0 {
+		fdCount += fmt.Sprintf(" of %d (%.1f%%)", *l.Soft, float64(info.FDCount)/float64(*l.Soft)*100)
+	}
+	switch {
+	case !r.show("fd_count"):
+	case prev != nil:
+		r.row("FDs count", "fd_count", fdCount, fmt.Sprintf("(%+d)", info.FDCount-prev.FDCount))
+	default:
+		r.row("FDs count", "fd_count", fdCount)
+	}
+	if r.show("fd_types", "fds") && !r.unavailable("FD types", "fds") && len(info.FDTypes) > 0 {
+		fmt.Fprintln(w, "FD types:\t", formatFDTypes(info.FDTypes))
+	}
+	if r.show("rlimits") && !r.unavailable("Rlimits", "rlimits") && info.Rlimits != nil {
+		for _, name := range []string{"nofile", "nproc", "as", "memlock", "core"} {
+			if l, ok := info.Rlimits[name]; ok {
+				fmt.Fprintf(w, "Rlimit %s:\t %s / %s %s\n", name, formatRlimit(l.Soft), formatRlimit(l.Hard), l.Unit)
+			}
+		}
+	}
+	if r.show("fds") && len(info.FDs) > 0 {
+		fds := slices.Clone(info.FDs)
+		slices.SortStableFunc(fds, func(a, b sysinfo.FDInfo) int { return strings.Compare(a.Type, b.Type) })
+		for _, fd := range fds {
+			fmt.Fprintf(w, "  fd %d %s:\t %s\n", fd.Num, fd.Type, fd.Target)
+		}
+	}
+	// PSS goes next to VmRSS: it is what the process costs once shared
+	// pages are split among the processes mapping them.
+	var pss []any
+	if md := info.MemoryDetail; md != nil && r.show("memory_detail") {
+		pss = []any{"(PSS " + formatSize(md.PssBytes) + ")"}
+	}
+	switch {
+	case !r.show("vmrss_bytes"):
+		// Without VmRSS (--fields memory_detail) PSS has a row of its own.
+		if pss != nil {
+			fmt.Fprintf(w, "PSS:\t %s\n", formatSize(info.MemoryDetail.PssBytes))
+		}
+	case prev != nil:
+		r.row("VmRSS", "vmrss_bytes", append([]any{formatSize(uint64(info.VmRSS) * 1024),
+			"(" + formatSignedSize(int64(info.VmRSS-prev.VmRSS)*1024) + ")"}, pss...)...)
+	default:
+		r.row("VmRSS", "vmrss_bytes", append([]any{formatSize(uint64(info.VmRSS) * 1024)}, pss...)...)
+	}
+	if r.show("memory_detail") && !r.unavailable("Memory detail", "memory_detail") && info.MemoryDetail != nil {
+		md := info.MemoryDetail
+		fmt.Fprintf(w, "Shared clean/dirty:\t %s / %s\n", formatSize(md.SharedCleanBytes), formatSize(md.SharedDirtyBytes))
+		fmt.Fprintf(w, "Private clean/dirty:\t %s / %s\n", formatSize(md.PrivateCleanBytes), formatSize(md.PrivateDirtyBytes))
+		fmt.Fprintf(w, "Swap (SwapPss):\t %s (%s)\n", formatSize(md.SwapBytes), formatSize(md.SwapPssBytes))
+	}
+	if rss := info.RSS; r.show("rss") && rss != nil && rss.Split {
+		fmt.Fprintf(w, "RSS anon/file/shmem:\t %s / %s / %s", formatSize(uint64(rss.AnonBytes)),
+			formatSize(uint64(rss.FileBytes)), formatSize(uint64(rss.ShmemBytes)))
+		if rss.Delta != nil {
+			fmt.Fprintf(w, " (%s / %s / %s)", formatSignedSize(rss.Delta.AnonBytes),
+				formatSignedSize(rss.Delta.FileBytes), formatSignedSize(rss.Delta.ShmemBytes))
+		}
+		fmt.Fprintln(w)
+	}
+	if r.show("rss_growth") && !r.unavailable("VmRSS growth", "rss_growth") && info.RSSGrowth != nil {
+		fmt.Fprintf(w, "VmRSS growth:\t %s over %.1fs (%.0f B/s)\n",
+			formatSignedSize(info.RSSGrowth.DeltaBytes), info.RSSGrowth.IntervalSeconds, info.RSSGrowth.RSSGrowthBytesPerSec)
+	}
+	if r.show("exe_path") {
+		r.row("EXE path", "exe_path", info.ExePath)
+	}
+	if r.show("process") && !r.unavailable("Peak RSS", "peak_rss") && info.Process != nil && info.Process.PeakRSSBytes != nil {
+		fmt.Fprintln(w, "Peak RSS (VmHWM):\t", formatSize(*info.Process.PeakRSSBytes))
+	}
+	// One line for what a stuck service shows: D-state threads and
+	// nonvoluntary switches.
+	if p := info.Process; r.show("process") && !r.unavailable("Threads", "thread_states") && p != nil && p.ThreadsTotal != nil {
+		fmt.Fprintf(w, "Threads:\t %d", *p.ThreadsTotal)
+		states := slices.SortedFunc(maps.Keys(p.ThreadStates), func(a, b string) int {
+			return cmp.Or(p.ThreadStates[b]-p.ThreadStates[a], strings.Compare(a, b))
+		})
+		sep := " ("
+		for _, s := range states {
+			fmt.Fprintf(w, "%s%d %s", sep, p.ThreadStates[s], s)
+			sep = ", "
+		}
+		if len(states) > 0 {
+			fmt.Fprint(w, ")")
+		}
+		if c := p.CtxSwitches; c != nil {
+			fmt.Fprintf(w, ", ctx switches vol/nonvol %d / %d", c.Voluntary, c.Nonvoluntary)
+			if c.VoluntaryPerSec != nil && c.NonvoluntaryPerSec != nil {
+				fmt.Fprintf(w, " (%.1f/s / %.1f/s)", *c.VoluntaryPerSec, *c.NonvoluntaryPerSec)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	if r.show("process") {
+		r.unavailable("Ctx switches", "sched")
+	}
+	if r.show("process") && !r.unavailable("CPU time", "cpu_time") && info.Process != nil && info.Process.CPUTime != nil {
+		t := info.Process.CPUTime
+		fmt.Fprintf(w, "CPU time user/sys:\t %.2fs / %.2fs (children %.2fs / %.2fs)\n",
+			t.UserSeconds, t.SystemSeconds, t.ChildrenUserSeconds, t.ChildrenSystemSeconds)
+	}
+	if r.show("process_tree") && !r.unavailable("Process tree", "process_tree") && len(info.ProcessTree) > 0 {
+		t := info.ProcessTreeTotal
+		fdTotal := strconv.Itoa(t.FDs)
+		if t.FDsUnknown > 0 {
+			fdTotal += fmt.Sprintf(" (+%d unreadable)", t.FDsUnknown)
+		}
+		fmt.Fprintf(w, "Process tree:\t %d processes, RSS %s, %d threads, %s fds\n",
+			t.Processes, formatSize(t.RSSBytes), t.Threads, fdTotal)
+		fmt.Fprintln(w, "PID:\tState:\tRSS:\tThreads:\tFDs:")
+		for _, p := range info.ProcessTree {
+			fds := "?"
+			if p.FDs != nil {
+				fds = strconv.Itoa(*p.FDs)
+			}
+			fmt.Fprintf(w, "%s%d %s\t%s\t%s\t%d\t%s\n", strings.Repeat("  ", p.Depth), p.PID, p.Comm,
+				p.State, formatSize(p.RSSBytes), p.Threads, fds)
+		}
+	}
+	if r.show("threads") && !r.unavailable("Threads", "threads") && info.Threads != nil {
+		t := info.Threads
+		limits := []string{fmt.Sprintf("%d threads", t.Total)}
+		if t.PidsMax != nil {
+			limits = append(limits, fmt.Sprintf("pids.max %d", *t.PidsMax))
+		}
+		if t.NprocLimit != nil {
+			limits = append(limits, fmt.Sprintf("nproc %d", *t.NprocLimit))
+		}
+		fmt.Fprintln(w, "Thread limits:\t", strings.Join(limits, " of "))
+		// The busiest groups by count; JSON has every thread.
+		const shown = 8
+		var groups []string
+		for _, g := range t.Groups[:min(len(t.Groups), shown)] {
+			groups = append(groups, fmt.Sprintf("%s x%d (%.1fs)", g.Name, g.Count, g.CPUSeconds))
+		}
+		if len(t.Groups) > shown {
+			groups = append(groups, fmt.Sprintf("+%d more", len(t.Groups)-shown))
+		}
+		fmt.Fprintln(w, "Thread groups:\t", strings.Join(groups, ", "))
+	}
+}
+
+func (r *textReport) cpu() {
+	w, info := r.w, r.info
+	if r.show("cpu_model") {
+		r.row("CPU model", "cpu", info.CPUModel)
+	}
+	if r.show("cpu_cores") {
+		r.row("CPU cores", "cpu", info.CPUCores)
+	}
+	if r.show("cores") && len(info.Cores) > 0 {
+		// Only hybrid CPUs get a breakdown; otherwise it repeats CPU model.
+		var models []string
+		byModel := make(map[string][]string)
+		for _, c := range info.Cores {
+			if _, seen := byModel[c.Model]; !seen {
+				models = append(models, c.Model)
+			}
+			byModel[c.Model] = append(byModel[c.Model], strconv.Itoa(c.Processor))
+		}
+		if len(models) > 1 {
+			for _, m := range models {
+				fmt.Fprintf(w, "  %s:\t %d cores (cpu %s)\n", m, len(byModel[m]), strings.Join(byModel[m], ","))
+			}
+		}
+	}
+	if cpu := info.CPU; r.show("cpu") && cpu != nil {
+		fmt.Fprintf(w, "CPU topology:\t %d socket(s), %d physical, %d logical\n",
+			cpu.Sockets, cpu.PhysicalCores, cpu.LogicalCores)
+		switch {
+		case cpu.MaxMHz > 0:
+			fmt.Fprintf(w, "CPU MHz:\t %.0f (max %.0f)\n", cpu.MHz, cpu.MaxMHz)
+		case cpu.MHz > 0:
+			fmt.Fprintf(w, "CPU MHz:\t %.0f\n", cpu.MHz)
+		}
+	}
+	if r.show("cpu_flags") && !r.unavailable("CPU flags", "cpu") && len(info.CPUFlags) > 0 {
+		fmt.Fprintln(w, "CPU flags:\t", strings.Join(info.CPUFlags, " "))
+	}
+	if r.show("virtualized") && !r.unavailable("Virtualized", "cpu") {
+		virtualized := "no"
+		if info.Virtualized {
+			virtualized = "yes (hypervisor flag)"
+		}
+		fmt.Fprintln(w, "Virtualized:\t", virtualized)
+	}
+	if r.show("cpu_usage_percent") && !r.unavailable("CPU usage", "cpu_sample") && info.CPUUsagePercent != nil {
+		fmt.Fprintf(w, "CPU usage:\t %.1f%%\n", *info.CPUUsagePercent)
+	}
+	if r.show("cpufreq") && !r.unavailable("CPU turbo", "cpufreq") && info.CPUFreq != nil {
+		switch {
+		case info.CPUFreq.TurboEnabled == nil:
+			fmt.Fprintln(w, "CPU turbo:\t", "unknown")
+		case *info.CPUFreq.TurboEnabled:
+			fmt.Fprintln(w, "CPU turbo:\t", "enabled")
+		default:
+			fmt.Fprintln(w, "CPU turbo:\t", "disabled")
+		}
+	}
+	if t := info.Throttle; r.show("throttle") && !r.unavailable("CPU throttling", "throttle") && t != nil {
+		line := fmt.Sprintf("avg %.0f%% of max over %gs", t.AvgFreqPercent, t.WindowSeconds)
+		switch {
+		case t.CoreThrottleEvents == nil && t.PackageThrottleEvents == nil:
+			line += ", no throttle counters"
+		default:
+			line += fmt.Sprintf(", %d thermal events", t.Events())
+		}
+		if t.LimitPercent < 100 {
+			line += fmt.Sprintf(", capped at %.0f%% of hardware max by %s", t.LimitPercent, t.LimitSource)
+		} else if t.Capped {
+			line += ", thermally capped"
+		}
+		fmt.Fprintln(w, "CPU throttling:\t", line)
+	}
+	if r.show("sched_features") && !r.unavailable("Sched features", "sched_features") && info.SchedFeatures != nil {
+		fmt.Fprintln(w, "Sched features:\t", formatSchedFeatures(info.SchedFeatures))
+	}
+}
+
+func (r *textReport) load() {
+	w, info := r.w, r.info
+	if r.show("uptime_seconds") {
+		r.row("Uptime", "uptime", formatUptime(info.UptimeSeconds), "(since "+info.BootTime+")")
+	}
+	if r.show("loadavg") && !r.unavailable("Load avg", "loadavg") && info.LoadAvg != nil {
+		l := info.LoadAvg
+		fmt.Fprintf(w, "Load avg:\t %.2f %.2f %.2f\n", l.One, l.Five, l.Fifteen)
+		fmt.Fprintf(w, "Processes:\t %d running / %d total\n", l.RunnableProcs, l.TotalProcs)
+	}
+	if r.show("pressure") && !r.unavailable("Pressure", "pressure") {
+		printPressure(w, "Pressure", info.Pressure)
+	}
+}
+
+func (r *textReport) memory() {
+	w, info := r.w, r.info
+	if m := info.Memory; r.show("memory", "mem_total_kb", "mem_available_kb", "mem_used_percent") &&
+		!r.unavailable("Memory", "memory") && m != nil {
+		fmt.Fprintf(w, "Memory total/avail/used:\t %s / %s / %s (%.1f%%)\n", formatSize(m.TotalBytes),
+			formatSize(m.AvailableBytes), formatSize(m.UsedBytes), info.MemUsedPct)
+	}
+	if m := info.Memory; r.show("memory", "swap_total_kb", "swap_free_kb") && m != nil {
+		if m.SwapTotalBytes == 0 {
+			fmt.Fprintln(w, "Swap:\t", "none")
+		} else {
+			fmt.Fprintf(w, "Swap used:\t %s of %s\n", formatSize(m.SwapTotalBytes-m.SwapFreeBytes), formatSize(m.SwapTotalBytes))
+		}
+	}
+	if sw := info.Swap; r.show("swap") && !r.unavailable("Swap areas", "swap") && sw != nil {
+		for _, d := range sw.Devices {
+			kind := d.Type
+			switch {
+			case d.Zram:
+				kind += ", zram"
+			case len(d.Backing) == 0:
+				kind += ", backing unknown"
+			case d.Encrypted:
+				kind += ", encrypted"
+			default:
+				kind += ", not encrypted"
+			}
+			fmt.Fprintf(w, "  %s:\t %s of %s (%s, prio %d) %s\n", d.Path, formatSize(d.UsedBytes),
+				formatSize(d.SizeBytes), kind, d.Priority, strings.Join(d.Backing, " <- "))
+		}
+		for _, z := range sw.Zram {
+			line := fmt.Sprintf("%s -> %s", formatSize(z.OrigDataBytes), formatSize(z.ComprDataBytes))
+			if z.Ratio != nil {
+				saved := "saves " + formatSize(uint64(z.SavedBytes))
+				if z.SavedBytes < 0 {
+					saved = "costs " + formatSize(uint64(-z.SavedBytes))
+				}
+				line += fmt.Sprintf(" (ratio %.2f, %s)", *z.Ratio, saved)
+			}
+			line += fmt.Sprintf(", %s, same pages %d", cmp.Or(z.Algorithm, "?"), z.SamePages)
+			if z.HugePages != nil {
+				line += fmt.Sprintf(", huge pages %d", *z.HugePages)
+			}
+			if wb := z.Writeback; wb != nil {
+				line += fmt.Sprintf(", writeback to %s: %s stored, %s written, %s read back", wb.BackingDevice,
+					formatSize(wb.StoredBytes), formatSize(wb.WrittenBytes), formatSize(wb.ReadBytes))
+			}
+			fmt.Fprintf(w, "  %s:\t %s\n", z.Name, line)
+		}
+		if z := sw.Zswap; z != nil {
+			line := "disabled"
+			if z.Enabled {
+				line = "enabled (" + cmp.Or(z.Compressor, "?")
+				if z.MaxPoolPercent != nil {
+					line += fmt.Sprintf(", max pool %d%%", *z.MaxPoolPercent)
+				}
+				line += ")"
+			}
+			if z.StoredPages != nil && z.PoolTotalBytes != nil {
+				line += fmt.Sprintf(", %d pages in %s", *z.StoredPages, formatSize(*z.PoolTotalBytes))
+				if z.Ratio != nil {
+					line += fmt.Sprintf(" (ratio %.2f)", *z.Ratio)
+				}
+			}
+			if len(z.Rejects) > 0 {
+				var rejects []string
+				for _, name := range slices.Sorted(maps.Keys(z.Rejects)) {
+					rejects = append(rejects, fmt.Sprintf("%s=%d", name, z.Rejects[name]))
+				}
+				line += ", rejects " + strings.Join(rejects, " ")
+			}
+			fmt.Fprintln(w, "Zswap:\t", line)
+		}
+		hibernation := "not available"
+		if sw.Hibernation {
+			hibernation = "available"
+			if sw.ResumeDevice != "" {
+				hibernation += ", resume=" + sw.ResumeDevice
+			}
+		}
+		fmt.Fprintln(w, "Hibernation:\t", hibernation)
+	}
+	if pc := info.PageCache; r.show("page_cache") && !r.unavailable("Page cache", "page_cache") && pc != nil {
+		fmt.Fprintf(w, "Page cache:\t %s (dirty %s, writeback %s, from %s)\n", formatSize(pc.FileBytes),
+			formatSize(pc.DirtyBytes), formatSize(pc.WritebackBytes), pc.Source)
+		for _, d := range pc.Devices {
+			fmt.Fprintf(w, "  %s %s:\t ~%s dirty, ~%s writeback (%.1f%%) %s\n", d.Device, d.Source,
+				formatSize(d.DirtyBytes), formatSize(d.WritebackBytes), d.DirtySharePercent, strings.Join(d.Mounts, " "))
+		}
+		fmt.Fprintln(w, "  approximate:\t clean pages are not attributed to devices")
+	}
+}
+
+func (r *textReport) numa() {
+	w, info := r.w, r.info
+	if r.show("numa_nodes") && !r.unavailable("NUMA nodes", "numa_nodes") && len(info.NUMANodes) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "NUMA nodes:\t", len(info.NUMANodes))
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Node:\tCPUs:\tMemTotal:\tMemFree:")
+		for _, n := range info.NUMANodes {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", n.Node, cmp.Or(n.CPUList, "-"), formatSize(n.MemTotalBytes), formatSize(n.MemFreeBytes))
+		}
+	}
+}
+
+func (r *textReport) sysctl() {
+	w, info := r.w, r.info
+	if r.show("sysctl") && !r.unavailable("Sysctl", "sysctl") && len(info.Sysctls) > 0 {
+		names := slices.Sorted(maps.Keys(info.Sysctls))
+		for i, name := range names {
+			names[i] = name + "=" + info.Sysctls[name]
+		}
+		fmt.Fprintln(w, "Sysctl:\t", strings.Join(names, " "))
+	}
+}
+
+func (r *textReport) cgroup() {
+	w, info := r.w, r.info
+	if r.show("container_runtime") && !r.unavailable("Container", "container_runtime") {
+		runtime := info.ContainerRuntime
+		if runtime == "" {
+			runtime = "host"
+		}
+		fmt.Fprintln(w, "Container:\t", runtime)
+	}
+	if cg := info.CgroupV1; r.show("cgroup_v1") && cg != nil {
+		if !r.unavailable("Cgroup (v1) MemLimit", "memory_limit") {
+			if cg.MemoryLimitBytes == nil {
+				fmt.Fprintln(w, "Cgroup (v1) MemLimit:\t", paint(colorDim, "unlimited"))
+			} else {
+				fmt.Fprintln(w, "Cgroup (v1) MemLimit:\t", formatSize(*cg.MemoryLimitBytes))
+			}
+		}
+		if !r.unavailable("Cgroup (v1) CPULimit", "cpu_limit") {
+			if cg.CPULimitCores == nil {
+				fmt.Fprintln(w, "Cgroup (v1) CPULimit:\t", paint(colorDim, "unlimited"))
+			} else {
+				fmt.Fprintf(w, "Cgroup (v1) CPULimit:\t%.2f cores\n", *cg.CPULimitCores)
+			}
+		}
+		if !r.unavailable("Cgroup (v1) MemUsage", "memory_usage") && cg.MemoryUsageBytes != nil {
+			fmt.Fprintln(w, "Cgroup (v1) MemUsage:\t", usedOfLimit(*cg.MemoryUsageBytes, cg.MemoryLimitBytes))
+		}
+		if !r.unavailable("Cgroup (v1) MemPeak", "memory_peak") && cg.MemoryPeakBytes != nil {
+			fmt.Fprintln(w, "Cgroup (v1) MemPeak:\t", usedOfLimit(*cg.MemoryPeakBytes, cg.MemoryLimitBytes))
+		}
+		if !r.unavailable("Cgroup (v1) CPU usage", "cpu_usage") {
+			if cg.CPUUsageNs != nil {
+				fmt.Fprintf(w, "Cgroup (v1) CPU usage:\t %.1fs\n", float64(*cg.CPUUsageNs)/1e9)
+			}
+			printThrottling(w, "Cgroup (v1) Throttled", cg.Throttling)
+		}
+		if info.CgroupV2 == nil && !r.unavailable("Cgroup (v1) CPU of quota", "cgroup_cpu_utilization") && cg.CPUUtilizationPercent != nil {
+			fmt.Fprintf(w, "Cgroup (v1) CPU of quota:\t %.1f%%\n", *cg.CPUUtilizationPercent)
+		}
+	}
+	if cg := info.CgroupV2; r.show("cgroup_v2") && !r.unavailable("Cgroup (v2)", "cgroup_v2") && cg != nil {
+		if cg.MemoryCurrentBytes != nil {
+			fmt.Fprintln(w, "Cgroup (v2) Memory:\t", usedOfLimit(*cg.MemoryCurrentBytes, cg.MemoryMaxBytes))
+		}
+		if cg.MemoryPeakBytes != nil {
+			fmt.Fprintln(w, "Cgroup (v2) MemPeak:\t", usedOfLimit(*cg.MemoryPeakBytes, cg.MemoryMaxBytes))
+		}
+		if cg.MemoryHighBytes != nil {
+			fmt.Fprintln(w, "Cgroup (v2) MemHigh:\t", formatSize(*cg.MemoryHighBytes))
+		}
+		if cg.CPUMaxCores == nil {
+			fmt.Fprintln(w, "Cgroup (v2) CPULimit:\t", paint(colorDim, "unlimited"))
+		} else {
+			fmt.Fprintf(w, "Cgroup (v2) CPULimit:\t %.2f cores\n", *cg.CPUMaxCores)
+		}
+		fmt.Fprintf(w, "Cgroup (v2) CPU usage:\t %.1fs\n", float64(cg.CPUUsageUsec)/1e6)
+		if !r.unavailable("Cgroup (v2) CPU of quota", "cgroup_cpu_utilization") && cg.CPUUtilizationPercent != nil {
+			fmt.Fprintf(w, "Cgroup (v2) CPU of quota:\t %.1f%%\n", *cg.CPUUtilizationPercent)
+		}
+		printThrottling(w, "Cgroup (v2) Throttled", cg.Throttling)
+		printPressure(w, "Cgroup (v2) pressure", cg.Pressure)
+	}
+}
+
+func (r *textReport) mounts() {
+	w, info, prev := r.w, r.info, r.prev
+	if r.show("mounts") && !r.unavailable("Mounts count", "mounts") {
+		fmt.Fprintln(w)
+		if mountTop > 0 && len(info.Mounts) > mountTop {
+			order := "in /proc/mounts order"
+			if mountSort != "" {
+				order = "by " + mountSort
+			}
+			fmt.Fprintf(w, "Mounts count:\t %d (showing %d %s)\n", len(info.Mounts), mountTop, order)
+		} else {
+			fmt.Fprintln(w, "Mounts count:\t", len(info.Mounts))
+		}
+		fmt.Fprintln(w)
+
+		header := "IFree:"
+		if longTable {
+			header += "\tOptions:"
+		}
+		fmt.Fprintf(w, "Mount:\tFS:\tTotal:\tFree:\t%s\t%s\t%s\n", paint(colorDefault, "Used%:"), paint(colorDefault, "Inodes%:"), header)
+
+		prevAvail := make(map[string]uint64)
+		if prev != nil {
+			for _, d := range prev.Mounts {
+				prevAvail[d.Mountpoint] = d.Avail
+			}
+		}
+		for _, d := range orderMounts(info.Mounts) {
+			options := ""
+			if longTable {
+				options = "\t" + cmp.Or(strings.Join(d.Options, ","), "-")
+			}
+			if d.Error != "" {
+				unknown := paint(colorDefault, "?")
+				fmt.Fprintf(w, "%s\t%s\t?\t?\t%s\t%s\t?%s\t(%s)\n", d.Mountpoint, d.FSType, unknown, unknown, options, d.Error)
+				continue
+			}
+			free := formatSize(d.Avail)
+			if before, ok := prevAvail[d.Mountpoint]; ok {
+				free += " (" + formatSignedSize(int64(d.Avail)-int64(before)) + ")"
+			}
+			// Files == 0: tmpfs with nr_inodes=0 and some network
+			// filesystems do not count inodes.
+			inodes, inodesFree := paint(colorDefault, "-"), "-"
+			if d.Inodes > 0 {
+				inodes = paint(usageColor(d.InodesUsedPercent), fmt.Sprintf("%.1f%%", d.InodesUsedPercent))
+				inodesFree = strconv.FormatUint(d.InodesFree, 10)
+			}
+			used := paint(usageColor(d.UsedPercent), fmt.Sprintf("%.1f%%", d.UsedPercent))
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n",
+				d.Mountpoint, d.FSType, formatSize(d.Total), free, used, inodes, inodesFree, options)
+		}
+	}
+}
+
+func (r *textReport) diskHealth() {
+	w, info := r.w, r.info
+	if r.show("disk_health") {
+		if _, failed := info.Errors["disk_health"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Disk health", "disk_health")
+		} else if len(info.DiskHealth) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "Disk:\tHealth:\tLatency:\tReasons:")
+			for _, d := range info.DiskHealth {
+				latency := "-"
+				if d.AvgLatencyMs != nil {
+					latency = fmt.Sprintf("%.1f ms", *d.AvgLatencyMs)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.Device, d.Health, latency, strings.Join(d.Reasons, ", "))
+			}
+		}
+	}
+}
+
+func (r *textReport) diskIO() {
+	w, info := r.w, r.info
+	if r.show("disk_io") {
+		if _, failed := info.Errors["disk_io"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Disk I/O", "disk_io")
+		} else if len(info.DiskIO) > 0 {
+			rates := slices.ContainsFunc(info.DiskIO, func(d sysinfo.DiskIOStat) bool { return d.ReadsPerSec != nil })
+			fmt.Fprintln(w)
+			if rates {
+				fmt.Fprintln(w, "Disk:\tReads:\tRead:\tWrites:\tWritten:\tRead/s:\tWrite/s:\tIOPS:")
+			} else {
+				fmt.Fprintln(w, "Disk:\tReads:\tRead:\tWrites:\tWritten:")
+			}
+			for _, d := range info.DiskIO {
+				// A re-rendered report has the counters only if it was
+				// written with --raw-counters.
+				if c, ok := d.Counters(); ok {
+					fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s", d.Device, c.ReadsCompleted, formatSize(c.ReadBytes()),
+						c.WritesCompleted, formatSize(c.WrittenBytes()))
+				} else {
+					fmt.Fprintf(w, "%s\t-\t-\t-\t-", d.Device)
+				}
+				if rates && d.ReadsPerSec != nil {
+					fmt.Fprintf(w, "\t%s\t%s\t%.0f", formatSize(uint64(*d.ReadBytesPerSec)), formatSize(uint64(*d.WriteBytesPerSec)),
+						*d.ReadsPerSec+*d.WritesPerSec)
+				} else if rates {
+					fmt.Fprint(w, "\t-\t-\t-")
+				}
+				fmt.Fprintln(w)
+			}
+		}
+	}
+}
+
+func (r *textReport) bindFiles() {
+	w, info := r.w, r.info
+	if r.show("bind_files") {
+		if _, failed := info.Errors["bind_files"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Bind-mounted files", "bind_files")
+		} else if len(info.BindFiles) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "Bind file:\tSource:\tRoot:")
+			for _, b := range info.BindFiles {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", b.Target, b.Source, b.Root)
+			}
+		}
+	}
+}
+
+func (r *textReport) configFiles() {
+	w, info := r.w, r.info
+	if r.show("config_files") && len(info.ConfigFiles) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Config file:\tSize:\tModified:\tSHA256:\tSymlink:")
+		for _, f := range info.ConfigFiles {
+			if f.Error != "" {
+				fmt.Fprintf(w, "%s\t?\t\t%s\t%s\n", f.Path, f.Error, f.SymlinkTarget)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", f.Path, formatSize(uint64(f.Size)),
+				f.Mtime.Format(time.RFC3339), f.SHA256, f.SymlinkTarget)
+		}
+	}
+}
+
+func (r *textReport) network() {
+	w, info := r.w, r.info
+	if r.show("network") {
+		if _, failed := info.Errors["dns"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("DNS", "dns")
+		} else if info.Network != nil && info.Network.DNS != nil {
+			dns := info.Network.DNS
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "NSS hosts:\t", dns.NSSHosts)
+			if dns.ResolvConfTarget != "" {
+				fmt.Fprintln(w, "resolv.conf ->\t", dns.ResolvConfTarget)
+			}
+			fmt.Fprintln(w, "Nameservers:\t", strings.Join(dns.Nameservers, " "))
+			if len(dns.Search) > 0 {
+				fmt.Fprintln(w, "Search:\t", strings.Join(dns.Search, " "))
+			}
+			fmt.Fprintln(w, "ndots:\t", dns.Ndots)
+		}
+
+		if _, failed := info.Errors["interfaces"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Interfaces", "interfaces")
+		} else if info.Network != nil && len(info.Network.Interfaces) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "Interface:\tMAC:\tMTU:\tState:\tAddresses:\tRX:\tTX:")
+			for _, ni := range info.Network.Interfaces {
+				if noLoopback && ni.Loopback || !allInterfaces && !ni.Up {
+					continue
+				}
+				addrs := strings.Join(ni.Addresses, " ")
+				if addrs == "" {
+					addrs = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s (%d pkts)\t%s (%d pkts)\n", ni.Name, ni.MAC, ni.MTU, ni.OperState,
+					addrs, formatSize(ni.RxBytes), ni.RxPackets, formatSize(ni.TxBytes), ni.TxPackets)
+			}
+		}
+
+		if _, failed := info.Errors["listening"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Listening", "listening")
+		} else if info.Network != nil {
+			if r := info.Network.DefaultRoute; r != nil {
+				fmt.Fprintln(w)
+				fmt.Fprintf(w, "Default gateway:\t %s via %s (%s)\n", r.Gateway, r.Device, r.Interface)
+			}
+			if len(info.Network.Listening) > 0 {
+				fmt.Fprintln(w)
+				fmt.Fprintln(w, "Proto:\tAddress:\tPort:\tInterface:")
+				for _, s := range info.Network.Listening {
+					fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", s.Proto, s.Address, s.Port, s.Interface)
+				}
+			}
+		}
+	}
+}
+
+func (r *textReport) containers() {
+	w, info := r.w, r.info
+	if r.show("containers") {
+		if _, failed := info.Errors["containers"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Containers", "containers")
+		} else if len(info.Containers) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "Container:\tCPU%:\tProcs:\tMemory:")
+			for _, c := range info.Containers {
+				mem := "-"
+				if c.MemoryCurrentBytes != nil {
+					mem = formatSize(*c.MemoryCurrentBytes)
+				}
+				fmt.Fprintf(w, "%.12s\t%.1f%%\t%d\t%s\n", c.ID, c.CPUPercent, c.Processes, mem)
+			}
+		}
+	}
+}
+
+func (r *textReport) agents() {
+	w, info := r.w, r.info
+	if r.show("agents") {
+		if _, failed := info.Errors["agents"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Agents", "agents")
+		} else if len(info.Agents) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "Agent:\tPID:\tVersion:\tExe:")
+			for _, a := range info.Agents {
+				version := cmp.Or(a.Version, "unknown")
+				if a.GoVersion != "" {
+					version += " (" + a.GoVersion + ")"
+				}
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", a.Name, a.PID, version, cmp.Or(a.Exe, "-"))
+			}
+		}
+	}
+}
+
+func (r *textReport) irq() {
+	w, info := r.w, r.info
+	if r.show("irq") {
+		if _, failed := info.Errors["irq"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Softirq", "irq")
+		} else if info.IRQ != nil {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "Softirq:\tTotal:\tImbalance:")
+			for _, s := range info.IRQ.Softirqs {
+				fmt.Fprintf(w, "%s\t%d\t%.2f\n", s.Name, s.Total, s.Imbalance)
+			}
+			if len(info.IRQ.TopIRQs) > 0 {
+				fmt.Fprintln(w)
+				fmt.Fprintln(w, "IRQ:\tTotal:\tAffinity:\tDevice:")
+				for _, irq := range info.IRQ.TopIRQs {
+					fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", irq.IRQ, irq.Total, irq.AffinityList, irq.Device)
+				}
+			}
+		}
+	}
+}
+
+func (r *textReport) isolation() {
+	w, info := r.w, r.info
+	if r.show("isolation") {
+		if _, failed := info.Errors["isolation"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Isolation", "isolation")
+		} else if info.Isolation != nil {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "CPU:\tIsolated:\tnohz_full:\trcu_nocbs:\tProcess:\tIRQ:")
+			for _, c := range info.Isolation.CPUs {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", c.CPU, yesNo(c.Isolated), yesNo(c.NohzFull),
+					yesNo(c.RCUNoCBs), yesNo(c.ProcessAllowed), yesNo(c.IRQAllowed))
+			}
+		}
+	}
+}
+
+func (r *textReport) hwmon() {
+	w, info := r.w, r.info
+	if r.show("hwmon") {
+		if _, failed := info.Errors["hwmon"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Hwmon", "hwmon")
+		} else if len(info.Hwmon) > 0 {
+			fmt.Fprintln(w)
+			for _, s := range info.Hwmon {
+				name := s.Sensor
+				if s.Label != "" {
+					name += " (" + s.Label + ")"
+				}
+				value := strconv.FormatFloat(s.Value, 'f', -1, 64)
+				if s.Type != "fan" {
+					value = strconv.FormatFloat(s.Value, 'f', 3, 64)
+				}
+				fmt.Fprintf(w, "%s:\t%s\t%s %s\n", s.Chip, name, value, s.Unit)
+			}
+		}
+	}
+}
+
+func (r *textReport) sensors() {
+	w, info := r.w, r.info
+	if r.show("sensors") {
+		if _, failed := info.Errors["sensors"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Sensors", "sensors")
+		} else if len(info.Sensors) > 0 {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "Chip:\tSensor:\tTemp:\tMax:\tCrit:")
+			celsius := func(v *float64) string {
+				if v == nil {
+					return "-"
+				}
+				return fmt.Sprintf("%.1f°C", *v)
+			}
+			for _, s := range info.Sensors {
+				fmt.Fprintf(w, "%s\t%s\t%.1f°C\t%s\t%s\n", s.Chip, s.Label, s.TempC, celsius(s.MaxC), celsius(s.CritC))
+			}
+		}
+	}
+}
+
+func (r *textReport) security() {
+	w, info := r.w, r.info
+	if r.show("security") {
+		sec := info.Security
+		if _, failed := info.Errors["security"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Security", "security")
+		} else if sec != nil {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "Security:")
+			fmt.Fprintf(w, "UID real/effective:\t %d / %d\n", sec.RealUID, sec.EffectiveUID)
+			fmt.Fprintf(w, "GID real/effective:\t %d / %d\n", sec.RealGID, sec.EffectiveGID)
+			groups := make([]string, len(sec.Groups))
+			for i, g := range sec.Groups {
+				groups[i] = strconv.Itoa(g)
+			}
+			fmt.Fprintln(w, "Groups:\t", cmp.Or(strings.Join(groups, " "), "none"))
+			if sec.Umask != "" {
+				fmt.Fprintln(w, "Umask:\t", sec.Umask)
+			}
+			fmt.Fprintln(w, "CapEff:\t", cmp.Or(strings.Join(sec.CapEff, ","), "none"))
+			fmt.Fprintln(w, "CapPrm:\t", cmp.Or(strings.Join(sec.CapPrm, ","), "none"))
+			fmt.Fprintln(w, "CapBnd:\t", cmp.Or(strings.Join(sec.CapBnd, ","), "none"))
+			if sec.NoNewPrivs != nil {
+				fmt.Fprintln(w, "NoNewPrivs:\t", *sec.NoNewPrivs)
+			}
+			if sec.Seccomp != "" {
+				fmt.Fprintln(w, "Seccomp:\t", sec.Seccomp)
+			}
+		}
+		if _, failed := info.Errors["privileged_files"]; failed {
+			fmt.Fprintln(w)
+			r.unavailable("Privileged files", "privileged_files")
+		} else if sec != nil && sec.Files != nil {
+			files := sec.Files
+			fmt.Fprintln(w)
+			scanned := strconv.Itoa(files.Scanned)
+			if files.Truncated {
+				scanned += " (truncated)"
+			}
+			fmt.Fprintf(w, "Privileged files:\t %d of %s scanned in %s\n", len(files.Privileged), scanned, strings.Join(files.Dirs, ":"))
+			for _, f := range files.Privileged {
+				var flags []string
+				if f.Setuid {
+					flags = append(flags, "setuid")
+				}
+				if f.Setgid {
+					flags = append(flags, "setgid")
+				}
+				if c := f.Capabilities; c != nil {
+					caps := strings.Join(c.Permitted, ",")
+					if c.Effective {
+						caps += "+ep"
+					} else {
+						caps += "+p"
+					}
+					flags = append(flags, caps)
+				}
+				fmt.Fprintf(w, "  %s\t%s\n", f.Path, strings.Join(flags, " "))
+			}
+		}
+	}
+}
+
+func (r *textReport) findings() {
+	w, info := r.w, r.info
+	if r.show("findings") && len(info.Findings) > 0 {
+		fmt.Fprintln(w)
+		for _, f := range info.Findings {
+			fmt.Fprintf(w, "[%s]\t%s\n", f.Severity, f.Message)
+		}
+	}
+}
+
+// explain notes, for --explain, where the figures that are derived rather
+// than read come from, for the sections the report has.
+func (r *textReport) explain() {
+	w, info := r.w, r.info
+	if !explainOutput {
+		return
+	}
+	if pc := info.PageCache; r.show("page_cache") && pc != nil {
+		total := "the root cgroup's memory.stat file, file_dirty and file_writeback"
+		if pc.Source == "meminfo" {
+			total = "Cached, Dirty and Writeback of /proc/meminfo (no cgroup v2 memory.stat)"
+		}
+		fmt.Fprintf(w, "Page cache:\t the total is %s.\n", total)
+		fmt.Fprintln(w, "\t per device: BdiReclaimable (dirty) and BdiWriteback of /sys/kernel/debug/bdi/<maj:min>/stats,")
+		fmt.Fprintln(w, "\t mapped to mounts by the device number in /proc/self/mountinfo; the share is of the")
+		fmt.Fprintln(w, "\t dirty and writeback pages of the listed devices. Clean pages are not attributed to")
+		fmt.Fprintln(w, "\t devices, so the per-device figures do not add up to the total.")
+	}
+	if peak, _, fromVmHWM := info.MemoryPeak(); r.show("cgroup_v1", "cgroup_v2") && peak != nil {
+		if fromVmHWM {
+			fmt.Fprintln(w, "Memory peak:\t this kernel has no cgroup memory.peak (added in Linux 5.19), so the peak held")
+			fmt.Fprintln(w, "\t against the cgroup limit is the process's VmHWM, which leaves out the rest of the cgroup.")
+		} else {
+			fmt.Fprintln(w, "Memory peak:\t the cgroup's own high-water mark (memory.peak on v2, memory.max_usage_in_bytes")
+			fmt.Fprintln(w, "\t on v1), held against its memory limit.")
+		}
+	}
+}
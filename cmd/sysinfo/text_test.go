@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+)
+
+// withFields sets --fields for the duration of the test.
+func withFields(t *testing.T, fields ...string) {
+	t.Helper()
+	saved := selectedFields
+	t.Cleanup(func() { selectedFields = saved })
+	selectedFields = make(map[string]bool)
+	for _, f := range fields {
+		selectedFields[f] = true
+	}
+}
+
+func TestPrintTextPSS(t *testing.T) {
+	info := &sysinfo.SysInfo{VmRSS: 8192, MemoryDetail: &sysinfo.MemoryDetail{PssBytes: 6 << 20, Source: "smaps_rollup"}}
+	tests := []struct {
+		fields []string
+		want   string
+	}{
+		{[]string{"vmrss_bytes", "memory_detail"}, "VmRSS: 8 MiB (PSS 6 MiB)"},
+		{[]string{"memory_detail"}, "PSS: 6 MiB"},
+		{[]string{"vmrss_bytes"}, "VmRSS: 8 MiB"},
+	}
+	for _, tt := range tests {
+		withFields(t, tt.fields...)
+		var buf bytes.Buffer
+		printText(&buf, info, nil)
+		// Column padding aside.
+		first, _, _ := strings.Cut(buf.String(), "\n")
+		if got := strings.Join(strings.Fields(first), " "); got != tt.want {
+			t.Errorf("--fields %s: first row %q, want %q", strings.Join(tt.fields, ","), got, tt.want)
+		}
+	}
+}
+
+func TestPrintTextSectionOrder(t *testing.T) {
+	withFields(t, "host", "memory")
+	saved, savedTitles := sectionOrder, sectionTitles
+	t.Cleanup(func() { sectionOrder, sectionTitles = saved, savedTitles })
+
+	info := &sysinfo.SysInfo{
+		Host:   &sysinfo.Host{Hostname: "db1", KernelRelease: "6.1.0", Arch: "x86_64"},
+		Memory: &sysinfo.MemInfo{TotalBytes: 1 << 30, AvailableBytes: 1 << 29, UsedBytes: 1 << 29},
+	}
+	host := "Host:     db1\nKernel:   6.1.0 x86_64\n"
+	memory := "Memory total/avail/used:   1 GiB / 512 MiB / 512 MiB (0.0%)\nSwap:                      none\n"
+	tests := []struct {
+		order  []string
+		titles map[string]string
+		want   string
+	}{
+		{nil, nil, host + "\n" + memory},
+		// Moved first, a section is still separated from the next one and
+		// keeps its own column width.
+		{[]string{"memory"}, nil, memory + "\n" + host},
+		{[]string{"memory", "host"}, map[string]string{"host": "== Host =="}, memory + "\n== Host ==\n" + host},
+	}
+	for _, tt := range tests {
+		sectionOrder, sectionTitles = tt.order, tt.titles
+		var buf bytes.Buffer
+		printText(&buf, info, nil)
+		if buf.String() != tt.want {
+			t.Errorf("--section-order %s:\n%s\nwant:\n%s", strings.Join(tt.order, ","), buf.String(), tt.want)
+		}
+	}
+}
+
+func TestPrintTextHwmon(t *testing.T) {
+	withFields(t, "hwmon")
+	info := &sysinfo.SysInfo{Hwmon: []sysinfo.HwmonSensor{
+		{Chip: "nct6775", Sensor: "fan1", Type: "fan", Value: 820, Unit: "RPM"},
+		{Chip: "nct6775", Sensor: "in0", Label: "Vcore", Type: "voltage", Value: 1.04, Unit: "V"},
+		{Chip: "pmbus", Sensor: "power1", Label: "pin", Type: "power", Value: 315.25, Unit: "W"},
+	}}
+	var buf bytes.Buffer
+	printText(&buf, info, nil)
+	var rows []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		rows = append(rows, strings.Join(strings.Fields(line), " "))
+	}
+	want := []string{"nct6775: fan1 820 RPM", "nct6775: in0 (Vcore) 1.040 V", "pmbus: power1 (pin) 315.250 W"}
+	if strings.Join(rows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("rows:\n%s\nwant:\n%s", strings.Join(rows, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPrintTextCgroupUsage(t *testing.T) {
+	withFields(t, "cgroup_v2")
+	limit, current := uint64(512<<20), uint64(128<<20)
+	tests := []struct {
+		name string
+		cg   *sysinfo.CgroupV2
+		want []string
+	}{
+		{
+			name: "limited",
+			cg: &sysinfo.CgroupV2{
+				MemoryMaxBytes: &limit, MemoryCurrentBytes: &current, CPUUsageUsec: 987654,
+				Throttling: &sysinfo.CgroupThrottling{Periods: 1200, Throttled: 30, ThrottledSeconds: 2.5},
+			},
+			want: []string{"Cgroup (v2) Memory: 128 MiB / 512 MiB (25.0%)", "Cgroup (v2) Throttled:"},
+		},
+		{
+			// Usage is shown without a limit to hold it against.
+			name: "unlimited",
+			cg:   &sysinfo.CgroupV2{MemoryCurrentBytes: &current, CPUUsageUsec: 5000},
+			want: []string{"Cgroup (v2) Memory: 128 MiB (no limit)", "Cgroup (v2) CPULimit: unlimited", "Cgroup (v2) CPU usage: 0.0s"},
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		printText(&buf, &sysinfo.SysInfo{CgroupV2: tt.cg}, nil)
+		var rows []string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			rows = append(rows, strings.Join(strings.Fields(line), " "))
+		}
+		out := strings.Join(rows, "\n")
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: no %q in\n%s", tt.name, want, out)
+			}
+		}
+	}
+}
+
+func TestPrintTextExplain(t *testing.T) {
+	withFields(t, "cgroup_v2", "process", "page_cache", "explain")
+	t.Cleanup(func() { explainOutput = false })
+	limit, hwm := uint64(1<<30), uint64(900<<20)
+	tests := []struct {
+		name    string
+		info    *sysinfo.SysInfo
+		explain bool
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "VmHWM without memory.peak",
+			info: &sysinfo.SysInfo{
+				CgroupV2: &sysinfo.CgroupV2{MemoryMaxBytes: &limit},
+				Process:  &sysinfo.ProcessInfo{PeakRSSBytes: &hwm},
+			},
+			explain: true,
+			want:    []string{"Memory peak:", "no cgroup memory.peak", "VmHWM"},
+			notWant: []string{"Page cache:"},
+		},
+		{
+			name: "memory.peak",
+			info: &sysinfo.SysInfo{
+				CgroupV2: &sysinfo.CgroupV2{MemoryMaxBytes: &limit, MemoryPeakBytes: &hwm},
+				Process:  &sysinfo.ProcessInfo{PeakRSSBytes: &hwm},
+			},
+			explain: true,
+			want:    []string{"Memory peak:", "memory.peak on v2"},
+			notWant: []string{"process's VmHWM"},
+		},
+		{
+			name:    "page cache from meminfo",
+			info:    &sysinfo.SysInfo{PageCache: &sysinfo.PageCache{Source: "meminfo", FileBytes: 1 << 30}},
+			explain: true,
+			want:    []string{"Page cache:", "Cached, Dirty and Writeback of /proc/meminfo", "BdiReclaimable"},
+			notWant: []string{"Memory peak:"},
+		},
+		{
+			name: "without --explain",
+			info: &sysinfo.SysInfo{
+				CgroupV2:  &sysinfo.CgroupV2{MemoryMaxBytes: &limit},
+				Process:   &sysinfo.ProcessInfo{PeakRSSBytes: &hwm},
+				PageCache: &sysinfo.PageCache{Source: "memory.stat"},
+			},
+			notWant: []string{"BdiReclaimable", "no cgroup memory.peak"},
+		},
+	}
+	for _, tt := range tests {
+		explainOutput = tt.explain
+		var buf bytes.Buffer
+		printText(&buf, tt.info, nil)
+		out := strings.Join(strings.Fields(buf.String()), " ")
+		for _, s := range tt.want {
+			if !strings.Contains(out, s) {
+				t.Errorf("%s: no %q in\n%s", tt.name, s, buf.String())
+			}
+		}
+		for _, s := range tt.notWant {
+			if strings.Contains(out, s) {
+				t.Errorf("%s: unexpected %q in\n%s", tt.name, s, buf.String())
+			}
+		}
+	}
+}
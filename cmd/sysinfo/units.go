@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// units selects how byte sizes are rendered in text mode: "auto" and "iec"
+// scale by 1024 (KiB, MiB, ...), "si" by 1000 (KB, MB, ...), and "bytes"
+// prints the raw number.
+var units = "auto"
+
+var (
+	iecSuffixes = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	siSuffixes  = []string{"B", "KB", "MB", "GB", "TB", "PB", "EB"}
+)
+
+func validUnits(u string) bool {
+	switch u {
+	case "auto", "iec", "si", "bytes":
+		return true
+	}
+	return false
+}
+
+func formatSize(b uint64) string {
+	switch units {
+	case "bytes":
+		return strconv.FormatUint(b, 10) + " B"
+	case "si":
+		return scaleSize(b, 1000, siSuffixes)
+	default:
+		return scaleSize(b, 1024, iecSuffixes)
+	}
+}
+
+func formatSignedSize(d int64) string {
+	if d < 0 {
+		return "-" + formatSize(uint64(-d))
+	}
+	return "+" + formatSize(uint64(d))
+}
+
+// scaleSize picks the largest unit in which b is at least 1 and prints up
+// to two decimals, dropping trailing zeros: "1.82 TiB", "512 MiB", "0 B".
+func scaleSize(b uint64, base float64, suffixes []string) string {
+	if float64(b) < base {
+		return fmt.Sprintf("%d %s", b, suffixes[0])
+	}
+	v := float64(b)
+	i := 0
+	for v >= base && i < len(suffixes)-1 {
+		v /= base
+		i++
+	}
+	// 1023.999 KiB would round to "1024 KiB"; show it as 1 MiB instead.
+	if math.Round(v*100)/100 >= base && i < len(suffixes)-1 {
+		v /= base
+		i++
+	}
+	str := strings.TrimRight(strconv.FormatFloat(v, 'f', 2, 64), "0")
+	return strings.TrimSuffix(str, ".") + " " + suffixes[i]
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		units string
+		in    uint64
+		want  string
+	}{
+		{"auto", 0, "0 B"},
+		{"auto", 1, "1 B"},
+		{"auto", 1023, "1023 B"},
+		{"auto", 1024, "1 KiB"},
+		{"auto", 1536, "1.5 KiB"},
+		{"auto", 1<<20 - 1, "1 MiB"},
+		{"auto", 1 << 20, "1 MiB"},
+		{"auto", 512 << 20, "512 MiB"},
+		{"auto", 2000398934016, "1.82 TiB"},
+		{"auto", math.MaxUint64, "16 EiB"},
+		{"iec", 0, "0 B"},
+		{"iec", 1023, "1023 B"},
+		{"iec", 1024, "1 KiB"},
+		{"iec", 1 << 30, "1 GiB"},
+		{"iec", 1<<30 + 1<<29, "1.5 GiB"},
+		{"si", 0, "0 B"},
+		{"si", 999, "999 B"},
+		{"si", 1000, "1 KB"},
+		{"si", 1024, "1.02 KB"},
+		{"si", 999_999, "1 MB"},
+		{"si", 1_000_000, "1 MB"},
+		{"si", 1_250_000_000, "1.25 GB"},
+		{"si", 1e18, "1 EB"},
+		{"bytes", 0, "0 B"},
+		{"bytes", 1024, "1024 B"},
+		{"bytes", math.MaxUint64, "18446744073709551615 B"},
+	}
+	saved := units
+	t.Cleanup(func() { units = saved })
+	for _, tt := range tests {
+		units = tt.units
+		if got := formatSize(tt.in); got != tt.want {
+			t.Errorf("--units %s: formatSize(%d) = %q, want %q", tt.units, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSignedSize(t *testing.T) {
+	saved := units
+	t.Cleanup(func() { units = saved })
+	units = "auto"
+	for in, want := range map[int64]string{0: "+0 B", 1024: "+1 KiB", -1536: "-1.5 KiB", math.MinInt64 + 1: "-8 EiB"} {
+		if got := formatSignedSize(in); got != want {
+			t.Errorf("formatSignedSize(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestValidUnits(t *testing.T) {
+	for u, want := range map[string]bool{"auto": true, "iec": true, "si": true, "bytes": true, "": false, "IEC": false, "kib": false} {
+		if validUnits(u) != want {
+			t.Errorf("validUnits(%q) = %v, want %v", u, !want, want)
+		}
+	}
+}
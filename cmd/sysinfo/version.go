@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// version, commit and buildDate are set at build time:
+//
+//	go build -ldflags "-X main.version=1.4.0 -X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%FT%TZ)" ./cmd/sysinfo
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+func printVersion(w io.Writer) {
+	fmt.Fprintf(w, "sysinfo %s (commit %s, built %s) %s %s/%s\n",
+		version, commit, buildDate, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
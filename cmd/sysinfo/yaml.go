@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// jsonToYAML re-emits a JSON document as block-style YAML. Going through
+// the JSON form keeps the keys, their order and omitempty identical to
+// --json without a second set of struct tags or a YAML dependency.
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, err := decodeYAMLNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	switch {
+	case root.isMap:
+		writeYAMLMap(&b, root.keys, root.values, 0, false)
+	case root.isSeq:
+		writeYAMLSeq(&b, root.values, 0)
+	default:
+		b.WriteString(root.scalar + "\n")
+	}
+	return b.Bytes(), nil
+}
+
+type yamlNode struct {
+	isMap, isSeq bool
+	keys         []string
+	values       []yamlNode
+	scalar       string
+}
+
+func (n yamlNode) empty() bool {
+	return (n.isMap || n.isSeq) && len(n.values) == 0
+}
+
+func decodeYAMLNode(dec *json.Decoder) (yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return yamlNode{}, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		n := yamlNode{isMap: tok == '{', isSeq: tok == '['}
+		for dec.More() {
+			if n.isMap {
+				key, err := dec.Token()
+				if err != nil {
+					return yamlNode{}, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+			v, err := decodeYAMLNode(dec)
+			if err != nil {
+				return yamlNode{}, err
+			}
+			n.values = append(n.values, v)
+		}
+		if _, err := dec.Token(); err != nil { // closing delimiter
+			return yamlNode{}, err
+		}
+		return n, nil
+	case string:
+		return yamlNode{scalar: yamlString(tok)}, nil
+	case json.Number:
+		return yamlNode{scalar: yamlNumber(tok.String())}, nil
+	case bool:
+		return yamlNode{scalar: fmt.Sprint(tok)}, nil
+	case nil:
+		return yamlNode{scalar: "null"}, nil
+	}
+	return yamlNode{}, fmt.Errorf("unexpected JSON token %v", tok)
+}
+
+// yamlNumber gives exponent forms a fraction: YAML 1.1 floats need the dot,
+// so PyYAML would read 1e-7 as a string but 1.0e-7 as a number.
+func yamlNumber(s string) string {
+	mantissa, exp, found := strings.Cut(s, "e")
+	if !found || strings.Contains(mantissa, ".") {
+		return s
+	}
+	if !strings.HasPrefix(exp, "-") && !strings.HasPrefix(exp, "+") {
+		exp = "+" + exp
+	}
+	return mantissa + ".0e" + exp
+}
+
+// writeYAMLMap writes key: value lines at indent. inline means the first
+// key continues a "- " already written by the enclosing sequence.
+func writeYAMLMap(b *bytes.Buffer, keys []string, values []yamlNode, indent int, inline bool) {
+	pad := strings.Repeat(" ", indent)
+	for i, key := range keys {
+		if i > 0 || !inline {
+			b.WriteString(pad)
+		}
+		b.WriteString(yamlString(key) + ":")
+		writeYAMLValue(b, values[i], indent)
+	}
+}
+
+func writeYAMLSeq(b *bytes.Buffer, values []yamlNode, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for _, v := range values {
+		b.WriteString(pad + "-")
+		if v.isMap && !v.empty() {
+			b.WriteString(" ")
+			writeYAMLMap(b, v.keys, v.values, indent+2, true)
+			continue
+		}
+		writeYAMLValue(b, v, indent)
+	}
+}
+
+func writeYAMLValue(b *bytes.Buffer, v yamlNode, indent int) {
+	switch {
+	case v.isMap && v.empty():
+		b.WriteString(" {}\n")
+	case v.isSeq && v.empty():
+		b.WriteString(" []\n")
+	case v.isMap:
+		b.WriteString("\n")
+		writeYAMLMap(b, v.keys, v.values, indent+2, false)
+	case v.isSeq:
+		b.WriteString("\n")
+		writeYAMLSeq(b, v.values, indent+2)
+	default:
+		b.WriteString(" " + v.scalar + "\n")
+	}
+}
+
+var (
+	yamlPlain = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_./@+-]*$`)
+	// yamlReserved are plain scalars YAML 1.1 parsers (as used by Ansible)
+	// read as booleans or null.
+	yamlReserved = map[string]bool{
+		"y": true, "n": true, "yes": true, "no": true, "on": true, "off": true,
+		"true": true, "false": true, "null": true,
+	}
+)
+
+// yamlString writes s plain when that cannot be misread, otherwise as a
+// double-quoted scalar; JSON string escapes are valid YAML escapes.
+func yamlString(s string) string {
+	if yamlPlain.MatchString(s) && !yamlReserved[strings.ToLower(s)] {
+		return s
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
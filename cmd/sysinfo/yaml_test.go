@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/sysinfo"
+	"gopkg.in/yaml.v3"
+)
+
+// numbersByValue replaces the non-integer json.Numbers in v by their
+// float64 value, so 1e-7 and 1.0e-7 compare equal; integers stay text and
+// keep their full 64 bits.
+func numbersByValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = numbersByValue(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = numbersByValue(e)
+		}
+	case json.Number:
+		if _, err := v.Int64(); err != nil {
+			f, _ := v.Float64()
+			return f
+		}
+	}
+	return v
+}
+
+func roundTripInfo() *sysinfo.SysInfo {
+	limit := uint64(512 << 20)
+	at := time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+	return &sysinfo.SysInfo{
+		Timestamp:     &at,
+		SchemaVersion: sysinfo.SchemaVersion,
+		Host:          &sysinfo.Host{Hostname: "yes", KernelRelease: "6.1.0-18-amd64", Arch: "x86_64"},
+		UptimeSeconds: 350735.47,
+		LoadAvg:       &sysinfo.LoadAvg{One: 0.5, Five: 1e-7, Fifteen: 123456789.25, RunnableProcs: 2, TotalProcs: 431},
+		Memory:        &sysinfo.MemInfo{TotalBytes: 1 << 62, AvailableBytes: 1 << 30},
+		// Only the memory limit is set: cpu_limit_cores must be left out,
+		// not written as null.
+		CgroupV1: &sysinfo.CgroupV1{MemoryLimitBytes: &limit},
+		Mounts: []sysinfo.DiskInfo{
+			{Mountpoint: "/", FSType: "ext4", Device: "/dev/sda1", Total: 100 << 30, UsedPercent: 55.6, Options: []string{"rw", "relatime"}},
+			{Mountpoint: "/mnt/my disk: backup", FSType: "ntfs3", Device: "", Options: []string{}, ReadOnly: true, Error: "statfs: permission denied"},
+		},
+		Network: &sysinfo.Network{
+			Interfaces: []sysinfo.NetInterface{
+				{Name: "eth0", MTU: 1500, OperState: "up", Up: true, Addresses: []string{"192.0.2.10/24", "2001:db8::10/64"}},
+				{Name: "off", OperState: "down", Addresses: []string{}},
+			},
+		},
+		Findings: []sysinfo.Finding{{Code: "memory_limit_unset_no_swap", Severity: "info", Message: "tab\there, \"quotes\", \\ and ü"}},
+		Errors:   map[string]string{"hwmon": "open /sys/class/hwmon: no such file or directory", "null": "#not a comment"},
+	}
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	info := roundTripInfo()
+	for _, fields := range [][]string{nil, {"mounts", "cgroup_v1"}} {
+		t.Run(strings.Join(fields, ","), func(t *testing.T) {
+			var jsonOut, yamlOut bytes.Buffer
+			if err := formats["json"](fields, false).render(&jsonOut, info, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := formats["yaml"](fields, false).render(&yamlOut, info, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			var parsed any
+			if err := yaml.Unmarshal(yamlOut.Bytes(), &parsed); err != nil {
+				t.Fatalf("%v in\n%s", err, yamlOut.String())
+			}
+			// Through JSON, so both sides hold the types encoding/json
+			// decodes to.
+			data, err := json.Marshal(parsed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.UseNumber()
+			var fromYAML any
+			if err := dec.Decode(&fromYAML); err != nil {
+				t.Fatal(err)
+			}
+			dec = json.NewDecoder(bytes.NewReader(jsonOut.Bytes()))
+			dec.UseNumber()
+			var fromJSON any
+			if err := dec.Decode(&fromJSON); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(numbersByValue(fromYAML), numbersByValue(fromJSON)) {
+				t.Fatalf("YAML and JSON differ:\n%s\nvs\n%v", yamlOut.String(), fromJSON)
+			}
+
+			// And back into the struct, as a consumer would read it.
+			var back sysinfo.SysInfo
+			if err := json.Unmarshal(data, &back); err != nil {
+				t.Fatal(err)
+			}
+			var want sysinfo.SysInfo
+			if err := json.Unmarshal(jsonOut.Bytes(), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(back, want) {
+				t.Errorf("round trip:\n%+v\nwant:\n%+v", back, want)
+			}
+			if fields == nil && (back.CgroupV1 == nil || *back.CgroupV1.MemoryLimitBytes != 512<<20 || back.LoadAvg.Five != 1e-7) {
+				t.Errorf("round trip lost values: %+v", back)
+			}
+			if strings.Contains(yamlOut.String(), "cpu_limit_cores") {
+				t.Error("nil cgroup limit written to YAML")
+			}
+		})
+	}
+}
+
+func TestYAMLNumber(t *testing.T) {
+	for in, want := range map[string]string{
+		"0":          "0",
+		"-12":        "-12",
+		"55.6":       "55.6",
+		"1e-7":       "1.0e-7",
+		"1e+21":      "1.0e+21",
+		"-4e21":      "-4.0e+21",
+		"1.5e-7":     "1.5e-7",
+		"4611686018": "4611686018",
+	} {
+		if got := yamlNumber(in); got != want {
+			t.Errorf("yamlNumber(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestYAMLString(t *testing.T) {
+	tests := map[string]string{
+		"eth0":           "eth0",
+		"/dev/sda1":      "/dev/sda1",
+		"x86_64":         "x86_64",
+		"":               `""`,
+		"yes":            `"yes"`,
+		"Off":            `"Off"`,
+		"null":           `"null"`,
+		"1.0":            `"1.0"`,
+		"-1":             `"-1"`,
+		"a: b":           `"a: b"`,
+		"#x":             `"#x"`,
+		"two words":      `"two words"`,
+		"tab\there":      `"tab\there"`,
+		"<html>&":        `"<html>&"`,
+		"2001:db8::1/64": `"2001:db8::1/64"`,
+	}
+	for in, want := range tests {
+		if got := yamlString(in); got != want {
+			t.Errorf("yamlString(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
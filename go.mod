@@ -1,7 +1,11 @@
-module lec-processes
+module github.com/OkiMol/sysinfo-lab
 
 go 1.23.0
 
 toolchain go1.24.6
 
-require golang.org/x/sys v0.35.0
+require (
+	golang.org/x/sys v0.35.0
+	golang.org/x/term v0.34.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,133 @@
+// Package clock abstracts time so that sampling and watch loops can run
+// against a manually advanced clock.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real is the wall clock.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ t *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+func (t realTicker) Stop()               { t.t.Stop() }
+
+// Manual is a clock that only moves when told to. Sleep advances it by the
+// requested duration instead of blocking, so samplers return immediately;
+// After channels and tickers fire when Advance passes their deadline.
+type Manual struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	at     time.Time
+	period time.Duration
+	c      chan time.Time
+	done   bool
+}
+
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+func (m *Manual) Sleep(d time.Duration) { m.Advance(d) }
+
+func (m *Manual) After(d time.Duration) <-chan time.Time {
+	return m.add(d, 0).c
+}
+
+func (m *Manual) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return &manualTicker{m: m, w: m.add(d, d)}
+}
+
+// Advance moves the clock forward by d and fires every timer and ticker
+// that became due, in deadline order. Like time.Ticker, a ticker whose
+// channel is full drops the tick.
+func (m *Manual) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	end := m.now.Add(d)
+	for {
+		var next *waiter
+		for _, w := range m.waiters {
+			if !w.done && !w.at.After(end) && (next == nil || w.at.Before(next.at)) {
+				next = w
+			}
+		}
+		if next == nil {
+			break
+		}
+		m.now = next.at
+		select {
+		case next.c <- next.at:
+		default:
+		}
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			next.done = true
+		}
+	}
+	m.now = end
+	live := m.waiters[:0]
+	for _, w := range m.waiters {
+		if !w.done {
+			live = append(live, w)
+		}
+	}
+	m.waiters = live
+}
+
+func (m *Manual) add(d, period time.Duration) *waiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w := &waiter{at: m.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	m.waiters = append(m.waiters, w)
+	return w
+}
+
+type manualTicker struct {
+	m *Manual
+	w *waiter
+}
+
+func (t *manualTicker) C() <-chan time.Time { return t.w.c }
+
+func (t *manualTicker) Stop() {
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+	t.w.done = true
+}
@@ -0,0 +1,132 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// fired returns what c holds without blocking.
+func fired(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-c:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestManualSleep(t *testing.T) {
+	m := NewManual(epoch)
+	m.Sleep(1500 * time.Millisecond)
+	if got, want := m.Now(), epoch.Add(1500*time.Millisecond); !got.Equal(want) {
+		t.Errorf("Now = %v, want %v", got, want)
+	}
+}
+
+func TestManualAfter(t *testing.T) {
+	m := NewManual(epoch)
+	c := m.After(time.Second)
+	m.Advance(999 * time.Millisecond)
+	if _, ok := fired(c); ok {
+		t.Fatal("After fired early")
+	}
+	m.Advance(5 * time.Second)
+	if at, ok := fired(c); !ok || !at.Equal(epoch.Add(time.Second)) {
+		t.Errorf("After = %v, %v, want the deadline", at, ok)
+	}
+	m.Advance(time.Second)
+	if _, ok := fired(c); ok {
+		t.Error("After fired twice")
+	}
+}
+
+func TestManualAdvanceOrder(t *testing.T) {
+	// Timers fire in deadline order and see the clock at their deadline,
+	// whatever order they were created in.
+	m := NewManual(epoch)
+	late := m.After(300 * time.Millisecond)
+	ticker := m.NewTicker(100 * time.Millisecond)
+	early := m.After(50 * time.Millisecond)
+	var order []time.Duration
+	m.Advance(time.Second)
+	for _, c := range []<-chan time.Time{early, ticker.C(), late} {
+		at, ok := fired(c)
+		if !ok {
+			t.Fatal("a due timer did not fire")
+		}
+		order = append(order, at.Sub(epoch))
+	}
+	want := []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 300 * time.Millisecond}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("fired at %v, want %v", order, want)
+			break
+		}
+	}
+	if got := m.Now(); !got.Equal(epoch.Add(time.Second)) {
+		t.Errorf("Now = %v after Advance, want the end of it", got)
+	}
+}
+
+func TestManualTickerPeriods(t *testing.T) {
+	m := NewManual(epoch)
+	ticker := m.NewTicker(time.Second)
+	defer ticker.Stop()
+	for i := 1; i <= 3; i++ {
+		m.Advance(time.Second)
+		at, ok := fired(ticker.C())
+		if want := epoch.Add(time.Duration(i) * time.Second); !ok || !at.Equal(want) {
+			t.Errorf("tick %d = %v, %v, want %v", i, at, ok, want)
+		}
+	}
+	// Half a period later nothing is due yet.
+	m.Advance(500 * time.Millisecond)
+	if _, ok := fired(ticker.C()); ok {
+		t.Error("ticker fired between periods")
+	}
+}
+
+func TestManualTickerDropsTicks(t *testing.T) {
+	// Like time.Ticker, ticks nobody received are dropped: advancing over
+	// several periods leaves only the first in the channel, and the
+	// ticker keeps its phase.
+	m := NewManual(epoch)
+	ticker := m.NewTicker(time.Second)
+	defer ticker.Stop()
+	m.Advance(3500 * time.Millisecond)
+	if at, ok := fired(ticker.C()); !ok || !at.Equal(epoch.Add(time.Second)) {
+		t.Errorf("tick = %v, %v, want the first period's", at, ok)
+	}
+	if _, ok := fired(ticker.C()); ok {
+		t.Error("a full channel kept a second tick")
+	}
+	m.Advance(500 * time.Millisecond)
+	if at, ok := fired(ticker.C()); !ok || !at.Equal(epoch.Add(4*time.Second)) {
+		t.Errorf("tick = %v, %v, want the fourth period's", at, ok)
+	}
+}
+
+func TestManualTickerStop(t *testing.T) {
+	m := NewManual(epoch)
+	ticker := m.NewTicker(time.Second)
+	ticker.Stop()
+	m.Advance(5 * time.Second)
+	if _, ok := fired(ticker.C()); ok {
+		t.Error("stopped ticker fired")
+	}
+	if n := len(m.waiters); n != 0 {
+		t.Errorf("%d waiters left after Stop and Advance", n)
+	}
+	ticker.Stop() // a second Stop is harmless
+}
+
+func TestManualTickerNonPositive(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewTicker(0) did not panic")
+		}
+	}()
+	NewManual(epoch).NewTicker(0)
+}
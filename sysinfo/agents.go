@@ -0,0 +1,154 @@
+package sysinfo
+
+import (
+	"cmp"
+	"context"
+	"debug/buildinfo"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Agent is a running observability agent. Version comes, in order of
+// preference, from the version package its Go build stamped in with -X
+// ("ldflags"), the main module version in the Go build info ("buildinfo"),
+// or a version-like directory in the path it was started as ("cmdline").
+// A stripped or non-Go binary started from an unversioned path has no
+// Version, which is not an error.
+type Agent struct {
+	Name          string `json:"name"`
+	PID           int    `json:"pid"`
+	Comm          string `json:"comm"`
+	Exe           string `json:"exe,omitempty"`
+	Version       string `json:"version,omitempty"`
+	VersionSource string `json:"version_source,omitempty"`
+	GoVersion     string `json:"go_version,omitempty"`
+}
+
+// knownAgents maps agent names to the comm or executable base names they
+// run as. Datadog's main binary is just "agent", so it is matched by its
+// install directory instead.
+var knownAgents = []struct {
+	name  string
+	bins  []string
+	inDir string
+}{
+	{name: "node_exporter", bins: []string{"node_exporter"}},
+	{name: "datadog-agent", bins: []string{"datadog-agent"}, inDir: "/datadog-agent/"},
+	{name: "telegraf", bins: []string{"telegraf"}},
+	{name: "fluent-bit", bins: []string{"fluent-bit", "td-agent-bit"}},
+	{name: "fluentd", bins: []string{"fluentd", "td-agent"}},
+	{name: "vector", bins: []string{"vector"}},
+	{name: "otelcol", bins: []string{"otelcol", "otelcol-contrib", "otelcol-k8s"}},
+	{name: "grafana-agent", bins: []string{"grafana-agent"}},
+	{name: "alloy", bins: []string{"alloy"}},
+	{name: "promtail", bins: []string{"promtail"}},
+	{name: "prometheus", bins: []string{"prometheus"}},
+	{name: "filebeat", bins: []string{"filebeat"}},
+	{name: "metricbeat", bins: []string{"metricbeat"}},
+	{name: "collectd", bins: []string{"collectd"}},
+	{name: "zabbix_agentd", bins: []string{"zabbix_agentd", "zabbix_agent2"}},
+	{name: "netdata", bins: []string{"netdata"}},
+	{name: "newrelic-infra", bins: []string{"newrelic-infra"}},
+}
+
+var (
+	ldflagsVersion = regexp.MustCompile(`-X[= ]'?"?[\w./-]*\.\w*(?:[Vv]ersion|VERSION)=([^\s'"]+)`)
+	pathVersion    = regexp.MustCompile(`\bv?(\d+\.\d+(?:\.\d+)?(?:[-+][\w.]+)?)\b`)
+)
+
+// CollectAgents walks /proc for known agents. Helper processes an agent
+// forks (fluentd's workers, netdata's plugins) are reported once, under
+// the topmost process. The build info is read from /proc/<pid>/exe
+// without running it; foreign processes need root for that and are
+// reported without a version.
+func CollectAgents(ctx context.Context, root string) ([]Agent, error) {
+	entries, err := os.ReadDir(rootPath(root, "proc"))
+	if err != nil {
+		return nil, err
+	}
+	type candidate struct {
+		agent Agent
+		ppid  int
+		argv0 string
+	}
+	found := make(map[int]candidate)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := readProcStat(ctx, root, pid)
+		if err != nil {
+			continue
+		}
+		cmdline, _ := os.ReadFile(procDir(root, pid, "cmdline"))
+		argv0, _, _ := strings.Cut(string(cmdline), "\x00")
+		name := matchAgent(stat.Comm, argv0)
+		if name == "" {
+			continue
+		}
+		found[pid] = candidate{Agent{Name: name, PID: pid, Comm: stat.Comm}, int(stat.uintField(4)), argv0}
+	}
+
+	var agents []Agent
+	for pid, c := range found {
+		if parent, ok := found[c.ppid]; ok && parent.agent.Name == c.agent.Name {
+			continue
+		}
+		a := c.agent
+		a.Exe, _ = os.Readlink(procDir(root, pid, "exe"))
+		agentVersion(root, &a, c.argv0)
+		agents = append(agents, a)
+	}
+	slices.SortFunc(agents, func(a, b Agent) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), a.PID-b.PID)
+	})
+	return agents, nil
+}
+
+func matchAgent(comm, argv0 string) string {
+	base := filepath.Base(argv0)
+	for _, k := range knownAgents {
+		if slices.Contains(k.bins, comm) || slices.Contains(k.bins, base) {
+			return k.name
+		}
+		if k.inDir != "" && strings.Contains(argv0, k.inDir) {
+			return k.name
+		}
+	}
+	return ""
+}
+
+// agentVersion fills the version fields from the Go build info of the
+// executable, falling back to the path it was started as. Non-Go binaries
+// have no build info, which buildinfo reports as an error like any
+// unreadable file.
+func agentVersion(root string, a *Agent, argv0 string) {
+	if bi, err := buildinfo.ReadFile(procDir(root, a.PID, "exe")); err == nil {
+		a.GoVersion = bi.GoVersion
+		for _, s := range bi.Settings {
+			if s.Key == "-ldflags" {
+				if m := ldflagsVersion.FindStringSubmatch(s.Value); m != nil {
+					a.Version, a.VersionSource = strings.TrimPrefix(m[1], "v"), "ldflags"
+					return
+				}
+			}
+		}
+		if v := bi.Main.Version; v != "" && v != "(devel)" {
+			a.Version, a.VersionSource = strings.TrimPrefix(v, "v"), "buildinfo"
+			return
+		}
+	}
+	for _, path := range []string{argv0, a.Exe} {
+		for _, dir := range strings.Split(path, "/") {
+			if m := pathVersion.FindStringSubmatch(dir); m != nil {
+				a.Version, a.VersionSource = m[1], "cmdline"
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package sysinfo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestCollectAgentsVersion(t *testing.T) {
+	notGo, err := filepath.Abs(filepath.Join("testdata", "agents", "fluent-bit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	// An ELF header with nothing behind it, as far as buildinfo is
+	// concerned no different from a stripped C binary.
+	stripped := filepath.Join(root, "bin", "collectd")
+	writeTree(t, root, map[string]string{
+		"bin/collectd":    "\x7fELF\x02\x01\x01\x00" + string(make([]byte, 56)),
+		"proc/10/stat":    "10 (fluent-bit) S 1 10 10 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0\n",
+		"proc/10/cmdline": "/opt/fluent-bit/bin/fluent-bit\x00-c\x00/etc/fluent-bit.conf\x00",
+		"proc/11/stat":    "11 (collectd) S 1 11 11 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0\n",
+		"proc/11/cmdline": "/usr/sbin/collectd\x00",
+		"proc/12/stat":    "12 (telegraf) S 1 12 12 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0\n",
+		"proc/12/cmdline": "/opt/telegraf-1.30.2/usr/bin/telegraf\x00",
+		"proc/13/stat":    "13 (vector) S 1 13 13 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0\n",
+		"proc/13/cmdline": "vector\x00",
+		"proc/14/stat":    "14 (bash) S 1 14 14 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0\n",
+		"proc/14/cmdline": "-bash\x00",
+	})
+	for pid, exe := range map[string]string{"10": notGo, "11": stripped, "12": notGo, "13": self} {
+		if err := os.Symlink(exe, filepath.Join(root, "proc", pid, "exe")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	agents, err := CollectAgents(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Agent{
+		{Name: "collectd", PID: 11, Comm: "collectd", Exe: stripped},
+		{Name: "fluent-bit", PID: 10, Comm: "fluent-bit", Exe: notGo},
+		// Without build info the version still comes from the path.
+		{Name: "telegraf", PID: 12, Comm: "telegraf", Exe: notGo, Version: "1.30.2", VersionSource: "cmdline"},
+		{Name: "vector", PID: 13, Comm: "vector", Exe: self, GoVersion: runtime.Version()},
+	}
+	// The Go binary (this test) is there for contrast; whatever module
+	// version the toolchain stamped into it is not the point.
+	if len(agents) == len(want) {
+		agents[3].Version, agents[3].VersionSource = "", ""
+	}
+	if !reflect.DeepEqual(agents, want) {
+		t.Errorf("agents:\n%+v\nwant:\n%+v", agents, want)
+	}
+}
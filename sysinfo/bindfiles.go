@@ -0,0 +1,66 @@
+package sysinfo
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+type BindFile struct {
+	Target string `json:"target"`
+	Source string `json:"source"`
+	Root   string `json:"root"`
+	FSType string `json:"fstype"`
+}
+
+// CollectBindFiles lists mounts whose target is a regular file, as container
+// runtimes create for /etc/resolv.conf, /etc/hosts and /etc/hostname. Root
+// is the path of the injected file inside the source filesystem, which for
+// Docker and Kubernetes names the container or pod directory on the host.
+func CollectBindFiles(root string) ([]BindFile, error) {
+	data, err := os.ReadFile(rootPath(root, "proc/self/mountinfo"))
+	if err != nil {
+		return nil, err
+	}
+	var files []BindFile
+	for _, line := range strings.Split(string(data), "\n") {
+		pre, post, found := strings.Cut(line, " - ")
+		fields := strings.Fields(pre)
+		extra := strings.Fields(post)
+		if !found || len(fields) < 5 || len(extra) < 2 {
+			continue
+		}
+		target := unescapeMountPath(fields[4])
+		fi, err := os.Stat(rootPath(root, target))
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, BindFile{
+			Target: target,
+			Source: unescapeMountPath(extra[1]),
+			Root:   unescapeMountPath(fields[3]),
+			FSType: extra[0],
+		})
+	}
+	return files, nil
+}
+
+// unescapeMountPath decodes the \ooo octal escapes the kernel uses for
+// spaces, tabs, newlines and backslashes in mount paths.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUnescapeMountPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"/data":                  "/data",
+		`/mnt/my\040disk`:        "/mnt/my disk",
+		`/a\011b\012c`:           "/a\tb\nc",
+		`/back\134slash`:         `/back\slash`,
+		`/short\04`:              `/short\04`,
+		`/not\999octal`:          `/not\999octal`,
+		`\040leading`:            " leading",
+		`/two\040spaces\040here`: "/two spaces here",
+	} {
+		if got := unescapeMountPath(in); got != want {
+			t.Errorf("unescapeMountPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// mountinfoTree is a fake root with the mountinfo fixture in testdata and
+// the mount targets it names: regular files, or directories for dirs.
+func mountinfoTree(t *testing.T, fixture string, files []string, dirs ...string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata/mountinfo", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	tree := map[string]string{"proc/self/mountinfo": string(data)}
+	for _, f := range files {
+		tree[f] = "\n"
+	}
+	writeTree(t, root, tree)
+	for _, d := range dirs {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestCollectBindFiles(t *testing.T) {
+	tests := []struct {
+		fixture string
+		files   []string
+		dirs    []string
+		want    []BindFile
+	}{
+		{
+			fixture: "docker",
+			files:   []string{"etc/resolv.conf", "etc/hostname", "etc/hosts", "etc/app config.yaml"},
+			// A directory bind mount belongs in the disk table.
+			dirs: []string{"data", "proc", "dev"},
+			want: []BindFile{
+				{Target: "/etc/resolv.conf", Source: "/dev/vda1", Root: "/var/lib/docker/containers/4f1c/resolv.conf", FSType: "ext4"},
+				{Target: "/etc/hostname", Source: "/dev/vda1", Root: "/var/lib/docker/containers/4f1c/hostname", FSType: "ext4"},
+				{Target: "/etc/hosts", Source: "/dev/vda1", Root: "/var/lib/docker/containers/4f1c/hosts", FSType: "ext4"},
+				{Target: "/etc/app config.yaml", Source: "/dev/vda1", Root: "/srv/app data/app config.yaml", FSType: "ext4"},
+			},
+		},
+		{
+			// Optional fields (shared:, master:, propagate_from:,
+			// unbindable) come before the separator, in any number.
+			fixture: "kubernetes",
+			files:   []string{"etc/hosts", "dev/termination-log", "etc/hostname", "etc/resolv.conf"},
+			dirs:    []string{"proc", "var/run/secrets/kubernetes.io/serviceaccount"},
+			want: []BindFile{
+				{Target: "/etc/hosts", Source: "/dev/sda1", Root: "/var/lib/kubelet/pods/6b0e-11ee/etc-hosts", FSType: "ext4"},
+				{Target: "/dev/termination-log", Source: "/dev/sda1", Root: "/var/lib/kubelet/pods/6b0e-11ee/containers/app/0a1b2c3d", FSType: "ext4"},
+				{Target: "/etc/hostname", Source: "/dev/sda1", Root: "/var/lib/containerd/io.containerd.grpc.v1.cri/sandboxes/77aa/hostname", FSType: "ext4"},
+				{Target: "/etc/resolv.conf", Source: "/dev/sda1", Root: "/var/lib/containerd/io.containerd.grpc.v1.cri/sandboxes/77aa/resolv.conf", FSType: "ext4"},
+			},
+		},
+		{
+			// Targets missing from the tree (the mount was seen from
+			// another namespace) are skipped.
+			fixture: "docker",
+			files:   []string{"etc/hosts"},
+			want: []BindFile{
+				{Target: "/etc/hosts", Source: "/dev/vda1", Root: "/var/lib/docker/containers/4f1c/hosts", FSType: "ext4"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			files, err := CollectBindFiles(mountinfoTree(t, tt.fixture, tt.files, tt.dirs...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(files, tt.want) {
+				t.Errorf("bind files:\n%+v\nwant:\n%+v", files, tt.want)
+			}
+		})
+	}
+}
+
+func TestMountsByDevice(t *testing.T) {
+	mounts, sources, err := mountsByDevice(mountinfoTree(t, "docker", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every bind of 254:1, whatever its root within the filesystem.
+	want := []string{"/etc/resolv.conf", "/etc/hostname", "/etc/hosts", "/data", "/etc/app config.yaml"}
+	if !reflect.DeepEqual(mounts["254:1"], want) || sources["254:1"] != "/dev/vda1" {
+		t.Errorf("254:1 = %q from %q, want %q from /dev/vda1", mounts["254:1"], sources["254:1"], want)
+	}
+	if !reflect.DeepEqual(mounts["0:97"], []string{"/"}) || sources["0:97"] != "overlay" {
+		t.Errorf("0:97 = %q from %q, want / from overlay", mounts["0:97"], sources["0:97"])
+	}
+}
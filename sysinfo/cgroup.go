@@ -0,0 +1,352 @@
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+// CgroupV1 holds the v1 controller limits and usage. MemoryUsagePercent
+// is nil without a limit; CPUUtilizationPercent is usage against the CPU
+// quota over Options.CgroupCPU and is nil unless sampled with a quota.
+type CgroupV1 struct {
+	MemoryLimitBytes      *uint64           `json:"memory_limit_bytes,omitempty"`
+	CPULimitCores         *float64          `json:"cpu_limit_cores,omitempty"`
+	MemoryUsageBytes      *uint64           `json:"memory_usage_bytes,omitempty"`
+	MemoryUsagePercent    *float64          `json:"memory_usage_percent,omitempty"`
+	MemoryPeakBytes       *uint64           `json:"memory_peak_bytes,omitempty"`
+	CPUUsageNs            *uint64           `json:"cpu_usage_ns,omitempty"`
+	Throttling            *CgroupThrottling `json:"throttling,omitempty"`
+	CPUUtilizationPercent *float64          `json:"cpu_utilization_percent,omitempty"`
+	// CPUUsageNsTotal is cpuacct.usage at the end of the utilization
+	// window, with Options.RawCounters.
+	CPUUsageNsTotal *uint64 `json:"cpu_usage_ns_total,omitempty"`
+}
+
+// CgroupV2 describes the cgroup v2 hierarchy mounted at /sys/fs/cgroup. In
+// the root cgroup there are no limits and no memory.current, so those stay
+// nil, as do the percentages derived from them (see CgroupV1).
+// MemoryHighBytes is the memory.high throttling threshold, nil at "max".
+type CgroupV2 struct {
+	MemoryMaxBytes        *uint64           `json:"memory_max_bytes,omitempty"`
+	MemoryHighBytes       *uint64           `json:"memory_high_bytes,omitempty"`
+	MemoryCurrentBytes    *uint64           `json:"memory_current_bytes,omitempty"`
+	MemoryUsagePercent    *float64          `json:"memory_usage_percent,omitempty"`
+	MemoryPeakBytes       *uint64           `json:"memory_peak_bytes,omitempty"`
+	CPUMaxCores           *float64          `json:"cpu_max_cores,omitempty"`
+	CPUUsageUsec          uint64            `json:"cpu_usage_usec"`
+	Throttling            *CgroupThrottling `json:"throttling,omitempty"`
+	CPUUtilizationPercent *float64          `json:"cpu_utilization_percent,omitempty"`
+	// CPUUsageUsecTotal is usage_usec at the end of the utilization
+	// window, with Options.RawCounters.
+	CPUUsageUsecTotal *uint64  `json:"cpu_usage_usec_total,omitempty"`
+	Pressure          Pressure `json:"pressure,omitempty"`
+}
+
+// CgroupThrottling is the CFS bandwidth part of cpu.stat.
+type CgroupThrottling struct {
+	Periods          uint64  `json:"nr_periods"`
+	Throttled        uint64  `json:"nr_throttled"`
+	ThrottledSeconds float64 `json:"throttled_seconds"`
+}
+
+// CollectCgroups reads the cgroup v1 memory and CPU limits. A nil limit
+// means unlimited. Each limit is read independently, so the returned value
+// carries whatever succeeded even when the error is non-nil.
+func CollectCgroups(root string) (*CgroupV1, error) {
+	memLimit, memErr := ReadCgroupMemoryLimit(root)
+	if memErr != nil {
+		memErr = fmt.Errorf("memory limit: %w", memErr)
+	}
+	cpuLimit, cpuErr := ReadCgroupCPULimit(root)
+	if cpuErr != nil {
+		cpuErr = fmt.Errorf("cpu limit: %w", cpuErr)
+	}
+	cg := &CgroupV1{
+		MemoryLimitBytes: memLimit,
+		CPULimitCores:    cpuLimit,
+	}
+	return cg, errors.Join(memErr, cpuErr)
+}
+
+// ReadCgroupMemoryLimit returns memory.limit_in_bytes of the v1 memory
+// controller, nil when unlimited. On a v2-only host there is no v1
+// hierarchy to read and it returns nil without an error.
+func ReadCgroupMemoryLimit(root string) (*uint64, error) {
+	value, err := readTrim(rootPath(root, "sys/fs/cgroup/memory/memory.limit_in_bytes"))
+	if errors.Is(err, fs.ErrNotExist) && unifiedOnly(root) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	num, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	const unlimitedThreshold = uint64(1<<63) - 4096
+	if num >= unlimitedThreshold {
+		return nil, nil
+	}
+	return &num, nil
+}
+
+// ReadCgroupCPULimit returns the v1 CFS quota in cores, nil when
+// unlimited or, as for ReadCgroupMemoryLimit, on a v2-only host.
+func ReadCgroupCPULimit(root string) (*float64, error) {
+	quotaStr, err := readTrim(rootPath(root, "sys/fs/cgroup/cpu/cpu.cfs_quota_us"))
+	if errors.Is(err, fs.ErrNotExist) && unifiedOnly(root) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	periodStr, err := readTrim(rootPath(root, "sys/fs/cgroup/cpu/cpu.cfs_period_us"))
+	if err != nil {
+		return nil, err
+	}
+	if quotaStr == "-1" {
+		return nil, nil
+	}
+
+	quota, err := strconv.ParseFloat(quotaStr, 64)
+	if err != nil {
+		return nil, err
+	}
+	period, err := strconv.ParseFloat(periodStr, 64)
+	if err != nil {
+		return nil, err
+	}
+	if period == 0 {
+		return nil, fmt.Errorf("cpu.cfs_period_us is zero")
+	}
+
+	cores := quota / period
+	return &cores, nil
+}
+
+// unifiedOnly reports whether /sys/fs/cgroup is the cgroup v2 hierarchy
+// itself, as opposed to a v1 or hybrid layout, so that missing v1 files
+// mean "no v1 controllers" rather than a read failure.
+func unifiedOnly(root string) bool {
+	_, err := readTrim(rootPath(root, "sys/fs/cgroup/cgroup.controllers"))
+	return err == nil
+}
+
+// ReadCgroupMemoryUsage returns memory.usage_in_bytes of the v1 memory
+// controller. Like the other usage readers it returns nil without an error
+// when the file does not exist, since usage is reported only where the
+// kernel accounts it.
+func ReadCgroupMemoryUsage(root string) (*uint64, error) {
+	return readOptionalUint(rootPath(root, "sys/fs/cgroup/memory/memory.usage_in_bytes"))
+}
+
+// ReadCgroupMemoryPeak returns memory.max_usage_in_bytes, the v1
+// high-water mark of memory.usage_in_bytes.
+func ReadCgroupMemoryPeak(root string) (*uint64, error) {
+	return readOptionalUint(rootPath(root, "sys/fs/cgroup/memory/memory.max_usage_in_bytes"))
+}
+
+// ReadCgroupCPUUsage returns cpuacct.usage (nanoseconds) and the
+// throttling counters of cpu.stat from the v1 hierarchy.
+func ReadCgroupCPUUsage(root string) (*uint64, *CgroupThrottling, error) {
+	usage, err := readOptionalUint(rootPath(root, "sys/fs/cgroup/cpuacct/cpuacct.usage"))
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := readKeyValues(rootPath(root, "sys/fs/cgroup/cpu/cpu.stat"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return usage, nil, nil
+	}
+	if err != nil {
+		return usage, nil, err
+	}
+	return usage, &CgroupThrottling{
+		Periods:          stat["nr_periods"],
+		Throttled:        stat["nr_throttled"],
+		ThrottledSeconds: float64(stat["throttled_time"]) / 1e9,
+	}, nil
+}
+
+// CollectCgroupV2 reads limits and usage of the unified hierarchy. A host
+// without one (no cgroup.controllers) yields nil.
+func CollectCgroupV2(root string) (*CgroupV2, error) {
+	base := rootPath(root, "sys/fs/cgroup")
+	if _, err := readTrim(base + "/cgroup.controllers"); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	cg := &CgroupV2{}
+	var errs []error
+
+	for _, limit := range []struct {
+		name string
+		dst  **uint64
+	}{{"memory.max", &cg.MemoryMaxBytes}, {"memory.high", &cg.MemoryHighBytes}} {
+		if value, err := readTrim(base + "/" + limit.name); err == nil && value != "max" {
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", limit.name, err))
+			} else {
+				*limit.dst = &n
+			}
+		}
+	}
+	current, err := readOptionalUint(base + "/memory.current")
+	if err != nil {
+		errs = append(errs, fmt.Errorf("memory.current: %w", err))
+	}
+	cg.MemoryCurrentBytes = current
+	cg.MemoryUsagePercent = percentOfLimit(current, cg.MemoryMaxBytes)
+	// memory.peak exists since Linux 5.19.
+	peak, err := readOptionalUint(base + "/memory.peak")
+	if err != nil {
+		errs = append(errs, fmt.Errorf("memory.peak: %w", err))
+	}
+	cg.MemoryPeakBytes = peak
+
+	if value, err := readTrim(base + "/cpu.max"); err == nil {
+		quota, period, _ := strings.Cut(value, " ")
+		if quota != "max" {
+			q, qErr := strconv.ParseFloat(quota, 64)
+			p, pErr := strconv.ParseFloat(period, 64)
+			if qErr != nil || pErr != nil || p == 0 {
+				errs = append(errs, fmt.Errorf("malformed cpu.max %q", value))
+			} else {
+				cores := q / p
+				cg.CPUMaxCores = &cores
+			}
+		}
+	}
+
+	stat, err := readKeyValues(base + "/cpu.stat")
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cpu.stat: %w", err))
+	} else {
+		cg.CPUUsageUsec = stat["usage_usec"]
+		if _, ok := stat["nr_periods"]; ok {
+			cg.Throttling = &CgroupThrottling{
+				Periods:          stat["nr_periods"],
+				Throttled:        stat["nr_throttled"],
+				ThrottledSeconds: float64(stat["throttled_usec"]) / 1e6,
+			}
+		}
+	}
+
+	// The cgroup's own cpu, memory and io.pressure, next to the host's.
+	pressure, err := readPressure(base, "%s.pressure")
+	if err != nil {
+		errs = append(errs, err)
+	}
+	cg.Pressure = pressure
+	return cg, errors.Join(errs...)
+}
+
+// cgroupCPUQuotaNearPercent is the quota utilization from which
+// cgroupCPUFindings warns.
+const cgroupCPUQuotaNearPercent = 90
+
+// sampleCgroupCPUUtilization reads the cgroup CPU usage (cpu.stat
+// usage_usec on v2, cpuacct.usage on v1) window apart and returns it as a
+// percentage of the quota's capacity over the measured interval. It is nil
+// without a quota; v2 tells which hierarchy was read. Bursts can push it
+// above 100 for a sample. usageTotal is the usage counter at the end of
+// the window, for Options.RawCounters.
+func sampleCgroupCPUUtilization(clk clock.Clock, root string, window time.Duration) (pct *float64, usageTotal time.Duration, v2 bool, err error) {
+	var cores *float64
+	var usage func() (time.Duration, error)
+	base := rootPath(root, "sys/fs/cgroup")
+	if _, err := readTrim(base + "/cgroup.controllers"); err == nil {
+		v2 = true
+		cg, err := CollectCgroupV2(root)
+		if cg == nil {
+			return nil, 0, v2, err
+		}
+		cores = cg.CPUMaxCores
+		usage = func() (time.Duration, error) {
+			stat, err := readKeyValues(base + "/cpu.stat")
+			return time.Duration(stat["usage_usec"]) * time.Microsecond, err
+		}
+	} else {
+		if cores, err = ReadCgroupCPULimit(root); err != nil {
+			return nil, 0, v2, err
+		}
+		usage = func() (time.Duration, error) {
+			ns, _, err := ReadCgroupCPUUsage(root)
+			if err == nil && ns == nil {
+				err = fmt.Errorf("no cpuacct.usage")
+			}
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(*ns), nil
+		}
+	}
+	if cores == nil || *cores <= 0 {
+		return nil, 0, v2, nil
+	}
+
+	before, err := usage()
+	if err != nil {
+		return nil, 0, v2, err
+	}
+	start := clk.Now()
+	clk.Sleep(window)
+	after, err := usage()
+	if err != nil {
+		return nil, 0, v2, err
+	}
+	elapsed := clk.Now().Sub(start)
+	if elapsed <= 0 || after < before {
+		return nil, 0, v2, fmt.Errorf("cgroup CPU usage went backwards or no time passed")
+	}
+	utilization := float64(after-before) / (float64(elapsed) * *cores) * 100
+	return &utilization, after, v2, nil
+}
+
+func cgroupCPUFindings(info *SysInfo) []Finding {
+	var utilization *float64
+	if cg := info.CgroupV2; cg != nil {
+		utilization = cg.CPUUtilizationPercent
+	} else if cg := info.CgroupV1; cg != nil {
+		utilization = cg.CPUUtilizationPercent
+	}
+	if utilization == nil || min(*utilization, 100) < cgroupCPUQuotaNearPercent {
+		return nil
+	}
+	return []Finding{{
+		Code:     "cgroup_cpu_quota_near",
+		Severity: "warning",
+		Message: fmt.Sprintf("the cgroup is using %.0f%% of its CPU quota; it will be throttled once it needs more",
+			*utilization),
+	}}
+}
+
+// percentOfLimit returns used as a percentage of limit, or nil when
+// either is unknown or the limit is zero.
+func percentOfLimit(used, limit *uint64) *float64 {
+	if used == nil || limit == nil || *limit == 0 {
+		return nil
+	}
+	pct := float64(*used) / float64(*limit) * 100
+	return &pct
+}
+
+func readOptionalUint(path string) (*uint64, error) {
+	value, err := readTrim(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
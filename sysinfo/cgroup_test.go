@@ -0,0 +1,257 @@
+package sysinfo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCgroupCPURawCounters(t *testing.T) {
+	tests := []struct {
+		name   string
+		before map[string]string
+		after  map[string]string
+		check  func(t *testing.T, info *SysInfo)
+	}{
+		{
+			name: "v2",
+			before: map[string]string{
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory\n",
+				"sys/fs/cgroup/cpu.max":            "200000 100000\n",
+				"sys/fs/cgroup/cpu.stat":           "usage_usec 1000000\n",
+			},
+			after: map[string]string{"sys/fs/cgroup/cpu.stat": "usage_usec 2000000\n"},
+			check: func(t *testing.T, info *SysInfo) {
+				cg := info.CgroupV2
+				if cg == nil || cg.CPUUtilizationPercent == nil || *cg.CPUUtilizationPercent != 50 {
+					t.Fatalf("cgroup_v2 = %+v, want 50%% of the quota", cg)
+				}
+				if cg.CPUUsageUsecTotal == nil || *cg.CPUUsageUsecTotal != 2000000 {
+					t.Errorf("cpu_usage_usec_total = %v, want the second sample's 2000000", cg.CPUUsageUsecTotal)
+				}
+			},
+		},
+		{
+			name: "v1",
+			before: map[string]string{
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         "100000\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us":        "100000\n",
+				"sys/fs/cgroup/cpuacct/cpuacct.usage":        "5000000000\n",
+				"sys/fs/cgroup/memory/memory.limit_in_bytes": "9223372036854771712\n",
+			},
+			after: map[string]string{"sys/fs/cgroup/cpuacct/cpuacct.usage": "5250000000\n"},
+			check: func(t *testing.T, info *SysInfo) {
+				cg := info.CgroupV1
+				if cg == nil || cg.CPUUtilizationPercent == nil || *cg.CPUUtilizationPercent != 25 {
+					t.Fatalf("cgroup_v1 = %+v, want 25%% of the quota", cg)
+				}
+				if cg.CPUUsageNsTotal == nil || *cg.CPUUsageNsTotal != 5250000000 {
+					t.Errorf("cpu_usage_ns_total = %v, want the second sample's 5250000000", cg.CPUUsageNsTotal)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, raw := range []bool{false, true} {
+				root := t.TempDir()
+				writeTree(t, root, tt.before)
+				opts := Options{Root: root, Fields: []string{"cgroup_v1", "cgroup_v2"}, CgroupCPU: time.Second, RawCounters: raw}
+				opts.Clock = newSampleClock(func(int) { writeTree(t, root, tt.after) })
+				info, _ := collectWith(context.Background(), opts)
+				if raw {
+					tt.check(t, info)
+					continue
+				}
+				if cg := info.CgroupV2; cg != nil && cg.CPUUsageUsecTotal != nil {
+					t.Errorf("cpu_usage_usec_total = %v without RawCounters", *cg.CPUUsageUsecTotal)
+				}
+				if cg := info.CgroupV1; cg != nil && cg.CPUUsageNsTotal != nil {
+					t.Errorf("cpu_usage_ns_total = %v without RawCounters", *cg.CPUUsageNsTotal)
+				}
+			}
+		})
+	}
+}
+
+// cgroupV1Tree is a container's view of the v1 hierarchy: a 512 MiB limit,
+// a 1.5 core quota and some throttling.
+var cgroupV1Tree = map[string]string{
+	"sys/fs/cgroup/memory/memory.limit_in_bytes":     "536870912\n",
+	"sys/fs/cgroup/memory/memory.usage_in_bytes":     "134217728\n",
+	"sys/fs/cgroup/memory/memory.max_usage_in_bytes": "268435456\n",
+	"sys/fs/cgroup/cpu/cpu.cfs_quota_us":             "150000\n",
+	"sys/fs/cgroup/cpu/cpu.cfs_period_us":            "100000\n",
+	"sys/fs/cgroup/cpu/cpu.stat":                     "nr_periods 1200\nnr_throttled 30\nthrottled_time 2500000000\n",
+	"sys/fs/cgroup/cpuacct/cpuacct.usage":            "987654321\n",
+}
+
+// cgroupV2Tree is the same container on the unified hierarchy.
+var cgroupV2Tree = map[string]string{
+	"sys/fs/cgroup/cgroup.controllers": "cpuset cpu io memory pids\n",
+	"sys/fs/cgroup/memory.max":         "536870912\n",
+	"sys/fs/cgroup/memory.high":        "max\n",
+	"sys/fs/cgroup/memory.current":     "134217728\n",
+	"sys/fs/cgroup/memory.peak":        "268435456\n",
+	"sys/fs/cgroup/cpu.max":            "150000 100000\n",
+	"sys/fs/cgroup/cpu.stat":           "usage_usec 987654\nuser_usec 600000\nsystem_usec 387654\nnr_periods 1200\nnr_throttled 30\nthrottled_usec 2500000\n",
+	"sys/fs/cgroup/memory.pressure":    "some avg10=1.50 avg60=0.75 avg300=0.25 total=12345\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+}
+
+func TestCollectCgroupV1Fixture(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, cgroupV1Tree)
+	info, err := collectWith(context.Background(), Options{Root: root, Fields: []string{"cgroup_v1", "cgroup_v2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &CgroupV1{
+		MemoryLimitBytes:   ptr[uint64](512 << 20),
+		CPULimitCores:      ptr(1.5),
+		MemoryUsageBytes:   ptr[uint64](128 << 20),
+		MemoryUsagePercent: ptr(25.0),
+		MemoryPeakBytes:    ptr[uint64](256 << 20),
+		CPUUsageNs:         ptr[uint64](987654321),
+		Throttling:         &CgroupThrottling{Periods: 1200, Throttled: 30, ThrottledSeconds: 2.5},
+	}
+	if !reflect.DeepEqual(info.CgroupV1, want) {
+		t.Errorf("cgroup_v1 = %+v, want %+v", info.CgroupV1, want)
+	}
+	if info.CgroupV2 != nil || len(info.Errors) != 0 {
+		t.Errorf("cgroup_v2 = %+v, errors %v, want neither", info.CgroupV2, info.Errors)
+	}
+}
+
+func TestCollectCgroupV1Unlimited(t *testing.T) {
+	// Usage is reported even when there is nothing to compare it with.
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"sys/fs/cgroup/memory/memory.limit_in_bytes": "9223372036854771712\n",
+		"sys/fs/cgroup/memory/memory.usage_in_bytes": "134217728\n",
+		"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         "-1\n",
+		"sys/fs/cgroup/cpu/cpu.cfs_period_us":        "100000\n",
+		"sys/fs/cgroup/cpuacct/cpuacct.usage":        "42\n",
+	})
+	cg, err := CollectCgroups(root)
+	if err != nil || cg.MemoryLimitBytes != nil || cg.CPULimitCores != nil {
+		t.Fatalf("limits = %+v, %v, want both unlimited", cg, err)
+	}
+	usage, err := ReadCgroupMemoryUsage(root)
+	if err != nil || usage == nil || *usage != 128<<20 {
+		t.Errorf("memory usage = %v, %v, want 128 MiB", usage, err)
+	}
+	if pct := percentOfLimit(usage, cg.MemoryLimitBytes); pct != nil {
+		t.Errorf("usage percent = %v without a limit", *pct)
+	}
+	cpu, throttling, err := ReadCgroupCPUUsage(root)
+	if err != nil || cpu == nil || *cpu != 42 || throttling != nil {
+		t.Errorf("cpu usage = %v, %+v, %v, want 42 without cpu.stat", cpu, throttling, err)
+	}
+}
+
+func TestCollectCgroupV2Fixture(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, cgroupV2Tree)
+	info, err := collectWith(context.Background(), Options{Root: root, Fields: []string{"cgroup_v2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cg := info.CgroupV2
+	if cg == nil {
+		t.Fatal("no cgroup_v2")
+	}
+	want := CgroupV2{
+		MemoryMaxBytes:     ptr[uint64](512 << 20),
+		MemoryCurrentBytes: ptr[uint64](128 << 20),
+		MemoryUsagePercent: ptr(25.0),
+		MemoryPeakBytes:    ptr[uint64](256 << 20),
+		CPUMaxCores:        ptr(1.5),
+		CPUUsageUsec:       987654,
+		Throttling:         &CgroupThrottling{Periods: 1200, Throttled: 30, ThrottledSeconds: 2.5},
+	}
+	pressure := cg.Pressure
+	cg.Pressure = nil
+	if !reflect.DeepEqual(*cg, want) {
+		t.Errorf("cgroup_v2 = %+v, want %+v", *cg, want)
+	}
+	if mem, ok := pressure["memory"]; len(pressure) != 1 || !ok || mem.Some == nil || mem.Some.Avg10 != 1.5 {
+		t.Errorf("pressure = %+v, want memory only", pressure)
+	}
+}
+
+func TestCollectCgroupV2DefaultFields(t *testing.T) {
+	// A default run on a v2-only host must not fail the v1 collectors. The
+	// rest of the tree is the live system, for the collectors that have no
+	// fixture of their own.
+	if err := unsupported(); err != nil {
+		t.Skip(err)
+	}
+	root := t.TempDir()
+	liveTree(t, root, "sys/fs/cgroup")
+	writeTree(t, root, cgroupV2Tree)
+	info, err := collectWith(context.Background(), Options{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Errors) != 0 || info.CgroupV1 != nil {
+		t.Errorf("errors %v, cgroup_v1 %+v, want neither on a v2-only host", info.Errors, info.CgroupV1)
+	}
+	if info.CgroupV2 == nil || info.CgroupV2.MemoryMaxBytes == nil {
+		t.Errorf("cgroup_v2 = %+v, want the unified limits", info.CgroupV2)
+	}
+}
+
+func TestCollectCgroupV2Root(t *testing.T) {
+	// The root cgroup has no limits, no memory.current and no throttling.
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"sys/fs/cgroup/cgroup.controllers": "cpu memory\n",
+		"sys/fs/cgroup/cpu.stat":           "usage_usec 5000\nuser_usec 3000\nsystem_usec 2000\n",
+	})
+	cg, err := CollectCgroupV2(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (CgroupV2{CPUUsageUsec: 5000}); !reflect.DeepEqual(*cg, want) {
+		t.Errorf("cgroup_v2 = %+v, want usage only", *cg)
+	}
+}
+
+func TestCollectCgroupV2Malformed(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"sys/fs/cgroup/cgroup.controllers": "cpu memory\n",
+		"sys/fs/cgroup/memory.max":         "lots\n",
+		"sys/fs/cgroup/memory.current":     "1024\n",
+		"sys/fs/cgroup/cpu.max":            "150000\n",
+		"sys/fs/cgroup/cpu.stat":           "usage_usec 5000\n",
+	})
+	// What parsed is kept next to the error.
+	cg, err := CollectCgroupV2(root)
+	if err == nil || cg == nil || cg.MemoryCurrentBytes == nil || cg.CPUUsageUsec != 5000 {
+		t.Errorf("cgroup_v2 = %+v, %v, want the readable parts and an error", cg, err)
+	}
+}
+
+func TestCollectCgroupHybrid(t *testing.T) {
+	// systemd's hybrid layout: the controllers on v1, an empty unified
+	// hierarchy for process tracking only. The v2 reader must not take
+	// /sys/fs/cgroup/unified for the container's cgroup.
+	tree := map[string]string{"sys/fs/cgroup/unified/cgroup.controllers": "\n", "sys/fs/cgroup/unified/cgroup.procs": "1\n"}
+	for path, data := range cgroupV1Tree {
+		tree[path] = data
+	}
+	root := t.TempDir()
+	writeTree(t, root, tree)
+	info, err := collectWith(context.Background(), Options{Root: root, Fields: []string{"cgroup_v1", "cgroup_v2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CgroupV2 != nil {
+		t.Errorf("cgroup_v2 = %+v on a hybrid host", info.CgroupV2)
+	}
+	if cg := info.CgroupV1; cg == nil || cg.MemoryLimitBytes == nil || *cg.MemoryLimitBytes != 512<<20 || cg.Throttling == nil {
+		t.Errorf("cgroup_v1 = %+v, want the v1 controllers", cg)
+	}
+}
@@ -0,0 +1,82 @@
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned by Collector.Collect after Close.
+var ErrClosed = errors.New("collector closed")
+
+// Collector collects reports repeatedly with the same options, for servers
+// and watch loops. Concurrent Collect calls share one collection: a call
+// made while a collection is running waits for it and gets its result, so
+// at most one collection goroutine exists however many requests arrive or
+// time out. Callers sharing a Collector may therefore receive the same
+// *SysInfo and must not modify it.
+type Collector struct {
+	opts   Options
+	mu     sync.Mutex
+	flight *flight // the running collection, if any
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type flight struct {
+	done chan struct{}
+	info *SysInfo
+	err  error
+}
+
+// New validates opts.Fields and returns a Collector for opts.
+func New(opts Options) (*Collector, error) {
+	if err := CheckFields(opts.Fields); err != nil {
+		return nil, err
+	}
+	return &Collector{opts: opts}, nil
+}
+
+// Collect returns a report collected with the Collector's options, started
+// by this call or by a concurrent one. When ctx is done first it returns
+// ctx.Err(); the collection still finishes and is handed to callers that
+// joined it.
+func (c *Collector) Collect(ctx context.Context) (*SysInfo, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	f := c.flight
+	if f == nil {
+		f = &flight{done: make(chan struct{})}
+		c.flight = f
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			f.info, f.err = CollectWith(c.opts)
+			c.mu.Lock()
+			c.flight = nil
+			c.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	c.mu.Unlock()
+	select {
+	case <-f.done:
+		return f.info, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close makes further Collect calls fail with ErrClosed and waits for a
+// collection in progress to finish, which Options.Mounts.Timeout bounds
+// when set. It is safe to call more than once.
+func (c *Collector) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.wg.Wait()
+	return nil
+}
@@ -0,0 +1,97 @@
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ownResources returns the open fds and goroutines of the test binary.
+func ownResources(t *testing.T) (fds, goroutines int) {
+	t.Helper()
+	fds, err := CountFDs("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fds, runtime.NumGoroutine()
+}
+
+func TestCollectorNoLeak(t *testing.T) {
+	if testing.Short() {
+		t.Skip("1000 collections of the live system")
+	}
+	if err := unsupported(); err != nil {
+		t.Skip(err)
+	}
+	// The live system rather than a fixture tree: the mount probes, netlink
+	// and /proc readers are what could leak.
+	c, err := New(Options{Mounts: MountOptions{Timeout: time.Second}, FDs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	// Warm up once- and lazily-initialized state first.
+	for range 3 {
+		if _, err := c.Collect(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fds, goroutines := ownResources(t)
+
+	for i := range 1000 {
+		switch i % 10 {
+		case 0:
+			// Callers that give up leave the collection to finish on its own.
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+			if _, err := c.Collect(cancelled); !errors.Is(err, context.Canceled) {
+				t.Fatalf("cycle %d: cancelled Collect = %v", i, err)
+			}
+		case 1:
+			var wg sync.WaitGroup
+			for range 4 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c.Collect(ctx)
+				}()
+			}
+			wg.Wait()
+		default:
+			if _, err := c.Collect(ctx); err != nil {
+				t.Fatalf("cycle %d: %v", i, err)
+			}
+		}
+	}
+	if _, err := c.Collect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Goroutines of the last cycle may still be on their way out.
+	var nowFDs, nowGoroutines int
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		nowFDs, nowGoroutines = ownResources(t)
+		if nowFDs <= fds && nowGoroutines <= goroutines || time.Now().After(deadline) {
+			break
+		}
+	}
+	if nowFDs > fds {
+		t.Errorf("fds grew from %d to %d over 1000 collections", fds, nowFDs)
+	}
+	if nowGoroutines > goroutines {
+		t.Errorf("goroutines grew from %d to %d over 1000 collections", goroutines, nowGoroutines)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Collect(ctx); !errors.Is(err, ErrClosed) {
+		t.Errorf("Collect after Close = %v, want ErrClosed", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+}
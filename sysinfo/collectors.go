@@ -0,0 +1,770 @@
+package sysinfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// collector fills part of a SysInfo. name is the key used in SysInfo.Errors
+// and keys are the top-level JSON keys it populates, in output order.
+type collector struct {
+	name    string
+	keys    []string
+	enabled func(opts Options) bool
+	run     func(ctx context.Context, info *SysInfo, opts Options) error
+}
+
+var collectors = []collector{
+	{
+		// The flat fields repeat the most asked-for parts of host for
+		// consumers that do not want to walk into it.
+		name: "host",
+		keys: []string{"host", "hostname", "kernel", "arch", "os_release"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Host, err = CollectHost(ctx, opts.Root)
+			if h := info.Host; h != nil {
+				info.Hostname, info.Kernel, info.Arch = h.Hostname, h.KernelRelease, h.Arch
+				info.OSRelease = h.OS.Name()
+			}
+			return err
+		},
+	},
+	{
+		name: "pid",
+		keys: []string{"pid", "comm"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) error {
+			stat, err := readProcStat(ctx, opts.Root, opts.PID)
+			if err != nil {
+				return err
+			}
+			info.PID, info.Comm = stat.PID, stat.Comm
+			return nil
+		},
+	},
+	{
+		name: "fd_count",
+		keys: []string{"fd_count"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.FDCount, err = CountFDs(opts.Root, opts.PID)
+			return err
+		},
+	},
+	{
+		// The summary by type is always collected; the fds themselves only
+		// with Options.FDs.
+		name: "fds",
+		keys: []string{"fd_types", "fds"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) error {
+			fds, err := ListFDs(opts.Root, opts.PID, opts.FDs)
+			if err != nil {
+				return err
+			}
+			info.FDTypes = FDTypes(fds)
+			if opts.FDs {
+				info.FDs = fds
+			}
+			return nil
+		},
+	},
+	{
+		name: "rlimits",
+		keys: []string{"rlimits"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Rlimits, err = ReadRlimits(opts.Root, opts.PID)
+			return err
+		},
+	},
+	{
+		name: "vmrss_bytes",
+		keys: []string{"vmrss_bytes"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.VmRSS, err = ReadRSS(ctx, opts.Root, opts.PID)
+			return err
+		},
+	},
+	{
+		name: "rss",
+		keys: []string{"rss"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.RSS, err = ReadRSSBreakdown(ctx, opts.Root, opts.PID)
+			return err
+		},
+	},
+	{
+		name:    "rss_growth",
+		keys:    []string{"rss_growth"},
+		enabled: func(opts Options) bool { return opts.RSSSample > 0 },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.RSSGrowth, err = sampleRSSGrowth(ctx, opts.clk(), opts.Root, opts.PID, opts.RSSSample)
+			return err
+		},
+	},
+	{
+		name:    "memory_detail",
+		keys:    []string{"memory_detail"},
+		enabled: func(opts Options) bool { return opts.MemoryDetail || slices.Contains(opts.Fields, "memory_detail") },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.MemoryDetail, err = ReadMemoryDetail(opts.Root, opts.PID)
+			return err
+		},
+	},
+	{
+		name:    "cpu_time",
+		keys:    []string{"process"},
+		enabled: func(opts Options) bool { return opts.CPUTime },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			cpuTime, err := ReadCPUTime(ctx, opts.Root, opts.PID)
+			if cpuTime != nil {
+				if opts.RawCounters {
+					cpuTime.IncludeRawCounters()
+				}
+				info.process().CPUTime = cpuTime
+			}
+			return err
+		},
+	},
+	{
+		name:    "sched",
+		keys:    []string{"process"},
+		enabled: func(opts Options) bool { return opts.Sched },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			switches, err := sampleCtxSwitches(ctx, opts.clk(), opts.Root, opts.PID, opts.Delta)
+			if switches != nil {
+				if opts.RawCounters {
+					switches.IncludeRawCounters()
+				}
+				info.process().CtxSwitches = switches
+			}
+			return err
+		},
+	},
+	{
+		// Without --sched the counters are read once, with no rates.
+		name: "thread_states",
+		keys: []string{"process"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) error {
+			total, states, err := ReadThreadStates(ctx, opts.Root, opts.PID)
+			if err != nil {
+				return err
+			}
+			p := info.process()
+			p.ThreadsTotal, p.ThreadStates = &total, states
+			if p.CtxSwitches == nil {
+				p.CtxSwitches, err = readCtxSwitches(ctx, opts.Root, opts.PID)
+				if p.CtxSwitches != nil && opts.RawCounters {
+					p.CtxSwitches.IncludeRawCounters()
+				}
+			}
+			return err
+		},
+	},
+	{
+		name: "peak_rss",
+		keys: []string{"process"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) error {
+			peak, err := ReadPeakRSS(ctx, opts.Root, opts.PID)
+			if err != nil {
+				return err
+			}
+			info.process().PeakRSSBytes = &peak
+			return nil
+		},
+	},
+	{
+		name: "exe_path",
+		keys: []string{"exe_path"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.ExePath, err = ExePath(opts.Root, opts.PID)
+			return err
+		},
+	},
+	{
+		name:    "process_tree",
+		keys:    []string{"process_tree", "process_tree_total"},
+		enabled: func(opts Options) bool { return opts.Children },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.ProcessTree, info.ProcessTreeTotal, err = CollectProcessTree(ctx, opts.Root, opts.PID)
+			return err
+		},
+	},
+	{
+		name:    "threads",
+		keys:    []string{"threads"},
+		enabled: func(opts Options) bool { return opts.Threads || slices.Contains(opts.Fields, "threads") },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Threads, err = ReadThreads(ctx, opts.Root, opts.PID)
+			return err
+		},
+	},
+	{
+		name: "cpu",
+		keys: []string{"cpu_model", "cpu_cores", "cores", "cpu", "cpu_flags", "virtualized"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.CPUCores = runtime.NumCPU()
+			info.CPU, info.Cores, err = collectCPU(opts.Root)
+			if info.CPU != nil {
+				info.CPUModel = info.CPU.Model
+				info.CPUFlags = info.CPU.NotableFlags()
+				info.Virtualized = info.CPU.HasFlag("hypervisor")
+			}
+			return err
+		},
+	},
+	{
+		name:    "cpu_sample",
+		keys:    []string{"cpu_usage_percent", "cpu_jiffies"},
+		enabled: func(opts Options) bool { return opts.CPUSample > 0 },
+		run: func(ctx context.Context, info *SysInfo, opts Options) error {
+			pct, jiffies, err := sampleCPUUsage(ctx, opts.clk(), opts.Root, opts.CPUSample)
+			if err != nil {
+				return err
+			}
+			info.CPUUsagePercent = &pct
+			if opts.RawCounters {
+				info.CPUJiffies = jiffies
+			}
+			return nil
+		},
+	},
+	{
+		name: "cpufreq",
+		keys: []string{"cpufreq"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.CPUFreq, err = CollectCPUFreq(opts.Root)
+			return err
+		},
+	},
+	{
+		name:    "throttle",
+		keys:    []string{"throttle"},
+		enabled: func(opts Options) bool { return opts.ThrottleSample > 0 },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Throttle, err = SampleThrottle(opts.clk(), opts.Root, opts.ThrottleSample)
+			return err
+		},
+	},
+	{
+		name:    "sched_features",
+		keys:    []string{"sched_features"},
+		enabled: func(opts Options) bool { return opts.SchedFeatures },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.SchedFeatures, err = CollectSchedFeatures(opts.Root)
+			return err
+		},
+	},
+	{
+		name: "uptime",
+		keys: []string{"uptime_seconds", "idle_seconds", "boot_time"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.UptimeSeconds, info.IdleSeconds, err = CollectUptime(opts.Root)
+			if err != nil {
+				return err
+			}
+			uptime := time.Duration(info.UptimeSeconds * float64(time.Second))
+			info.BootTime = opts.clk().Now().Add(-uptime).Truncate(time.Second).Format(time.RFC3339)
+			return nil
+		},
+	},
+	{
+		name: "loadavg",
+		keys: []string{"loadavg"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.LoadAvg, err = CollectLoadAvg(opts.Root)
+			return err
+		},
+	},
+	{
+		name: "pressure",
+		keys: []string{"pressure"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Pressure, err = CollectPressure(opts.Root)
+			return err
+		},
+	},
+	{
+		name: "memory",
+		keys: []string{"mem_total_kb", "mem_available_kb", "mem_used_percent", "swap_total_kb", "swap_free_kb", "memory"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) error {
+			meminfo, err := readMeminfo(ctx, opts.Root)
+			if err != nil {
+				return err
+			}
+			info.MemTotal = int(meminfo["MemTotal"])
+			info.MemAvailable = int(memAvailable(meminfo))
+			info.MemUsedPct = usedPercent(uint64(info.MemTotal), uint64(info.MemAvailable))
+			info.SwapTotal = int(meminfo["SwapTotal"])
+			info.SwapFree = int(meminfo["SwapFree"])
+			info.Memory = newMemInfo(meminfo)
+			return nil
+		},
+	},
+	{
+		name: "page_cache",
+		keys: []string{"page_cache"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.PageCache, err = CollectPageCache(ctx, opts.Root)
+			return err
+		},
+	},
+	{
+		name: "swap",
+		keys: []string{"swap"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Swap, err = CollectSwap(opts.Root)
+			return err
+		},
+	},
+	{
+		name: "numa_nodes",
+		keys: []string{"numa_nodes"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.NUMANodes, err = CollectNUMA(opts.Root)
+			return err
+		},
+	},
+	{
+		name: "sysctl",
+		keys: []string{"sysctl"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Sysctls, err = CollectSysctls(opts.Root)
+			return err
+		},
+	},
+	{
+		name: "mounts",
+		keys: []string{"mounts"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Mounts, err = CollectDisks(ctx, opts.Root, opts.Mounts)
+			return err
+		},
+	},
+	{
+		name:    "disk_health",
+		keys:    []string{"disk_health"},
+		enabled: func(opts Options) bool { return opts.DiskHealth },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.DiskHealth, err = CollectDiskHealth(opts.clk(), opts.Root, opts.DiskLatency)
+			return err
+		},
+	},
+	{
+		name: "disk_io",
+		keys: []string{"disk_io"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.DiskIO, err = CollectDiskIO(opts.clk(), opts.Root, opts.DiskIOSample, opts.DiskIOAll)
+			if opts.RawCounters {
+				for i := range info.DiskIO {
+					info.DiskIO[i].IncludeRawCounters()
+				}
+			}
+			return err
+		},
+	},
+	{
+		name: "bind_files",
+		keys: []string{"bind_files"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.BindFiles, err = CollectBindFiles(opts.Root)
+			return err
+		},
+	},
+	{
+		name:    "config_files",
+		keys:    []string{"config_files"},
+		enabled: func(opts Options) bool { return opts.ConfigFiles },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.ConfigFiles, err = CollectConfigFiles(opts.Root)
+			return err
+		},
+	},
+	{
+		name: "memory_limit",
+		keys: []string{"cgroup_v1"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			limit, err := ReadCgroupMemoryLimit(opts.Root)
+			if limit != nil {
+				info.cgroupV1().MemoryLimitBytes = limit
+			}
+			return err
+		},
+	},
+	{
+		name: "cpu_limit",
+		keys: []string{"cgroup_v1"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			cores, err := ReadCgroupCPULimit(opts.Root)
+			if cores != nil {
+				info.cgroupV1().CPULimitCores = cores
+			}
+			return err
+		},
+	},
+	{
+		name: "memory_usage",
+		keys: []string{"cgroup_v1"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			usage, err := ReadCgroupMemoryUsage(opts.Root)
+			if usage != nil {
+				cg := info.cgroupV1()
+				cg.MemoryUsageBytes = usage
+				cg.MemoryUsagePercent = percentOfLimit(usage, cg.MemoryLimitBytes)
+			}
+			return err
+		},
+	},
+	{
+		name: "memory_peak",
+		keys: []string{"cgroup_v1"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			peak, err := ReadCgroupMemoryPeak(opts.Root)
+			if peak != nil {
+				info.cgroupV1().MemoryPeakBytes = peak
+			}
+			return err
+		},
+	},
+	{
+		name: "cpu_usage",
+		keys: []string{"cgroup_v1"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			usage, throttling, err := ReadCgroupCPUUsage(opts.Root)
+			if usage != nil || throttling != nil {
+				cg := info.cgroupV1()
+				cg.CPUUsageNs, cg.Throttling = usage, throttling
+			}
+			return err
+		},
+	},
+	{
+		name: "cgroup_v2",
+		keys: []string{"cgroup_v2"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.CgroupV2, err = CollectCgroupV2(opts.Root)
+			return err
+		},
+	},
+	{
+		name:    "cgroup_cpu_utilization",
+		keys:    []string{"cgroup_v1", "cgroup_v2"},
+		enabled: func(opts Options) bool { return opts.CgroupCPU > 0 },
+		run: func(ctx context.Context, info *SysInfo, opts Options) error {
+			pct, usage, v2, err := sampleCgroupCPUUtilization(opts.clk(), opts.Root, opts.CgroupCPU)
+			raw := pct != nil && opts.RawCounters
+			switch {
+			case v2 && info.CgroupV2 != nil:
+				info.CgroupV2.CPUUtilizationPercent = pct
+				if raw {
+					usec := uint64(usage / time.Microsecond)
+					info.CgroupV2.CPUUsageUsecTotal = &usec
+				}
+			case !v2 && pct != nil:
+				cg := info.cgroupV1()
+				cg.CPUUtilizationPercent = pct
+				if raw {
+					ns := uint64(usage)
+					cg.CPUUsageNsTotal = &ns
+				}
+			}
+			return err
+		},
+	},
+	{
+		name: "container_runtime",
+		keys: []string{"container_runtime"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.ContainerRuntime, err = DetectContainerRuntime(opts.Root)
+			return err
+		},
+	},
+	{
+		name: "dns",
+		keys: []string{"network"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) error {
+			dns, err := CollectDNS(opts.Root)
+			if dns != nil {
+				info.network().DNS = dns
+			}
+			return err
+		},
+	},
+	{
+		name: "interfaces",
+		keys: []string{"network"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) error {
+			ifaces, err := CollectInterfaces(opts.Root)
+			if ifaces != nil {
+				info.network().Interfaces = ifaces
+			}
+			return err
+		},
+	},
+	{
+		name: "listening",
+		keys: []string{"network"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) error {
+			sockets, err := CollectListening(opts.Root)
+			if err != nil {
+				return err
+			}
+			n := info.network()
+			n.Listening = sockets
+			n.DefaultRoute, err = CollectDefaultRoute(opts.Root)
+			AnnotateNetwork(n)
+			return err
+		},
+	},
+	{
+		name:    "containers",
+		keys:    []string{"containers"},
+		enabled: func(opts Options) bool { return opts.ContainerCPU > 0 },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Containers, err = sampleContainerCPU(ctx, opts.clk(), opts.Root, opts.ContainerCPU)
+			if opts.RawCounters {
+				for i := range info.Containers {
+					info.Containers[i].IncludeRawCounters()
+				}
+			}
+			return err
+		},
+	},
+	{
+		// Opt-in since it walks every process and opens their binaries.
+		name:    "agents",
+		keys:    []string{"agents"},
+		enabled: func(opts Options) bool { return opts.Agents || slices.Contains(opts.Fields, "agents") },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Agents, err = CollectAgents(ctx, opts.Root)
+			return err
+		},
+	},
+	{
+		name: "irq",
+		keys: []string{"irq"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.IRQ, err = CollectIRQ(opts.Root, opts.IRQDetail)
+			return err
+		},
+	},
+	{
+		name:    "isolation",
+		keys:    []string{"isolation"},
+		enabled: func(opts Options) bool { return opts.Isolation },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Isolation, err = CollectIsolation(ctx, opts.Root, opts.PID)
+			return err
+		},
+	},
+	{
+		name: "hwmon",
+		keys: []string{"hwmon"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Hwmon, err = CollectHwmon(opts.Root)
+			return err
+		},
+	},
+	{
+		// Opt-in since it reads several files per channel; selecting
+		// the key with Options.Fields enables it too.
+		name:    "sensors",
+		keys:    []string{"sensors"},
+		enabled: func(opts Options) bool { return opts.Sensors || slices.Contains(opts.Fields, "sensors") },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Sensors, err = CollectTemperatures(opts.Root)
+			return err
+		},
+	},
+	{
+		name: "security",
+		keys: []string{"security"},
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.Security, err = ReadSecurityContext(ctx, opts.Root, opts.PID)
+			return err
+		},
+	},
+	{
+		name:    "privileged_files",
+		keys:    []string{"security"},
+		enabled: func(opts Options) bool { return opts.PrivilegedScan },
+		run: func(ctx context.Context, info *SysInfo, opts Options) (err error) {
+			info.security().Files, err = ScanPrivilegedFiles(opts.Root, opts.PrivilegedDirs)
+			return err
+		},
+	},
+}
+
+// collect runs c. Once the process has been identified, a later "no such
+// process" means it exited while the report was being collected.
+func (c collector) collect(ctx context.Context, info *SysInfo, opts Options) error {
+	if err := unsupported(); err != nil {
+		return err
+	}
+	err := c.run(ctx, info, opts)
+	if opts.PID != 0 && info.Comm != "" && errors.Is(err, ErrNoProcess) {
+		return fmt.Errorf("process %d %w: %w", opts.PID, ErrProcessExited, ErrNoProcess)
+	}
+	return err
+}
+
+func (info *SysInfo) security() *Security {
+	if info.Security == nil {
+		info.Security = &Security{}
+	}
+	return info.Security
+}
+
+func (info *SysInfo) process() *ProcessInfo {
+	if info.Process == nil {
+		info.Process = &ProcessInfo{}
+	}
+	return info.Process
+}
+
+func (info *SysInfo) cgroupV1() *CgroupV1 {
+	if info.CgroupV1 == nil {
+		info.CgroupV1 = &CgroupV1{}
+	}
+	return info.CgroupV1
+}
+
+func (info *SysInfo) network() *Network {
+	if info.Network == nil {
+		info.Network = &Network{}
+	}
+	return info.Network
+}
+
+func enabledCollectors(opts Options) []collector {
+	var enabled []collector
+	for _, c := range collectors {
+		if c.enabled != nil && !c.enabled(opts) {
+			continue
+		}
+		if !opts.wants("findings") && !opts.wantsAny(c.keys) {
+			continue
+		}
+		enabled = append(enabled, c)
+	}
+	return enabled
+}
+
+// wants reports whether key is in opts.Fields; an empty selection wants
+// everything.
+func (opts Options) wants(key string) bool {
+	if len(opts.Fields) == 0 {
+		return true
+	}
+	for _, f := range opts.Fields {
+		if f == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (opts Options) wantsAny(keys []string) bool {
+	for _, k := range keys {
+		if opts.wants(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields returns the names of the collectors that run for opts. They are
+// the keys that can appear in SysInfo.Errors.
+func Fields(opts Options) []string {
+	var names []string
+	for _, c := range enabledCollectors(opts) {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// Keys returns the top-level JSON keys of a report that Options.Fields can
+// select, in output order. Selecting "findings" runs every collector, since
+// findings cross-check several of them.
+func Keys() []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, c := range collectors {
+		for _, k := range c.keys {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	return append(keys, "findings", "errors")
+}
+
+// CheckFields returns an error naming the valid keys if any of fields is
+// not one of Keys.
+func CheckFields(fields []string) error {
+	valid := Keys()
+	for _, f := range fields {
+		if !slices.Contains(valid, f) {
+			return fmt.Errorf("unknown field %q (valid: %s)", f, strings.Join(valid, ", "))
+		}
+	}
+	return nil
+}
+
+// fieldAliases are short names for groups of keys, e.g. --fields cgroup,mem,fd.
+var fieldAliases = map[string][]string{
+	"cgroup": {"cgroup_v1", "cgroup_v2"},
+	"mem":    {"mem_total_kb", "mem_available_kb", "mem_used_percent", "swap_total_kb", "swap_free_kb", "memory", "swap"},
+	"fd":     {"fd_count", "fd_types", "fds"},
+	"disk":   {"mounts", "disk_io"},
+	"load":   {"loadavg", "pressure"},
+	"net":    {"network"},
+}
+
+// ExpandFields resolves aliases in fields, drops duplicates and checks the
+// result like CheckFields.
+func ExpandFields(fields []string) ([]string, error) {
+	var expanded []string
+	for _, f := range fields {
+		f = strings.TrimSpace(f)
+		keys, ok := fieldAliases[f]
+		if !ok {
+			keys = []string{f}
+		}
+		for _, k := range keys {
+			if !slices.Contains(expanded, k) {
+				expanded = append(expanded, k)
+			}
+		}
+	}
+	if err := CheckFields(expanded); err != nil {
+		return nil, fmt.Errorf("%w; aliases: %s", err, strings.Join(slices.Sorted(maps.Keys(fieldAliases)), ", "))
+	}
+	return expanded, nil
+}
+
+// Select returns the selected top-level keys of info's JSON form. Errors
+// are always kept when present, so a failed field is not silently absent,
+// and so is schema_version.
+func (info *SysInfo) Select(fields []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]json.RawMessage)
+	for _, f := range append(slices.Clip(fields), "schema_version", "read_issues", "errors") {
+		if raw, ok := all[f]; ok {
+			selected[f] = raw
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,66 @@
+package sysinfo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+)
+
+// configFiles are the files whose drift CollectConfigFiles reports.
+var configFiles = []string{
+	"/etc/resolv.conf", "/etc/hosts", "/etc/nsswitch.conf", "/etc/fstab", "/etc/os-release",
+}
+
+// FileInfo identifies a version of a config file without its contents.
+// Size, Mtime and SHA256 describe the file a symlink points to.
+type FileInfo struct {
+	Path          string    `json:"path"`
+	Size          int64     `json:"size"`
+	Mtime         time.Time `json:"mtime"`
+	SHA256        string    `json:"sha256"`
+	SymlinkTarget string    `json:"symlink_target,omitempty"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// sha256Prefix is the number of hex digits of the content hash kept: enough
+// to tell versions apart in snapshot history.
+const sha256Prefix = 16
+
+// CollectConfigFiles stats and hashes the files in configFiles. Contents are
+// never reported, so no redaction applies beyond leaving them out. A file
+// that is missing or unreadable gets an entry with Error set.
+func CollectConfigFiles(root string) ([]FileInfo, error) {
+	files := make([]FileInfo, 0, len(configFiles))
+	for _, path := range configFiles {
+		f := FileInfo{Path: path}
+		if err := statConfigFile(rootPath(root, path), &f); err != nil {
+			f.Error = err.Error()
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func statConfigFile(path string, f *FileInfo) error {
+	if target, err := os.Readlink(path); err == nil {
+		f.SymlinkTarget = target
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return err
+	}
+	f.Size, f.Mtime = fi.Size(), fi.ModTime()
+	f.SHA256 = hex.EncodeToString(h.Sum(nil))[:sha256Prefix]
+	return nil
+}
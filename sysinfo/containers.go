@@ -0,0 +1,235 @@
+package sysinfo
+
+import (
+	"context"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+const topContainers = 10
+
+type ContainerCPU struct {
+	ID                 string  `json:"id"`
+	CgroupPath         string  `json:"cgroup_path"`
+	CPUPercent         float64 `json:"cpu_percent"`
+	Processes          int     `json:"processes"`
+	MemoryCurrentBytes *uint64 `json:"memory_current_bytes,omitempty"`
+	// CPUTicksTotal is the utime+stime of the container's processes at
+	// the second pass, set by IncludeRawCounters.
+	CPUTicksTotal *uint64 `json:"cpu_ticks_total,omitempty"`
+
+	ticks uint64
+}
+
+// IncludeRawCounters fills CPUTicksTotal, the counter CPUPercent was
+// computed from.
+func (c *ContainerCPU) IncludeRawCounters() {
+	c.CPUTicksTotal = &c.ticks
+}
+
+// containerIDPattern matches the 64-hex container ID that docker,
+// containerd, CRI-O and podman put in the cgroup path, e.g.
+// /docker/<id>, /system.slice/docker-<id>.scope or
+// /kubepods/.../cri-containerd-<id>.scope.
+var containerIDPattern = regexp.MustCompile(`(?:^|[/\-:])([0-9a-f]{64})(?:\.scope)?(?:/|$)`)
+
+// DetectContainerRuntime names the container runtime this process runs
+// under: "podman" (/run/.containerenv), "docker" (/.dockerenv), or one
+// guessed from the segments of /proc/self/cgroup: docker, containerd,
+// cri-o, podman, lxc, or "kubernetes" for a kubepods cgroup whose runtime
+// is not named. "" means no container was detected, which includes
+// containers with a private cgroup namespace and neither marker file.
+func DetectContainerRuntime(root string) (string, error) {
+	if _, err := os.Stat(rootPath(root, "run/.containerenv")); err == nil {
+		return "podman", nil
+	}
+	if _, err := os.Stat(rootPath(root, ".dockerenv")); err == nil {
+		return "docker", nil
+	}
+	data, err := os.ReadFile(rootPath(root, "proc/self/cgroup"))
+	if err != nil {
+		return "", err
+	}
+	kubepods := false
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		for _, seg := range strings.Split(parts[2], "/") {
+			switch {
+			case seg == "docker" || strings.HasPrefix(seg, "docker-"):
+				return "docker", nil
+			case strings.HasPrefix(seg, "cri-containerd-"):
+				return "containerd", nil
+			case strings.HasPrefix(seg, "crio-"):
+				return "cri-o", nil
+			case strings.HasPrefix(seg, "libpod-"):
+				return "podman", nil
+			case seg == "lxc" || strings.HasPrefix(seg, "lxc.payload"):
+				return "lxc", nil
+			case strings.HasPrefix(seg, "kubepods"):
+				kubepods = true
+			}
+		}
+	}
+	if kubepods {
+		return "kubernetes", nil
+	}
+	return "", nil
+}
+
+type procSample struct {
+	ticks     uint64
+	startTime uint64
+	cgroup    string
+}
+
+// SampleContainerCPU walks /proc twice, window apart, and attributes the
+// utime+stime of every process to the container its cgroup belongs to.
+// Processes that exit or whose pid is reused between the passes are left
+// out of the delta. The busiest containers come first.
+func SampleContainerCPU(ctx context.Context, root string, window time.Duration) ([]ContainerCPU, error) {
+	return sampleContainerCPU(ctx, clock.Real, root, window)
+}
+
+func sampleContainerCPU(ctx context.Context, clk clock.Clock, root string, window time.Duration) ([]ContainerCPU, error) {
+	before, err := walkProcCPU(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	begin := clk.Now()
+	clk.Sleep(window)
+	after, err := walkProcCPU(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := clk.Now().Sub(begin).Seconds()
+	hz := clockTicks(root)
+
+	byID := make(map[string]*ContainerCPU)
+	for pid, cur := range after {
+		id := containerID(cur.cgroup)
+		if id == "" {
+			continue
+		}
+		c, ok := byID[id]
+		if !ok {
+			c = &ContainerCPU{ID: id, CgroupPath: cur.cgroup}
+			byID[id] = c
+		}
+		c.Processes++
+		c.ticks += cur.ticks
+		prev, ok := before[pid]
+		if !ok || prev.startTime != cur.startTime || cur.ticks < prev.ticks {
+			continue
+		}
+		c.CPUPercent += float64(cur.ticks-prev.ticks) / hz / elapsed * 100
+	}
+
+	var result []ContainerCPU
+	for _, c := range byID {
+		c.CPUPercent = math.Round(c.CPUPercent*10) / 10
+		c.MemoryCurrentBytes = readCgroupMemoryCurrent(root, c.CgroupPath)
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CPUPercent != result[j].CPUPercent {
+			return result[i].CPUPercent > result[j].CPUPercent
+		}
+		return result[i].ID < result[j].ID
+	})
+	if len(result) > topContainers {
+		result = result[:topContainers]
+	}
+	return result, nil
+}
+
+// walkProcCPU reads utime+stime, the start time and the cgroup of every
+// process. Processes that vanish during the walk are skipped.
+func walkProcCPU(ctx context.Context, root string) (map[int]procSample, error) {
+	entries, err := os.ReadDir(rootPath(root, "proc"))
+	if err != nil {
+		return nil, err
+	}
+	samples := make(map[int]procSample)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := readProcStat(ctx, root, pid)
+		if err != nil {
+			continue
+		}
+		cgroup, err := readProcCgroup(root, pid)
+		if err != nil {
+			continue
+		}
+		samples[pid] = procSample{
+			ticks:     stat.uintField(14) + stat.uintField(15),
+			startTime: stat.uintField(22),
+			cgroup:    cgroup,
+		}
+	}
+	return samples, nil
+}
+
+// readProcCgroup returns the cgroup v2 path of pid, or on a v1-only host
+// the path in the memory hierarchy.
+func readProcCgroup(root string, pid int) (string, error) {
+	data, err := os.ReadFile(procDir(root, pid, "cgroup"))
+	if err != nil {
+		return "", processError(pid, err)
+	}
+	var unified, memory string
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			unified = parts[2]
+		case strings.Contains(","+parts[1]+",", ",memory,"):
+			memory = parts[2]
+		}
+	}
+	if memory != "" && memory != "/" {
+		return memory, nil
+	}
+	return unified, nil
+}
+
+func containerID(cgroupPath string) string {
+	m := containerIDPattern.FindStringSubmatch(cgroupPath)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// readCgroupMemoryCurrent returns memory.current (v2) or
+// memory.usage_in_bytes (v1) of the cgroup at path, or nil.
+func readCgroupMemoryCurrent(root, path string) *uint64 {
+	for _, file := range []string{
+		rootPath(root, "sys/fs/cgroup", path, "memory.current"),
+		rootPath(root, "sys/fs/cgroup/memory", path, "memory.usage_in_bytes"),
+	} {
+		value, err := readTrim(file)
+		if err != nil {
+			continue
+		}
+		if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return &n
+		}
+	}
+	return nil
+}
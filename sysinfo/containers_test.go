@@ -0,0 +1,43 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContainerCPURawCounters(t *testing.T) {
+	id := strings.Repeat("ab", 32)
+	stat := func(pid, utime, stime int) string {
+		return fmt.Sprintf("%d (app) S 1 %d %d 0 -1 4194560 100 0 0 0 %d %d 0 0 20 0 1 0 1000 10000000 500 0 0 0 0 0\n", pid, pid, pid, utime, stime)
+	}
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"proc/10/stat":   stat(10, 100, 50),
+		"proc/10/cgroup": "0::/system.slice/docker-" + id + ".scope\n",
+		"proc/11/stat":   stat(11, 10, 0),
+		"proc/11/cgroup": "0::/system.slice/docker-" + id + ".scope\n",
+	})
+	opts := Options{Root: root, Fields: []string{"containers"}, ContainerCPU: time.Second, RawCounters: true}
+	opts.Clock = newSampleClock(func(int) {
+		// 100 ticks over the 1s window, at the default 100 Hz: one core.
+		writeTree(t, root, map[string]string{"proc/10/stat": stat(10, 180, 70)})
+	})
+	info, err := collectWith(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Containers) != 1 {
+		t.Fatalf("containers = %+v, want one", info.Containers)
+	}
+	c := info.Containers[0]
+	if c.CPUPercent != 100 || c.Processes != 2 {
+		t.Errorf("container = %+v, want 100%% over 2 processes", c)
+	}
+	// Both processes at the second pass: 180+70 and 10+0.
+	if c.CPUTicksTotal == nil || *c.CPUTicksTotal != 260 {
+		t.Errorf("cpu_ticks_total = %v, want 260", c.CPUTicksTotal)
+	}
+}
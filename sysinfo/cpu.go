@@ -0,0 +1,293 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+type CPUInfo struct {
+	Model         string   `json:"model"`
+	Sockets       int      `json:"sockets"`
+	PhysicalCores int      `json:"physical_cores"`
+	LogicalCores  int      `json:"logical_cores"`
+	MHz           float64  `json:"mhz,omitempty"`
+	MaxMHz        float64  `json:"max_mhz,omitempty"`
+	Flags         []string `json:"flags,omitempty"`
+}
+
+// CoreInfo is one processor block of /proc/cpuinfo. MHz is whatever the
+// governor had set when the file was read; ARM kernels do not report it.
+type CoreInfo struct {
+	Processor int     `json:"processor"`
+	Model     string  `json:"model"`
+	MHz       float64 `json:"mhz,omitempty"`
+}
+
+// notableCPUFlags are the flags worth surfacing on their own: vector and
+// crypto extensions software dispatches on, and virtualization support.
+var notableCPUFlags = []string{
+	"sse4_1", "sse4_2", "avx", "avx2", "avx512f", "aes", "sha_ni", "pclmulqdq",
+	"vmx", "svm", "hypervisor",
+	"asimd", "sve", "sha2", "crc32", "atomics",
+}
+
+var armImplementers = map[string]string{
+	"0x41": "ARM",
+	"0x42": "Broadcom",
+	"0x43": "Cavium",
+	"0x46": "Fujitsu",
+	"0x48": "HiSilicon",
+	"0x4e": "NVIDIA",
+	"0x50": "APM",
+	"0x51": "Qualcomm",
+	"0x61": "Apple",
+	"0xc0": "Ampere",
+}
+
+// CollectCPU parses /proc/cpuinfo into a topology summary and reads the
+// current and maximum frequency from cpufreq where it is available.
+func CollectCPU(root string) (*CPUInfo, error) {
+	cpu, _, err := collectCPU(root)
+	return cpu, err
+}
+
+// collectCPU also returns the model and frequency of each processor, since
+// on hybrid CPUs (big.LITTLE, P/E cores) one model name does not fit all.
+func collectCPU(root string) (*CPUInfo, []CoreInfo, error) {
+	cpuData, err := os.ReadFile(rootPath(root, "proc/cpuinfo"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cpu := &CPUInfo{}
+	var perCore []CoreInfo
+	sockets := make(map[string]bool)
+	cores := make(map[string]bool)
+	var hardware, implementer, part string
+	var cpuinfoMHz float64
+	var mhzCount int
+
+	for _, block := range strings.Split(string(cpuData), "\n\n") {
+		fields := parseCPUBlock(block)
+		if processor, ok := fields["processor"]; ok {
+			cpu.LogicalCores++
+			core := CoreInfo{Model: fields["model name"]}
+			core.Processor, _ = strconv.Atoi(processor)
+			core.MHz, _ = strconv.ParseFloat(fields["cpu MHz"], 64)
+			if core.Model == "" && fields["CPU implementer"] != "" {
+				core.Model = armModel(fields["CPU implementer"], fields["CPU part"])
+			}
+			perCore = append(perCore, core)
+		}
+		if physical, ok := fields["physical id"]; ok {
+			sockets[physical] = true
+			if core, ok := fields["core id"]; ok {
+				cores[physical+"/"+core] = true
+			}
+		}
+		if cpu.Model == "" {
+			cpu.Model = fields["model name"]
+		}
+		// Every processor block repeats the flags; the first one is enough.
+		if cpu.Flags == nil {
+			if flags, ok := fields["flags"]; ok {
+				cpu.Flags = strings.Fields(flags)
+			} else if features, ok := fields["Features"]; ok {
+				cpu.Flags = strings.Fields(features)
+			}
+		}
+		if value, ok := fields["cpu MHz"]; ok {
+			if mhz, err := strconv.ParseFloat(value, 64); err == nil {
+				cpuinfoMHz += mhz
+				mhzCount++
+			}
+		}
+		if v := fields["Hardware"]; v != "" {
+			hardware = v
+		}
+		if v := fields["CPU implementer"]; v != "" && implementer == "" {
+			implementer = v
+			part = fields["CPU part"]
+		}
+	}
+
+	// ARM kernels have no "model name"; fall back to the SoC name or to
+	// the implementer/part pair.
+	if cpu.Model == "" {
+		switch {
+		case hardware != "":
+			cpu.Model = hardware
+		case implementer != "":
+			cpu.Model = armModel(implementer, part)
+		}
+	}
+
+	if cpu.LogicalCores == 0 {
+		cpu.LogicalCores = runtime.NumCPU()
+	}
+	cpu.Sockets = len(sockets)
+	if cpu.Sockets == 0 {
+		cpu.Sockets = 1
+	}
+	cpu.PhysicalCores = len(cores)
+	if cpu.PhysicalCores == 0 {
+		cpu.PhysicalCores = cpu.LogicalCores
+	}
+
+	cpu.MHz, cpu.MaxMHz = readCPUFrequencies(root)
+	if cpu.MHz == 0 && mhzCount > 0 {
+		cpu.MHz = cpuinfoMHz / float64(mhzCount)
+	}
+	return cpu, perCore, nil
+}
+
+func armModel(implementer, part string) string {
+	name, ok := armImplementers[strings.ToLower(implementer)]
+	if !ok {
+		name = "implementer " + implementer
+	}
+	return strings.TrimSpace(name + " part " + part)
+}
+
+// HasFlag reports whether the CPU lists flag in /proc/cpuinfo.
+func (c *CPUInfo) HasFlag(flag string) bool {
+	return slices.Contains(c.Flags, flag)
+}
+
+// NotableFlags returns the notableCPUFlags the CPU has, in that order.
+func (c *CPUInfo) NotableFlags() []string {
+	var flags []string
+	for _, f := range notableCPUFlags {
+		if c.HasFlag(f) {
+			flags = append(flags, f)
+		}
+	}
+	return flags
+}
+
+func parseCPUBlock(block string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(block, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return fields
+}
+
+// readCPUFrequencies returns the average current and the highest maximum
+// frequency over all CPUs, in MHz. cpufreq reports kHz.
+func readCPUFrequencies(root string) (float64, float64) {
+	dirs, _ := filepath.Glob(rootPath(root, "sys/devices/system/cpu/cpu[0-9]*/cpufreq"))
+	var sum, max float64
+	var n int
+	for _, dir := range dirs {
+		if khz, err := readKHz(filepath.Join(dir, "scaling_cur_freq")); err == nil {
+			sum += khz
+			n++
+		}
+		if khz, err := readKHz(filepath.Join(dir, "cpuinfo_max_freq")); err == nil && khz > max {
+			max = khz
+		}
+	}
+	if n == 0 {
+		return 0, max / 1000
+	}
+	return sum / float64(n) / 1000, max / 1000
+}
+
+func readKHz(path string) (float64, error) {
+	value, err := readTrim(path)
+	if err != nil {
+		return 0, err
+	}
+	khz, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return khz, nil
+}
+
+// SampleCPUUsage reads the aggregate cpu line of /proc/stat twice, window
+// apart, and returns the share of jiffies that were not idle or iowait.
+func SampleCPUUsage(ctx context.Context, root string, window time.Duration) (float64, error) {
+	pct, _, err := sampleCPUUsage(ctx, clock.Real, root, window)
+	return pct, err
+}
+
+// sampleCPUUsage also returns the counters of the second read, the end of
+// the sampled window.
+func sampleCPUUsage(ctx context.Context, clk clock.Clock, root string, window time.Duration) (float64, *CPUJiffies, error) {
+	before, err := readCPUJiffies(ctx, root)
+	if err != nil {
+		return 0, nil, err
+	}
+	clk.Sleep(window)
+	after, err := readCPUJiffies(ctx, root)
+	if err != nil {
+		return 0, nil, err
+	}
+	total0, total1 := before.total(), after.total()
+	if total1 <= total0 {
+		return 0, after, nil
+	}
+	// iowait may go backwards on some kernels, hence signed and clamped.
+	dTotal := float64(total1 - total0)
+	dIdle := float64(int64(after.idle() - before.idle()))
+	return min(max((dTotal-dIdle)/dTotal*100, 0), 100), after, nil
+}
+
+// CPUJiffies is the aggregate cpu line of /proc/stat in clock ticks since
+// boot, per mode: the raw counters behind CPUUsagePercent. Guest time is
+// already counted in user and nice, so it is left out.
+type CPUJiffies struct {
+	UserTotal    uint64 `json:"user_total"`
+	NiceTotal    uint64 `json:"nice_total"`
+	SystemTotal  uint64 `json:"system_total"`
+	IdleTotal    uint64 `json:"idle_total"`
+	IOWaitTotal  uint64 `json:"iowait_total"`
+	IRQTotal     uint64 `json:"irq_total"`
+	SoftIRQTotal uint64 `json:"softirq_total"`
+	StealTotal   uint64 `json:"steal_total"`
+}
+
+func (j *CPUJiffies) idle() uint64 { return j.IdleTotal + j.IOWaitTotal }
+
+func (j *CPUJiffies) total() uint64 {
+	return j.UserTotal + j.NiceTotal + j.SystemTotal + j.IdleTotal + j.IOWaitTotal + j.IRQTotal + j.SoftIRQTotal + j.StealTotal
+}
+
+// readCPUJiffies parses the first eight fields of the cpu line; kernels
+// before 2.6.11 stop after fewer, which leave the rest zero.
+func readCPUJiffies(ctx context.Context, root string) (*CPUJiffies, error) {
+	data, err := readVerified(ctx, rootPath(root, "proc/stat"), hasLines("cpu ", "btime "))
+	if err != nil {
+		return nil, err
+	}
+	line, _, _ := strings.Cut(data, "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return nil, fmt.Errorf("malformed /proc/stat cpu line %q", line)
+	}
+	j := &CPUJiffies{}
+	modes := []*uint64{&j.UserTotal, &j.NiceTotal, &j.SystemTotal, &j.IdleTotal, &j.IOWaitTotal, &j.IRQTotal, &j.SoftIRQTotal, &j.StealTotal}
+	for i, f := range fields[1:min(len(fields), len(modes)+1)] {
+		n, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed /proc/stat cpu line %q", line)
+		}
+		*modes[i] = n
+	}
+	return j, nil
+}
@@ -0,0 +1,69 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSampleCPUUsageWindow(t *testing.T) {
+	root := t.TempDir()
+	stat := func(user, idle uint64) string {
+		return fmt.Sprintf("cpu  %d 0 0 %d 0 0 0 0 0 0\ncpu0 1 0 0 1 0 0 0 0 0 0\nbtime 1700000000\n", user, idle)
+	}
+	writeTree(t, root, map[string]string{"proc/stat": stat(100, 900)})
+	clk := newSampleClock(func(int) {
+		// 300 of the next 400 jiffies are busy.
+		writeTree(t, root, map[string]string{"proc/stat": stat(400, 1000)})
+	})
+	start := clk.Now()
+
+	pct, jiffies, err := sampleCPUUsage(context.Background(), clk, root, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pct != 75 {
+		t.Errorf("usage = %v%%, want 75%%", pct)
+	}
+	// The raw counters are those of the second read.
+	if jiffies == nil || jiffies.UserTotal != 400 || jiffies.IdleTotal != 1000 {
+		t.Errorf("jiffies = %+v, want user 400 and idle 1000", jiffies)
+	}
+	if got := clk.Now().Sub(start); got != 5*time.Second {
+		t.Errorf("clock advanced %v, want the 5s window", got)
+	}
+}
+
+func TestSampleCPUUsageNoProgress(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"proc/stat": "cpu  1 0 0 1 0 0 0 0 0 0\nbtime 1700000000\n"})
+	pct, _, err := sampleCPUUsage(context.Background(), newSampleClock(nil), root, time.Second)
+	if err != nil || pct != 0 {
+		t.Errorf("sampleCPUUsage = %v, %v; want 0, nil when no jiffies passed", pct, err)
+	}
+}
+
+func TestCPUSampleRawCounters(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"proc/stat": "cpu  10 1 2 80 3 4 5 6 7 8\nbtime 1700000000\n"})
+	for _, raw := range []bool{false, true} {
+		opts := Options{Root: root, Fields: []string{"cpu_usage_percent", "cpu_jiffies"}, CPUSample: time.Second, RawCounters: raw}
+		opts.Clock = newSampleClock(nil)
+		info, err := collectWith(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !raw {
+			if info.CPUJiffies != nil {
+				t.Errorf("cpu_jiffies = %+v without RawCounters", info.CPUJiffies)
+			}
+			continue
+		}
+		// Guest and guest_nice are already part of user and nice.
+		want := CPUJiffies{UserTotal: 10, NiceTotal: 1, SystemTotal: 2, IdleTotal: 80, IOWaitTotal: 3, IRQTotal: 4, SoftIRQTotal: 5, StealTotal: 6}
+		if info.CPUJiffies == nil || *info.CPUJiffies != want {
+			t.Errorf("cpu_jiffies = %+v, want %+v", info.CPUJiffies, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+)
+
+type CPUFreq struct {
+	Driver       string `json:"driver,omitempty"`
+	TurboEnabled *bool  `json:"turbo_enabled"`
+	TurboSource  string `json:"turbo_source,omitempty"`
+}
+
+// CollectCPUFreq reports the cpufreq driver and whether turbo/boost is
+// enabled. TurboEnabled is nil when the kernel exposes neither knob.
+func CollectCPUFreq(root string) (*CPUFreq, error) {
+	freq := &CPUFreq{}
+	if driver, err := readTrim(rootPath(root, "sys/devices/system/cpu/cpu0/cpufreq/scaling_driver")); err == nil {
+		freq.Driver = driver
+	}
+
+	// cpufreq/boost is the generic knob (acpi-cpufreq, amd-pstate): 1 means
+	// boost is allowed. intel_pstate has its own with inverted meaning:
+	// no_turbo=1 means turbo is disabled.
+	sources := []struct {
+		path     string
+		inverted bool
+	}{
+		{"sys/devices/system/cpu/cpufreq/boost", false},
+		{"sys/devices/system/cpu/intel_pstate/no_turbo", true},
+	}
+	for _, src := range sources {
+		value, err := readTrim(rootPath(root, src.path))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return freq, err
+		}
+		var enabled bool
+		switch value {
+		case "0":
+			enabled = src.inverted
+		case "1":
+			enabled = !src.inverted
+		default:
+			return freq, fmt.Errorf("%s: unexpected value %q", src.path, value)
+		}
+		freq.TurboEnabled = &enabled
+		freq.TurboSource = "/" + src.path
+		break
+	}
+	return freq, nil
+}
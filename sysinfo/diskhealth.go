@@ -0,0 +1,210 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+// DiskHealth is a heuristic, SMART-free health hint for one block device,
+// built from counters the kernel exposes to unprivileged users. Health is
+// "ok", "degraded" or "suspect"; Reasons say why it is not ok. Counters a
+// device does not have stay nil: ioerr_cnt and friends exist for SCSI/SATA
+// disks only, the PCIe AER counters for NVMe and other PCI devices. The
+// NVMe error log itself needs an admin command and is not read.
+type DiskHealth struct {
+	Device         string   `json:"device"`
+	IOErrors       *uint64  `json:"ioerr_cnt,omitempty"`
+	IODone         *uint64  `json:"iodone_cnt,omitempty"`
+	IORequests     *uint64  `json:"iorequest_cnt,omitempty"`
+	AERCorrectable *uint64  `json:"aer_correctable,omitempty"`
+	AERUncorrected *uint64  `json:"aer_uncorrectable,omitempty"`
+	AvgLatencyMs   *float64 `json:"avg_latency_ms,omitempty"`
+	SampleIOs      uint64   `json:"sample_ios,omitempty"`
+	Health         string   `json:"health"`
+	Reasons        []string `json:"reasons,omitempty"`
+}
+
+// diskLatencyDegradedMs is the average completion time above which a
+// device counts as degraded. It is generous enough for busy spinning
+// disks; SSDs normally stay far below it.
+const diskLatencyDegradedMs = 100
+
+// CollectDiskHealth reports every disk in /sys/block that has a device
+// behind it (loop, ram and device-mapper devices have none). With a
+// window > 0 it also samples /proc/diskstats twice and derives the
+// average I/O latency from the read and write milliseconds per completed
+// I/O; a device that completed no I/O in the window gets no latency.
+func CollectDiskHealth(clk clock.Clock, root string, window time.Duration) ([]DiskHealth, error) {
+	entries, err := os.ReadDir(rootPath(root, "sys/block"))
+	if err != nil {
+		return nil, err
+	}
+	var before map[string]diskStat
+	if window > 0 {
+		if before, err = readDiskStats(root); err != nil {
+			return nil, err
+		}
+		clk.Sleep(window)
+	}
+
+	var disks []DiskHealth
+	for _, e := range entries {
+		dev := e.Name()
+		devDir := rootPath(root, "sys/block", dev, "device")
+		if _, err := os.Stat(devDir); err != nil {
+			continue
+		}
+		d := DiskHealth{Device: dev}
+		d.IOErrors = readHexCounter(devDir + "/ioerr_cnt")
+		d.IODone = readHexCounter(devDir + "/iodone_cnt")
+		d.IORequests = readHexCounter(devDir + "/iorequest_cnt")
+		// For NVMe, device/ is the controller and device/device the PCI
+		// function carrying the AER counters.
+		for _, pci := range []string{devDir, devDir + "/device"} {
+			if corr, ok := readAERTotal(pci+"/aer_dev_correctable", "TOTAL_ERR_COR"); ok {
+				nonfatal, _ := readAERTotal(pci+"/aer_dev_nonfatal", "TOTAL_ERR_NONFATAL")
+				fatal, _ := readAERTotal(pci+"/aer_dev_fatal", "TOTAL_ERR_FATAL")
+				uncorr := nonfatal + fatal
+				d.AERCorrectable, d.AERUncorrected = &corr, &uncorr
+				break
+			}
+		}
+		disks = append(disks, d)
+	}
+
+	if window > 0 {
+		after, err := readDiskStats(root)
+		if err != nil {
+			return nil, err
+		}
+		for i := range disks {
+			b, okB := before[disks[i].Device]
+			a, okA := after[disks[i].Device]
+			if !okB || !okA || a.ios < b.ios {
+				continue
+			}
+			disks[i].SampleIOs = a.ios - b.ios
+			if disks[i].SampleIOs > 0 {
+				lat := float64(a.ms-b.ms) / float64(disks[i].SampleIOs)
+				disks[i].AvgLatencyMs = &lat
+			}
+		}
+	}
+	for i := range disks {
+		disks[i].assess()
+	}
+	return disks, nil
+}
+
+// assess sets Health: any I/O error or uncorrectable PCIe error makes a
+// device suspect; corrected PCIe errors or slow completions make it
+// degraded.
+func (d *DiskHealth) assess() {
+	d.Health = "ok"
+	if d.AvgLatencyMs != nil && *d.AvgLatencyMs > diskLatencyDegradedMs {
+		d.Health = "degraded"
+		d.Reasons = append(d.Reasons, fmt.Sprintf("average I/O latency %.0f ms", *d.AvgLatencyMs))
+	}
+	if d.AERCorrectable != nil && *d.AERCorrectable > 0 {
+		d.Health = "degraded"
+		d.Reasons = append(d.Reasons, fmt.Sprintf("%d corrected PCIe errors", *d.AERCorrectable))
+	}
+	if d.IOErrors != nil && *d.IOErrors > 0 {
+		d.Health = "suspect"
+		d.Reasons = append(d.Reasons, fmt.Sprintf("%d I/O errors", *d.IOErrors))
+	}
+	if d.AERUncorrected != nil && *d.AERUncorrected > 0 {
+		d.Health = "suspect"
+		d.Reasons = append(d.Reasons, fmt.Sprintf("%d uncorrectable PCIe errors", *d.AERUncorrected))
+	}
+}
+
+// readHexCounter parses the "0x1a" values of the SCSI counters; nil when
+// the device has no such counter or it is not in that form, so that a
+// decimal value is not taken for hex.
+func readHexCounter(path string) *uint64 {
+	value, err := readTrim(path)
+	if err != nil {
+		return nil
+	}
+	digits, ok := strings.CutPrefix(value, "0x")
+	if !ok {
+		return nil
+	}
+	n, err := strconv.ParseUint(digits, 16, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// readAERTotal returns the total line of an aer_dev_* file.
+func readAERTotal(path, key string) (uint64, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			n, err := strconv.ParseUint(fields[1], 10, 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// diskStat is the part of a /proc/diskstats line the latency and the
+// I/O counters need: completed reads plus writes and the milliseconds
+// spent on them, and each direction's operations and sectors.
+type diskStat struct {
+	ios, ms                uint64
+	reads, sectorsRead     uint64
+	writes, sectorsWritten uint64
+}
+
+func readDiskStats(root string) (map[string]diskStat, error) {
+	data, err := os.ReadFile(rootPath(root, "proc/diskstats"))
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]diskStat)
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 14 {
+			continue
+		}
+		var v [6]uint64
+		for i, col := range []int{3, 5, 6, 7, 9, 10} {
+			if v[i], err = strconv.ParseUint(f[col], 10, 64); err != nil {
+				return nil, fmt.Errorf("/proc/diskstats: %s: %w", f[2], err)
+			}
+		}
+		stats[f[2]] = diskStat{
+			ios: v[0] + v[3], ms: v[2] + v[5],
+			reads: v[0], sectorsRead: v[1],
+			writes: v[3], sectorsWritten: v[4],
+		}
+	}
+	return stats, nil
+}
+
+func diskHealthFindings(disks []DiskHealth) []Finding {
+	var findings []Finding
+	for _, d := range disks {
+		if d.Health == "suspect" {
+			findings = append(findings, Finding{
+				Code:     "disk_suspect",
+				Severity: "warning",
+				Message: fmt.Sprintf("%s: %s (heuristic from kernel error counters; check SMART)",
+					d.Device, strings.Join(d.Reasons, ", ")),
+			})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,76 @@
+package sysinfo
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// counterString formats an optional counter for comparison and messages.
+func counterString(n *uint64) string {
+	if n == nil {
+		return "nil"
+	}
+	return strconv.FormatUint(*n, 10)
+}
+
+func TestReadHexCounter(t *testing.T) {
+	tests := []struct {
+		data string
+		want *uint64
+	}{
+		{"0x0\n", ptr[uint64](0)},
+		{"0x1a\n", ptr[uint64](26)},
+		{"0xFFFF", ptr[uint64](65535)},
+		{"0xffffffffffffffff\n", ptr[uint64](1<<64 - 1)},
+		// The kernel writes 0x%x; anything else is not a counter.
+		{"26\n", nil},
+		{"1a\n", nil},
+		{"0x\n", nil},
+		{"0x1g\n", nil},
+		{"0x-1\n", nil},
+		{"0x10000000000000000\n", nil},
+		{"\n", nil},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		writeTree(t, dir, map[string]string{"ioerr_cnt": tt.data})
+		got := readHexCounter(filepath.Join(dir, "ioerr_cnt"))
+		if counterString(got) != counterString(tt.want) {
+			t.Errorf("readHexCounter(%q) = %s, want %s", tt.data, counterString(got), counterString(tt.want))
+		}
+	}
+	if got := readHexCounter(filepath.Join(dir, "missing")); got != nil {
+		t.Errorf("missing counter = %d, want nil", *got)
+	}
+}
+
+func TestCollectDiskHealthIOErrors(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"sys/block/sda/device/ioerr_cnt":     "0x2\n",
+		"sys/block/sda/device/iodone_cnt":    "0x1f4\n",
+		"sys/block/sda/device/iorequest_cnt": "0x1f6\n",
+		"sys/block/sdb/device/ioerr_cnt":     "0x0\n",
+		// A decimal value does not pass for a count of 0x12 errors.
+		"sys/block/sdc/device/ioerr_cnt": "12\n",
+		"sys/block/loop0/size":           "0\n",
+	})
+	disks, err := CollectDiskHealth(newSampleClock(nil), root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(disks) != 3 {
+		t.Fatalf("disks = %+v, want sda, sdb and sdc", disks)
+	}
+	sda, sdb, sdc := disks[0], disks[1], disks[2]
+	if counterString(sda.IOErrors) != "2" || counterString(sda.IODone) != "500" || counterString(sda.IORequests) != "502" || sda.Health != "suspect" {
+		t.Errorf("sda = %+v, want 2 errors of 502 requests and suspect", sda)
+	}
+	if counterString(sdb.IOErrors) != "0" || sdb.Health != "ok" {
+		t.Errorf("sdb = %+v, want no errors and ok", sdb)
+	}
+	if sdc.IOErrors != nil || sdc.Health != "ok" {
+		t.Errorf("sdc = %+v, want no ioerr_cnt", sdc)
+	}
+}
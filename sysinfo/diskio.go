@@ -0,0 +1,134 @@
+package sysinfo
+
+import (
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+// diskSectorSize is the unit of the /proc/diskstats sector counters: the
+// kernel always counts 512-byte sectors, whatever the device's own sector
+// size.
+const diskSectorSize = 512
+
+// DiskIOStat holds the I/O of a block device. The per-second rates are set
+// only when the counters were sampled over a window; the cumulative
+// counters since boot they come from are in the report only with
+// IncludeRawCounters.
+type DiskIOStat struct {
+	Device           string   `json:"device"`
+	ReadsPerSec      *float64 `json:"reads_per_sec,omitempty"`
+	WritesPerSec     *float64 `json:"writes_per_sec,omitempty"`
+	ReadBytesPerSec  *float64 `json:"read_bytes_per_sec,omitempty"`
+	WriteBytesPerSec *float64 `json:"write_bytes_per_sec,omitempty"`
+
+	ReadsCompletedTotal  *uint64 `json:"reads_completed_total,omitempty"`
+	SectorsReadTotal     *uint64 `json:"sectors_read_total,omitempty"`
+	WritesCompletedTotal *uint64 `json:"writes_completed_total,omitempty"`
+	SectorsWrittenTotal  *uint64 `json:"sectors_written_total,omitempty"`
+
+	counters    DiskCounters
+	hasCounters bool
+}
+
+// DiskCounters are the cumulative /proc/diskstats counters of a device.
+type DiskCounters struct {
+	ReadsCompleted  uint64
+	SectorsRead     uint64
+	WritesCompleted uint64
+	SectorsWritten  uint64
+}
+
+// ReadBytes and WrittenBytes convert the sector counters to bytes.
+func (c DiskCounters) ReadBytes() uint64    { return c.SectorsRead * diskSectorSize }
+func (c DiskCounters) WrittenBytes() uint64 { return c.SectorsWritten * diskSectorSize }
+
+// IncludeRawCounters fills the *_total fields next to the rates. With a
+// window they come from the second sample, the end of the rate interval.
+func (d *DiskIOStat) IncludeRawCounters() {
+	d.ReadsCompletedTotal = &d.counters.ReadsCompleted
+	d.SectorsReadTotal = &d.counters.SectorsRead
+	d.WritesCompletedTotal = &d.counters.WritesCompleted
+	d.SectorsWrittenTotal = &d.counters.SectorsWritten
+}
+
+// Counters returns the cumulative counters: those CollectDiskIO read or,
+// for a decoded report, the *_total fields. ok is false for a decoded
+// report written without raw counters.
+func (d DiskIOStat) Counters() (c DiskCounters, ok bool) {
+	if d.hasCounters {
+		return d.counters, true
+	}
+	if d.ReadsCompletedTotal == nil || d.SectorsReadTotal == nil || d.WritesCompletedTotal == nil || d.SectorsWrittenTotal == nil {
+		return DiskCounters{}, false
+	}
+	return DiskCounters{
+		ReadsCompleted:  *d.ReadsCompletedTotal,
+		SectorsRead:     *d.SectorsReadTotal,
+		WritesCompleted: *d.WritesCompletedTotal,
+		SectorsWritten:  *d.SectorsWrittenTotal,
+	}, true
+}
+
+// CollectDiskIO reads /proc/diskstats for every whole disk: devices listed
+// in /sys/block other than loop and ram devices, so partitions are left
+// out. With all set every line is reported. With window > 0 the counters
+// are read twice and the rates derived; a device that appeared or was
+// reset in the window gets none.
+func CollectDiskIO(clk clock.Clock, root string, window time.Duration, all bool) ([]DiskIOStat, error) {
+	before, err := readDiskStats(root)
+	if err != nil {
+		return nil, err
+	}
+	after := before
+	if window > 0 {
+		clk.Sleep(window)
+		if after, err = readDiskStats(root); err != nil {
+			return nil, err
+		}
+	}
+
+	var disks []DiskIOStat
+	for _, dev := range slices.Sorted(maps.Keys(after)) {
+		if !all && !isWholeDisk(root, dev) {
+			continue
+		}
+		a := after[dev]
+		d := DiskIOStat{
+			Device: dev,
+			counters: DiskCounters{
+				ReadsCompleted:  a.reads,
+				SectorsRead:     a.sectorsRead,
+				WritesCompleted: a.writes,
+				SectorsWritten:  a.sectorsWritten,
+			},
+			hasCounters: true,
+		}
+		if b, ok := before[dev]; ok && window > 0 && a.reads >= b.reads && a.writes >= b.writes &&
+			a.sectorsRead >= b.sectorsRead && a.sectorsWritten >= b.sectorsWritten {
+			secs := window.Seconds()
+			rate := func(delta uint64) *float64 {
+				r := float64(delta) / secs
+				return &r
+			}
+			d.ReadsPerSec = rate(a.reads - b.reads)
+			d.WritesPerSec = rate(a.writes - b.writes)
+			d.ReadBytesPerSec = rate((a.sectorsRead - b.sectorsRead) * diskSectorSize)
+			d.WriteBytesPerSec = rate((a.sectorsWritten - b.sectorsWritten) * diskSectorSize)
+		}
+		disks = append(disks, d)
+	}
+	return disks, nil
+}
+
+func isWholeDisk(root, dev string) bool {
+	if strings.HasPrefix(dev, "loop") || strings.HasPrefix(dev, "ram") {
+		return false
+	}
+	_, err := os.Stat(rootPath(root, "sys/block", strings.ReplaceAll(dev, "/", "!")))
+	return err == nil
+}
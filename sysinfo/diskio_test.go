@@ -0,0 +1,61 @@
+package sysinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDiskIORawCounters(t *testing.T) {
+	line := func(reads, sectorsRead, writes, sectorsWritten int) string {
+		return fmt.Sprintf(" 252 0 vda %d 0 %d 100 %d 0 %d 200 0 300 300\n 252 1 vda1 1 0 8 0 0 0 0 0 0 0 0\n",
+			reads, sectorsRead, writes, sectorsWritten)
+	}
+	for _, raw := range []bool{false, true} {
+		root := t.TempDir()
+		writeTree(t, root, map[string]string{
+			"proc/diskstats":    line(100, 8000, 50, 4000),
+			"sys/block/vda/dev": "252:0\n",
+		})
+		opts := Options{Root: root, Fields: []string{"disk_io"}, DiskIOSample: time.Second, RawCounters: raw}
+		opts.Clock = newSampleClock(func(int) {
+			writeTree(t, root, map[string]string{"proc/diskstats": line(110, 8200, 60, 5000)})
+		})
+		info, err := collectWith(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(info.DiskIO) != 1 {
+			t.Fatalf("disk_io = %+v, want vda only", info.DiskIO)
+		}
+		d := info.DiskIO[0]
+		if d.ReadsPerSec == nil || *d.ReadsPerSec != 10 || d.WriteBytesPerSec == nil || *d.WriteBytesPerSec != 1000*512 {
+			t.Errorf("rates = %v reads/s, %v B/s written, want 10 and 512000", d.ReadsPerSec, d.WriteBytesPerSec)
+		}
+		// The text and Prometheus output always have the counters.
+		if c, ok := d.Counters(); !ok || c != (DiskCounters{110, 8200, 60, 5000}) {
+			t.Errorf("counters = %+v, %v, want the second sample's", c, ok)
+		}
+
+		out, err := json.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded DiskIOStat
+		if err := json.Unmarshal(out, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		c, ok := decoded.Counters()
+		if !raw {
+			if ok || d.ReadsCompletedTotal != nil {
+				t.Errorf("report %s has counters without RawCounters", out)
+			}
+			continue
+		}
+		if !ok || c != (DiskCounters{110, 8200, 60, 5000}) {
+			t.Errorf("report %s: counters = %+v, want the second sample's", out, c)
+		}
+	}
+}
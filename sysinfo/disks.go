@@ -0,0 +1,303 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type DiskInfo struct {
+	Mountpoint        string
+	FSType            string
+	Device            string `json:"device"`
+	Total             uint64
+	Free              uint64
+	Avail             uint64
+	UsedPercent       float64 `json:"used_percent"`
+	Inodes            uint64  `json:"inodes"`
+	InodesFree        uint64  `json:"inodes_free"`
+	InodesUsedPercent float64 `json:"inodes_used_percent"`
+	// Options are the mount options from /proc/mounts (rw, nosuid, noexec,
+	// relatime, ...); ReadOnly is set when they include ro.
+	Options  []string `json:"options"`
+	ReadOnly bool     `json:"read_only"`
+	// Error is set, and the sizes are zero, when the mount did not answer
+	// within MountOptions.Timeout or statfs failed on it.
+	Error string `json:"error,omitempty"`
+}
+
+// MountOptions selects which mounts are reported and how long to wait for
+// each. By default pseudo and layered filesystems are skipped and each
+// device is reported once, under its shortest mountpoint. All disables
+// both; FSTypes, if set, keeps only those types. SkipFSTypes replaces
+// DefaultSkipFSTypes when not nil. SkipNetworkFS drops network filesystems
+// in every mode.
+type MountOptions struct {
+	All           bool
+	FSTypes       []string
+	SkipFSTypes   []string
+	SkipNetworkFS bool
+	// Timeout bounds stat/statfs per mount; zero waits forever.
+	Timeout time.Duration
+}
+
+// DefaultSkipFSTypes are the pseudo and layered filesystems that never hold
+// user data worth a row in the disk table. A trailing * matches any suffix.
+var DefaultSkipFSTypes = []string{
+	"proc", "sysfs", "cgroup", "cgroup2", "devtmpfs", "tmpfs", "devpts", "mqueue",
+	"overlay", "squashfs", "autofs", "nsfs", "debugfs", "tracefs", "securityfs",
+	"pstore", "bpf", "configfs", "hugetlbfs", "fusectl", "binfmt_misc", "ramfs",
+	"fuse.*",
+}
+
+var networkFSTypes = []string{
+	"nfs", "nfs4", "cifs", "smb3", "smbfs", "ceph", "glusterfs", "afs",
+	"fuse.sshfs", "fuse.glusterfs", "fuse.s3fs",
+}
+
+func (o MountOptions) keep(fsType string) bool {
+	if o.SkipNetworkFS && slices.Contains(networkFSTypes, fsType) {
+		return false
+	}
+	if len(o.FSTypes) > 0 {
+		return slices.Contains(o.FSTypes, fsType)
+	}
+	if o.All {
+		return true
+	}
+	skip := o.SkipFSTypes
+	if skip == nil {
+		skip = DefaultSkipFSTypes
+	}
+	return !slices.ContainsFunc(skip, func(t string) bool {
+		if prefix, ok := strings.CutSuffix(t, "*"); ok {
+			return strings.HasPrefix(fsType, prefix)
+		}
+		return t == fsType
+	})
+}
+
+// CollectDisks returns size information for every mounted filesystem that
+// passes opts.
+func CollectDisks(ctx context.Context, root string, opts MountOptions) ([]DiskInfo, error) {
+	var disks []DiskInfo
+	err := WalkDisks(ctx, root, opts, func(d DiskInfo) error {
+		disks = append(disks, d)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return disks, nil
+}
+
+type mountEntry struct {
+	device, mountpoint, fsType string
+	options                    []string
+	probe                      mountProbe
+	err                        error
+}
+
+// WalkDisks stats every mount, concurrently when opts.Timeout is set, and
+// then calls fn for each in /proc/mounts order. It stops and returns the
+// error if fn returns one.
+func WalkDisks(ctx context.Context, root string, opts MountOptions, fn func(DiskInfo) error) error {
+	if err := unsupported(); err != nil {
+		return err
+	}
+	data, err := readVerified(ctx, rootPath(root, "proc/mounts"), hasLines())
+	if err != nil {
+		return err
+	}
+
+	var mounts []mountEntry
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		m := mountEntry{device: fields[0], mountpoint: unescapeMountPath(fields[1]), fsType: fields[2]}
+		if len(fields) > 3 {
+			m.options = strings.Split(fields[3], ",")
+		}
+		if opts.keep(m.fsType) {
+			mounts = append(mounts, m)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range mounts {
+		path := rootPath(root, mounts[i].mountpoint)
+		if opts.Timeout <= 0 {
+			mounts[i].probe = statMount(path)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mounts[i].probe, mounts[i].err = probeMount(path, opts.Timeout)
+		}()
+	}
+	wg.Wait()
+
+	// Single-file bind mounts (resolv.conf, hosts, ... in containers) would
+	// report the parent filesystem; they are listed by CollectBindFiles
+	// instead. A mount statfs fails on (permission denied, a mountpoint
+	// since removed) is still listed, with the error.
+	mounts = slices.DeleteFunc(mounts, func(m mountEntry) bool {
+		return m.err == nil && m.probe.regular
+	})
+	for i, m := range mounts {
+		if m.err == nil && m.probe.statfsErr != nil {
+			mounts[i].err = fmt.Errorf("%s%w", statfsErrPrefix, m.probe.statfsErr)
+		}
+	}
+	if !opts.All {
+		mounts = dedupMounts(mounts)
+	}
+
+	for _, m := range mounts {
+		d := DiskInfo{Mountpoint: m.mountpoint, FSType: m.fsType, Device: m.device, Options: m.options}
+		d.ReadOnly = slices.Contains(d.Options, "ro")
+		if m.err != nil {
+			d.Error = m.err.Error()
+		} else {
+			p := m.probe
+			d.Total, d.Free, d.Avail = p.total, p.free, p.avail
+			// As df: used over what unprivileged users could have, so
+			// blocks reserved for root count neither way.
+			d.UsedPercent = usedPercent(d.Total-d.Free+d.Avail, d.Avail)
+			d.Inodes, d.InodesFree = p.inodes, p.inodesFree
+			d.InodesUsedPercent = usedPercent(p.inodes, p.inodesFree)
+		}
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mountProbe is what stat and statfs report for a mountpoint, with sizes
+// already in bytes.
+type mountProbe struct {
+	regular            bool
+	dev                uint64
+	devKnown           bool
+	total, free, avail uint64
+	inodes, inodesFree uint64
+	statfsErr          error
+}
+
+// maxMountProbes caps the stat goroutines in flight. A probe stuck in the
+// kernel on a dead NFS server cannot be cancelled: it is abandoned, keeps
+// its slot until the syscall returns, and the mount is reported with an
+// error. While it is stuck, later walks (e.g. in --watch) time the same
+// mount out at once instead of starting another probe, so at most
+// maxMountProbes goroutines ever leak, however long the process runs.
+const maxMountProbes = 8
+
+var (
+	probeSlots = make(chan struct{}, maxMountProbes)
+	probeMu    sync.Mutex
+	hungProbes = make(map[string]bool)
+)
+
+func probeMount(path string, timeout time.Duration) (mountProbe, error) {
+	probeMu.Lock()
+	hung := hungProbes[path]
+	probeMu.Unlock()
+	if hung {
+		return mountProbe{}, fmt.Errorf("not responding (an earlier stat is still blocked)")
+	}
+
+	// Waiting for a slot is bounded by a timeout of its own, so a mount
+	// queued behind slow ones still gets the whole timeout for its stat.
+	wait := time.NewTimer(timeout)
+	select {
+	case probeSlots <- struct{}{}:
+		wait.Stop()
+	case <-wait.C:
+		return mountProbe{}, fmt.Errorf("no free probe slot after %s (other mounts are not responding)", timeout)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	done := make(chan mountProbe, 1)
+	finished := false
+	go func() {
+		p := statMount(path)
+		<-probeSlots
+		probeMu.Lock()
+		finished = true
+		delete(hungProbes, path)
+		probeMu.Unlock()
+		done <- p
+	}()
+	select {
+	case p := <-done:
+		return p, nil
+	case <-timer.C:
+		probeMu.Lock()
+		if !finished {
+			hungProbes[path] = true
+		}
+		probeMu.Unlock()
+		return mountProbe{}, fmt.Errorf("stat timed out after %s", timeout)
+	}
+}
+
+// dedupMounts keeps one mount per filesystem (st_dev), the one with the
+// shortest mountpoint, so bind mounts of the same device appear once.
+// Mounts whose device is unknown are kept as they are.
+func dedupMounts(mounts []mountEntry) []mountEntry {
+	best := make(map[uint64]int)
+	for i, m := range mounts {
+		if !m.probe.devKnown {
+			continue
+		}
+		if j, ok := best[m.probe.dev]; !ok || len(m.mountpoint) < len(mounts[j].mountpoint) {
+			best[m.probe.dev] = i
+		}
+	}
+	var result []mountEntry
+	for i, m := range mounts {
+		if !m.probe.devKnown || best[m.probe.dev] == i {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// statfsErrPrefix starts DiskInfo.Error when statfs answered with an
+// error, as opposed to not answering at all.
+const statfsErrPrefix = "statfs: "
+
+func diskFindings(mounts []DiskInfo) []Finding {
+	var findings []Finding
+	for _, d := range mounts {
+		if d.Error == "" {
+			continue
+		}
+		f := Finding{
+			Code:     "mount_unresponsive",
+			Severity: "warning",
+			Message:  fmt.Sprintf("%s (%s from %s): %s", d.Mountpoint, d.FSType, d.Device, d.Error),
+		}
+		if strings.HasPrefix(d.Error, statfsErrPrefix) {
+			f.Code, f.Severity = "mount_statfs_failed", "info"
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+func usedPercent(total, avail uint64) float64 {
+	if total == 0 || avail > total {
+		return 0
+	}
+	return math.Round(float64(total-avail)/float64(total)*1000) / 10
+}
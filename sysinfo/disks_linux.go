@@ -0,0 +1,22 @@
+package sysinfo
+
+import "golang.org/x/sys/unix"
+
+func statMount(path string) mountProbe {
+	var p mountProbe
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err == nil {
+		p.regular = st.Mode&unix.S_IFMT == unix.S_IFREG
+		p.dev, p.devKnown = st.Dev, true
+	}
+	var fs unix.Statfs_t
+	if p.statfsErr = unix.Statfs(path, &fs); p.statfsErr != nil {
+		return p
+	}
+	// Bfree includes blocks reserved for root; Bavail is what an
+	// unprivileged user can actually write, which is what df shows.
+	bsize := uint64(fs.Bsize)
+	p.total, p.free, p.avail = fs.Blocks*bsize, fs.Bfree*bsize, fs.Bavail*bsize
+	p.inodes, p.inodesFree = fs.Files, fs.Ffree
+	return p
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package sysinfo
+
+func statMount(path string) mountProbe {
+	return mountProbe{statfsErr: unsupported()}
+}
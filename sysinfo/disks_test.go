@@ -0,0 +1,166 @@
+package sysinfo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func walkAll(t *testing.T, root string, opts MountOptions) []DiskInfo {
+	t.Helper()
+	var disks []DiskInfo
+	err := WalkDisks(context.Background(), root, opts, func(d DiskInfo) error {
+		disks = append(disks, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return disks
+}
+
+func diskMountpoints(disks []DiskInfo) string {
+	var points []string
+	for _, d := range disks {
+		points = append(points, d.Mountpoint)
+	}
+	return strings.Join(points, " ")
+}
+
+func TestDedupMounts(t *testing.T) {
+	dev := func(mountpoint string, dev uint64) mountEntry {
+		return mountEntry{mountpoint: mountpoint, probe: mountProbe{dev: dev, devKnown: true}}
+	}
+	mounts := []mountEntry{
+		dev("/srv/data", 1),
+		dev("/", 1),
+		dev("/home/user/data", 1),
+		dev("/boot", 2),
+		{mountpoint: "/mnt/timed-out"},
+		dev("/mnt/b", 3),
+		dev("/mnt/a", 3),
+		{mountpoint: "/mnt/timed-out-too"},
+	}
+	// The shortest mountpoint of each device, the first of equally short
+	// ones, in the original order; unknown devices all stay.
+	var got []string
+	for _, m := range dedupMounts(mounts) {
+		got = append(got, m.mountpoint)
+	}
+	want := "/ /boot /mnt/timed-out /mnt/b /mnt/timed-out-too"
+	if strings.Join(got, " ") != want {
+		t.Errorf("dedupMounts = %q, want %s", got, want)
+	}
+}
+
+// mountsFixture is a /proc/mounts with bind mounts of the root device, a
+// container's overlay layer and the usual pseudo filesystems. In the test
+// tree every mountpoint is a directory on the same filesystem, so all of
+// them share one device.
+const mountsFixture = `/dev/sda1 / ext4 rw,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+tmpfs /run tmpfs rw,nosuid,nodev,size=1628876k,mode=755 0 0
+/dev/sda1 /srv/data ext4 rw,relatime 0 0
+overlay /var/lib/docker/overlay2/3c1f/merged overlay rw,relatime,lowerdir=/var/lib/docker/overlay2/l/A:/var/lib/docker/overlay2/l/B,upperdir=/var/lib/docker/overlay2/3c1f/diff,workdir=/var/lib/docker/overlay2/3c1f/work 0 0
+/dev/sda1 /var/lib/docker/containers/3c1f/mounts/shm\040data ext4 ro,relatime 0 0
+/dev/loop3 /snap/core22/1380 squashfs ro,nodev,relatime 0 0
+`
+
+func TestWalkDisksMountsFixture(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"proc/mounts": mountsFixture})
+	for _, dir := range []string{"run", "srv/data", "var/lib/docker/overlay2/3c1f/merged", "var/lib/docker/containers/3c1f/mounts/shm data", "snap/core22/1380"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name string
+		opts MountOptions
+		want string
+	}{
+		{"default", MountOptions{}, "/"},
+		{"all", MountOptions{All: true}, "/ /proc /run /srv/data /var/lib/docker/overlay2/3c1f/merged /var/lib/docker/containers/3c1f/mounts/shm data /snap/core22/1380"},
+		{"fstype ext4", MountOptions{FSTypes: []string{"ext4"}}, "/"},
+		{"fstype overlay", MountOptions{FSTypes: []string{"overlay"}}, "/var/lib/docker/overlay2/3c1f/merged"},
+		{"all ext4", MountOptions{All: true, FSTypes: []string{"ext4"}}, "/ /srv/data /var/lib/docker/containers/3c1f/mounts/shm data"},
+		{"concurrent", MountOptions{All: true, FSTypes: []string{"ext4", "tmpfs"}, Timeout: time.Second}, "/ /run /srv/data /var/lib/docker/containers/3c1f/mounts/shm data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disks := walkAll(t, root, tt.opts)
+			if got := diskMountpoints(disks); got != tt.want {
+				t.Errorf("mountpoints = %q, want %q", got, tt.want)
+			}
+			for _, d := range disks {
+				if d.Error != "" {
+					t.Errorf("%s: %s", d.Mountpoint, d.Error)
+				}
+				if d.Mountpoint == "/var/lib/docker/containers/3c1f/mounts/shm data" && !d.ReadOnly {
+					t.Errorf("%s: not read-only with options %q", d.Mountpoint, d.Options)
+				}
+			}
+		})
+	}
+}
+
+func TestWalkDisksStatfsFailure(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"proc/mounts": "/dev/sda1 / ext4 rw 0 0\n/dev/sdb1 /gone xfs rw 0 0\n/dev/sda1 /etc/hosts ext4 ro 0 0\n",
+		"etc/hosts":   "127.0.0.1 localhost\n",
+	})
+	for _, timeout := range []time.Duration{0, time.Second} {
+		disks := walkAll(t, root, MountOptions{Timeout: timeout})
+		// The single-file bind mount is left out; the mount statfs fails
+		// on is kept, with the error.
+		var points []string
+		for _, d := range disks {
+			points = append(points, d.Mountpoint)
+		}
+		if strings.Join(points, " ") != "/ /gone" {
+			t.Fatalf("timeout %v: mountpoints = %q, want / and /gone", timeout, points)
+		}
+		if disks[0].Error != "" || disks[0].Total == 0 {
+			t.Errorf("timeout %v: / = %+v, want its sizes", timeout, disks[0])
+		}
+		gone := disks[1]
+		if !strings.HasPrefix(gone.Error, statfsErrPrefix) || gone.Total != 0 {
+			t.Errorf("timeout %v: /gone = %+v, want a statfs error", timeout, gone)
+		}
+		findings := diskFindings(disks)
+		if len(findings) != 1 || findings[0].Code != "mount_statfs_failed" || findings[0].Severity != "info" {
+			t.Errorf("timeout %v: findings = %+v, want one mount_statfs_failed", timeout, findings)
+		}
+	}
+}
+
+func TestProbeMountSlotWait(t *testing.T) {
+	// With every slot taken, a probe gives up after its timeout; once one
+	// frees up, the stat itself gets the whole timeout again.
+	for range maxMountProbes {
+		probeSlots <- struct{}{}
+	}
+	release := func(n int) {
+		for range n {
+			<-probeSlots
+		}
+	}
+	defer func() { release(maxMountProbes - 1) }()
+
+	dir := t.TempDir()
+	if _, err := probeMount(dir, 10*time.Millisecond); err == nil || !strings.Contains(err.Error(), "no free probe slot") {
+		t.Fatalf("err = %v, want no free probe slot", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		release(1)
+	}()
+	p, err := probeMount(dir, time.Second)
+	if err != nil || p.statfsErr != nil || p.total == 0 {
+		t.Errorf("probe = %+v, %v, want the sizes", p, err)
+	}
+}
@@ -0,0 +1,165 @@
+package sysinfo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DMI is the hardware identity from /sys/class/dmi/id. The serials and
+// product UUID are readable by root only and stay empty otherwise.
+type DMI struct {
+	SysVendor      string `json:"sys_vendor,omitempty"`
+	ProductName    string `json:"product_name,omitempty"`
+	ProductVersion string `json:"product_version,omitempty"`
+	ProductSerial  string `json:"product_serial,omitempty"`
+	ProductUUID    string `json:"product_uuid,omitempty"`
+	BoardVendor    string `json:"board_vendor,omitempty"`
+	BoardName      string `json:"board_name,omitempty"`
+	BoardSerial    string `json:"board_serial,omitempty"`
+	ChassisType    string `json:"chassis_type,omitempty"`
+	ChassisSerial  string `json:"chassis_serial,omitempty"`
+	BIOSVendor     string `json:"bios_vendor,omitempty"`
+	BIOSVersion    string `json:"bios_version,omitempty"`
+	BIOSDate       string `json:"bios_date,omitempty"`
+}
+
+// chassisTypes names the SMBIOS chassis types (DSP0134 7.4.1) that show up
+// on servers, desktops and laptops; others are reported by number.
+var chassisTypes = map[string]string{
+	"1": "other", "2": "unknown", "3": "desktop", "4": "low profile desktop",
+	"6": "mini tower", "7": "tower", "8": "portable", "9": "laptop",
+	"10": "notebook", "13": "all in one", "14": "sub notebook", "17": "main server chassis",
+	"23": "rack mount chassis", "24": "sealed-case pc", "28": "blade", "30": "tablet",
+	"31": "convertible", "32": "detachable", "35": "mini pc", "36": "stick pc",
+}
+
+// CollectDMI reads /sys/class/dmi/id. It returns nil without an error on
+// machines without DMI, such as most ARM boards.
+func CollectDMI(root string) (*DMI, error) {
+	dir := rootPath(root, "sys/class/dmi/id")
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	d := &DMI{}
+	for _, f := range []struct {
+		name string
+		dst  *string
+	}{
+		{"sys_vendor", &d.SysVendor}, {"product_name", &d.ProductName},
+		{"product_version", &d.ProductVersion}, {"product_serial", &d.ProductSerial},
+		{"product_uuid", &d.ProductUUID}, {"board_vendor", &d.BoardVendor},
+		{"board_name", &d.BoardName}, {"board_serial", &d.BoardSerial},
+		{"chassis_type", &d.ChassisType}, {"chassis_serial", &d.ChassisSerial},
+		{"bios_vendor", &d.BIOSVendor}, {"bios_version", &d.BIOSVersion},
+		{"bios_date", &d.BIOSDate},
+	} {
+		// Missing and root-only attributes are left empty.
+		v, _ := readTrim(filepath.Join(dir, f.name))
+		*f.dst = v
+	}
+	if name, ok := chassisTypes[d.ChassisType]; ok {
+		d.ChassisType = name
+	}
+	return d, nil
+}
+
+// DIMM is a populated memory device (SMBIOS type 17).
+type DIMM struct {
+	Locator      string `json:"locator"`
+	Bank         string `json:"bank,omitempty"`
+	SizeBytes    uint64 `json:"size_bytes"`
+	Type         string `json:"type,omitempty"`
+	SpeedMTs     int    `json:"speed_mts,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	PartNumber   string `json:"part_number,omitempty"`
+	Serial       string `json:"serial,omitempty"`
+}
+
+// smbiosMemoryTypes maps the memory type byte of a type 17 structure.
+var smbiosMemoryTypes = map[byte]string{
+	0x12: "DDR", 0x13: "DDR2", 0x18: "DDR3", 0x1a: "DDR4", 0x22: "DDR5",
+	0x1b: "LPDDR", 0x1c: "LPDDR2", 0x1d: "LPDDR3", 0x1e: "LPDDR4", 0x23: "LPDDR5",
+	0x1f: "Logical non-volatile device", 0x20: "HBM", 0x21: "HBM2",
+}
+
+// CollectDIMMs decodes the SMBIOS memory device entries that the kernel
+// exposes under /sys/firmware/dmi/entries/17-*. It returns the number of
+// slots and the populated ones; the entries are readable by root only.
+func CollectDIMMs(root string) (slots int, dimms []DIMM, err error) {
+	paths, _ := filepath.Glob(rootPath(root, "sys/firmware/dmi/entries/17-*/raw"))
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return 0, nil, err
+		}
+		d, populated, err := parseSMBIOSMemoryDevice(raw)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%s: %w", path, err)
+		}
+		slots++
+		if populated {
+			dimms = append(dimms, d)
+		}
+	}
+	return slots, dimms, nil
+}
+
+// parseSMBIOSMemoryDevice decodes one type 17 structure: the formatted
+// area, whose length is byte 1, followed by its NUL-terminated strings.
+func parseSMBIOSMemoryDevice(raw []byte) (DIMM, bool, error) {
+	if len(raw) < 0x15 || raw[0] != 17 || int(raw[1]) > len(raw) || raw[1] < 0x15 {
+		return DIMM{}, false, errors.New("malformed SMBIOS memory device")
+	}
+	formatted := raw[:raw[1]]
+	strs := strings.Split(string(raw[raw[1]:]), "\x00")
+	str := func(off int) string {
+		if off >= len(formatted) || formatted[off] == 0 || int(formatted[off]) > len(strs) {
+			return ""
+		}
+		return strings.TrimSpace(strs[formatted[off]-1])
+	}
+	word := func(off int) uint16 {
+		if off+2 > len(formatted) {
+			return 0
+		}
+		return binary.LittleEndian.Uint16(formatted[off:])
+	}
+
+	d := DIMM{Locator: str(0x10), Bank: str(0x11), Type: smbiosMemoryTypes[formatted[0x12]]}
+	switch size := word(0x0c); {
+	case size == 0 || size == 0xffff:
+		return d, false, nil
+	case size == 0x7fff && len(formatted) >= 0x20:
+		// Extended size, in MiB.
+		d.SizeBytes = uint64(binary.LittleEndian.Uint32(formatted[0x1c:])&0x7fffffff) << 20
+	case size&0x8000 != 0:
+		d.SizeBytes = uint64(size&0x7fff) << 10
+	default:
+		d.SizeBytes = uint64(size) << 20
+	}
+	// The configured speed is what the memory runs at; fall back to the
+	// rated one on older SMBIOS versions.
+	d.SpeedMTs = int(word(0x20))
+	if d.SpeedMTs == 0 || d.SpeedMTs == 0xffff {
+		d.SpeedMTs = int(word(0x15))
+	}
+	if d.SpeedMTs == 0xffff {
+		d.SpeedMTs = 0
+	}
+	d.Manufacturer, d.Serial, d.PartNumber = str(0x17), str(0x18), str(0x1a)
+	for _, unknown := range []string{"Unknown", "Not Specified", "NO DIMM"} {
+		for _, s := range []*string{&d.Manufacturer, &d.Serial, &d.PartNumber} {
+			if strings.EqualFold(*s, unknown) {
+				*s = ""
+			}
+		}
+	}
+	return d, true, nil
+}
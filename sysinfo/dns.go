@@ -0,0 +1,143 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const resolvedStubAddress = "127.0.0.53"
+
+type DNSConfig struct {
+	NSSHosts            string   `json:"nss_hosts"`
+	ResolvConfTarget    string   `json:"resolv_conf_target,omitempty"`
+	SystemdResolvedStub bool     `json:"systemd_resolved_stub"`
+	StubListener        bool     `json:"stub_listener"`
+	Nameservers         []string `json:"nameservers"`
+	Search              []string `json:"search,omitempty"`
+	Ndots               int      `json:"ndots"`
+	Options             []string `json:"options,omitempty"`
+}
+
+// CollectDNS describes how name resolution is configured, from
+// /etc/nsswitch.conf and /etc/resolv.conf only. No queries are sent.
+func CollectDNS(root string) (*DNSConfig, error) {
+	cfg := &DNSConfig{Ndots: 1}
+
+	if data, err := os.ReadFile(rootPath(root, "etc/nsswitch.conf")); err == nil {
+		cfg.NSSHosts = parseNSSHosts(string(data))
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	resolvConf := rootPath(root, "etc/resolv.conf")
+	if target, err := os.Readlink(resolvConf); err == nil {
+		if !path.IsAbs(target) {
+			target = path.Join("/etc", target)
+		}
+		cfg.ResolvConfTarget = target
+		cfg.SystemdResolvedStub = path.Base(target) == "stub-resolv.conf" &&
+			strings.HasPrefix(target, "/run/systemd/resolve/")
+	}
+
+	data, err := os.ReadFile(resolvConf)
+	if err != nil {
+		return cfg, err
+	}
+	parseResolvConf(string(data), cfg)
+	return cfg, nil
+}
+
+func parseNSSHosts(data string) string {
+	for _, line := range strings.Split(data, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		key, value, found := strings.Cut(line, ":")
+		if found && strings.TrimSpace(key) == "hosts" {
+			return strings.Join(strings.Fields(value), " ")
+		}
+	}
+	return ""
+}
+
+func parseResolvConf(data string, cfg *DNSConfig) {
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			cfg.Nameservers = append(cfg.Nameservers, fields[1])
+			if fields[1] == resolvedStubAddress {
+				cfg.StubListener = true
+			}
+		case "search", "domain":
+			// The last search/domain line wins, as in the libc resolver.
+			cfg.Search = fields[1:]
+		case "options":
+			for _, opt := range fields[1:] {
+				cfg.Options = append(cfg.Options, opt)
+				if value, found := strings.CutPrefix(opt, "ndots:"); found {
+					if n, err := strconv.Atoi(value); err == nil {
+						cfg.Ndots = n
+					}
+				}
+			}
+		}
+	}
+}
+
+func dnsFindings(network *Network) []Finding {
+	if network == nil || network.DNS == nil {
+		return nil
+	}
+	cfg := network.DNS
+	var findings []Finding
+
+	if len(cfg.Nameservers) == 0 {
+		findings = append(findings, Finding{
+			Code:     "dns_no_nameservers",
+			Severity: "warning",
+			Message:  "/etc/resolv.conf lists no nameservers",
+		})
+	}
+	if cfg.NSSHosts != "" && !nssHasSource(cfg.NSSHosts, "dns") && !nssHasSource(cfg.NSSHosts, "resolve") {
+		findings = append(findings, Finding{
+			Code:     "nss_hosts_without_dns",
+			Severity: "warning",
+			Message: fmt.Sprintf("nsswitch hosts line %q has no dns or resolve source: "+
+				"applications will not use DNS even though dig does", cfg.NSSHosts),
+		})
+	}
+	if cfg.StubListener && !cfg.SystemdResolvedStub {
+		findings = append(findings, Finding{
+			Code:     "dns_stale_resolved_stub",
+			Severity: "warning",
+			Message: resolvedStubAddress + " is configured but /etc/resolv.conf is not the systemd-resolved " +
+				"stub symlink; lookups fail wherever resolved is not running (e.g. copied into a container)",
+		})
+	}
+	if cfg.Ndots >= 5 && len(cfg.Search) >= 3 {
+		findings = append(findings, Finding{
+			Code:     "dns_ndots_search_amplification",
+			Severity: "info",
+			Message: fmt.Sprintf("ndots:%d with %d search domains: external names are tried against every "+
+				"search domain first, multiplying lookups and latency", cfg.Ndots, len(cfg.Search)),
+		})
+	}
+	return findings
+}
+
+func nssHasSource(hosts, source string) bool {
+	for _, field := range strings.Fields(hosts) {
+		if field == source {
+			return true
+		}
+	}
+	return false
+}
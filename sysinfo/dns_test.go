@@ -0,0 +1,146 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseResolvConf(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want DNSConfig
+	}{
+		{"empty", "", DNSConfig{Ndots: 1}},
+		{
+			"resolved stub",
+			"# This is /run/systemd/resolve/stub-resolv.conf managed by man:systemd-resolved(8).\n" +
+				"nameserver 127.0.0.53\noptions edns0 trust-ad\nsearch corp.example.\n",
+			DNSConfig{Ndots: 1, StubListener: true, Nameservers: []string{"127.0.0.53"}, Search: []string{"corp.example."}, Options: []string{"edns0", "trust-ad"}},
+		},
+		{
+			"kubernetes pod",
+			"search default.svc.cluster.local svc.cluster.local cluster.local\n" +
+				"nameserver 10.96.0.10\n" +
+				"options ndots:5\n",
+			DNSConfig{Ndots: 5, Nameservers: []string{"10.96.0.10"}, Search: []string{"default.svc.cluster.local", "svc.cluster.local", "cluster.local"}, Options: []string{"ndots:5"}},
+		},
+		{
+			"comments, repeats and junk",
+			"nameserver 192.0.2.1 # primary\n" +
+				"; nameserver 192.0.2.9\n" +
+				"nameserver\t2001:db8::1\n" +
+				"nameserver\n" +
+				"domain example.com\n" +
+				"search a.example b.example\n" +
+				"options ndots:x rotate\n" +
+				"options timeout:1 ndots:2\n" +
+				"sortlist 130.155.160.0/255.255.240.0\n",
+			DNSConfig{
+				Ndots:       2,
+				Nameservers: []string{"192.0.2.1", "2001:db8::1"},
+				// The last of domain and search wins.
+				Search:  []string{"a.example", "b.example"},
+				Options: []string{"ndots:x", "rotate", "timeout:1", "ndots:2"},
+			},
+		},
+		{"domain after search", "search a.example b.example\ndomain example.com\n", DNSConfig{Ndots: 1, Search: []string{"example.com"}}},
+	}
+	for _, tt := range tests {
+		cfg := DNSConfig{Ndots: 1}
+		parseResolvConf(tt.data, &cfg)
+		if !reflect.DeepEqual(cfg, tt.want) {
+			t.Errorf("%s: parseResolvConf = %+v, want %+v", tt.name, cfg, tt.want)
+		}
+	}
+}
+
+func TestParseNSSHosts(t *testing.T) {
+	tests := map[string]string{
+		"": "",
+		"passwd: files\nhosts:          files dns\n":                                  "files dns",
+		"hosts: files mymachines resolve [!UNAVAIL=return] myhostname dns # Ubuntu\n": "files mymachines resolve [!UNAVAIL=return] myhostname dns",
+		"#hosts: dns\nhosts: files\n":                                                 "files",
+		"  hosts : files\tdns\n":                                                      "files dns",
+	}
+	for data, want := range tests {
+		if got := parseNSSHosts(data); got != want {
+			t.Errorf("parseNSSHosts(%q) = %q, want %q", data, got, want)
+		}
+	}
+}
+
+func TestCollectDNSStubSymlink(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+		stub   bool
+	}{
+		{"../run/systemd/resolve/stub-resolv.conf", "/run/systemd/resolve/stub-resolv.conf", true},
+		{"/run/systemd/resolve/stub-resolv.conf", "/run/systemd/resolve/stub-resolv.conf", true},
+		// resolv.conf without the stub lists the real upstream servers.
+		{"../run/systemd/resolve/resolv.conf", "/run/systemd/resolve/resolv.conf", false},
+		{"../run/resolvconf/stub-resolv.conf", "/run/resolvconf/stub-resolv.conf", false},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		writeTree(t, root, map[string]string{
+			"etc/nsswitch.conf":                    "hosts: files resolve dns\n",
+			"run/systemd/resolve/stub-resolv.conf": "nameserver 127.0.0.53\n",
+			"run/systemd/resolve/resolv.conf":      "nameserver 127.0.0.53\n",
+			"run/resolvconf/stub-resolv.conf":      "nameserver 127.0.0.53\n",
+		})
+		// A relative target is reported as resolved against /etc.
+		if err := os.Symlink(tt.target, filepath.Join(root, "etc/resolv.conf")); err != nil {
+			t.Fatal(err)
+		}
+		cfg, _ := CollectDNS(root)
+		if cfg == nil {
+			t.Fatalf("%s: no config", tt.target)
+		}
+		if cfg.SystemdResolvedStub != tt.stub || cfg.NSSHosts != "files resolve dns" {
+			t.Errorf("%s: stub %v, nss %q; want %v", tt.target, cfg.SystemdResolvedStub, cfg.NSSHosts, tt.stub)
+		}
+		if cfg.ResolvConfTarget != tt.want {
+			t.Errorf("%s: target %q, want %q", tt.target, cfg.ResolvConfTarget, tt.want)
+		}
+	}
+}
+
+func TestDNSFindings(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *DNSConfig
+		want []string
+	}{
+		{"no network", nil, []string{}},
+		{"healthy", &DNSConfig{NSSHosts: "files dns", Nameservers: []string{"192.0.2.1"}, Ndots: 1}, []string{}},
+		{"no nameservers", &DNSConfig{NSSHosts: "files dns", Ndots: 1}, []string{"dns_no_nameservers"}},
+		{"files only", &DNSConfig{NSSHosts: "files myhostname", Nameservers: []string{"192.0.2.1"}}, []string{"nss_hosts_without_dns"}},
+		// Without nsswitch.conf glibc falls back to dns.
+		{"no nsswitch", &DNSConfig{Nameservers: []string{"192.0.2.1"}}, []string{}},
+		{"resolve only", &DNSConfig{NSSHosts: "files resolve", Nameservers: []string{"127.0.0.53"}, StubListener: true, SystemdResolvedStub: true}, []string{}},
+		{"copied stub", &DNSConfig{NSSHosts: "files dns", Nameservers: []string{"127.0.0.53"}, StubListener: true}, []string{"dns_stale_resolved_stub"}},
+		{
+			"ndots amplification",
+			&DNSConfig{NSSHosts: "files dns", Nameservers: []string{"10.96.0.10"}, Ndots: 5, Search: []string{"a", "b", "c"}},
+			[]string{"dns_ndots_search_amplification"},
+		},
+		{"two search domains", &DNSConfig{NSSHosts: "files dns", Nameservers: []string{"10.96.0.10"}, Ndots: 5, Search: []string{"a", "b"}}, []string{}},
+		{"ndots 4", &DNSConfig{NSSHosts: "files dns", Nameservers: []string{"10.96.0.10"}, Ndots: 4, Search: []string{"a", "b", "c"}}, []string{}},
+		{"all at once", &DNSConfig{NSSHosts: "files", StubListener: true, Ndots: 5, Search: []string{"a", "b", "c"}}, []string{
+			"dns_no_nameservers", "nss_hosts_without_dns", "dns_stale_resolved_stub", "dns_ndots_search_amplification",
+		}},
+	}
+	for _, tt := range tests {
+		var network *Network
+		if tt.cfg != nil {
+			network = &Network{DNS: tt.cfg}
+		}
+		if got := findingCodes(dnsFindings(network)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: findings = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package sysinfo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+type EnvChange struct {
+	Key  string `json:"key"`
+	Kind string `json:"kind"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// ReadEnviron returns the initial environment of pid (0 for self) from
+// /proc/<pid>/environ. It reflects the environment the process was started
+// with, so later changes made by a config reload are not visible.
+func ReadEnviron(root string, pid int) (map[string]string, error) {
+	data, err := os.ReadFile(procDir(root, pid, "environ"))
+	if err != nil {
+		if pid != 0 && errors.Is(err, fs.ErrPermission) {
+			return nil, fmt.Errorf("process %d: cannot read environ: %w (run as the process owner or root)", pid, fs.ErrPermission)
+		}
+		return nil, processError(pid, err)
+	}
+	return ParseEnv(strings.Split(string(data), "\x00")), nil
+}
+
+// ParseEnv turns KEY=value entries into a map. Entries without "=" and
+// empty ones are ignored.
+func ParseEnv(entries []string) map[string]string {
+	env := make(map[string]string)
+	for _, entry := range entries {
+		key, value, found := strings.Cut(entry, "=")
+		if found && key != "" {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// ReadEnvFile reads expected KEY=value pairs, one per line. Blank lines
+// and lines starting with '#' are skipped.
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ParseEnv(entries), nil
+}
+
+// DiffEnv compares the environment a process actually has against the one
+// expected. Keys only in actual are "added", keys only in expected are
+// "removed". The result is sorted by key. Values are passed through
+// RedactValue unless showValues is set.
+func DiffEnv(actual, expected map[string]string, showValues bool) []EnvChange {
+	keys := make(map[string]bool)
+	for k := range actual {
+		keys[k] = true
+	}
+	for k := range expected {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	value := func(k, v string) string {
+		if showValues {
+			return v
+		}
+		return RedactValue(k, v)
+	}
+	var changes []EnvChange
+	for _, k := range sorted {
+		got, inActual := actual[k]
+		want, inExpected := expected[k]
+		switch {
+		case inActual && !inExpected:
+			changes = append(changes, EnvChange{Key: k, Kind: "added", New: value(k, got)})
+		case !inActual && inExpected:
+			changes = append(changes, EnvChange{Key: k, Kind: "removed", Old: value(k, want)})
+		case got != want:
+			changes = append(changes, EnvChange{Key: k, Kind: "changed", Old: value(k, want), New: value(k, got)})
+		}
+	}
+	return changes
+}
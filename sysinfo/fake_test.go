@@ -0,0 +1,72 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+// writeTree creates files under root, keyed by their path relative to it.
+func writeTree(t testing.TB, root string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// liveTree fills root with symlinks to the live filesystem, except along
+// path, which is left as empty directories for writeTree to fill.
+func liveTree(t testing.TB, root, path string) {
+	t.Helper()
+	dir := "/"
+	for _, name := range strings.Split(path, "/") {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			if e.Name() == name {
+				continue
+			}
+			if err := os.Symlink(filepath.Join(dir, e.Name()), filepath.Join(root, dir, e.Name())); err != nil {
+				t.Fatal(err)
+			}
+		}
+		dir = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// sampleClock is a manual clock that calls onSleep before each Sleep
+// advances it, so a test can change the fake tree between two samples.
+type sampleClock struct {
+	*clock.Manual
+	sleeps  int
+	onSleep func(n int)
+}
+
+func newSampleClock(onSleep func(n int)) *sampleClock {
+	return &sampleClock{Manual: clock.NewManual(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)), onSleep: onSleep}
+}
+
+func (c *sampleClock) Sleep(d time.Duration) {
+	c.sleeps++
+	if c.onSleep != nil {
+		c.onSleep(c.sleeps)
+	}
+	c.Manual.Sleep(d)
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,144 @@
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FDInfo is one open file descriptor. Type is file, socket, pipe, eventfd,
+// anon_inode or other; Target is the path, the resolved socket endpoints
+// or the raw link, e.g. "anon_inode:[eventpoll]".
+type FDInfo struct {
+	Num    int    `json:"num"`
+	Type   string `json:"type"`
+	Target string `json:"target"`
+}
+
+// ListFDs reads the link of every entry in /proc/<pid>/fd (self for 0),
+// sorted by number. Descriptors closed during the scan are skipped. With
+// resolveSockets, socket targets are looked up by inode in the process's
+// /proc/net tables and shown as "tcp 10.0.0.2:51234 -> 1.2.3.4:443
+// ESTABLISHED"; sockets not found there keep their "socket:[inode]" link.
+func ListFDs(root string, pid int, resolveSockets bool) ([]FDInfo, error) {
+	dir := procDir(root, pid, "fd")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, processError(pid, err)
+	}
+	var sockets map[string]string
+	if resolveSockets {
+		sockets = readSocketTable(root, pid)
+	}
+	var fds []FDInfo
+	for _, e := range entries {
+		num, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		target, err := os.Readlink(dir + "/" + e.Name())
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, processError(pid, err)
+		}
+		fd := FDInfo{Num: num, Type: fdType(target), Target: target}
+		if fd.Type == "socket" {
+			inode := strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")
+			if s, ok := sockets[inode]; ok {
+				fd.Target = s
+			}
+		}
+		fds = append(fds, fd)
+	}
+	slices.SortFunc(fds, func(a, b FDInfo) int { return a.Num - b.Num })
+	return fds, nil
+}
+
+func fdType(target string) string {
+	switch {
+	case strings.HasPrefix(target, "socket:"):
+		return "socket"
+	case strings.HasPrefix(target, "pipe:"):
+		return "pipe"
+	case target == "anon_inode:[eventfd]":
+		return "eventfd"
+	case strings.HasPrefix(target, "anon_inode:"):
+		return "anon_inode"
+	case strings.HasPrefix(target, "/"):
+		return "file"
+	}
+	return "other"
+}
+
+// FDTypes counts fds by type.
+func FDTypes(fds []FDInfo) map[string]int {
+	counts := make(map[string]int)
+	for _, fd := range fds {
+		counts[fd.Type]++
+	}
+	return counts
+}
+
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED", "02": "SYN_SENT", "03": "SYN_RECV", "04": "FIN_WAIT1",
+	"05": "FIN_WAIT2", "06": "TIME_WAIT", "07": "CLOSE", "08": "CLOSE_WAIT",
+	"09": "LAST_ACK", "0A": "LISTEN", "0B": "CLOSING", "0C": "NEW_SYN_RECV",
+}
+
+// readSocketTable maps socket inodes to a description, from the tcp, udp
+// and unix tables of pid's network namespace. Missing or unreadable
+// tables only leave their sockets unresolved.
+func readSocketTable(root string, pid int) map[string]string {
+	sockets := make(map[string]string)
+	for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		data, err := os.ReadFile(procDir(root, pid, "net", proto))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 10 {
+				continue
+			}
+			local, lErr := formatProcNetAddr(fields[1])
+			remote, rErr := formatProcNetAddr(fields[2])
+			if lErr != nil || rErr != nil {
+				continue
+			}
+			desc := fmt.Sprintf("%s %s -> %s", proto, local, remote)
+			if state, ok := tcpStates[fields[3]]; ok && strings.HasPrefix(proto, "tcp") {
+				desc += " " + state
+			}
+			sockets[fields[9]] = desc
+		}
+	}
+	if data, err := os.ReadFile(procDir(root, pid, "net", "unix")); err == nil {
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 7 {
+				continue
+			}
+			desc := "unix (unnamed)"
+			if len(fields) > 7 {
+				desc = "unix " + fields[7]
+			}
+			sockets[fields[6]] = desc
+		}
+	}
+	return sockets
+}
+
+func formatProcNetAddr(s string) (string, error) {
+	ip, port, err := parseProcNetAddr(s)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(port)), nil
+}
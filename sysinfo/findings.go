@@ -0,0 +1,164 @@
+package sysinfo
+
+import "fmt"
+
+type Finding struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Analyze derives findings from an already collected report.
+func Analyze(info *SysInfo) []Finding {
+	var findings []Finding
+	findings = append(findings, irqFindings(info.IRQ)...)
+	findings = append(findings, dnsFindings(info.Network)...)
+	findings = append(findings, networkFindings(info)...)
+	findings = append(findings, isolationFindings(info.Isolation)...)
+	findings = append(findings, hwmonFindings(info.Hwmon)...)
+	findings = append(findings, memoryLimitFindings(info)...)
+	findings = append(findings, memoryPeakFindings(info)...)
+	findings = append(findings, cgroupCPUFindings(info)...)
+	findings = append(findings, throttleFindings(info.Throttle)...)
+	findings = append(findings, diskFindings(info.Mounts)...)
+	findings = append(findings, diskHealthFindings(info.DiskHealth)...)
+	findings = append(findings, rlimitFindings(info)...)
+	findings = append(findings, swapFindings(info.Swap)...)
+	findings = append(findings, sysctlFindings(info)...)
+	findings = append(findings, readIssueFindings(info.ReadIssues)...)
+	return findings
+}
+
+func (info *SysInfo) collected(fields ...string) bool {
+	for _, f := range fields {
+		if _, failed := info.Errors[f]; failed {
+			return false
+		}
+	}
+	return true
+}
+
+// memoryLimitFindings cross-checks the cgroup memory limit against the
+// host's MemTotal and the inspected process's RSS: memory.max on cgroup v2,
+// where memory.high is checked too, memory.limit_in_bytes on v1.
+func memoryLimitFindings(info *SysInfo) []Finding {
+	if !info.collected("memory") || info.MemTotal == 0 {
+		return nil
+	}
+	var limit, high, current *uint64
+	name := "cgroup memory limit"
+	switch {
+	case info.CgroupV2 != nil && info.collected("cgroup_v2"):
+		cg := info.CgroupV2
+		limit, high, current = cg.MemoryMaxBytes, cg.MemoryHighBytes, cg.MemoryCurrentBytes
+		name = "cgroup memory.max"
+	case info.CgroupV1 != nil && info.collected("memory_limit"):
+		limit = info.CgroupV1.MemoryLimitBytes
+	default:
+		return nil
+	}
+	memTotal := uint64(info.MemTotal) * 1024
+	var findings []Finding
+
+	if high != nil && current != nil && *current >= *high && (limit == nil || *high < *limit) {
+		findings = append(findings, Finding{
+			Code:     "memory_high_exceeded",
+			Severity: "warning",
+			Message: fmt.Sprintf("cgroup memory.current (%d B) is at or above memory.high (%d B): "+
+				"the kernel throttles the cgroup and reclaims its memory", *current, *high),
+		})
+	}
+	if high != nil && limit != nil && *high >= *limit {
+		findings = append(findings, Finding{
+			Code:     "memory_high_not_below_max",
+			Severity: "info",
+			Message: fmt.Sprintf("cgroup memory.high (%d B) is not below memory.max (%d B): "+
+				"the cgroup is OOM-killed without being throttled first", *high, *limit),
+		})
+	}
+
+	if limit == nil {
+		if high == nil && info.SwapTotal == 0 {
+			findings = append(findings, Finding{
+				Code:     "memory_limit_unset_no_swap",
+				Severity: "info",
+				Message: "no cgroup memory limit and swap is disabled: a runaway process can " +
+					"exhaust host memory and trigger the global OOM killer",
+			})
+		}
+		return findings
+	}
+
+	if *limit > memTotal {
+		findings = append(findings, Finding{
+			Code:     "memory_limit_exceeds_memtotal",
+			Severity: "warning",
+			Message: fmt.Sprintf("%s (%d B) exceeds MemTotal (%d B) and has no effect",
+				name, *limit, memTotal),
+		})
+	}
+	if info.collected("vmrss_bytes") && info.VmRSS > 0 {
+		rss := uint64(info.VmRSS) * 1024
+		if rss >= *limit {
+			findings = append(findings, Finding{
+				Code:     "memory_limit_below_usage",
+				Severity: "critical",
+				Message: fmt.Sprintf("%s (%d B) is at or below the process RSS (%d B): "+
+					"OOM kill is imminent", name, *limit, rss),
+			})
+		}
+	}
+	return findings
+}
+
+// memoryPeakWarnPercent is the share of the cgroup memory limit a past peak
+// must have reached to be reported.
+const memoryPeakWarnPercent = 95
+
+// MemoryPeak returns the memory high-water mark to hold against the cgroup
+// memory limit, and that limit. The cgroup's own peak is preferred;
+// kernels before 5.19 have no memory.peak on v2, and then the process's
+// VmHWM stands in for it and fromVmHWM is set. peak is nil when neither is
+// known, limit when the cgroup has none.
+func (info *SysInfo) MemoryPeak() (peak, limit *uint64, fromVmHWM bool) {
+	if cg := info.CgroupV2; cg != nil && cg.MemoryMaxBytes != nil {
+		limit, peak = cg.MemoryMaxBytes, cg.MemoryPeakBytes
+	} else if cg := info.CgroupV1; cg != nil && cg.MemoryLimitBytes != nil && info.collected("memory_limit") {
+		limit = cg.MemoryLimitBytes
+		if info.collected("memory_peak") {
+			peak = cg.MemoryPeakBytes
+		}
+	}
+	if limit == nil || *limit == 0 {
+		return nil, nil, false
+	}
+	if peak == nil && info.Process != nil && info.Process.PeakRSSBytes != nil {
+		return info.Process.PeakRSSBytes, limit, true
+	}
+	return peak, limit, false
+}
+
+// memoryPeakFindings reports a cgroup that has already come close to its
+// memory limit, which explains past OOM kills while current usage looks
+// fine. See MemoryPeak for which peak is used.
+func memoryPeakFindings(info *SysInfo) []Finding {
+	peak, limit, fromVmHWM := info.MemoryPeak()
+	if peak == nil {
+		return nil
+	}
+	source := "cgroup peak"
+	if fromVmHWM {
+		source = "process VmHWM; this kernel has no cgroup memory.peak"
+	}
+	pct := float64(*peak) / float64(*limit) * 100
+	if pct < memoryPeakWarnPercent {
+		return nil
+	}
+	return []Finding{{
+		Code:     "memory_peak_near_limit",
+		Severity: "warning",
+		Message: fmt.Sprintf("memory peak of %d B (%s) reached %.0f%% of the cgroup limit (%d B): "+
+			"this workload has already been within %d%% of being OOM-killed", *peak, source, pct, *limit,
+			100-memoryPeakWarnPercent),
+	}}
+}
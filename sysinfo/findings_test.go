@@ -0,0 +1,161 @@
+package sysinfo
+
+import (
+	"slices"
+	"testing"
+)
+
+func findingCodes(findings []Finding) []string {
+	codes := []string{}
+	for _, f := range findings {
+		codes = append(codes, f.Code)
+	}
+	return codes
+}
+
+func TestMemoryLimitFindings(t *testing.T) {
+	const gib = 1 << 30
+	// 16 GiB of host memory, a process using 1 GiB, swap enabled.
+	host := func(edit func(info *SysInfo)) *SysInfo {
+		info := &SysInfo{MemTotal: 16 * gib / 1024, VmRSS: gib / 1024, SwapTotal: 1}
+		edit(info)
+		return info
+	}
+	tests := []struct {
+		name string
+		info *SysInfo
+		want []string
+	}{
+		{
+			name: "no cgroup",
+			info: host(func(info *SysInfo) {}),
+			want: []string{},
+		},
+		{
+			name: "v1 within limits",
+			info: host(func(info *SysInfo) { info.CgroupV1 = &CgroupV1{MemoryLimitBytes: ptr[uint64](4 * gib)} }),
+			want: []string{},
+		},
+		{
+			name: "v1 limit above MemTotal",
+			info: host(func(info *SysInfo) { info.CgroupV1 = &CgroupV1{MemoryLimitBytes: ptr[uint64](32 * gib)} }),
+			want: []string{"memory_limit_exceeds_memtotal"},
+		},
+		{
+			name: "v1 limit at RSS",
+			info: host(func(info *SysInfo) { info.CgroupV1 = &CgroupV1{MemoryLimitBytes: ptr[uint64](gib)} }),
+			want: []string{"memory_limit_below_usage"},
+		},
+		{
+			name: "v1 unset without swap",
+			info: host(func(info *SysInfo) { info.CgroupV1, info.SwapTotal = &CgroupV1{}, 0 }),
+			want: []string{"memory_limit_unset_no_swap"},
+		},
+		{
+			name: "v1 limit unreadable",
+			info: host(func(info *SysInfo) {
+				info.CgroupV1 = &CgroupV1{MemoryLimitBytes: ptr[uint64](gib)}
+				info.Errors = map[string]string{"memory_limit": "permission denied"}
+			}),
+			want: []string{},
+		},
+		{
+			name: "v2 within limits",
+			info: host(func(info *SysInfo) {
+				info.CgroupV2 = &CgroupV2{MemoryMaxBytes: ptr[uint64](4 * gib), MemoryHighBytes: ptr[uint64](3 * gib), MemoryCurrentBytes: ptr[uint64](2 * gib)}
+			}),
+			want: []string{},
+		},
+		{
+			name: "v2 memory.max above MemTotal",
+			info: host(func(info *SysInfo) { info.CgroupV2 = &CgroupV2{MemoryMaxBytes: ptr[uint64](32 * gib)} }),
+			want: []string{"memory_limit_exceeds_memtotal"},
+		},
+		{
+			name: "v2 memory.max below RSS",
+			info: host(func(info *SysInfo) { info.CgroupV2 = &CgroupV2{MemoryMaxBytes: ptr[uint64](gib / 2)} }),
+			want: []string{"memory_limit_below_usage"},
+		},
+		{
+			name: "v2 root cgroup without swap",
+			info: host(func(info *SysInfo) { info.CgroupV2, info.SwapTotal = &CgroupV2{}, 0 }),
+			want: []string{"memory_limit_unset_no_swap"},
+		},
+		{
+			name: "v2 memory.high alone bounds usage",
+			info: host(func(info *SysInfo) {
+				info.CgroupV2, info.SwapTotal = &CgroupV2{MemoryHighBytes: ptr[uint64](8 * gib)}, 0
+			}),
+			want: []string{},
+		},
+		{
+			name: "v2 usage at memory.high",
+			info: host(func(info *SysInfo) {
+				info.CgroupV2 = &CgroupV2{MemoryMaxBytes: ptr[uint64](4 * gib), MemoryHighBytes: ptr[uint64](3 * gib), MemoryCurrentBytes: ptr[uint64](3 * gib)}
+			}),
+			want: []string{"memory_high_exceeded"},
+		},
+		{
+			name: "v2 usage above memory.high without memory.max",
+			info: host(func(info *SysInfo) {
+				info.CgroupV2 = &CgroupV2{MemoryHighBytes: ptr[uint64](2 * gib), MemoryCurrentBytes: ptr[uint64](3 * gib)}
+			}),
+			want: []string{"memory_high_exceeded"},
+		},
+		{
+			name: "v2 memory.high equal to memory.max",
+			info: host(func(info *SysInfo) {
+				info.CgroupV2 = &CgroupV2{MemoryMaxBytes: ptr[uint64](4 * gib), MemoryHighBytes: ptr[uint64](4 * gib), MemoryCurrentBytes: ptr[uint64](4 * gib)}
+			}),
+			want: []string{"memory_high_not_below_max"},
+		},
+		{
+			name: "v2 preferred over an empty v1",
+			info: host(func(info *SysInfo) {
+				info.CgroupV1, info.SwapTotal = &CgroupV1{}, 0
+				info.CgroupV2 = &CgroupV2{MemoryMaxBytes: ptr[uint64](4 * gib)}
+			}),
+			want: []string{},
+		},
+		{
+			name: "v2 unreadable",
+			info: host(func(info *SysInfo) {
+				info.CgroupV2 = &CgroupV2{MemoryMaxBytes: ptr[uint64](gib / 2)}
+				info.Errors = map[string]string{"cgroup_v2": "memory.current: permission denied"}
+			}),
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findingCodes(memoryLimitFindings(tt.info)); !slices.Equal(got, tt.want) {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectCgroupV2MemoryHigh(t *testing.T) {
+	for _, tt := range []struct {
+		high string
+		want *uint64
+	}{
+		{"max\n", nil},
+		{"3221225472\n", ptr[uint64](3 << 30)},
+	} {
+		root := t.TempDir()
+		writeTree(t, root, map[string]string{
+			"sys/fs/cgroup/cgroup.controllers": "memory cpu\n",
+			"sys/fs/cgroup/memory.max":         "4294967296\n",
+			"sys/fs/cgroup/memory.high":        tt.high,
+			"sys/fs/cgroup/cpu.stat":           "usage_usec 100\n",
+		})
+		cg, err := CollectCgroupV2(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (cg.MemoryHighBytes == nil) != (tt.want == nil) || tt.want != nil && *cg.MemoryHighBytes != *tt.want {
+			t.Errorf("memory.high %q: MemoryHighBytes = %v, want %v", tt.high, cg.MemoryHighBytes, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package sysinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Handler returns c.Handler for a new Collector. If opts is invalid every
+// request is answered with 500 and the reason.
+func Handler(opts Options) http.Handler {
+	c, err := New(opts)
+	if err != nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			httpError(w, http.StatusInternalServerError, err)
+		})
+	}
+	return c.Handler()
+}
+
+// Handler serves GET /json (the report), /metrics (Prometheus text
+// format) and /healthz; anything else is 404. The whole path is matched,
+// so to mount the handler under a prefix strip it with http.StripPrefix.
+// A request whose context ends before the report is ready gets 503; set a
+// deadline on it to bound collection time. /json?stream writes the report
+// with EncodeJSON instead, for hosts whose report is too large to buffer.
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// StripPrefix("/debug/sysinfo/") leaves "json", without the slash.
+		route := strings.TrimPrefix(r.URL.Path, "/")
+		switch route {
+		case "json", "metrics", "healthz":
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			httpError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		if route == "healthz" {
+			fmt.Fprintln(w, "ok")
+			return
+		}
+		if route == "json" && r.URL.Query().Has("stream") {
+			c.streamJSON(w, r)
+			return
+		}
+		info, err := c.Collect(r.Context())
+		if info == nil {
+			// Only a timeout, a dropped client or Close gets here: field
+			// errors still produce a report.
+			httpError(w, http.StatusServiceUnavailable, fmt.Errorf("collection failed: %w", err))
+			return
+		}
+		var buf bytes.Buffer
+		if route == "json" {
+			out, err := MarshalReport(info, c.opts.Fields, "  ")
+			if err != nil {
+				httpError(w, http.StatusInternalServerError, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(append(out, '\n'))
+			return
+		}
+		if err := WritePrometheus(&buf, info, c.opts.Fields); err != nil {
+			httpError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+}
+
+// streamJSON runs a collection of its own for the request, not shared with
+// concurrent ones, and sends each section as it is encoded. The status is
+// sent before collection starts, so a request whose context ends midway
+// gets a truncated body, which no JSON parser accepts, instead of 503.
+func (c *Collector) streamJSON(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		httpError(w, http.StatusServiceUnavailable, fmt.Errorf("collection failed: %w", ErrClosed))
+		return
+	}
+	c.wg.Add(1)
+	c.mu.Unlock()
+	defer c.wg.Done()
+
+	opts := c.opts
+	opts.Indent = "  "
+	w.Header().Set("Content-Type", "application/json")
+	// Field errors are in the report; anything else has cut it short.
+	EncodeJSON(r.Context(), flushWriter{w, http.NewResponseController(w)}, opts)
+}
+
+// flushWriter sends every write to the client at once, where the
+// ResponseWriter can flush. EncodeJSON writes once per section.
+type flushWriter struct {
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if err := f.rc.Flush(); !errors.Is(err, http.ErrNotSupported) {
+		return n, err
+	}
+	return n, nil
+}
+
+// MarshalReport encodes info as JSON, limited to fields (and timestamp and
+// errors) when fields is set. An empty indent gives a single line.
+func MarshalReport(info *SysInfo, fields []string, indent string) ([]byte, error) {
+	var v any = info
+	if len(fields) > 0 {
+		selected, err := info.Select(append([]string{"timestamp"}, fields...))
+		if err != nil {
+			return nil, err
+		}
+		v = selected
+	}
+	if indent == "" {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", indent)
+}
+
+// httpError replies with {"error": "..."} so clients can parse failures the
+// same way as reports.
+func httpError(w http.ResponseWriter, status int, err error) {
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
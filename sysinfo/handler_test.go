@@ -0,0 +1,81 @@
+package sysinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerRoutes(t *testing.T) {
+	h := Handler(Options{Root: streamTree(t), Fields: []string{"memory", "loadavg"}})
+	tests := []struct {
+		method, path string
+		status       int
+		contentType  string
+		body         string
+	}{
+		{method: "GET", path: "/json", status: 200, contentType: "application/json", body: `"total_bytes"`},
+		{method: "GET", path: "/json?stream", status: 200, contentType: "application/json", body: `"total_bytes"`},
+		{method: "HEAD", path: "/json", status: 200, contentType: "application/json"},
+		{method: "GET", path: "/metrics", status: 200, contentType: "text/plain; version=0.0.4; charset=utf-8", body: "sysinfo_load1 "},
+		{method: "GET", path: "/healthz", status: 200, body: "ok\n"},
+		{method: "POST", path: "/json", status: 405, contentType: "application/json", body: `"error"`},
+		{method: "DELETE", path: "/healthz", status: 405, contentType: "application/json"},
+		{method: "GET", path: "/", status: 404},
+		{method: "GET", path: "/jsonx", status: 404},
+		// Only the path the handler was given counts: a prefix must be
+		// stripped, not ignored.
+		{method: "GET", path: "/debug/sysinfo/json", status: 404},
+		{method: "GET", path: "/json/extra", status: 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if got := rec.Header().Get("Content-Type"); tt.contentType != "" && got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if tt.status == 405 && rec.Header().Get("Allow") != "GET, HEAD" {
+				t.Errorf("Allow = %q, want GET, HEAD", rec.Header().Get("Allow"))
+			}
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Errorf("body = %q, want it to contain %q", rec.Body, tt.body)
+			}
+			if tt.contentType == "application/json" && tt.method != "HEAD" && !json.Valid(rec.Body.Bytes()) {
+				t.Errorf("body is not JSON: %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestHandlerUnderPrefix(t *testing.T) {
+	h := Handler(Options{Root: streamTree(t), Fields: []string{"loadavg"}})
+	for _, prefix := range []string{"/debug/sysinfo", "/debug/sysinfo/"} {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/sysinfo/", http.StripPrefix(prefix, h))
+		for path, status := range map[string]int{
+			"/debug/sysinfo/healthz":    200,
+			"/debug/sysinfo/json":       200,
+			"/debug/sysinfo/other/json": 404,
+		} {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+			if rec.Code != status {
+				t.Errorf("StripPrefix(%q): GET %s = %d, want %d", prefix, path, rec.Code, status)
+			}
+		}
+	}
+}
+
+func TestHandlerInvalidOptions(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler(Options{Fields: []string{"no_such_field"}}).ServeHTTP(rec, httptest.NewRequest("GET", "/json", nil))
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"error"`) {
+		t.Errorf("got %d %s, want 500 with the reason", rec.Code, rec.Body)
+	}
+}
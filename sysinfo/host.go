@@ -0,0 +1,158 @@
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Host identifies the machine and OS a report came from.
+type Host struct {
+	Hostname      string     `json:"hostname"`
+	KernelRelease string     `json:"kernel_release"`
+	KernelVersion string     `json:"kernel_version"`
+	Arch          string     `json:"arch"`
+	BootTime      string     `json:"boot_time,omitempty"`
+	OS            *OSRelease `json:"os,omitempty"`
+}
+
+// OSRelease is the identifying part of os-release(5).
+type OSRelease struct {
+	PrettyName string `json:"pretty_name,omitempty"`
+	ID         string `json:"id,omitempty"`
+	VersionID  string `json:"version_id,omitempty"`
+}
+
+// Name returns ID and VERSION_ID, as in "debian 12", or "" for a nil o.
+func (o *OSRelease) Name() string {
+	if o == nil {
+		return ""
+	}
+	return strings.TrimSpace(o.ID + " " + o.VersionID)
+}
+
+// CollectHost reads uname(2) and the hostname on the live system; with a
+// root set they come from proc/sys/kernel instead, with the architecture
+// left empty. BootTime is the btime line of /proc/stat. OS is nil when
+// neither /etc/os-release nor /usr/lib/os-release exists, as in scratch
+// containers.
+func CollectHost(ctx context.Context, root string) (*Host, error) {
+	h := &Host{}
+	var errs []error
+	if root == "" {
+		u, err := uname()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("uname: %w", err))
+		}
+		h.Hostname, h.KernelRelease, h.KernelVersion, h.Arch = u.nodename, u.release, u.version, u.machine
+		if name, err := os.Hostname(); err == nil {
+			h.Hostname = name
+		}
+	} else {
+		for _, f := range []struct {
+			name string
+			dst  *string
+		}{
+			{"hostname", &h.Hostname},
+			{"osrelease", &h.KernelRelease},
+			{"version", &h.KernelVersion},
+		} {
+			v, err := readTrim(rootPath(root, "proc/sys/kernel", f.name))
+			if err != nil {
+				errs = append(errs, err)
+			}
+			*f.dst = v
+		}
+	}
+
+	if btime, err := readBootTime(ctx, root); err != nil {
+		errs = append(errs, err)
+	} else {
+		h.BootTime = btime.UTC().Format(time.RFC3339)
+	}
+
+	osr, err := ReadOSRelease(root)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	h.OS = osr
+	return h, errors.Join(errs...)
+}
+
+type utsname struct {
+	nodename, release, version, machine string
+}
+
+func readBootTime(ctx context.Context, root string) (time.Time, error) {
+	data, err := readVerified(ctx, rootPath(root, "proc/stat"), hasLines("cpu ", "btime "))
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(data, "\n") {
+		if v, ok := strings.CutPrefix(line, "btime "); ok {
+			secs, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("malformed btime %q", v)
+			}
+			return time.Unix(secs, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no btime in /proc/stat")
+}
+
+// ReadOSRelease parses /etc/os-release, falling back to
+// /usr/lib/os-release as os-release(5) prescribes. It returns nil without
+// an error when neither exists.
+func ReadOSRelease(root string) (*OSRelease, error) {
+	data, err := os.ReadFile(rootPath(root, "etc/os-release"))
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = os.ReadFile(rootPath(root, "usr/lib/os-release"))
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	vars := parseOSRelease(string(data))
+	return &OSRelease{
+		PrettyName: vars["PRETTY_NAME"],
+		ID:         vars["ID"],
+		VersionID:  vars["VERSION_ID"],
+	}, nil
+}
+
+// parseOSRelease reads shell-style KEY=value assignments: values may be
+// single- or double-quoted, and inside double quotes a backslash escapes
+// the next character. Blank lines and # comments are skipped.
+func parseOSRelease(data string) map[string]string {
+	vars := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		key, value, found := strings.Cut(line, "=")
+		if !found || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			var b strings.Builder
+			inner := value[1 : len(value)-1]
+			for i := 0; i < len(inner); i++ {
+				if inner[i] == '\\' && i+1 < len(inner) {
+					i++
+				}
+				b.WriteByte(inner[i])
+			}
+			value = b.String()
+		}
+		vars[key] = value
+	}
+	return vars
+}
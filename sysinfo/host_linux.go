@@ -0,0 +1,17 @@
+package sysinfo
+
+import "golang.org/x/sys/unix"
+
+func uname() (utsname, error) {
+	var u unix.Utsname
+	if err := unix.Uname(&u); err != nil {
+		return utsname{}, err
+	}
+	// The fields are NUL-padded byte arrays.
+	return utsname{
+		nodename: unix.ByteSliceToString(u.Nodename[:]),
+		release:  unix.ByteSliceToString(u.Release[:]),
+		version:  unix.ByteSliceToString(u.Version[:]),
+		machine:  unix.ByteSliceToString(u.Machine[:]),
+	}, nil
+}
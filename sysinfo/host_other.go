@@ -0,0 +1,7 @@
+//go:build !linux
+
+package sysinfo
+
+func uname() (utsname, error) {
+	return utsname{}, unsupported()
+}
@@ -0,0 +1,106 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectHostFlatFields(t *testing.T) {
+	files := map[string]string{
+		"proc/sys/kernel/hostname":  "db1\n",
+		"proc/sys/kernel/osrelease": "6.1.0-18-amd64\n",
+		"proc/sys/kernel/version":   "#1 SMP PREEMPT_DYNAMIC Debian 6.1.76-1\n",
+		"proc/stat":                 "cpu  1 2 3 4\nbtime 1700000000\n",
+	}
+	tests := []struct {
+		name      string
+		osRelease string
+		want      string
+	}{
+		{"os-release", "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nID=debian\nVERSION_ID=\"12\"\n", "debian 12"},
+		{"rolling release", "ID=arch\n", "arch"},
+		// Scratch containers have none: the field is blank, not an error.
+		{"none", "", ""},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		writeTree(t, root, files)
+		if tt.osRelease != "" {
+			writeTree(t, root, map[string]string{"etc/os-release": tt.osRelease})
+		}
+		info, err := CollectWith(Options{Root: root, Fields: []string{"host", "hostname", "kernel", "arch", "os_release"}})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if info.Errors["host"] != "" {
+			t.Errorf("%s: host error %q", tt.name, info.Errors["host"])
+		}
+		if info.Hostname != "db1" || info.Kernel != "6.1.0-18-amd64" || info.Arch != info.Host.Arch {
+			t.Errorf("%s: hostname %q, kernel %q, arch %q; want db1, 6.1.0-18-amd64 and %q", tt.name, info.Hostname, info.Kernel, info.Arch, info.Host.Arch)
+		}
+		if info.OSRelease != tt.want {
+			t.Errorf("%s: OSRelease = %q, want %q", tt.name, info.OSRelease, tt.want)
+		}
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	data := `# This is os-release(5) as Debian ships it, plus the hard cases.
+PRETTY_NAME="Debian GNU/Linux 12 (bookworm)"
+NAME='Debian GNU/Linux'
+
+ID=debian
+VERSION_ID="12"
+VERSION="12 (\"bookworm\") \\ \$HOME"
+  # An indented comment=with an equals sign
+HOME_URL="https://www.debian.org/"
+EMPTY=
+ODD="unterminated
+`
+	want := map[string]string{
+		"PRETTY_NAME": "Debian GNU/Linux 12 (bookworm)",
+		"NAME":        "Debian GNU/Linux",
+		"ID":          "debian",
+		"VERSION_ID":  "12",
+		"VERSION":     `12 ("bookworm") \ $HOME`,
+		"HOME_URL":    "https://www.debian.org/",
+		"EMPTY":       "",
+		"ODD":         `"unterminated`,
+	}
+	if got := parseOSRelease(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseOSRelease:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestReadOSRelease(t *testing.T) {
+	etc := "ID=alpine\nVERSION_ID=3.19.1\nPRETTY_NAME=\"Alpine Linux v3.19\"\n"
+	usrLib := "ID=fedora\nVERSION_ID=40\n"
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  *OSRelease
+	}{
+		{"etc", map[string]string{"etc/os-release": etc, "usr/lib/os-release": usrLib}, &OSRelease{PrettyName: "Alpine Linux v3.19", ID: "alpine", VersionID: "3.19.1"}},
+		{"usr/lib fallback", map[string]string{"usr/lib/os-release": usrLib}, &OSRelease{ID: "fedora", VersionID: "40"}},
+		{"neither", map[string]string{"etc/hostname": "db1\n"}, nil},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		writeTree(t, root, tt.files)
+		got, err := ReadOSRelease(root)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ReadOSRelease = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	// Anything but a missing file is an error, and does not fall back.
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"etc/os-release/x": "", "usr/lib/os-release": usrLib})
+	if got, err := ReadOSRelease(root); err == nil {
+		t.Errorf("unreadable etc/os-release: %+v without an error", got)
+	}
+}
@@ -0,0 +1,174 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type HwmonSensor struct {
+	Chip   string  `json:"chip"`
+	Sensor string  `json:"sensor"`
+	Label  string  `json:"label,omitempty"`
+	Type   string  `json:"type"`
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit"`
+	Alarm  bool    `json:"alarm,omitempty"`
+}
+
+// hwmonTypes maps the sysfs attribute prefix to the reported type, unit and
+// the divisor that converts the raw value (hwmon sysfs ABI) into it.
+var hwmonTypes = map[string]struct {
+	kind, unit string
+	divisor    float64
+}{
+	"fan":   {"fan", "RPM", 1},
+	"in":    {"voltage", "V", 1000},
+	"power": {"power", "W", 1000000},
+	"curr":  {"current", "A", 1000},
+}
+
+var hwmonInput = regexp.MustCompile(`^(fan|in|power|curr)(\d+)_input$`)
+
+var hwmonTempInput = regexp.MustCompile(`^temp\d+_input$`)
+
+// TempSensor is one hwmon temperature channel. Label falls back to the
+// channel name (temp1) when the driver has no temp*_label; MaxC and CritC
+// are the driver's thresholds, when it has them.
+type TempSensor struct {
+	Chip  string   `json:"chip"`
+	Label string   `json:"label"`
+	TempC float64  `json:"temp_c"`
+	MaxC  *float64 `json:"max_c,omitempty"`
+	CritC *float64 `json:"crit_c,omitempty"`
+}
+
+// CollectHwmon reads fan speeds, voltages, power and current from
+// /sys/class/hwmon. Hosts without hwmon (VMs, containers) yield nil, and
+// sensors whose input cannot be read (drivers return EIO for absent
+// channels) are skipped.
+func CollectHwmon(root string) ([]HwmonSensor, error) {
+	dirs, err := filepath.Glob(rootPath(root, "sys/class/hwmon/hwmon*"))
+	if err != nil {
+		return nil, err
+	}
+	var sensors []HwmonSensor
+	for _, dir := range dirs {
+		chip, err := readTrim(filepath.Join(dir, "name"))
+		if err != nil {
+			chip = filepath.Base(dir)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			m := hwmonInput.FindStringSubmatch(entry.Name())
+			if m == nil {
+				continue
+			}
+			t := hwmonTypes[m[1]]
+			sensor := m[1] + m[2]
+			raw, err := readTrim(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+			s := HwmonSensor{Chip: chip, Sensor: sensor, Type: t.kind, Value: v / t.divisor, Unit: t.unit}
+			s.Label, _ = readTrim(filepath.Join(dir, sensor+"_label"))
+			if alarm, err := readTrim(filepath.Join(dir, sensor+"_alarm")); err == nil {
+				s.Alarm = alarm == "1"
+			}
+			sensors = append(sensors, s)
+		}
+	}
+	sort.SliceStable(sensors, func(i, j int) bool {
+		if sensors[i].Chip != sensors[j].Chip {
+			return sensors[i].Chip < sensors[j].Chip
+		}
+		return sensors[i].Sensor < sensors[j].Sensor
+	})
+	return sensors, nil
+}
+
+// CollectTemperatures reads the temp*_input channels of /sys/class/hwmon
+// in degrees Celsius (sysfs has millidegrees). Like CollectHwmon it yields
+// nil without hwmon and skips channels that fail to read.
+func CollectTemperatures(root string) ([]TempSensor, error) {
+	dirs, err := filepath.Glob(rootPath(root, "sys/class/hwmon/hwmon*"))
+	if err != nil {
+		return nil, err
+	}
+	millidegrees := func(path string) (float64, bool) {
+		raw, err := readTrim(path)
+		if err != nil {
+			return 0, false
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		return v / 1000, err == nil
+	}
+	var sensors []TempSensor
+	for _, dir := range dirs {
+		chip, err := readTrim(filepath.Join(dir, "name"))
+		if err != nil {
+			chip = filepath.Base(dir)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !hwmonTempInput.MatchString(entry.Name()) {
+				continue
+			}
+			channel := strings.TrimSuffix(entry.Name(), "_input")
+			t, ok := millidegrees(filepath.Join(dir, entry.Name()))
+			if !ok {
+				continue
+			}
+			s := TempSensor{Chip: chip, Label: channel, TempC: t}
+			if label, err := readTrim(filepath.Join(dir, channel+"_label")); err == nil && label != "" {
+				s.Label = label
+			}
+			if v, ok := millidegrees(filepath.Join(dir, channel+"_max")); ok {
+				s.MaxC = &v
+			}
+			if v, ok := millidegrees(filepath.Join(dir, channel+"_crit")); ok {
+				s.CritC = &v
+			}
+			sensors = append(sensors, s)
+		}
+	}
+	sort.SliceStable(sensors, func(i, j int) bool {
+		if sensors[i].Chip != sensors[j].Chip {
+			return sensors[i].Chip < sensors[j].Chip
+		}
+		return sensors[i].Label < sensors[j].Label
+	})
+	return sensors, nil
+}
+
+func hwmonFindings(sensors []HwmonSensor) []Finding {
+	var findings []Finding
+	for _, s := range sensors {
+		if s.Type == "fan" && s.Value == 0 && s.Alarm {
+			name := s.Sensor
+			if s.Label != "" {
+				name += " (" + s.Label + ")"
+			}
+			findings = append(findings, Finding{
+				Code:     "hwmon_fan_failed",
+				Severity: "critical",
+				Message:  fmt.Sprintf("%s: %s reports 0 RPM with its alarm set: the fan has likely failed", s.Chip, name),
+			})
+		}
+	}
+	return findings
+}
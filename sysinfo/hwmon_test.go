@@ -0,0 +1,128 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+// desktopHwmon is a desktop board: a Super I/O chip (nct6775) with fans and
+// voltages, next to the CPU temperature driver.
+var desktopHwmon = map[string]string{
+	"sys/class/hwmon/hwmon0/name":        "coretemp\n",
+	"sys/class/hwmon/hwmon0/temp1_input": "45000\n",
+	"sys/class/hwmon/hwmon0/temp1_label": "Package id 0\n",
+	"sys/class/hwmon/hwmon0/temp1_max":   "80000\n",
+	"sys/class/hwmon/hwmon0/temp1_crit":  "100000\n",
+	"sys/class/hwmon/hwmon0/temp2_input": "41000\n",
+
+	"sys/class/hwmon/hwmon2/name":        "nct6775\n",
+	"sys/class/hwmon/hwmon2/fan1_input":  "820\n",
+	"sys/class/hwmon/hwmon2/fan1_alarm":  "0\n",
+	"sys/class/hwmon/hwmon2/fan2_input":  "0\n",
+	"sys/class/hwmon/hwmon2/fan2_alarm":  "1\n",
+	"sys/class/hwmon/hwmon2/fan3_input":  "0\n",
+	"sys/class/hwmon/hwmon2/fan3_alarm":  "0\n",
+	"sys/class/hwmon/hwmon2/fan3_min":    "0\n",
+	"sys/class/hwmon/hwmon2/in0_input":   "1040\n",
+	"sys/class/hwmon/hwmon2/in0_label":   "Vcore\n",
+	"sys/class/hwmon/hwmon2/in1_input":   "3344\n",
+	"sys/class/hwmon/hwmon2/in1_label":   "+3.3V\n",
+	"sys/class/hwmon/hwmon2/in1_min":     "2968\n",
+	"sys/class/hwmon/hwmon2/temp7_input": "38000\n",
+	"sys/class/hwmon/hwmon2/temp7_label": "SYSTIN\n",
+	// An absent channel: the driver has the file but no reading.
+	"sys/class/hwmon/hwmon2/in9_input": "N/A\n",
+}
+
+// serverHwmon is a server whose BMC exposes its sensors through the PSU's
+// PMBus interface: volts, amps and watts in micro/milli units.
+var serverHwmon = map[string]string{
+	"sys/class/hwmon/hwmon1/name":         "pmbus\n",
+	"sys/class/hwmon/hwmon1/in1_input":    "229500\n",
+	"sys/class/hwmon/hwmon1/in1_label":    "vin\n",
+	"sys/class/hwmon/hwmon1/in2_input":    "12125\n",
+	"sys/class/hwmon/hwmon1/in2_label":    "vout1\n",
+	"sys/class/hwmon/hwmon1/curr1_input":  "1375\n",
+	"sys/class/hwmon/hwmon1/curr1_label":  "iin\n",
+	"sys/class/hwmon/hwmon1/power1_input": "315250000\n",
+	"sys/class/hwmon/hwmon1/power1_label": "pin\n",
+	"sys/class/hwmon/hwmon1/fan1_input":   "0\n",
+	"sys/class/hwmon/hwmon1/fan1_alarm":   "1\n",
+	"sys/class/hwmon/hwmon1/fan1_label":   "psu_fan\n",
+	// No name file: the chip is the directory.
+	"sys/class/hwmon/hwmon3/fan1_input": "6600\n",
+}
+
+func TestCollectHwmon(t *testing.T) {
+	tests := []struct {
+		name     string
+		tree     map[string]string
+		want     []HwmonSensor
+		findings []string
+	}{
+		{
+			name: "desktop",
+			tree: desktopHwmon,
+			want: []HwmonSensor{
+				{Chip: "nct6775", Sensor: "fan1", Type: "fan", Value: 820, Unit: "RPM"},
+				{Chip: "nct6775", Sensor: "fan2", Type: "fan", Value: 0, Unit: "RPM", Alarm: true},
+				{Chip: "nct6775", Sensor: "fan3", Type: "fan", Value: 0, Unit: "RPM"},
+				{Chip: "nct6775", Sensor: "in0", Label: "Vcore", Type: "voltage", Value: 1.04, Unit: "V"},
+				{Chip: "nct6775", Sensor: "in1", Label: "+3.3V", Type: "voltage", Value: 3.344, Unit: "V"},
+			},
+			// A stopped fan without its alarm is just unplugged.
+			findings: []string{"hwmon_fan_failed"},
+		},
+		{
+			name: "server",
+			tree: serverHwmon,
+			want: []HwmonSensor{
+				{Chip: "hwmon3", Sensor: "fan1", Type: "fan", Value: 6600, Unit: "RPM"},
+				{Chip: "pmbus", Sensor: "curr1", Label: "iin", Type: "current", Value: 1.375, Unit: "A"},
+				{Chip: "pmbus", Sensor: "fan1", Label: "psu_fan", Type: "fan", Value: 0, Unit: "RPM", Alarm: true},
+				{Chip: "pmbus", Sensor: "in1", Label: "vin", Type: "voltage", Value: 229.5, Unit: "V"},
+				{Chip: "pmbus", Sensor: "in2", Label: "vout1", Type: "voltage", Value: 12.125, Unit: "V"},
+				{Chip: "pmbus", Sensor: "power1", Label: "pin", Type: "power", Value: 315.25, Unit: "W"},
+			},
+			findings: []string{"hwmon_fan_failed"},
+		},
+		{
+			name:     "no hwmon",
+			tree:     map[string]string{"proc/uptime": "1 1\n"},
+			findings: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, tt.tree)
+			sensors, err := CollectHwmon(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(sensors, tt.want) {
+				t.Errorf("sensors:\n%+v\nwant:\n%+v", sensors, tt.want)
+			}
+			if got := findingCodes(hwmonFindings(sensors)); !reflect.DeepEqual(got, tt.findings) {
+				t.Errorf("findings = %q, want %q", got, tt.findings)
+			}
+		})
+	}
+}
+
+func TestCollectTemperatures(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, desktopHwmon)
+	sensors, err := CollectTemperatures(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TempSensor{
+		{Chip: "coretemp", Label: "Package id 0", TempC: 45, MaxC: ptr(80.0), CritC: ptr(100.0)},
+		{Chip: "coretemp", Label: "temp2", TempC: 41},
+		{Chip: "nct6775", Label: "SYSTIN", TempC: 38},
+	}
+	if !reflect.DeepEqual(sensors, want) {
+		t.Errorf("temperatures:\n%+v\nwant:\n%+v", sensors, want)
+	}
+}
@@ -0,0 +1,266 @@
+package sysinfo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// InventoryKind is the kind of an Inventory document. Monitoring reports
+// (SysInfo) have no kind key.
+const InventoryKind = "inventory"
+
+// Inventory is the static identity of a machine for asset management:
+// what it is, not how busy it is. It holds nothing that changes without a
+// hardware swap, reinstall or reboot.
+type Inventory struct {
+	SchemaVersion int                  `json:"schema_version"`
+	Kind          string               `json:"kind"`
+	Host          *Host                `json:"host,omitempty"`
+	MachineID     string               `json:"machine_id,omitempty"`
+	Hardware      *DMI                 `json:"hardware,omitempty"`
+	CPU           *CPUInfo             `json:"cpu,omitempty"`
+	Memory        *MemoryInventory     `json:"memory,omitempty"`
+	BlockDevices  []BlockDevice        `json:"block_devices,omitempty"`
+	Interfaces    []InventoryInterface `json:"interfaces,omitempty"`
+	Errors        map[string]string    `json:"errors,omitempty"`
+}
+
+// MemoryInventory is the memory the kernel manages plus, when SMBIOS is
+// readable, the installed DIMMs and the number of slots.
+type MemoryInventory struct {
+	TotalBytes     uint64 `json:"total_bytes"`
+	InstalledBytes uint64 `json:"installed_bytes,omitempty"`
+	Slots          int    `json:"slots,omitempty"`
+	DIMMs          []DIMM `json:"dimms,omitempty"`
+}
+
+// BlockDevice is a disk backed by a device (loop, dm, md and zram are
+// left out), identified as udev would.
+type BlockDevice struct {
+	Name       string `json:"name"`
+	SizeBytes  uint64 `json:"size_bytes"`
+	Vendor     string `json:"vendor,omitempty"`
+	Model      string `json:"model,omitempty"`
+	Serial     string `json:"serial,omitempty"`
+	WWN        string `json:"wwn,omitempty"`
+	Rotational bool   `json:"rotational"`
+	Removable  bool   `json:"removable"`
+}
+
+// InventoryInterface is a network interface backed by a device; bridges,
+// veths and other software interfaces are configuration, not inventory.
+type InventoryInterface struct {
+	Name   string `json:"name"`
+	MAC    string `json:"mac,omitempty"`
+	Driver string `json:"driver,omitempty"`
+}
+
+// CollectInventory assembles the inventory from the static collectors.
+// Like CollectWith it fills what it can; failed sections are recorded in
+// Errors and joined into the returned error as *FieldError.
+func CollectInventory(ctx context.Context, root string) (*Inventory, error) {
+	inv := &Inventory{SchemaVersion: SchemaVersion, Kind: InventoryKind}
+	var errs []error
+	record := func(field string, err error) {
+		if err == nil {
+			return
+		}
+		if inv.Errors == nil {
+			inv.Errors = make(map[string]string)
+		}
+		inv.Errors[field] = err.Error()
+		errs = append(errs, &FieldError{Field: field, Err: err})
+	}
+
+	host, err := CollectHost(ctx, root)
+	if host != nil {
+		host.BootTime = ""
+	}
+	inv.Host = host
+	record("host", err)
+	if id, err := readTrim(rootPath(root, "etc/machine-id")); err == nil {
+		inv.MachineID = id
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		record("machine_id", err)
+	}
+	inv.Hardware, err = CollectDMI(root)
+	record("hardware", err)
+	if cpu, err := CollectCPU(root); err == nil {
+		// The current frequency is a metric; the flags are kept to the
+		// notable ones.
+		cpu.MHz, cpu.Flags = 0, cpu.NotableFlags()
+		inv.CPU = cpu
+	} else {
+		record("cpu", err)
+	}
+	inv.Memory, err = collectMemoryInventory(ctx, root)
+	record("memory", err)
+	inv.BlockDevices, err = collectBlockDevices(root)
+	record("block_devices", err)
+	inv.Interfaces, err = collectInventoryInterfaces(root)
+	record("interfaces", err)
+	return inv, errors.Join(errs...)
+}
+
+func collectMemoryInventory(ctx context.Context, root string) (*MemoryInventory, error) {
+	meminfo, err := readMeminfo(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	m := &MemoryInventory{TotalBytes: uint64(max(meminfo["MemTotal"], 0)) * 1024}
+	if m.Slots, m.DIMMs, err = CollectDIMMs(root); errors.Is(err, fs.ErrPermission) {
+		// SMBIOS entries are root-only; the total is still worth having.
+		return m, nil
+	} else if err != nil {
+		return m, err
+	}
+	for _, d := range m.DIMMs {
+		m.InstalledBytes += d.SizeBytes
+	}
+	return m, nil
+}
+
+func collectBlockDevices(root string) ([]BlockDevice, error) {
+	entries, err := os.ReadDir(rootPath(root, "sys/block"))
+	if err != nil {
+		return nil, err
+	}
+	var devices []BlockDevice
+	for _, e := range entries {
+		dir := rootPath(root, "sys/block", e.Name())
+		devDir := filepath.Join(dir, "device")
+		if _, err := os.Stat(devDir); err != nil {
+			continue
+		}
+		d := BlockDevice{Name: e.Name()}
+		if sectors, err := readTrim(filepath.Join(dir, "size")); err == nil {
+			n, _ := strconv.ParseUint(sectors, 10, 64)
+			d.SizeBytes = n * 512
+		}
+		d.Vendor, _ = readTrim(filepath.Join(devDir, "vendor"))
+		d.Model, _ = readTrim(filepath.Join(devDir, "model"))
+		d.Rotational = readFlag(filepath.Join(dir, "queue/rotational"))
+		d.Removable = readFlag(filepath.Join(dir, "removable"))
+
+		// NVMe and MMC have the serial in sysfs; for SCSI and SATA it is
+		// in udev's database, or in VPD page 0x80 (a 4-byte header).
+		udev := readUdevProperties(root, dir)
+		d.Serial, _ = readTrim(filepath.Join(devDir, "serial"))
+		if d.Serial == "" {
+			d.Serial = udev["ID_SERIAL_SHORT"]
+		}
+		if d.Serial == "" {
+			if vpd, err := os.ReadFile(filepath.Join(devDir, "vpd_pg80")); err == nil && len(vpd) > 4 {
+				d.Serial = strings.TrimSpace(strings.Trim(string(vpd[4:]), "\x00"))
+			}
+		}
+		d.WWN, _ = readTrim(filepath.Join(devDir, "wwid"))
+		if d.WWN == "" {
+			d.WWN = udev["ID_WWN"]
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
+
+// readUdevProperties returns the E: lines of the udev database entry of
+// the block device in dir, whose dev file holds "major:minor".
+func readUdevProperties(root, dir string) map[string]string {
+	props := make(map[string]string)
+	dev, err := readTrim(filepath.Join(dir, "dev"))
+	if err != nil {
+		return props
+	}
+	data, err := os.ReadFile(rootPath(root, "run/udev/data", "b"+dev))
+	if err != nil {
+		return props
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if kv, ok := strings.CutPrefix(line, "E:"); ok {
+			k, v, _ := strings.Cut(kv, "=")
+			props[k] = v
+		}
+	}
+	return props
+}
+
+func readFlag(path string) bool {
+	v, err := readTrim(path)
+	return err == nil && v == "1"
+}
+
+func collectInventoryInterfaces(root string) ([]InventoryInterface, error) {
+	entries, err := os.ReadDir(rootPath(root, "sys/class/net"))
+	if err != nil {
+		return nil, err
+	}
+	var ifaces []InventoryInterface
+	for _, e := range entries {
+		dir := rootPath(root, "sys/class/net", e.Name())
+		if _, err := os.Stat(filepath.Join(dir, "device")); err != nil {
+			continue
+		}
+		ni := InventoryInterface{Name: e.Name()}
+		ni.MAC, _ = readTrim(filepath.Join(dir, "address"))
+		if driver, err := os.Readlink(filepath.Join(dir, "device/driver")); err == nil {
+			ni.Driver = filepath.Base(driver)
+		}
+		ifaces = append(ifaces, ni)
+	}
+	return ifaces, nil
+}
+
+// HideSerials clears the serial numbers, UUIDs and WWNs, which identify a
+// single unit rather than a model.
+func (inv *Inventory) HideSerials() {
+	for _, s := range inv.serials() {
+		*s = ""
+	}
+}
+
+// Anonymize replaces the host name, machine-id, MACs and any serials left
+// by HideSerials with a hash salted with the machine-id. Documents of one
+// machine still match each other, and their values cannot be recovered
+// without the machine-id.
+func (inv *Inventory) Anonymize() {
+	salt := inv.MachineID
+	hash := func(s *string) {
+		if *s != "" {
+			sum := sha256.Sum256([]byte(salt + "\x00" + *s))
+			*s = "anon-" + hex.EncodeToString(sum[:6])
+		}
+	}
+	ids := inv.serials()
+	if inv.Host != nil {
+		ids = append(ids, &inv.Host.Hostname)
+	}
+	for i := range inv.Interfaces {
+		ids = append(ids, &inv.Interfaces[i].MAC)
+	}
+	for _, s := range append(ids, &inv.MachineID) {
+		hash(s)
+	}
+}
+
+func (inv *Inventory) serials() []*string {
+	var s []*string
+	if h := inv.Hardware; h != nil {
+		s = append(s, &h.ProductSerial, &h.ProductUUID, &h.BoardSerial, &h.ChassisSerial)
+	}
+	if inv.Memory != nil {
+		for i := range inv.Memory.DIMMs {
+			s = append(s, &inv.Memory.DIMMs[i].Serial)
+		}
+	}
+	for i := range inv.BlockDevices {
+		s = append(s, &inv.BlockDevices[i].Serial, &inv.BlockDevices[i].WWN)
+	}
+	return s
+}
@@ -0,0 +1,196 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const topIRQCount = 10
+
+type SoftirqStat struct {
+	Name      string   `json:"name"`
+	Total     uint64   `json:"total"`
+	Imbalance float64  `json:"imbalance"`
+	PerCPU    []uint64 `json:"per_cpu,omitempty"`
+}
+
+type IRQStat struct {
+	IRQ          string   `json:"irq"`
+	Device       string   `json:"device"`
+	Total        uint64   `json:"total"`
+	AffinityList string   `json:"smp_affinity_list"`
+	PerCPU       []uint64 `json:"per_cpu,omitempty"`
+}
+
+type IRQReport struct {
+	CPUs     int           `json:"cpus"`
+	Softirqs []SoftirqStat `json:"softirqs"`
+	TopIRQs  []IRQStat     `json:"top_irqs,omitempty"`
+}
+
+// CollectIRQ reports softirq distribution across CPUs. With detail set it
+// keeps the per-CPU counts and adds the busiest hardware IRQs.
+func CollectIRQ(root string, detail bool) (*IRQReport, error) {
+	ncpu, softirqs, err := readSoftirqs(root)
+	if err != nil {
+		return nil, err
+	}
+	report := &IRQReport{CPUs: ncpu, Softirqs: softirqs}
+	if detail {
+		irqs, err := readTopIRQs(root, topIRQCount)
+		if err != nil {
+			return nil, err
+		}
+		report.TopIRQs = irqs
+	} else {
+		for i := range report.Softirqs {
+			report.Softirqs[i].PerCPU = nil
+		}
+	}
+	return report, nil
+}
+
+// parseCPUMatrix parses the /proc/softirqs and /proc/interrupts layout: a
+// header of CPU columns followed by "NAME: count count ... [description]"
+// rows. Rows may carry fewer counters than there are CPUs (ERR, MIS), and
+// anything after the counters is returned as the row description.
+func parseCPUMatrix(data string) (int, [][]string, error) {
+	lines := strings.Split(data, "\n")
+	if len(lines) == 0 {
+		return 0, nil, fmt.Errorf("empty CPU matrix")
+	}
+	ncpu := 0
+	for _, col := range strings.Fields(lines[0]) {
+		if strings.HasPrefix(col, "CPU") {
+			ncpu++
+		}
+	}
+	if ncpu == 0 {
+		return 0, nil, fmt.Errorf("no CPU columns in header")
+	}
+	var rows [][]string
+	for _, line := range lines[1:] {
+		name, rest, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		row := []string{strings.TrimSpace(name)}
+		row = append(row, strings.Fields(rest)...)
+		rows = append(rows, row)
+	}
+	return ncpu, rows, nil
+}
+
+func splitCounters(fields []string, ncpu int) ([]uint64, string) {
+	counts := make([]uint64, 0, ncpu)
+	i := 0
+	for ; i < len(fields) && i < ncpu; i++ {
+		n, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			break
+		}
+		counts = append(counts, n)
+	}
+	return counts, strings.Join(fields[i:], " ")
+}
+
+func imbalance(counts []uint64, ncpu int) (uint64, float64) {
+	var total, max uint64
+	for _, n := range counts {
+		total += n
+		if n > max {
+			max = n
+		}
+	}
+	if total == 0 || ncpu == 0 {
+		return total, 0
+	}
+	mean := float64(total) / float64(ncpu)
+	return total, float64(max) / mean
+}
+
+func readSoftirqs(root string) (int, []SoftirqStat, error) {
+	data, err := os.ReadFile(rootPath(root, "proc/softirqs"))
+	if err != nil {
+		return 0, nil, err
+	}
+	ncpu, rows, err := parseCPUMatrix(string(data))
+	if err != nil {
+		return 0, nil, fmt.Errorf("/proc/softirqs: %w", err)
+	}
+	var stats []SoftirqStat
+	for _, row := range rows {
+		counts, _ := splitCounters(row[1:], ncpu)
+		total, ratio := imbalance(counts, ncpu)
+		stats = append(stats, SoftirqStat{
+			Name:      row[0],
+			Total:     total,
+			Imbalance: ratio,
+			PerCPU:    counts,
+		})
+	}
+	return ncpu, stats, nil
+}
+
+func readTopIRQs(root string, limit int) ([]IRQStat, error) {
+	data, err := os.ReadFile(rootPath(root, "proc/interrupts"))
+	if err != nil {
+		return nil, err
+	}
+	ncpu, rows, err := parseCPUMatrix(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("/proc/interrupts: %w", err)
+	}
+	var irqs []IRQStat
+	for _, row := range rows {
+		if _, err := strconv.Atoi(row[0]); err != nil {
+			continue
+		}
+		counts, desc := splitCounters(row[1:], ncpu)
+		total, _ := imbalance(counts, ncpu)
+		irqs = append(irqs, IRQStat{
+			IRQ:    row[0],
+			Device: desc,
+			Total:  total,
+			PerCPU: counts,
+		})
+	}
+	sort.SliceStable(irqs, func(i, j int) bool { return irqs[i].Total > irqs[j].Total })
+	if len(irqs) > limit {
+		irqs = irqs[:limit]
+	}
+	for i := range irqs {
+		affinity, err := readTrim(rootPath(root, "proc/irq", irqs[i].IRQ, "smp_affinity_list"))
+		if err == nil {
+			irqs[i].AffinityList = affinity
+		}
+	}
+	return irqs, nil
+}
+
+func irqFindings(report *IRQReport) []Finding {
+	if report == nil || report.CPUs < 2 {
+		return nil
+	}
+	var findings []Finding
+	for _, s := range report.Softirqs {
+		switch s.Name {
+		case "NET_RX", "NET_TX", "BLOCK":
+		default:
+			continue
+		}
+		if s.Total < 10000 || s.Imbalance < 0.75*float64(report.CPUs) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Code:     "softirq_imbalance",
+			Severity: "warning",
+			Message: fmt.Sprintf("%s softirqs are concentrated on one CPU (max/mean %.1f over %d CPUs)",
+				s.Name, s.Imbalance, report.CPUs),
+		})
+	}
+	return findings
+}
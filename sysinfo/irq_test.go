@@ -0,0 +1,242 @@
+package sysinfo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// interruptsFixture is a 4-CPU /proc/interrupts: numbered IRQs with their
+// chip, hwirq and device after the counters, the named per-CPU rows and
+// the single-column ERR and MIS rows.
+const interruptsFixture = `           CPU0       CPU1       CPU2       CPU3
+  0:         36          0          0          0   IO-APIC   2-edge      timer
+  1:          0          0          9          0   IO-APIC   1-edge      i8042
+  8:          0          1          0          0   IO-APIC   8-edge      rtc0
+ 24:     120000      10000      10000      10000   PCI-MSI 524288-edge      nvme0q0
+ 25:       5000       5000       5000       5000   PCI-MSI 1572864-edge      eth0-rx-0
+ 26:          0          0          0          0   PCI-MSI 1572865-edge      eth0-tx-0
+NMI:          0          0          0          0   Non-maskable interrupts
+LOC:    1234567    1234000    1233000    1232000   Local timer interrupts
+ERR:          0
+MIS:          0
+`
+
+// softirqsFixture is a 4-CPU /proc/softirqs with NET_RX steered onto CPU0.
+const softirqsFixture = `                    CPU0       CPU1       CPU2       CPU3
+          HI:          1          0          0          0
+       TIMER:     100000     100000     100000     100000
+      NET_TX:         10          2          3          5
+      NET_RX:     900000       1000       1000       1000
+       BLOCK:      25000      25000      25000      25000
+    IRQ_POLL:          0          0          0          0
+`
+
+func TestParseCPUMatrix(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		ncpu    int
+		rows    [][]string
+		wantErr bool
+	}{
+		{
+			name: "one CPU",
+			data: "           CPU0       \n  0:         36   IO-APIC   2-edge      timer\nERR:          0\n",
+			ncpu: 1,
+			rows: [][]string{{"0", "36", "IO-APIC", "2-edge", "timer"}, {"ERR", "0"}},
+		},
+		{
+			name: "offline CPUs leave gaps in the numbering",
+			data: "       CPU0  CPU2  CPU5\n HI:  1  2  3\n",
+			ncpu: 3,
+			rows: [][]string{{"HI", "1", "2", "3"}},
+		},
+		{
+			name: "rows without a colon are skipped",
+			data: "       CPU0  CPU1\n\nNET_RX:  4  5\ngarbage\n",
+			ncpu: 2,
+			rows: [][]string{{"NET_RX", "4", "5"}},
+		},
+		{name: "empty", data: "", wantErr: true},
+		{name: "no CPU columns", data: "HI: 1 2\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		ncpu, rows, err := parseCPUMatrix(tt.data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if ncpu != tt.ncpu || !reflect.DeepEqual(rows, tt.rows) {
+			t.Errorf("%s: %d CPUs, rows %q; want %d, %q", tt.name, ncpu, rows, tt.ncpu, tt.rows)
+		}
+	}
+}
+
+func TestParseCPUMatrixInterrupts(t *testing.T) {
+	ncpu, rows, err := parseCPUMatrix(interruptsFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ncpu != 4 || len(rows) != 10 {
+		t.Fatalf("%d CPUs, %d rows; want 4 and 10", ncpu, len(rows))
+	}
+	if want := []string{"ERR", "0"}; !reflect.DeepEqual(rows[8], want) {
+		t.Errorf("ERR row = %q, want %q", rows[8], want)
+	}
+}
+
+func TestSplitCounters(t *testing.T) {
+	tests := []struct {
+		fields []string
+		ncpu   int
+		counts []uint64
+		desc   string
+	}{
+		{strings.Fields("36 0 0 0 IO-APIC 2-edge timer"), 4, []uint64{36, 0, 0, 0}, "IO-APIC 2-edge timer"},
+		// ERR and MIS have one column, whatever the CPU count.
+		{[]string{"0"}, 4, []uint64{0}, ""},
+		// A numeric description is not mistaken for more CPUs.
+		{strings.Fields("1 2 524288 edge"), 2, []uint64{1, 2}, "524288 edge"},
+		{strings.Fields("Non-maskable interrupts"), 2, []uint64{}, "Non-maskable interrupts"},
+		{nil, 2, []uint64{}, ""},
+	}
+	for _, tt := range tests {
+		counts, desc := splitCounters(tt.fields, tt.ncpu)
+		if !reflect.DeepEqual(counts, tt.counts) || desc != tt.desc {
+			t.Errorf("splitCounters(%q, %d) = %v, %q; want %v, %q", tt.fields, tt.ncpu, counts, desc, tt.counts, tt.desc)
+		}
+	}
+}
+
+func TestReadSoftirqs(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"proc/softirqs": softirqsFixture})
+	ncpu, stats, err := readSoftirqs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ncpu != 4 {
+		t.Errorf("%d CPUs, want 4", ncpu)
+	}
+	ratios := make(map[string]float64)
+	for i := range stats {
+		ratios[stats[i].Name] = stats[i].Imbalance
+		stats[i].Imbalance = 0
+	}
+	want := []SoftirqStat{
+		{Name: "HI", Total: 1, PerCPU: []uint64{1, 0, 0, 0}},
+		{Name: "TIMER", Total: 400000, PerCPU: []uint64{100000, 100000, 100000, 100000}},
+		{Name: "NET_TX", Total: 20, PerCPU: []uint64{10, 2, 3, 5}},
+		{Name: "NET_RX", Total: 903000, PerCPU: []uint64{900000, 1000, 1000, 1000}},
+		{Name: "BLOCK", Total: 100000, PerCPU: []uint64{25000, 25000, 25000, 25000}},
+		{Name: "IRQ_POLL", Total: 0, PerCPU: []uint64{0, 0, 0, 0}},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("softirqs:\n%+v\nwant:\n%+v", stats, want)
+	}
+	// Max over mean: 1 when even, the CPU count when all on one CPU.
+	for name, ratio := range map[string]float64{"HI": 4, "TIMER": 1, "NET_TX": 2, "IRQ_POLL": 0} {
+		if ratios[name] != ratio {
+			t.Errorf("%s imbalance = %v, want %v", name, ratios[name], ratio)
+		}
+	}
+}
+
+func TestReadTopIRQs(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"proc/interrupts":                 interruptsFixture,
+		"proc/irq/24/smp_affinity_list":   "0-3\n",
+		"proc/irq/25/smp_affinity_list":   "1\n",
+		"proc/irq/0/smp_affinity_list":    "0\n",
+		"proc/irq/1/smp_affinity_list":    "2\n",
+		"proc/irq/8/smp_affinity_list":    "0-3\n",
+		"proc/irq/1000/smp_affinity_list": "3\n",
+	})
+	irqs, err := readTopIRQs(root, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Busiest first; NMI, LOC, ERR and MIS are not IRQ numbers.
+	want := []IRQStat{
+		{IRQ: "24", Device: "PCI-MSI 524288-edge nvme0q0", Total: 150000, AffinityList: "0-3", PerCPU: []uint64{120000, 10000, 10000, 10000}},
+		{IRQ: "25", Device: "PCI-MSI 1572864-edge eth0-rx-0", Total: 20000, AffinityList: "1", PerCPU: []uint64{5000, 5000, 5000, 5000}},
+		{IRQ: "0", Device: "IO-APIC 2-edge timer", Total: 36, AffinityList: "0", PerCPU: []uint64{36, 0, 0, 0}},
+	}
+	if !reflect.DeepEqual(irqs, want) {
+		t.Errorf("top IRQs:\n%+v\nwant:\n%+v", irqs, want)
+	}
+
+	// Without a readable smp_affinity_list the IRQ is still reported.
+	irqs, err = readTopIRQs(root, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(irqs) != 6 || irqs[5].IRQ != "26" || irqs[5].AffinityList != "" {
+		t.Errorf("all IRQs = %+v, want 6 ending with 26 without affinity", irqs)
+	}
+}
+
+func TestCollectIRQDetail(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"proc/softirqs": softirqsFixture, "proc/interrupts": interruptsFixture})
+	report, err := CollectIRQ(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TopIRQs != nil || report.Softirqs[0].PerCPU != nil {
+		t.Errorf("per-CPU counts or IRQs without detail: %+v", report)
+	}
+	report, err = CollectIRQ(root, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.TopIRQs) != 6 || len(report.Softirqs[0].PerCPU) != 4 {
+		t.Errorf("detail report = %+v, want per-CPU counts and the IRQs", report)
+	}
+}
+
+func TestIRQFindings(t *testing.T) {
+	report := func(cpus int, stats ...SoftirqStat) *IRQReport {
+		return &IRQReport{CPUs: cpus, Softirqs: stats}
+	}
+	tests := []struct {
+		name   string
+		report *IRQReport
+		want   []string
+	}{
+		{"nil", nil, []string{}},
+		// The threshold is max/mean of 3/4 of the CPU count.
+		{"at threshold", report(4, SoftirqStat{Name: "NET_RX", Total: 10000, Imbalance: 3}), []string{"softirq_imbalance"}},
+		{"below threshold", report(4, SoftirqStat{Name: "NET_RX", Total: 10000, Imbalance: 2.99}), []string{}},
+		{"too few to matter", report(4, SoftirqStat{Name: "NET_RX", Total: 9999, Imbalance: 4}), []string{}},
+		{"one CPU", report(1, SoftirqStat{Name: "NET_RX", Total: 1e6, Imbalance: 1}), []string{}},
+		{"not steerable", report(4, SoftirqStat{Name: "TIMER", Total: 1e6, Imbalance: 4}, SoftirqStat{Name: "HI", Total: 1e6, Imbalance: 4}), []string{}},
+		{
+			"each of NET_RX, NET_TX and BLOCK",
+			report(2,
+				SoftirqStat{Name: "NET_TX", Total: 1e6, Imbalance: 1.5},
+				SoftirqStat{Name: "NET_RX", Total: 1e6, Imbalance: 2},
+				SoftirqStat{Name: "BLOCK", Total: 1e6, Imbalance: 1.49}),
+			[]string{"softirq_imbalance", "softirq_imbalance"},
+		},
+	}
+	for _, tt := range tests {
+		if got := findingCodes(irqFindings(tt.report)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: findings = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// The fixture's NET_RX crosses the threshold, its even BLOCK does not.
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"proc/softirqs": softirqsFixture})
+	r, err := CollectIRQ(root, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := irqFindings(r)
+	if len(findings) != 1 || !strings.HasPrefix(findings[0].Message, "NET_RX ") {
+		t.Errorf("fixture findings = %+v, want NET_RX only", findings)
+	}
+}
@@ -0,0 +1,190 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Isolation struct {
+	IsolCPUs       []int          `json:"isolcpus"`
+	NohzFull       []int          `json:"nohz_full"`
+	RCUNoCBs       []int          `json:"rcu_nocbs"`
+	ProcessCPUs    []int          `json:"process_cpus"`
+	IRQDefaultCPUs []int          `json:"irq_default_cpus"`
+	CPUs           []CPUIsolation `json:"cpus"`
+}
+
+type CPUIsolation struct {
+	CPU            int  `json:"cpu"`
+	Isolated       bool `json:"isolated"`
+	NohzFull       bool `json:"nohz_full"`
+	RCUNoCBs       bool `json:"rcu_nocbs"`
+	ProcessAllowed bool `json:"process_allowed"`
+	IRQAllowed     bool `json:"irq_allowed"`
+}
+
+// CollectIsolation combines isolcpus=, nohz_full= and rcu_nocbs= from the kernel
+// command line, the CPUs pid may run on and the default IRQ affinity into a
+// per-CPU view of which CPUs are isolated.
+func CollectIsolation(ctx context.Context, root string, pid int) (*Isolation, error) {
+	cmdline, err := readTrim(rootPath(root, "proc/cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	iso := &Isolation{}
+	if iso.IsolCPUs, iso.NohzFull, iso.RCUNoCBs, err = parseIsolationCmdline(cmdline); err != nil {
+		return nil, err
+	}
+
+	status, err := readProcStatus(ctx, root, pid)
+	if err != nil {
+		return nil, err
+	}
+	if iso.ProcessCPUs, err = parseCPUList(status["Cpus_allowed_list"]); err != nil {
+		return nil, fmt.Errorf("Cpus_allowed_list: %w", err)
+	}
+
+	mask, err := readTrim(rootPath(root, "proc/irq/default_smp_affinity"))
+	if err != nil {
+		return nil, err
+	}
+	if iso.IRQDefaultCPUs, err = parseCPUMask(mask); err != nil {
+		return nil, fmt.Errorf("default_smp_affinity: %w", err)
+	}
+
+	online, err := readTrim(rootPath(root, "sys/devices/system/cpu/online"))
+	if err != nil {
+		return nil, err
+	}
+	cpus, err := parseCPUList(online)
+	if err != nil {
+		return nil, fmt.Errorf("cpu/online: %w", err)
+	}
+	for _, cpu := range cpus {
+		iso.CPUs = append(iso.CPUs, CPUIsolation{
+			CPU:            cpu,
+			Isolated:       slices.Contains(iso.IsolCPUs, cpu),
+			NohzFull:       slices.Contains(iso.NohzFull, cpu),
+			RCUNoCBs:       slices.Contains(iso.RCUNoCBs, cpu),
+			ProcessAllowed: slices.Contains(iso.ProcessCPUs, cpu),
+			IRQAllowed:     slices.Contains(iso.IRQDefaultCPUs, cpu),
+		})
+	}
+	return iso, nil
+}
+
+// parseIsolationCmdline returns the CPU lists of isolcpus=, nohz_full= and
+// rcu_nocbs=. isolcpus may be prefixed with flags:
+// "isolcpus=managed_irq,domain,2-5". A bare rcu_nocbs (no list) offloads
+// no CPU at boot and is ignored.
+func parseIsolationCmdline(cmdline string) (isolcpus, nohzFull, rcuNoCBs []int, err error) {
+	for _, arg := range strings.Fields(cmdline) {
+		if arg == "--" {
+			break
+		}
+		key, value, found := strings.Cut(arg, "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "isolcpus":
+			parts := strings.Split(value, ",")
+			for len(parts) > 0 && parts[0] != "" && (parts[0][0] < '0' || parts[0][0] > '9') {
+				parts = parts[1:]
+			}
+			if isolcpus, err = parseCPUList(strings.Join(parts, ",")); err != nil {
+				return nil, nil, nil, fmt.Errorf("isolcpus: %w", err)
+			}
+		case "nohz_full":
+			if nohzFull, err = parseCPUList(value); err != nil {
+				return nil, nil, nil, fmt.Errorf("nohz_full: %w", err)
+			}
+		case "rcu_nocbs":
+			if rcuNoCBs, err = parseCPUList(value); err != nil {
+				return nil, nil, nil, fmt.Errorf("rcu_nocbs: %w", err)
+			}
+		}
+	}
+	return isolcpus, nohzFull, rcuNoCBs, nil
+}
+
+// parseCPUList parses the kernel list format "0-3,8,10-11".
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(list), ",") {
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("malformed cpu list %q", list)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first {
+				return nil, fmt.Errorf("malformed cpu list %q", list)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// parseCPUMask parses a hex CPU mask in comma-separated 32-bit groups,
+// most significant first: "00000000,0000000f".
+func parseCPUMask(mask string) ([]int, error) {
+	groups := strings.Split(strings.TrimSpace(mask), ",")
+	var cpus []int
+	for i := len(groups) - 1; i >= 0; i-- {
+		v, err := strconv.ParseUint(groups[i], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("malformed cpu mask %q", mask)
+		}
+		base := (len(groups) - 1 - i) * 32
+		for bit := 0; bit < 32; bit++ {
+			if v&(1<<bit) != 0 {
+				cpus = append(cpus, base+bit)
+			}
+		}
+	}
+	return cpus, nil
+}
+
+func isolationFindings(iso *Isolation) []Finding {
+	if iso == nil || len(iso.IsolCPUs) == 0 {
+		return nil
+	}
+	var housekeeping, irqIsolated []string
+	for _, c := range iso.CPUs {
+		if c.ProcessAllowed && !c.Isolated {
+			housekeeping = append(housekeeping, strconv.Itoa(c.CPU))
+		}
+		if c.IRQAllowed && c.Isolated {
+			irqIsolated = append(irqIsolated, strconv.Itoa(c.CPU))
+		}
+	}
+	var findings []Finding
+	if len(housekeeping) > 0 {
+		findings = append(findings, Finding{
+			Code:     "isolation_process_on_housekeeping",
+			Severity: "warning",
+			Message: fmt.Sprintf("isolcpus is set but the process may run on non-isolated CPUs %s",
+				strings.Join(housekeeping, ",")),
+		})
+	}
+	if len(irqIsolated) > 0 {
+		findings = append(findings, Finding{
+			Code:     "isolation_irq_on_isolated",
+			Severity: "warning",
+			Message: fmt.Sprintf("default IRQ affinity includes isolated CPUs %s: new IRQs will land there",
+				strings.Join(irqIsolated, ",")),
+		})
+	}
+	return findings
+}
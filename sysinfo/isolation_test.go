@@ -0,0 +1,221 @@
+package sysinfo
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []int
+		wantErr bool
+	}{
+		{list: "0-3,8,10-11", want: []int{0, 1, 2, 3, 8, 10, 11}},
+		{list: "5", want: []int{5}},
+		{list: "0-0", want: []int{0}},
+		{list: "0-3,8,10-11\n", want: []int{0, 1, 2, 3, 8, 10, 11}},
+		{list: "2\n\n", want: []int{2}},
+		{list: ""},
+		{list: "\n"},
+		{list: "1,,3", want: []int{1, 3}},
+		{list: "3-1", wantErr: true},
+		{list: "1-", wantErr: true},
+		{list: "-1", wantErr: true},
+		{list: "1-2-3", wantErr: true},
+		{list: "a-b", wantErr: true},
+		{list: "0-3,x", wantErr: true},
+		{list: "0 1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCPUList(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCPUList(%q) error = %v, want error %v", tt.list, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCPUList(%q) = %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestParseIsolationCmdline(t *testing.T) {
+	tests := []struct {
+		cmdline                      string
+		isolcpus, nohzFull, rcuNoCBs []int
+		wantErr                      bool
+	}{
+		{cmdline: "BOOT_IMAGE=/vmlinuz root=/dev/sda1 ro quiet"},
+		{cmdline: "isolcpus=2-3", isolcpus: []int{2, 3}},
+		{cmdline: "nohz_full=2-3", nohzFull: []int{2, 3}},
+		{cmdline: "rcu_nocbs=1,3", rcuNoCBs: []int{1, 3}},
+		{
+			cmdline:  "ro isolcpus=2-5 nohz_full=2-5 rcu_nocbs=2-5 rcu_nocb_poll",
+			isolcpus: []int{2, 3, 4, 5}, nohzFull: []int{2, 3, 4, 5}, rcuNoCBs: []int{2, 3, 4, 5},
+		},
+		// Flags before the list, in any number.
+		{cmdline: "isolcpus=managed_irq,domain,4-5", isolcpus: []int{4, 5}},
+		{cmdline: "isolcpus=nohz,7", isolcpus: []int{7}},
+		{cmdline: "isolcpus=domain"},
+		// The lists need not agree with each other.
+		{cmdline: "isolcpus=2-3 nohz_full=3-4 rcu_nocbs=4", isolcpus: []int{2, 3}, nohzFull: []int{3, 4}, rcuNoCBs: []int{4}},
+		// A bare rcu_nocbs offloads nothing at boot.
+		{cmdline: "rcu_nocbs nohz_full=1", nohzFull: []int{1}},
+		// The last one wins, as in the kernel.
+		{cmdline: "isolcpus=1 isolcpus=2", isolcpus: []int{2}},
+		// Arguments after -- are for init.
+		{cmdline: "isolcpus=1 -- nohz_full=1 rcu_nocbs=1", isolcpus: []int{1}},
+		{cmdline: "isolcpus=3-1", wantErr: true},
+		{cmdline: "nohz_full=1-x", wantErr: true},
+		{cmdline: "rcu_nocbs=all", wantErr: true},
+	}
+	for _, tt := range tests {
+		isolcpus, nohzFull, rcuNoCBs, err := parseIsolationCmdline(tt.cmdline)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: error = %v, want error %v", tt.cmdline, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(isolcpus, tt.isolcpus) || !slices.Equal(nohzFull, tt.nohzFull) || !slices.Equal(rcuNoCBs, tt.rcuNoCBs) {
+			t.Errorf("%q: isolcpus %v nohz_full %v rcu_nocbs %v, want %v %v %v",
+				tt.cmdline, isolcpus, nohzFull, rcuNoCBs, tt.isolcpus, tt.nohzFull, tt.rcuNoCBs)
+		}
+	}
+}
+
+func TestParseCPUMask(t *testing.T) {
+	tests := []struct {
+		mask string
+		want []int
+	}{
+		{"f\n", []int{0, 1, 2, 3}},
+		{"00000000,00000001", []int{0}},
+		{"00000001,00000000", []int{32}},
+		{"80000000,00000003", []int{0, 1, 63}},
+		{"0", nil},
+	}
+	for _, tt := range tests {
+		if got, err := parseCPUMask(tt.mask); err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("parseCPUMask(%q) = %v, %v, want %v", tt.mask, got, err, tt.want)
+		}
+	}
+	for _, mask := range []string{"", "g", "1,,2", "100000000"} {
+		if _, err := parseCPUMask(mask); err == nil {
+			t.Errorf("parseCPUMask(%q) accepted, want an error", mask)
+		}
+	}
+}
+
+func TestCollectIsolation(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmdline   string
+		processes string
+		irqMask   string
+		want      []CPUIsolation
+		findings  []string
+	}{
+		{
+			name:      "no isolation",
+			cmdline:   "ro quiet",
+			processes: "0-3",
+			irqMask:   "f",
+			want: []CPUIsolation{
+				{CPU: 0, ProcessAllowed: true, IRQAllowed: true},
+				{CPU: 1, ProcessAllowed: true, IRQAllowed: true},
+				{CPU: 2, ProcessAllowed: true, IRQAllowed: true},
+				{CPU: 3, ProcessAllowed: true, IRQAllowed: true},
+			},
+			findings: []string{},
+		},
+		{
+			name:      "aligned",
+			cmdline:   "isolcpus=2-3 nohz_full=2-3 rcu_nocbs=2-3",
+			processes: "2-3",
+			irqMask:   "3",
+			want: []CPUIsolation{
+				{CPU: 0, IRQAllowed: true},
+				{CPU: 1, IRQAllowed: true},
+				{CPU: 2, Isolated: true, NohzFull: true, RCUNoCBs: true, ProcessAllowed: true},
+				{CPU: 3, Isolated: true, NohzFull: true, RCUNoCBs: true, ProcessAllowed: true},
+			},
+			findings: []string{},
+		},
+		{
+			name:      "process on housekeeping CPUs",
+			cmdline:   "isolcpus=2-3 nohz_full=2-3",
+			processes: "0-3",
+			irqMask:   "3",
+			want: []CPUIsolation{
+				{CPU: 0, ProcessAllowed: true, IRQAllowed: true},
+				{CPU: 1, ProcessAllowed: true, IRQAllowed: true},
+				{CPU: 2, Isolated: true, NohzFull: true, ProcessAllowed: true},
+				{CPU: 3, Isolated: true, NohzFull: true, ProcessAllowed: true},
+			},
+			findings: []string{"isolation_process_on_housekeeping"},
+		},
+		{
+			name:      "IRQs on isolated CPUs",
+			cmdline:   "isolcpus=managed_irq,domain,2-3 rcu_nocbs=2-3",
+			processes: "2-3",
+			irqMask:   "f",
+			want: []CPUIsolation{
+				{CPU: 0, IRQAllowed: true},
+				{CPU: 1, IRQAllowed: true},
+				{CPU: 2, Isolated: true, RCUNoCBs: true, ProcessAllowed: true, IRQAllowed: true},
+				{CPU: 3, Isolated: true, RCUNoCBs: true, ProcessAllowed: true, IRQAllowed: true},
+			},
+			findings: []string{"isolation_irq_on_isolated"},
+		},
+		{
+			// nohz_full and rcu_nocbs without isolcpus (cpusets do the
+			// isolating) leave nothing to check against.
+			name:      "nohz_full without isolcpus",
+			cmdline:   "nohz_full=1-3 rcu_nocbs=1-3",
+			processes: "0",
+			irqMask:   "f",
+			want: []CPUIsolation{
+				{CPU: 0, ProcessAllowed: true, IRQAllowed: true},
+				{CPU: 1, NohzFull: true, RCUNoCBs: true, IRQAllowed: true},
+				{CPU: 2, NohzFull: true, RCUNoCBs: true, IRQAllowed: true},
+				{CPU: 3, NohzFull: true, RCUNoCBs: true, IRQAllowed: true},
+			},
+			findings: []string{},
+		},
+		{
+			name:      "both misalignments",
+			cmdline:   "isolcpus=3 nohz_full=3 rcu_nocbs=3",
+			processes: "1,3",
+			irqMask:   "00000000,0000000a",
+			want: []CPUIsolation{
+				{CPU: 0},
+				{CPU: 1, ProcessAllowed: true, IRQAllowed: true},
+				{CPU: 2},
+				{CPU: 3, Isolated: true, NohzFull: true, RCUNoCBs: true, ProcessAllowed: true, IRQAllowed: true},
+			},
+			findings: []string{"isolation_process_on_housekeeping", "isolation_irq_on_isolated"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, map[string]string{
+				"proc/cmdline":                  tt.cmdline + "\n",
+				"proc/42/status":                "Name:\tapp\nPid:\t42\nThreads:\t1\nCpus_allowed_list:\t" + tt.processes + "\n",
+				"proc/irq/default_smp_affinity": tt.irqMask + "\n",
+				"sys/devices/system/cpu/online": "0-3\n",
+			})
+			iso, err := CollectIsolation(context.Background(), root, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(iso.CPUs, tt.want) {
+				t.Errorf("cpus:\n%+v\nwant:\n%+v", iso.CPUs, tt.want)
+			}
+			if got := findingCodes(isolationFindings(iso)); !slices.Equal(got, tt.findings) {
+				t.Errorf("findings = %q, want %q", got, tt.findings)
+			}
+		})
+	}
+}
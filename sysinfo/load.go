@@ -0,0 +1,82 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type LoadAvg struct {
+	One           float64 `json:"load1"`
+	Five          float64 `json:"load5"`
+	Fifteen       float64 `json:"load15"`
+	RunnableProcs int     `json:"running_procs"`
+	TotalProcs    int     `json:"total_procs"`
+}
+
+// CollectUptime returns the seconds since boot and the summed idle time of
+// all CPUs from /proc/uptime.
+func CollectUptime(root string) (uptime, idle float64, err error) {
+	data, err := os.ReadFile(rootPath(root, "proc/uptime"))
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseUptime(string(data))
+}
+
+func parseUptime(data string) (uptime, idle float64, err error) {
+	fields := strings.Fields(data)
+	if len(fields) < 2 {
+		return 0, 0, fmt.Errorf("malformed /proc/uptime %q", data)
+	}
+	if uptime, err = parseDecimal(fields[0]); err != nil {
+		return 0, 0, fmt.Errorf("uptime: %w", err)
+	}
+	if idle, err = parseDecimal(fields[1]); err != nil {
+		return 0, 0, fmt.Errorf("idle: %w", err)
+	}
+	return uptime, idle, nil
+}
+
+// CollectLoadAvg parses /proc/loadavg: "0.52 0.48 0.40 2/431 12345".
+func CollectLoadAvg(root string) (*LoadAvg, error) {
+	data, err := os.ReadFile(rootPath(root, "proc/loadavg"))
+	if err != nil {
+		return nil, err
+	}
+	return parseLoadAvg(string(data))
+}
+
+func parseLoadAvg(data string) (*LoadAvg, error) {
+	fields := strings.Fields(data)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("malformed /proc/loadavg %q", data)
+	}
+	load := &LoadAvg{}
+	for i, dst := range []*float64{&load.One, &load.Five, &load.Fifteen} {
+		v, err := parseDecimal(fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("loadavg: %w", err)
+		}
+		*dst = v
+	}
+	running, total, found := strings.Cut(fields[3], "/")
+	if !found {
+		return nil, fmt.Errorf("malformed process counts %q", fields[3])
+	}
+	var err error
+	if load.RunnableProcs, err = strconv.Atoi(running); err != nil {
+		return nil, fmt.Errorf("running procs: %w", err)
+	}
+	if load.TotalProcs, err = strconv.Atoi(total); err != nil {
+		return nil, fmt.Errorf("total procs: %w", err)
+	}
+	return load, nil
+}
+
+// parseDecimal accepts a decimal comma as well: the kernel always writes a
+// dot, but copies of these files made through locale-aware tools may not.
+func parseDecimal(s string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+}
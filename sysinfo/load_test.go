@@ -0,0 +1,78 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUptime(t *testing.T) {
+	tests := []struct {
+		data         string
+		uptime, idle float64
+	}{
+		{"350735.47 234388.90\n", 350735.47, 234388.90},
+		{"  12.00   3.50  \n\n", 12, 3.5},
+		// A copy run through a locale-aware tool.
+		{"350735,47 234388,90\n", 350735.47, 234388.90},
+		{"0.01 0.00", 0.01, 0},
+	}
+	for _, tt := range tests {
+		uptime, idle, err := parseUptime(tt.data)
+		if err != nil || uptime != tt.uptime || idle != tt.idle {
+			t.Errorf("parseUptime(%q) = %v, %v, %v, want %v, %v", tt.data, uptime, idle, err, tt.uptime, tt.idle)
+		}
+	}
+	for _, data := range []string{"", "\n", "350735.47\n", "abc 1.0\n", "1.0 x\n", "1,2,3 4\n"} {
+		if _, _, err := parseUptime(data); err == nil {
+			t.Errorf("parseUptime(%q) accepted, want an error", data)
+		}
+	}
+}
+
+func TestParseLoadAvg(t *testing.T) {
+	tests := []struct {
+		data string
+		want LoadAvg
+	}{
+		{"0.52 0.48 0.40 2/431 12345\n", LoadAvg{0.52, 0.48, 0.40, 2, 431}},
+		{"\t0.00  0.01\t0.05   1/98   7\n\n", LoadAvg{0, 0.01, 0.05, 1, 98}},
+		{"12,50 8,25 4,00 17/2048 99999\n", LoadAvg{12.5, 8.25, 4, 17, 2048}},
+		// The last PID is not needed.
+		{"1.00 1.00 1.00 1/1", LoadAvg{1, 1, 1, 1, 1}},
+	}
+	for _, tt := range tests {
+		load, err := parseLoadAvg(tt.data)
+		if err != nil || !reflect.DeepEqual(*load, tt.want) {
+			t.Errorf("parseLoadAvg(%q) = %+v, %v, want %+v", tt.data, load, err, tt.want)
+		}
+	}
+	for _, data := range []string{
+		"",
+		"0.52 0.48 0.40\n",
+		"0.52 0.48 0.40 2-431 12345\n",
+		"0.52 x 0.40 2/431 12345\n",
+		"0.52 0.48 0.40 a/431 12345\n",
+		"0.52 0.48 0.40 2/ 12345\n",
+	} {
+		if _, err := parseLoadAvg(data); err == nil {
+			t.Errorf("parseLoadAvg(%q) accepted, want an error", data)
+		}
+	}
+}
+
+func TestCollectUptimeAndLoadAvg(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"proc/uptime":  "100.25 380.75\n",
+		"proc/loadavg": "0.10 0.20 0.30 3/120 4567\n",
+	})
+	if uptime, idle, err := CollectUptime(root); err != nil || uptime != 100.25 || idle != 380.75 {
+		t.Errorf("CollectUptime = %v, %v, %v", uptime, idle, err)
+	}
+	if load, err := CollectLoadAvg(root); err != nil || *load != (LoadAvg{0.1, 0.2, 0.3, 3, 120}) {
+		t.Errorf("CollectLoadAvg = %+v, %v", load, err)
+	}
+	if _, _, err := CollectUptime(t.TempDir()); err == nil {
+		t.Error("CollectUptime without /proc/uptime succeeded")
+	}
+}
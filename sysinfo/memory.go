@@ -0,0 +1,99 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CollectMemory returns MemTotal from /proc/meminfo in kB.
+func CollectMemory(ctx context.Context, root string) (int, error) {
+	meminfo, err := readMeminfo(ctx, root)
+	if err != nil {
+		return 0, err
+	}
+	return int(meminfo["MemTotal"]), nil
+}
+
+// MemInfo is the canonical memory breakdown, in bytes. The flat *_kb
+// fields of SysInfo are kept for existing consumers.
+type MemInfo struct {
+	TotalBytes     uint64 `json:"total_bytes"`
+	FreeBytes      uint64 `json:"free_bytes"`
+	AvailableBytes uint64 `json:"available_bytes"`
+	UsedBytes      uint64 `json:"used_bytes"`
+	BuffersBytes   uint64 `json:"buffers_bytes"`
+	CachedBytes    uint64 `json:"cached_bytes"`
+	DirtyBytes     uint64 `json:"dirty_bytes"`
+	SlabBytes      uint64 `json:"slab_bytes"`
+	SwapTotalBytes uint64 `json:"swap_total_bytes"`
+	SwapFreeBytes  uint64 `json:"swap_free_bytes"`
+}
+
+// CollectMemInfo returns the /proc/meminfo breakdown in bytes. Missing
+// lines read as zero, except MemAvailable, which is estimated.
+func CollectMemInfo(ctx context.Context, root string) (*MemInfo, error) {
+	meminfo, err := readMeminfo(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	return newMemInfo(meminfo), nil
+}
+
+func newMemInfo(meminfo map[string]int64) *MemInfo {
+	bytes := func(kb int64) uint64 { return uint64(max(kb, 0)) * 1024 }
+	m := &MemInfo{
+		TotalBytes:     bytes(meminfo["MemTotal"]),
+		FreeBytes:      bytes(meminfo["MemFree"]),
+		AvailableBytes: bytes(memAvailable(meminfo)),
+		BuffersBytes:   bytes(meminfo["Buffers"]),
+		CachedBytes:    bytes(meminfo["Cached"]),
+		DirtyBytes:     bytes(meminfo["Dirty"]),
+		SlabBytes:      bytes(meminfo["Slab"]),
+		SwapTotalBytes: bytes(meminfo["SwapTotal"]),
+		SwapFreeBytes:  bytes(meminfo["SwapFree"]),
+	}
+	if m.AvailableBytes < m.TotalBytes {
+		m.UsedBytes = m.TotalBytes - m.AvailableBytes
+	}
+	return m
+}
+
+// memAvailable returns the kernel's estimate of memory available for new
+// allocations without swapping, in kB.
+func memAvailable(meminfo map[string]int64) int64 {
+	if avail, ok := meminfo["MemAvailable"]; ok {
+		return avail
+	}
+	// Kernels before 3.14 have no MemAvailable line. Free + Buffers +
+	// Cached is the classic approximation; it overstates availability a
+	// little since not all page cache can be reclaimed.
+	return meminfo["MemFree"] + meminfo["Buffers"] + meminfo["Cached"]
+}
+
+// readMeminfo parses /proc/meminfo into kB values keyed by field name.
+// Lines without a unit (HugePages_Total, ...) are kept as plain counts.
+func readMeminfo(ctx context.Context, root string) (map[string]int64, error) {
+	data, err := readVerified(ctx, rootPath(root, "proc/meminfo"), hasLines("MemTotal:", "MemFree:", "SwapFree:"))
+	if err != nil {
+		return nil, err
+	}
+	meminfo := make(map[string]int64)
+	for _, line := range strings.Split(data, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		n, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("meminfo %s: %w", key, err)
+		}
+		meminfo[key] = n
+	}
+	return meminfo, nil
+}
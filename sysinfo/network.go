@@ -0,0 +1,333 @@
+package sysinfo
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	labelAllInterfaces = "all interfaces"
+	labelForeign       = "foreign"
+)
+
+type Network struct {
+	DNS          *DNSConfig     `json:"dns,omitempty"`
+	Interfaces   []NetInterface `json:"interfaces,omitempty"`
+	Listening    []ListenSocket `json:"listening,omitempty"`
+	DefaultRoute *DefaultRoute  `json:"default_route,omitempty"`
+}
+
+type NetInterface struct {
+	Name      string   `json:"name"`
+	MAC       string   `json:"mac,omitempty"`
+	MTU       int      `json:"mtu"`
+	OperState string   `json:"operstate"`
+	Up        bool     `json:"up"`
+	Loopback  bool     `json:"loopback"`
+	Addresses []string `json:"addresses"`
+	RxBytes   uint64   `json:"rx_bytes"`
+	RxPackets uint64   `json:"rx_packets"`
+	TxBytes   uint64   `json:"tx_bytes"`
+	TxPackets uint64   `json:"tx_packets"`
+}
+
+type ListenSocket struct {
+	Proto     string `json:"proto"`
+	Address   string `json:"address"`
+	Port      int    `json:"port"`
+	Interface string `json:"interface"`
+}
+
+type DefaultRoute struct {
+	Gateway   string `json:"gateway"`
+	Device    string `json:"device"`
+	Interface string `json:"interface"`
+}
+
+// CollectInterfaces lists network interfaces from /sys/class/net with
+// their link counters from /proc/net/dev. Addresses come from the kernel
+// via net.Interfaces and are therefore always those of the live system.
+// Interfaces that vanish while being read are skipped.
+func CollectInterfaces(root string) ([]NetInterface, error) {
+	entries, err := os.ReadDir(rootPath(root, "sys/class/net"))
+	if err != nil {
+		return nil, err
+	}
+	counters, err := readNetDev(root)
+	if err != nil {
+		return nil, err
+	}
+	var result []NetInterface
+	for _, entry := range entries {
+		ni, err := readInterface(root, entry.Name())
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if c, ok := counters[ni.Name]; ok {
+			ni.RxBytes, ni.RxPackets, ni.TxBytes, ni.TxPackets = c[0], c[1], c[8], c[9]
+		}
+		if iface, err := net.InterfaceByName(ni.Name); err == nil {
+			if addrs, err := iface.Addrs(); err == nil {
+				for _, a := range addrs {
+					ni.Addresses = append(ni.Addresses, a.String())
+				}
+			}
+		}
+		result = append(result, *ni)
+	}
+	return result, nil
+}
+
+func readInterface(root, name string) (*NetInterface, error) {
+	const (
+		iffUp       = 0x1
+		iffLoopback = 0x8
+	)
+	dir := rootPath(root, "sys/class/net", name)
+	ni := &NetInterface{Name: name}
+	flagsStr, err := readTrim(filepath.Join(dir, "flags"))
+	if err != nil {
+		return nil, err
+	}
+	flags, err := strconv.ParseUint(flagsStr, 0, 32)
+	if err != nil {
+		return nil, fmt.Errorf("flags: %w", err)
+	}
+	ni.Up = flags&iffUp != 0
+	ni.Loopback = flags&iffLoopback != 0
+	mtu, err := readTrim(filepath.Join(dir, "mtu"))
+	if err != nil {
+		return nil, err
+	}
+	if ni.MTU, err = strconv.Atoi(mtu); err != nil {
+		return nil, fmt.Errorf("mtu: %w", err)
+	}
+	if ni.OperState, err = readTrim(filepath.Join(dir, "operstate")); err != nil {
+		return nil, err
+	}
+	if ni.MAC, err = readTrim(filepath.Join(dir, "address")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return ni, nil
+}
+
+// readNetDev returns the 16 counters of each interface in /proc/net/dev:
+// rx bytes, packets, errs, drop, fifo, frame, compressed, multicast, then
+// tx bytes, packets, errs, drop, fifo, colls, carrier, compressed.
+func readNetDev(root string) (map[string][16]uint64, error) {
+	data, err := os.ReadFile(rootPath(root, "proc/net/dev"))
+	if err != nil {
+		return nil, err
+	}
+	counters := make(map[string][16]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
+		name, rest, found := strings.Cut(line, ":")
+		fields := strings.Fields(rest)
+		if !found || len(fields) < 16 {
+			continue
+		}
+		var c [16]uint64
+		for i := range c {
+			c[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		counters[strings.TrimSpace(name)] = c
+	}
+	return counters, nil
+}
+
+// CollectListening returns TCP sockets in LISTEN state and bound UDP
+// sockets from /proc/net/{tcp,tcp6,udp,udp6}, sorted by proto and port.
+func CollectListening(root string) ([]ListenSocket, error) {
+	tables := []struct {
+		file, proto, state string
+	}{
+		{"tcp", "tcp", "0A"},
+		{"tcp6", "tcp6", "0A"},
+		{"udp", "udp", "07"},
+		{"udp6", "udp6", "07"},
+	}
+	seen := make(map[ListenSocket]bool)
+	var sockets []ListenSocket
+	var read int
+	for _, t := range tables {
+		data, err := os.ReadFile(rootPath(root, "proc/net", t.file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		read++
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			fields := strings.Fields(line)
+			if len(fields) < 4 || fields[3] != t.state {
+				continue
+			}
+			ip, port, err := parseProcNetAddr(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("/proc/net/%s: %w", t.file, err)
+			}
+			s := ListenSocket{Proto: t.proto, Address: ip.String(), Port: port}
+			if !seen[s] {
+				seen[s] = true
+				sockets = append(sockets, s)
+			}
+		}
+	}
+	if read == 0 {
+		return nil, fmt.Errorf("no /proc/net socket tables found")
+	}
+	sort.SliceStable(sockets, func(i, j int) bool {
+		if sockets[i].Proto != sockets[j].Proto {
+			return sockets[i].Proto < sockets[j].Proto
+		}
+		return sockets[i].Port < sockets[j].Port
+	})
+	return sockets, nil
+}
+
+// parseProcNetAddr decodes "0100007F:0035". The address is hex in host
+// byte order, 32 bits at a time; the port is plain big-endian hex.
+func parseProcNetAddr(s string) (net.IP, int, error) {
+	addrHex, portHex, found := strings.Cut(s, ":")
+	if !found {
+		return nil, 0, fmt.Errorf("malformed address %q", s)
+	}
+	raw, err := hex.DecodeString(addrHex)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return nil, 0, fmt.Errorf("malformed address %q", s)
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.NativeEndian.Uint32(raw[i:]))
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("malformed port %q", s)
+	}
+	return ip, int(port), nil
+}
+
+// CollectDefaultRoute returns the IPv4 default route from /proc/net/route,
+// or nil if there is none.
+func CollectDefaultRoute(root string) (*DefaultRoute, error) {
+	data, err := os.ReadFile(rootPath(root, "proc/net/route"))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			return nil, fmt.Errorf("/proc/net/route: malformed gateway %q", fields[2])
+		}
+		gw := make(net.IP, 4)
+		binary.BigEndian.PutUint32(gw, binary.NativeEndian.Uint32(raw))
+		return &DefaultRoute{Gateway: gw.String(), Device: fields[0]}, nil
+	}
+	return nil, nil
+}
+
+// AnnotateNetwork labels listening sockets and the default gateway with the
+// local interface they belong to, using only already collected data.
+// Listening addresses must be assigned to an interface; wildcard binds are
+// labelled "all interfaces". The gateway must fall within an interface's
+// subnet. Anything else is labelled "foreign".
+func AnnotateNetwork(n *Network) {
+	if n == nil {
+		return
+	}
+	type ifaceNet struct {
+		name     string
+		ipnet    *net.IPNet
+		loopback bool
+	}
+	var nets []ifaceNet
+	for _, iface := range n.Interfaces {
+		for _, a := range iface.Addresses {
+			ip, ipnet, err := net.ParseCIDR(a)
+			if err != nil {
+				continue
+			}
+			ipnet.IP = ip
+			nets = append(nets, ifaceNet{iface.Name, ipnet, iface.Loopback})
+		}
+	}
+	owner := func(ip net.IP, subnet bool) string {
+		for _, in := range nets {
+			if in.ipnet.IP.Equal(ip) {
+				return in.name
+			}
+		}
+		for _, in := range nets {
+			// Any 127/8 (or ::1) address is local to the loopback device,
+			// whatever its configured prefix.
+			if (subnet || in.loopback) && in.ipnet.Contains(ip) {
+				return in.name
+			}
+		}
+		return labelForeign
+	}
+
+	for i := range n.Listening {
+		ip := net.ParseIP(n.Listening[i].Address)
+		if ip == nil {
+			n.Listening[i].Interface = labelForeign
+			continue
+		}
+		if ip.IsUnspecified() {
+			n.Listening[i].Interface = labelAllInterfaces
+			continue
+		}
+		n.Listening[i].Interface = owner(ip, false)
+	}
+	if n.DefaultRoute != nil {
+		if ip := net.ParseIP(n.DefaultRoute.Gateway); ip != nil {
+			n.DefaultRoute.Interface = owner(ip, true)
+		}
+	}
+}
+
+// networkFindings reports addresses that no local interface owns. Without
+// the interface list every address would look foreign, so nothing is
+// reported then.
+func networkFindings(info *SysInfo) []Finding {
+	n := info.Network
+	if n == nil || len(n.Interfaces) == 0 || !info.collected("interfaces", "listening") {
+		return nil
+	}
+	var findings []Finding
+	for _, s := range n.Listening {
+		if s.Interface == labelForeign {
+			findings = append(findings, Finding{
+				Code:     "listen_foreign_address",
+				Severity: "warning",
+				Message: fmt.Sprintf("%s socket bound to %s:%d, which is not assigned to any local interface "+
+					"(stale configuration?)", s.Proto, s.Address, s.Port),
+			})
+		}
+	}
+	if r := n.DefaultRoute; r != nil && r.Interface == labelForeign {
+		findings = append(findings, Finding{
+			Code:     "gateway_foreign",
+			Severity: "warning",
+			Message:  fmt.Sprintf("default gateway %s via %s is not within any local subnet", r.Gateway, r.Device),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,80 @@
+package sysinfo
+
+import "testing"
+
+func TestAnnotateNetwork(t *testing.T) {
+	interfaces := []NetInterface{
+		{Name: "lo", Loopback: true, Addresses: []string{"127.0.0.1/8", "::1/128"}},
+		{Name: "eth0", Addresses: []string{"192.168.1.10/24", "fe80::1/64", "2001:db8::10/64"}},
+		{Name: "wg0", Addresses: []string{"10.8.0.2/32"}},
+	}
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{"IPv4 wildcard", "0.0.0.0", labelAllInterfaces},
+		{"IPv6 wildcard", "::", labelAllInterfaces},
+		{"own IPv4 address", "192.168.1.10", "eth0"},
+		{"own IPv6 address", "2001:db8::10", "eth0"},
+		{"link-local", "fe80::1", "eth0"},
+		{"loopback", "127.0.0.1", "lo"},
+		// Anything in 127/8 belongs to lo, not only its configured address.
+		{"other loopback address", "127.0.0.53", "lo"},
+		{"IPv6 loopback", "::1", "lo"},
+		{"/32 address", "10.8.0.2", "wg0"},
+		// A listener must own its address: being in the subnet is not enough.
+		{"same subnet, not assigned", "192.168.1.20", labelForeign},
+		{"stale address", "172.16.0.5", labelForeign},
+		{"unparsable", "not-an-ip", labelForeign},
+		{"IPv4-mapped own address", "::ffff:192.168.1.10", "eth0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &Network{Interfaces: interfaces, Listening: []ListenSocket{{Proto: "tcp", Address: tt.address, Port: 80}}}
+			AnnotateNetwork(n)
+			if got := n.Listening[0].Interface; got != tt.want {
+				t.Errorf("listener on %s labelled %q, want %q", tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnotateNetworkGateway(t *testing.T) {
+	interfaces := []NetInterface{
+		{Name: "lo", Loopback: true, Addresses: []string{"127.0.0.1/8"}},
+		{Name: "eth0", Addresses: []string{"192.168.1.10/24", "2001:db8::10/64"}},
+	}
+	tests := []struct {
+		gateway string
+		want    string
+	}{
+		// Unlike a listener, the gateway only has to be in the subnet.
+		{"192.168.1.1", "eth0"},
+		{"2001:db8::1", "eth0"},
+		{"10.0.0.1", labelForeign},
+		// Left alone when there is no address to place.
+		{"", ""},
+	}
+	for _, tt := range tests {
+		n := &Network{Interfaces: interfaces, DefaultRoute: &DefaultRoute{Gateway: tt.gateway, Device: "eth0"}}
+		AnnotateNetwork(n)
+		if got := n.DefaultRoute.Interface; got != tt.want {
+			t.Errorf("gateway %q labelled %q, want %q", tt.gateway, got, tt.want)
+		}
+	}
+}
+
+func TestAnnotateNetworkWithoutInterfaces(t *testing.T) {
+	AnnotateNetwork(nil)
+	n := &Network{Listening: []ListenSocket{{Address: "0.0.0.0"}, {Address: "10.0.0.1"}}, DefaultRoute: &DefaultRoute{Gateway: "10.0.0.254"}}
+	AnnotateNetwork(n)
+	if n.Listening[0].Interface != labelAllInterfaces || n.Listening[1].Interface != labelForeign || n.DefaultRoute.Interface != labelForeign {
+		t.Errorf("labels = %q, %q, gateway %q", n.Listening[0].Interface, n.Listening[1].Interface, n.DefaultRoute.Interface)
+	}
+	// Without the interface list every address looks foreign, which the
+	// findings must not report.
+	if f := networkFindings(&SysInfo{Network: n}); len(f) != 0 {
+		t.Errorf("findings = %+v, want none without interfaces", f)
+	}
+}
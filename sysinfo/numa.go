@@ -0,0 +1,87 @@
+package sysinfo
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// NUMANode is one node of /sys/devices/system/node. CPUList is the
+// kernel's cpulist as written ("0-7,16-23"), CPUs the same expanded.
+type NUMANode struct {
+	Node          int    `json:"node"`
+	CPUList       string `json:"cpulist"`
+	CPUs          []int  `json:"cpus"`
+	MemTotalBytes uint64 `json:"mem_total_bytes"`
+	MemFreeBytes  uint64 `json:"mem_free_bytes"`
+}
+
+// CollectNUMA reads every node directory in node order. A kernel without
+// NUMA support still has node0; only when the directory is missing
+// altogether (some containers and VMs) is the result nil.
+func CollectNUMA(root string) ([]NUMANode, error) {
+	dirs, err := filepath.Glob(rootPath(root, "sys/devices/system/node/node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	var nodes []NUMANode
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		n := NUMANode{Node: id, CPUs: []int{}}
+		if n.CPUList, err = readTrim(dir + "/cpulist"); err != nil {
+			return nil, err
+		}
+		cpus, err := parseCPUList(n.CPUList)
+		if err != nil {
+			return nil, fmt.Errorf("node%d: %w", id, err)
+		}
+		n.CPUs = append(n.CPUs, cpus...)
+		if n.MemTotalBytes, n.MemFreeBytes, err = readNodeMeminfo(dir + "/meminfo"); err != nil {
+			return nil, fmt.Errorf("node%d: %w", id, err)
+		}
+		nodes = append(nodes, n)
+	}
+	slices.SortFunc(nodes, func(a, b NUMANode) int { return cmp.Compare(a.Node, b.Node) })
+	return nodes, nil
+}
+
+// readNodeMeminfo parses the per-node meminfo, whose lines carry a node
+// prefix: "Node 0 MemTotal:  5865208 kB".
+func readNodeMeminfo(path string) (total, free uint64, err error) {
+	data, err := readTrim(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	var seen int
+	for _, line := range strings.Split(data, "\n") {
+		f := strings.Fields(line)
+		if len(f) < 4 {
+			continue
+		}
+		var dst *uint64
+		switch f[2] {
+		case "MemTotal:":
+			dst = &total
+		case "MemFree:":
+			dst = &free
+		default:
+			continue
+		}
+		kb, err := strconv.ParseUint(f[3], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("malformed %s line %q", path, line)
+		}
+		*dst = kb * 1024
+		seen++
+	}
+	if seen < 2 {
+		return 0, 0, fmt.Errorf("%s: no MemTotal/MemFree", path)
+	}
+	return total, free, nil
+}
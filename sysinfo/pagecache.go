@@ -0,0 +1,168 @@
+package sysinfo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// pageCacheNote is carried in the report so that consumers of the JSON see
+// the same caveat as readers of the table.
+const pageCacheNote = "approximate: the kernel does not attribute clean page cache to devices; " +
+	"per-device figures are dirty and writeback pages from debugfs bdi stats, " +
+	"file_bytes is the page cache total"
+
+type PageCache struct {
+	FileBytes      uint64            `json:"file_bytes"`
+	DirtyBytes     uint64            `json:"dirty_bytes"`
+	WritebackBytes uint64            `json:"writeback_bytes"`
+	Source         string            `json:"source"`
+	Devices        []DevicePageCache `json:"devices"`
+	Note           string            `json:"note"`
+}
+
+type DevicePageCache struct {
+	Device         string   `json:"device"`
+	Source         string   `json:"source"`
+	Mounts         []string `json:"mounts"`
+	DirtyBytes     uint64   `json:"dirty_bytes"`
+	WritebackBytes uint64   `json:"writeback_bytes"`
+	// DirtySharePercent is this device's part of all dirty and writeback
+	// pages across the reported devices.
+	DirtySharePercent float64 `json:"dirty_share_percent"`
+}
+
+// CollectPageCache combines the page cache total (root cgroup memory.stat,
+// or /proc/meminfo without cgroup v2) with per backing device dirty and
+// writeback pages from /sys/kernel/debug/bdi/<maj:min>/stats, mapped to
+// mounts through mountinfo. Without readable bdi stats (debugfs not
+// mounted, not root) it returns nil: a total alone says nothing new.
+func CollectPageCache(ctx context.Context, root string) (*PageCache, error) {
+	bdis, err := os.ReadDir(rootPath(root, "sys/kernel/debug/bdi"))
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	mounts, sources, err := mountsByDevice(root)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := &PageCache{Note: pageCacheNote}
+	var dirtyTotal uint64
+	for _, bdi := range bdis {
+		dev := bdi.Name()
+		if len(mounts[dev]) == 0 {
+			continue
+		}
+		stats, err := readBDIStats(rootPath(root, "sys/kernel/debug/bdi", dev, "stats"))
+		if err != nil {
+			continue
+		}
+		pc.Devices = append(pc.Devices, DevicePageCache{
+			Device:         dev,
+			Source:         sources[dev],
+			Mounts:         mounts[dev],
+			DirtyBytes:     stats["BdiReclaimable"] * 1024,
+			WritebackBytes: stats["BdiWriteback"] * 1024,
+		})
+		dirtyTotal += (stats["BdiReclaimable"] + stats["BdiWriteback"]) * 1024
+	}
+	if len(pc.Devices) == 0 {
+		return nil, nil
+	}
+	for i := range pc.Devices {
+		d := &pc.Devices[i]
+		if dirtyTotal > 0 {
+			d.DirtySharePercent = math.Round(float64(d.DirtyBytes+d.WritebackBytes)/float64(dirtyTotal)*1000) / 10
+		}
+	}
+	slices.SortFunc(pc.Devices, func(a, b DevicePageCache) int {
+		return cmp.Compare(b.DirtyBytes+b.WritebackBytes, a.DirtyBytes+a.WritebackBytes)
+	})
+
+	if stat, err := readKeyValues(rootPath(root, "sys/fs/cgroup/memory.stat")); err == nil {
+		pc.Source = "memory.stat"
+		pc.FileBytes, pc.DirtyBytes, pc.WritebackBytes = stat["file"], stat["file_dirty"], stat["file_writeback"]
+		return pc, nil
+	}
+	meminfo, err := readMeminfo(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	pc.Source = "meminfo"
+	pc.FileBytes = uint64(meminfo["Cached"]) * 1024
+	pc.DirtyBytes = uint64(meminfo["Dirty"]) * 1024
+	pc.WritebackBytes = uint64(meminfo["Writeback"]) * 1024
+	return pc, nil
+}
+
+// mountsByDevice maps "maj:min" to its mountpoints and source from
+// /proc/self/mountinfo.
+func mountsByDevice(root string) (mounts map[string][]string, sources map[string]string, err error) {
+	data, err := os.ReadFile(rootPath(root, "proc/self/mountinfo"))
+	if err != nil {
+		return nil, nil, err
+	}
+	mounts = make(map[string][]string)
+	sources = make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		pre, post, found := strings.Cut(line, " - ")
+		fields := strings.Fields(pre)
+		extra := strings.Fields(post)
+		if !found || len(fields) < 5 || len(extra) < 2 {
+			continue
+		}
+		dev := fields[2]
+		mounts[dev] = append(mounts[dev], unescapeMountPath(fields[4]))
+		sources[dev] = unescapeMountPath(extra[1])
+	}
+	return mounts, sources, nil
+}
+
+// readBDIStats parses "BdiWriteback:   12 kB" lines into kB values.
+func readBDIStats(path string) (map[string]uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[string]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, found := strings.Cut(line, ":")
+		fields := strings.Fields(value)
+		if !found || len(fields) != 2 || fields[1] != "kB" {
+			continue
+		}
+		if n, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			stats[key] = n
+		}
+	}
+	return stats, nil
+}
+
+// readKeyValues parses "key value" lines as in cgroup memory.stat.
+func readKeyValues(path string) (map[string]uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if n, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = n
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,7 @@
+package sysinfo
+
+// unsupported returns nil: every collector reads Linux /proc, /sys and
+// cgroupfs.
+func unsupported() error {
+	return nil
+}
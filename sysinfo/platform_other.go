@@ -0,0 +1,16 @@
+//go:build !linux
+
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// unsupported makes each collector fail with a clear error instead of a
+// missing /proc file, so the report still renders (every section marked
+// unavailable) on the developer's Mac or Windows box.
+func unsupported() error {
+	return fmt.Errorf("not supported on %s: %w", runtime.GOOS, errors.ErrUnsupported)
+}
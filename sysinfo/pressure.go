@@ -0,0 +1,110 @@
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// pressureResources are the PSI files read, in output order.
+var pressureResources = []string{"cpu", "memory", "io"}
+
+// Pressure is the pressure stall information (PSI) of each resource, keyed
+// cpu, memory and io.
+type Pressure map[string]ResourcePressure
+
+// ResourcePressure is one pressure file: Some is the share of time at
+// least one task stalled on the resource, Full the share all non-idle
+// tasks did. Kernels before 5.13 have no full line for cpu.
+type ResourcePressure struct {
+	Some *PSI `json:"some,omitempty"`
+	Full *PSI `json:"full,omitempty"`
+}
+
+// PSI holds the stall percentages averaged over 10s, 60s and 300s and the
+// total stall time.
+type PSI struct {
+	Avg10     float64 `json:"avg10"`
+	Avg60     float64 `json:"avg60"`
+	Avg300    float64 `json:"avg300"`
+	TotalUsec uint64  `json:"total_usec"`
+}
+
+// CollectPressure reads /proc/pressure. It returns nil without an error
+// when the kernel has no PSI (before Linux 4.20, or booted with psi=0).
+func CollectPressure(root string) (Pressure, error) {
+	return readPressure(rootPath(root, "proc/pressure"), "%s")
+}
+
+// readPressure reads the files named by format (cpu, memory, io) in dir.
+// Missing files and files PSI is disabled for are left out.
+func readPressure(dir, format string) (Pressure, error) {
+	p := make(Pressure)
+	var errs []error
+	for _, res := range pressureResources {
+		name := fmt.Sprintf(format, res)
+		data, err := readTrim(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.EOPNOTSUPP) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rp, err := parsePressure(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		p[res] = rp
+	}
+	if len(p) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	return p, errors.Join(errs...)
+}
+
+// parsePressure parses lines of the form
+//
+//	some avg10=0.12 avg60=0.05 avg300=0.01 total=123456
+func parsePressure(data string) (ResourcePressure, error) {
+	var rp ResourcePressure
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 5 {
+			continue
+		}
+		psi := &PSI{}
+		for _, f := range fields[1:] {
+			key, value, _ := strings.Cut(f, "=")
+			var err error
+			switch key {
+			case "avg10":
+				psi.Avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				psi.Avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				psi.Avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				psi.TotalUsec, err = strconv.ParseUint(value, 10, 64)
+			}
+			if err != nil {
+				return rp, fmt.Errorf("malformed line %q", line)
+			}
+		}
+		switch fields[0] {
+		case "some":
+			rp.Some = psi
+		case "full":
+			rp.Full = psi
+		}
+	}
+	if rp.Some == nil {
+		return rp, errors.New("no some line")
+	}
+	return rp, nil
+}
@@ -0,0 +1,82 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePressure(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    ResourcePressure
+		wantErr bool
+	}{
+		{
+			name: "some and full",
+			data: "some avg10=1.23 avg60=0.45 avg300=0.06 total=123456789\n" +
+				"full avg10=0.50 avg60=0.10 avg300=0.00 total=42\n",
+			want: ResourcePressure{
+				Some: &PSI{Avg10: 1.23, Avg60: 0.45, Avg300: 0.06, TotalUsec: 123456789},
+				Full: &PSI{Avg10: 0.5, Avg60: 0.1, TotalUsec: 42},
+			},
+		},
+		{
+			// cpu before Linux 5.13.
+			name: "some only",
+			data: "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+			want: ResourcePressure{Some: &PSI{}},
+		},
+		{
+			// cpu since 5.13 at the system level, where full is always zero.
+			name: "cpu full of zeros",
+			data: "some avg10=3.00 avg60=2.00 avg300=1.00 total=900\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+			want: ResourcePressure{Some: &PSI{Avg10: 3, Avg60: 2, Avg300: 1, TotalUsec: 900}, Full: &PSI{}},
+		},
+		{
+			name: "unknown lines are skipped",
+			data: "\nsome avg10=0.10 avg60=0.20 avg300=0.30 total=7\nnew kind of line\n",
+			want: ResourcePressure{Some: &PSI{Avg10: 0.1, Avg60: 0.2, Avg300: 0.3, TotalUsec: 7}},
+		},
+		{name: "full only", data: "full avg10=0.00 avg60=0.00 avg300=0.00 total=0\n", wantErr: true},
+		{name: "empty", data: "", wantErr: true},
+		{name: "bad number", data: "some avg10=x avg60=0.00 avg300=0.00 total=0\n", wantErr: true},
+		{name: "negative total", data: "some avg10=0.00 avg60=0.00 avg300=0.00 total=-1\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePressure(tt.data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parsePressure = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollectPressure(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"proc/pressure/cpu":    "some avg10=2.50 avg60=1.00 avg300=0.25 total=5000\n",
+		"proc/pressure/memory": "some avg10=0.00 avg60=0.00 avg300=0.00 total=10\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=5\n",
+		"proc/pressure/io":     "garbage\n",
+	})
+	p, err := CollectPressure(root)
+	if err == nil {
+		t.Error("malformed io file without an error")
+	}
+	want := Pressure{
+		"cpu":    {Some: &PSI{Avg10: 2.5, Avg60: 1, Avg300: 0.25, TotalUsec: 5000}},
+		"memory": {Some: &PSI{TotalUsec: 10}, Full: &PSI{TotalUsec: 5}},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("pressure = %+v, want %+v", p, want)
+	}
+
+	// Without /proc/pressure the kernel has no PSI: no section, no error.
+	p, err = CollectPressure(t.TempDir())
+	if p != nil || err != nil {
+		t.Errorf("no /proc/pressure: %+v, %v; want nil, nil", p, err)
+	}
+}
@@ -0,0 +1,333 @@
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+// ErrNoProcess is returned when the inspected pid does not exist.
+var ErrNoProcess = errors.New("no such process")
+
+// ErrProcessExited is returned when the inspected pid existed when
+// collection started but was gone before it finished.
+var ErrProcessExited = errors.New("exited during collection")
+
+// procDir returns the /proc directory of pid, or of the current process
+// when pid is 0.
+func procDir(root string, pid int, elem ...string) string {
+	name := "self"
+	if pid != 0 {
+		name = strconv.Itoa(pid)
+	}
+	return rootPath(root, append([]string{"proc", name}, elem...)...)
+}
+
+// processError turns raw /proc read failures for a foreign pid into
+// "process <pid>: no such process" and "process <pid>: permission denied".
+func processError(pid int, err error) error {
+	if err == nil || pid == 0 {
+		return err
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("process %d: %w", pid, ErrNoProcess)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("process %d: %w (it belongs to another user; run as that user or root)", pid, fs.ErrPermission)
+	}
+	return fmt.Errorf("process %d: %w", pid, err)
+}
+
+// CountFDs returns the number of open file descriptors of pid (0 for self).
+func CountFDs(root string, pid int) (int, error) {
+	entries, err := os.ReadDir(procDir(root, pid, "fd"))
+	if err != nil {
+		return 0, processError(pid, err)
+	}
+	return len(entries), nil
+}
+
+// ReadRSS returns VmRSS of pid (0 for self) as reported by /proc.
+func ReadRSS(ctx context.Context, root string, pid int) (int, error) {
+	status, err := readProcStatus(ctx, root, pid)
+	if err != nil {
+		return 0, err
+	}
+	rss, ok := statusKB(status, "VmRSS")
+	if !ok {
+		return 0, fmt.Errorf("VmRSS not found")
+	}
+	return int(rss / 1024), nil
+}
+
+// ReadPeakRSS returns VmHWM of pid (0 for self) in bytes.
+func ReadPeakRSS(ctx context.Context, root string, pid int) (uint64, error) {
+	status, err := readProcStatus(ctx, root, pid)
+	if err != nil {
+		return 0, err
+	}
+	hwm, ok := statusKB(status, "VmHWM")
+	if !ok {
+		return 0, fmt.Errorf("VmHWM not found")
+	}
+	return uint64(hwm), nil
+}
+
+// readProcStatus parses /proc/<pid>/status into its "Key: value" pairs.
+func readProcStatus(ctx context.Context, root string, pid int) (map[string]string, error) {
+	data, err := readVerified(ctx, procDir(root, pid, "status"), hasLines("Name:", "Pid:", "Threads:"))
+	if err != nil {
+		return nil, processError(pid, err)
+	}
+	status := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if found {
+			status[key] = strings.TrimSpace(value)
+		}
+	}
+	return status, nil
+}
+
+// statusKB returns a "<n> kB" status value in bytes.
+func statusKB(status map[string]string, key string) (int64, bool) {
+	value, ok := status[key]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimSuffix(value, " kB"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n * 1024, true
+}
+
+type RSSBreakdown struct {
+	TotalBytes int64     `json:"total_bytes"`
+	AnonBytes  int64     `json:"anon_bytes"`
+	FileBytes  int64     `json:"file_bytes"`
+	ShmemBytes int64     `json:"shmem_bytes"`
+	Split      bool      `json:"split"`
+	Note       string    `json:"note,omitempty"`
+	Delta      *RSSDelta `json:"delta,omitempty"`
+}
+
+type RSSDelta struct {
+	TotalBytes int64 `json:"total_bytes"`
+	AnonBytes  int64 `json:"anon_bytes"`
+	FileBytes  int64 `json:"file_bytes"`
+	ShmemBytes int64 `json:"shmem_bytes"`
+}
+
+// ReadRSSBreakdown splits the resident set of pid into anonymous (heap,
+// stacks), file-backed and shared memory pages. Kernels before 4.5 only
+// report the total; Split is false then.
+func ReadRSSBreakdown(ctx context.Context, root string, pid int) (*RSSBreakdown, error) {
+	status, err := readProcStatus(ctx, root, pid)
+	if err != nil {
+		return nil, err
+	}
+	total, ok := statusKB(status, "VmRSS")
+	if !ok {
+		return nil, fmt.Errorf("VmRSS not found")
+	}
+	rss := &RSSBreakdown{TotalBytes: total}
+	anon, hasAnon := statusKB(status, "RssAnon")
+	file, hasFile := statusKB(status, "RssFile")
+	shmem, hasShmem := statusKB(status, "RssShmem")
+	if !hasAnon || !hasFile || !hasShmem {
+		rss.Note = "kernel does not split RSS by type; only the total is available"
+		return rss, nil
+	}
+	rss.AnonBytes, rss.FileBytes, rss.ShmemBytes = anon, file, shmem
+	rss.Split = true
+	return rss, nil
+}
+
+// Sub returns the change from prev to b.
+func (b *RSSBreakdown) Sub(prev *RSSBreakdown) RSSDelta {
+	return RSSDelta{
+		TotalBytes: b.TotalBytes - prev.TotalBytes,
+		AnonBytes:  b.AnonBytes - prev.AnonBytes,
+		FileBytes:  b.FileBytes - prev.FileBytes,
+		ShmemBytes: b.ShmemBytes - prev.ShmemBytes,
+	}
+}
+
+// Growing names the RSS component that grew the most, or "" if none grew.
+func (d RSSDelta) Growing() string {
+	name, max := "", int64(0)
+	for _, c := range []struct {
+		name  string
+		bytes int64
+	}{{"anon", d.AnonBytes}, {"file", d.FileBytes}, {"shmem", d.ShmemBytes}} {
+		if c.bytes > max {
+			name, max = c.name, c.bytes
+		}
+	}
+	return name
+}
+
+// ExePath returns the executable path of pid (0 for self).
+func ExePath(root string, pid int) (string, error) {
+	path, err := os.Readlink(procDir(root, pid, "exe"))
+	if err != nil {
+		return "", processError(pid, err)
+	}
+	return path, nil
+}
+
+type RSSGrowth struct {
+	IntervalSeconds      float64 `json:"interval_seconds"`
+	StartBytes           int64   `json:"start_bytes"`
+	EndBytes             int64   `json:"end_bytes"`
+	DeltaBytes           int64   `json:"delta_bytes"`
+	RSSGrowthBytesPerSec float64 `json:"rss_growth_bytes_per_sec"`
+}
+
+// SampleRSSGrowth reads VmRSS of pid twice, interval apart, and reports how
+// fast it grew. A steadily positive rate is a cheap memory-leak indicator.
+func SampleRSSGrowth(ctx context.Context, root string, pid int, interval time.Duration) (*RSSGrowth, error) {
+	return sampleRSSGrowth(ctx, clock.Real, root, pid, interval)
+}
+
+func sampleRSSGrowth(ctx context.Context, clk clock.Clock, root string, pid int, interval time.Duration) (*RSSGrowth, error) {
+	start, err := ReadRSS(ctx, root, pid)
+	if err != nil {
+		return nil, err
+	}
+	begin := clk.Now()
+	clk.Sleep(interval)
+	end, err := ReadRSS(ctx, root, pid)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := clk.Now().Sub(begin).Seconds()
+
+	growth := &RSSGrowth{
+		IntervalSeconds: elapsed,
+		StartBytes:      int64(start) * 1024,
+		EndBytes:        int64(end) * 1024,
+	}
+	growth.DeltaBytes = growth.EndBytes - growth.StartBytes
+	if elapsed > 0 {
+		growth.RSSGrowthBytesPerSec = float64(growth.DeltaBytes) / elapsed
+	}
+	return growth, nil
+}
+
+type ProcessInfo struct {
+	CPUTime      *CPUTime       `json:"cpu_time,omitempty"`
+	CtxSwitches  *CtxSwitches   `json:"ctxt_switches,omitempty"`
+	ThreadsTotal *int           `json:"threads_total,omitempty"`
+	ThreadStates map[string]int `json:"thread_states,omitempty"`
+	// PeakRSSBytes is VmHWM, the largest resident set the process has had.
+	PeakRSSBytes *uint64 `json:"peak_rss_bytes,omitempty"`
+}
+
+type CPUTime struct {
+	UserSeconds           float64 `json:"user_seconds"`
+	SystemSeconds         float64 `json:"system_seconds"`
+	ChildrenUserSeconds   float64 `json:"children_user_seconds"`
+	ChildrenSystemSeconds float64 `json:"children_system_seconds"`
+
+	UserTicksTotal           *uint64 `json:"user_ticks_total,omitempty"`
+	SystemTicksTotal         *uint64 `json:"system_ticks_total,omitempty"`
+	ChildrenUserTicksTotal   *uint64 `json:"children_user_ticks_total,omitempty"`
+	ChildrenSystemTicksTotal *uint64 `json:"children_system_ticks_total,omitempty"`
+
+	ticks [4]uint64
+}
+
+// IncludeRawCounters fills the *_ticks_total fields with the clock tick
+// counts the seconds were computed from.
+func (t *CPUTime) IncludeRawCounters() {
+	t.UserTicksTotal = &t.ticks[0]
+	t.SystemTicksTotal = &t.ticks[1]
+	t.ChildrenUserTicksTotal = &t.ticks[2]
+	t.ChildrenSystemTicksTotal = &t.ticks[3]
+}
+
+// ReadCPUTime returns the cumulative user and system CPU time of pid and of
+// its waited-for children (utime, stime, cutime, cstime in proc(5)).
+func ReadCPUTime(ctx context.Context, root string, pid int) (*CPUTime, error) {
+	stat, err := readProcStat(ctx, root, pid)
+	if err != nil {
+		return nil, err
+	}
+	hz := clockTicks(root)
+	t := &CPUTime{}
+	for i := range t.ticks {
+		t.ticks[i] = stat.uintField(14 + i)
+	}
+	t.UserSeconds = float64(t.ticks[0]) / hz
+	t.SystemSeconds = float64(t.ticks[1]) / hz
+	t.ChildrenUserSeconds = float64(t.ticks[2]) / hz
+	t.ChildrenSystemSeconds = float64(t.ticks[3]) / hz
+	return t, nil
+}
+
+type CtxSwitches struct {
+	Voluntary          uint64   `json:"voluntary"`
+	Nonvoluntary       uint64   `json:"nonvoluntary"`
+	VoluntaryPerSec    *float64 `json:"voluntary_per_sec,omitempty"`
+	NonvoluntaryPerSec *float64 `json:"nonvoluntary_per_sec,omitempty"`
+	VoluntaryTotal     *uint64  `json:"voluntary_total,omitempty"`
+	NonvoluntaryTotal  *uint64  `json:"nonvoluntary_total,omitempty"`
+}
+
+// IncludeRawCounters fills the *_total fields next to the rates. With a
+// delta they come from the second sample, the end of the rate interval.
+func (c *CtxSwitches) IncludeRawCounters() {
+	c.VoluntaryTotal = &c.Voluntary
+	c.NonvoluntaryTotal = &c.Nonvoluntary
+}
+
+// ReadCtxSwitches returns the voluntary (blocked/waiting) and nonvoluntary
+// (preempted) context switch counts of pid. With delta > 0 the counts are
+// sampled twice, delta apart, and the per-second rates are filled in.
+func ReadCtxSwitches(ctx context.Context, root string, pid int, delta time.Duration) (*CtxSwitches, error) {
+	return sampleCtxSwitches(ctx, clock.Real, root, pid, delta)
+}
+
+func sampleCtxSwitches(ctx context.Context, clk clock.Clock, root string, pid int, delta time.Duration) (*CtxSwitches, error) {
+	first, err := readCtxSwitches(ctx, root, pid)
+	if err != nil || delta <= 0 {
+		return first, err
+	}
+	begin := clk.Now()
+	clk.Sleep(delta)
+	second, err := readCtxSwitches(ctx, root, pid)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := clk.Now().Sub(begin).Seconds()
+	voluntary := float64(second.Voluntary-first.Voluntary) / elapsed
+	nonvoluntary := float64(second.Nonvoluntary-first.Nonvoluntary) / elapsed
+	second.VoluntaryPerSec = &voluntary
+	second.NonvoluntaryPerSec = &nonvoluntary
+	return second, nil
+}
+
+func readCtxSwitches(ctx context.Context, root string, pid int) (*CtxSwitches, error) {
+	status, err := readProcStatus(ctx, root, pid)
+	if err != nil {
+		return nil, err
+	}
+	voluntary, err := strconv.ParseUint(status["voluntary_ctxt_switches"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("voluntary_ctxt_switches: %w", err)
+	}
+	nonvoluntary, err := strconv.ParseUint(status["nonvoluntary_ctxt_switches"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("nonvoluntary_ctxt_switches: %w", err)
+	}
+	return &CtxSwitches{Voluntary: voluntary, Nonvoluntary: nonvoluntary}, nil
+}
@@ -0,0 +1,57 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func procStatusWithRSS(kb int) string {
+	return fmt.Sprintf("Name:\tapp\nPid:\t42\nThreads:\t1\nVmHWM:\t%d kB\nVmRSS:\t%d kB\n", kb, kb)
+}
+
+func TestSampleRSSGrowthWindow(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"proc/42/status": procStatusWithRSS(1000)})
+	clk := newSampleClock(func(int) {
+		writeTree(t, root, map[string]string{"proc/42/status": procStatusWithRSS(3000)})
+	})
+
+	growth, err := sampleRSSGrowth(context.Background(), clk, root, 42, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clk.sleeps != 1 {
+		t.Errorf("slept %d times, want 1", clk.sleeps)
+	}
+	if growth.IntervalSeconds != 10 {
+		t.Errorf("IntervalSeconds = %v, want 10", growth.IntervalSeconds)
+	}
+	if growth.DeltaBytes != 2000*1024 {
+		t.Errorf("DeltaBytes = %d, want %d", growth.DeltaBytes, 2000*1024)
+	}
+	if want := float64(2000*1024) / 10; growth.RSSGrowthBytesPerSec != want {
+		t.Errorf("RSSGrowthBytesPerSec = %v, want %v", growth.RSSGrowthBytesPerSec, want)
+	}
+}
+
+func TestCollectRSSSampleUsesClock(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"proc/42/status": procStatusWithRSS(1000)})
+	clk := newSampleClock(nil)
+	start := clk.Now()
+
+	// A minute-long window returns at once and covers exactly a minute of
+	// the injected clock.
+	info, _ := CollectWith(Options{Root: root, PID: 42, RSSSample: time.Minute, Clock: clk, Fields: []string{"rss_growth"}})
+	if info.RSSGrowth == nil {
+		t.Fatalf("no rss_growth: %v", info.Errors)
+	}
+	if info.RSSGrowth.IntervalSeconds != 60 {
+		t.Errorf("IntervalSeconds = %v, want 60", info.RSSGrowth.IntervalSeconds)
+	}
+	if got := clk.Now().Sub(start); got != time.Minute {
+		t.Errorf("clock advanced %v, want 1m", got)
+	}
+}
@@ -0,0 +1,113 @@
+package sysinfo
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ProcessSummary is one process as listed by ListProcesses. UID is the
+// effective uid, as ps shows it; User is empty when /etc/passwd has no
+// entry for it.
+type ProcessSummary struct {
+	PID        int     `json:"pid"`
+	PPID       int     `json:"ppid"`
+	Comm       string  `json:"comm"`
+	Cmdline    string  `json:"cmdline"`
+	State      string  `json:"state"`
+	UID        int     `json:"uid"`
+	User       string  `json:"user,omitempty"`
+	RSSBytes   uint64  `json:"rss_bytes"`
+	VSZBytes   uint64  `json:"vsz_bytes"`
+	Threads    int     `json:"threads"`
+	FDs        *int    `json:"fds,omitempty"`
+	AgeSeconds float64 `json:"age_seconds"`
+}
+
+// ListProcesses reads every process in /proc once, sorted by pid.
+// Processes that exit during the walk are left out. Counting fds means a
+// readdir per process, so it is done only with fds set; it needs root for
+// processes of other users, whose FDs stay nil otherwise. Cmdline has its
+// arguments joined by spaces and is empty for kernel threads.
+func ListProcesses(ctx context.Context, root string, fds bool) ([]ProcessSummary, error) {
+	entries, err := os.ReadDir(rootPath(root, "proc"))
+	if err != nil {
+		return nil, err
+	}
+	uptime, _, err := CollectUptime(root)
+	if err != nil {
+		return nil, err
+	}
+	users := readUserNames(root)
+	hz := clockTicks(root)
+	pageSize := uint64(os.Getpagesize())
+
+	var procs []ProcessSummary
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		st, err := readProcStat(ctx, root, pid)
+		if err != nil {
+			continue
+		}
+		status, err := readProcStatus(ctx, root, pid)
+		if err != nil {
+			continue
+		}
+		uids, err := statusIDs(status, "Uid")
+		if err != nil || len(uids) < 2 {
+			continue
+		}
+		p := ProcessSummary{
+			PID:        pid,
+			Comm:       st.Comm,
+			State:      st.State(),
+			UID:        uids[1],
+			User:       users[uids[1]],
+			RSSBytes:   st.uintField(24) * pageSize,
+			VSZBytes:   st.uintField(23),
+			Threads:    int(st.uintField(20)),
+			AgeSeconds: max(uptime-float64(st.uintField(22))/hz, 0),
+		}
+		p.PPID, _ = strconv.Atoi(st.field(4))
+		if cmdline, err := os.ReadFile(procDir(root, pid, "cmdline")); err == nil {
+			p.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+		}
+		if fds {
+			if n, err := CountFDs(root, pid); err == nil {
+				p.FDs = &n
+			}
+		}
+		procs = append(procs, p)
+	}
+	slices.SortFunc(procs, func(a, b ProcessSummary) int { return a.PID - b.PID })
+	return procs, nil
+}
+
+// readUserNames maps uids to names from /etc/passwd. Users known only to
+// NSS (LDAP, systemd-homed) are not resolved.
+func readUserNames(root string) map[int]string {
+	names := make(map[int]string)
+	data, err := os.ReadFile(rootPath(root, "etc/passwd"))
+	if err != nil {
+		return names
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		f := strings.Split(line, ":")
+		if len(f) < 3 {
+			continue
+		}
+		uid, err := strconv.Atoi(f[2])
+		if err != nil {
+			continue
+		}
+		if _, dup := names[uid]; !dup {
+			names[uid] = f[0]
+		}
+	}
+	return names
+}
@@ -0,0 +1,88 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Security is the security context of the inspected process, from
+// /proc/<pid>/status, plus the privileged file scan when requested.
+// Capabilities are named as in capabilities(7). Umask (Linux 4.7),
+// NoNewPrivs (4.10) and Seccomp (3.8) are left empty on kernels that do
+// not report them.
+type Security struct {
+	RealUID      int      `json:"real_uid"`
+	EffectiveUID int      `json:"effective_uid"`
+	RealGID      int      `json:"real_gid"`
+	EffectiveGID int      `json:"effective_gid"`
+	Groups       []int    `json:"groups"`
+	Umask        string   `json:"umask,omitempty"`
+	CapEff       []string `json:"cap_effective"`
+	CapPrm       []string `json:"cap_permitted"`
+	CapBnd       []string `json:"cap_bounding"`
+	NoNewPrivs   *bool    `json:"no_new_privs,omitempty"`
+	// Seccomp is "disabled", "strict" or "filter".
+	Seccomp string          `json:"seccomp,omitempty"`
+	Files   *PrivilegedScan `json:"files,omitempty"`
+}
+
+var seccompModes = map[string]string{"0": "disabled", "1": "strict", "2": "filter"}
+
+// ReadSecurityContext reads the ids, capabilities, no_new_privs flag and
+// seccomp mode of pid (0 for self).
+func ReadSecurityContext(ctx context.Context, root string, pid int) (*Security, error) {
+	status, err := readProcStatus(ctx, root, pid)
+	if err != nil {
+		return nil, err
+	}
+	sec := &Security{Groups: []int{}, Umask: status["Umask"]}
+	uids, errUID := statusIDs(status, "Uid")
+	gids, errGID := statusIDs(status, "Gid")
+	if errUID != nil || errGID != nil || len(uids) < 2 || len(gids) < 2 {
+		return nil, fmt.Errorf("malformed Uid/Gid lines in status")
+	}
+	sec.RealUID, sec.EffectiveUID = uids[0], uids[1]
+	sec.RealGID, sec.EffectiveGID = gids[0], gids[1]
+	if sec.Groups, err = statusIDs(status, "Groups"); err != nil {
+		return nil, err
+	}
+
+	for _, c := range []struct {
+		key string
+		dst *[]string
+	}{{"CapEff", &sec.CapEff}, {"CapPrm", &sec.CapPrm}, {"CapBnd", &sec.CapBnd}} {
+		mask, err := strconv.ParseUint(status[c.key], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed %s in status: %q", c.key, status[c.key])
+		}
+		*c.dst = []string{}
+		for _, name := range capNames(mask) {
+			*c.dst = append(*c.dst, strings.ToUpper(name))
+		}
+	}
+
+	if v, ok := status["NoNewPrivs"]; ok {
+		nnp := v == "1"
+		sec.NoNewPrivs = &nnp
+	}
+	if v, ok := status["Seccomp"]; ok {
+		sec.Seccomp = seccompModes[v]
+	}
+	return sec, nil
+}
+
+// statusIDs parses a whitespace-separated list of ids such as the Uid or
+// Groups status line.
+func statusIDs(status map[string]string, key string) ([]int, error) {
+	ids := []int{}
+	for _, f := range strings.Fields(status[key]) {
+		id, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("malformed %s in status: %q", key, status[key])
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,60 @@
+package sysinfo
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// dockerCaps are the capabilities Docker grants by default, CapEff
+// 00000000a80425fb.
+var dockerCaps = []string{
+	"CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_FOWNER", "CAP_FSETID", "CAP_KILL",
+	"CAP_SETGID", "CAP_SETUID", "CAP_SETPCAP", "CAP_NET_BIND_SERVICE",
+	"CAP_NET_RAW", "CAP_SYS_CHROOT", "CAP_MKNOD", "CAP_AUDIT_WRITE", "CAP_SETFCAP",
+}
+
+func securityStatus(capEff string) string {
+	return "Name:\tapp\nUmask:\t0022\nPid:\t42\nUid:\t1000\t0\t0\t0\nGid:\t1000\t1000\t1000\t1000\n" +
+		"Groups:\t4 27 \nThreads:\t1\n" +
+		"CapEff:\t" + capEff + "\nCapPrm:\t80000200a80425fb\nCapBnd:\t000001ffffffffff\n" +
+		"NoNewPrivs:\t1\nSeccomp:\t2\n"
+}
+
+func TestReadSecurityContextCaps(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"proc/42/status": securityStatus("00000000a80425fb")})
+	sec, err := ReadSecurityContext(context.Background(), root, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sec.CapEff, dockerCaps) {
+		t.Errorf("CapEff = %q, want %q", sec.CapEff, dockerCaps)
+	}
+	// Bits past CAP_CHECKPOINT_RESTORE (40) are named by number.
+	if want := slices.Concat(dockerCaps, []string{"CAP_41", "CAP_63"}); !reflect.DeepEqual(sec.CapPrm, want) {
+		t.Errorf("CapPrm = %q, want %q", sec.CapPrm, want)
+	}
+	if n := len(sec.CapBnd); n != 41 || sec.CapBnd[40] != "CAP_CHECKPOINT_RESTORE" {
+		t.Errorf("CapBnd = %q, want all 41 up to CAP_CHECKPOINT_RESTORE", sec.CapBnd)
+	}
+	if sec.RealUID != 1000 || sec.EffectiveUID != 0 || !reflect.DeepEqual(sec.Groups, []int{4, 27}) {
+		t.Errorf("uids %d/%d, groups %v", sec.RealUID, sec.EffectiveUID, sec.Groups)
+	}
+	if sec.Umask != "0022" || sec.NoNewPrivs == nil || !*sec.NoNewPrivs || sec.Seccomp != "filter" {
+		t.Errorf("umask %q, no_new_privs %v, seccomp %q", sec.Umask, sec.NoNewPrivs, sec.Seccomp)
+	}
+
+	// No capabilities at all is an empty list, not null.
+	writeTree(t, root, map[string]string{"proc/42/status": securityStatus("0000000000000000")})
+	if sec, err = ReadSecurityContext(context.Background(), root, 42); err != nil || sec.CapEff == nil || len(sec.CapEff) != 0 {
+		t.Errorf("CapEff = %#v, %v; want an empty list", sec.CapEff, err)
+	}
+
+	writeTree(t, root, map[string]string{"proc/42/status": securityStatus("zz")})
+	if _, err = ReadSecurityContext(context.Background(), root, 42); err == nil || !strings.Contains(err.Error(), "CapEff") {
+		t.Errorf("malformed CapEff: error %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package sysinfo
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultClockTicks = 100
+
+// procStat is a parsed /proc/<pid>/stat line. comm may contain spaces and
+// parentheses, so it is taken from between the first "(" and the last ")";
+// fields holds everything after it, starting with the state (field 3).
+type procStat struct {
+	PID    int
+	Comm   string
+	fields []string
+}
+
+func readProcStat(ctx context.Context, root string, pid int) (*procStat, error) {
+	data, err := readVerified(ctx, procDir(root, pid, "stat"), func(data string) error {
+		if !strings.HasSuffix(data, "\n") || !strings.Contains(data, ") ") {
+			return errors.New("truncated stat line")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, processError(pid, err)
+	}
+	return parseProcStat(data)
+}
+
+func parseProcStat(line string) (*procStat, error) {
+	open := strings.IndexByte(line, '(')
+	close := strings.LastIndexByte(line, ')')
+	if open < 0 || close < open {
+		return nil, fmt.Errorf("malformed stat line")
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(line[:open]))
+	if err != nil {
+		return nil, fmt.Errorf("malformed stat pid: %w", err)
+	}
+	return &procStat{
+		PID:    pid,
+		Comm:   line[open+1 : close],
+		fields: strings.Fields(line[close+1:]),
+	}, nil
+}
+
+// field returns stat field n using the 1-based numbering of proc(5).
+func (s *procStat) field(n int) string {
+	i := n - 3
+	if i < 0 || i >= len(s.fields) {
+		return ""
+	}
+	return s.fields[i]
+}
+
+func (s *procStat) uintField(n int) uint64 {
+	v, _ := strconv.ParseUint(s.field(n), 10, 64)
+	return v
+}
+
+func (s *procStat) State() string { return s.field(3) }
+
+// clockTicks returns USER_HZ, the unit of the stat time fields. It is
+// read from AT_CLKTCK in the auxiliary vector, which is what
+// sysconf(_SC_CLK_TCK) returns, and defaults to 100.
+func clockTicks(root string) float64 {
+	const atClkTck = 17
+	data, err := os.ReadFile(rootPath(root, "proc/self/auxv"))
+	if err != nil {
+		return defaultClockTicks
+	}
+	for i := 0; i+16 <= len(data); i += 16 {
+		key := binary.NativeEndian.Uint64(data[i:])
+		if key == atClkTck {
+			if v := binary.NativeEndian.Uint64(data[i+8:]); v > 0 {
+				return float64(v)
+			}
+		}
+	}
+	return defaultClockTicks
+}
@@ -0,0 +1,59 @@
+package sysinfo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		line   string
+		pid    int
+		comm   string
+		fields []string
+	}{
+		{"42 (app) S 1 42 42 0\n", 42, "app", []string{"S", "1", "42", "42", "0"}},
+		// comm is whatever the process chose; only the last ")" ends it.
+		{"7 (a) b) R 1 7\n", 7, "a) b", []string{"R", "1", "7"}},
+		{"8 (x (y) z) S 2\n", 8, "x (y) z", []string{"S", "2"}},
+		{"9 (tmux: server) S 1\n", 9, "tmux: server", []string{"S", "1"}},
+		{"10 () Z 1\n", 10, "", []string{"Z", "1"}},
+	}
+	for _, tt := range tests {
+		st, err := parseProcStat(tt.line)
+		if err != nil {
+			t.Errorf("parseProcStat(%q): %v", tt.line, err)
+			continue
+		}
+		if st.PID != tt.pid || st.Comm != tt.comm || !reflect.DeepEqual(st.fields, tt.fields) {
+			t.Errorf("parseProcStat(%q) = %d %q %q, want %d %q %q", tt.line, st.PID, st.Comm, st.fields, tt.pid, tt.comm, tt.fields)
+		}
+	}
+
+	for _, line := range []string{"", "42 app S 1\n", "42 ) (app S\n", "x (app) S 1\n"} {
+		if st, err := parseProcStat(line); err == nil {
+			t.Errorf("parseProcStat(%q) = %+v, want error", line, st)
+		}
+	}
+}
+
+func TestProcStatField(t *testing.T) {
+	st, err := parseProcStat("7 (a) b) R 1 7 7 0 -1 4194560 100 0 0 0 10 5 0 0 20 0 3 0 12345 1000000 256\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.State() != "R" || st.field(4) != "1" {
+		t.Errorf("state %q, ppid %q; want R and 1", st.State(), st.field(4))
+	}
+	if got := st.uintField(20); got != 3 {
+		t.Errorf("num_threads = %d, want 3", got)
+	}
+	if got := st.uintField(24); got != 256 {
+		t.Errorf("rss = %d, want 256", got)
+	}
+	// tpgid is -1 without a controlling terminal; fields past the end
+	// are empty.
+	if st.uintField(8) != 0 || st.field(2) != "" || st.field(52) != "" {
+		t.Errorf("tpgid = %d, field(2) = %q, field(52) = %q", st.uintField(8), st.field(2), st.field(52))
+	}
+}
@@ -0,0 +1,105 @@
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// TreeProcess is one process of the tree under the inspected pid, in
+// depth-first order; Depth is 0 for the root of the tree. FDs is nil when
+// the fd directory is not readable (another user's process).
+type TreeProcess struct {
+	PID      int    `json:"pid"`
+	PPID     int    `json:"ppid"`
+	Depth    int    `json:"depth"`
+	Comm     string `json:"comm"`
+	State    string `json:"state"`
+	RSSBytes uint64 `json:"rss_bytes"`
+	Threads  int    `json:"threads"`
+	FDs      *int   `json:"fds,omitempty"`
+}
+
+// TreeTotal sums a process tree. FDs only counts processes whose fds
+// could be read; FDsUnknown is the number of the others.
+type TreeTotal struct {
+	Processes  int    `json:"processes"`
+	RSSBytes   uint64 `json:"rss_bytes"`
+	Threads    int    `json:"threads"`
+	FDs        int    `json:"fds"`
+	FDsUnknown int    `json:"fds_unknown,omitempty"`
+}
+
+// CollectProcessTree returns pid (0 for self) and all its descendants,
+// found by reading every /proc/<pid>/stat once and linking them by ppid.
+// Processes that exit during the walk are left out together with their
+// subtree, whose members have been reparented by then.
+func CollectProcessTree(ctx context.Context, root string, pid int) ([]TreeProcess, *TreeTotal, error) {
+	self, err := readProcStat(ctx, root, pid)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, err := os.ReadDir(rootPath(root, "proc"))
+	if err != nil {
+		return nil, nil, err
+	}
+	stats := map[int]*procStat{self.PID: self}
+	children := make(map[int][]int)
+	for _, e := range entries {
+		n, err := strconv.Atoi(e.Name())
+		if err != nil || n == self.PID {
+			continue
+		}
+		st, err := readProcStat(ctx, root, n)
+		if err != nil {
+			continue
+		}
+		stats[n] = st
+		ppid, _ := strconv.Atoi(st.field(4))
+		children[ppid] = append(children[ppid], n)
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	var tree []TreeProcess
+	total := &TreeTotal{}
+	var walk func(pid, depth int)
+	walk = func(pid, depth int) {
+		st := stats[pid]
+		p := TreeProcess{
+			PID:      pid,
+			Depth:    depth,
+			Comm:     st.Comm,
+			State:    st.State(),
+			RSSBytes: st.uintField(24) * pageSize,
+			Threads:  int(st.uintField(20)),
+		}
+		p.PPID, _ = strconv.Atoi(st.field(4))
+		fds, err := CountFDs(root, pid)
+		switch {
+		case errors.Is(err, ErrNoProcess):
+			return
+		case err == nil:
+			p.FDs = &fds
+			total.FDs += fds
+		default:
+			total.FDsUnknown++
+		}
+		tree = append(tree, p)
+		total.Processes++
+		total.RSSBytes += p.RSSBytes
+		total.Threads += p.Threads
+		kids := children[pid]
+		slices.Sort(kids)
+		for _, c := range kids {
+			walk(c, depth+1)
+		}
+	}
+	walk(self.PID, 0)
+	if len(tree) == 0 {
+		return nil, nil, processError(pid, fs.ErrNotExist)
+	}
+	return tree, total, nil
+}
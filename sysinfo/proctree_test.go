@@ -0,0 +1,75 @@
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// treeStat is a /proc/<pid>/stat line with the fields CollectProcessTree
+// reads: state, ppid, num_threads (20) and rss in pages (24).
+func treeStat(pid int, comm string, ppid, threads, rssPages int) string {
+	return fmt.Sprintf("%d (%s) S %d %d %d 0 -1 4194560 0 0 0 0 1 1 0 0 20 0 %d 0 100 1000000 %d\n", pid, comm, ppid, pid, pid, threads, rssPages)
+}
+
+func TestCollectProcessTree(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"proc/100/stat":   treeStat(100, "supervisor", 1, 2, 10),
+		"proc/100/fd/0":   "",
+		"proc/100/fd/1":   "",
+		"proc/100/fd/2":   "",
+		"proc/101/stat":   treeStat(101, "a) b", 100, 1, 5),
+		"proc/101/fd/0":   "",
+		"proc/103/stat":   treeStat(103, "worker", 101, 4, 20),
+		"proc/103/fd":     "not a directory", // unreadable, as for another user
+		"proc/102/stat":   treeStat(102, "exiting", 100, 1, 1),
+		"proc/104/stat":   treeStat(104, "orphan", 102, 1, 1),
+		"proc/104/fd/0":   "",
+		"proc/105/status": "",
+		"proc/200/stat":   treeStat(200, "other", 1, 1, 1),
+		"proc/200/fd/0":   "",
+		"proc/net/dev":    "",
+		"proc/self/stat":  treeStat(300, "sysinfo", 1, 1, 1),
+	})
+	tree, total, err := CollectProcessTree(context.Background(), root, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 102 exited between the scan and the walk: its fd directory is gone
+	// and it is left out with 104 under it. 105 was already gone at the
+	// scan and 200 is not a descendant.
+	page := uint64(os.Getpagesize())
+	want := []TreeProcess{
+		{PID: 100, PPID: 1, Depth: 0, Comm: "supervisor", State: "S", RSSBytes: 10 * page, Threads: 2, FDs: ptr(3)},
+		{PID: 101, PPID: 100, Depth: 1, Comm: "a) b", State: "S", RSSBytes: 5 * page, Threads: 1, FDs: ptr(1)},
+		{PID: 103, PPID: 101, Depth: 2, Comm: "worker", State: "S", RSSBytes: 20 * page, Threads: 4},
+	}
+	if !reflect.DeepEqual(tree, want) {
+		t.Errorf("tree:\n%+v\nwant:\n%+v", tree, want)
+	}
+	wantTotal := &TreeTotal{Processes: 3, RSSBytes: 35 * page, Threads: 7, FDs: 4, FDsUnknown: 1}
+	if !reflect.DeepEqual(total, wantTotal) {
+		t.Errorf("total = %+v, want %+v", total, wantTotal)
+	}
+}
+
+func TestCollectProcessTreeGone(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"proc/100/stat": treeStat(100, "exiting", 1, 1, 1),
+		"proc/101/stat": treeStat(101, "child", 100, 1, 1),
+		"proc/101/fd/0": "",
+	})
+	// The root itself exiting is an error, not an empty tree.
+	for _, pid := range []int{100, 999} {
+		tree, _, err := CollectProcessTree(context.Background(), root, pid)
+		if !errors.Is(err, ErrNoProcess) {
+			t.Errorf("CollectProcessTree(%d) = %+v, %v; want ErrNoProcess", pid, tree, err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package sysinfo
+
+import (
+	"bufio"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type promSample struct {
+	labels []string // name, value, name, value, ...
+	value  float64
+}
+
+type promWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (p *promWriter) write(parts ...string) {
+	for _, s := range parts {
+		if p.err != nil {
+			return
+		}
+		_, p.err = p.w.WriteString(s)
+	}
+}
+
+// family writes one metric with its HELP and TYPE lines. Families without
+// samples are left out entirely.
+func (p *promWriter) family(name, typ, help string, samples ...promSample) {
+	if len(samples) == 0 {
+		return
+	}
+	p.write("# HELP ", name, " ", promHelpEscaper.Replace(help), "\n")
+	p.write("# TYPE ", name, " ", typ, "\n")
+	for _, s := range samples {
+		p.write(name)
+		if len(s.labels) > 0 {
+			p.write("{")
+			for i := 0; i+1 < len(s.labels); i += 2 {
+				if i > 0 {
+					p.write(",")
+				}
+				p.write(s.labels[i], `="`, promLabelEscaper.Replace(s.labels[i+1]), `"`)
+			}
+			p.write("}")
+		}
+		p.write(" ", strconv.FormatFloat(s.value, 'g', -1, 64), "\n")
+	}
+}
+
+func (p *promWriter) gauge(name, help string, value float64) {
+	p.family(name, "gauge", help, promSample{value: value})
+}
+
+// Label values escape backslash, double quote and newline; HELP text only
+// backslash and newline (exposition format 0.0.4).
+var (
+	promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	promHelpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// WritePrometheus writes info in the Prometheus text exposition format, as
+// read by the node_exporter textfile collector. With fields set only the
+// metrics behind those JSON keys are written. Sections whose collector
+// failed are left out rather than reported as zero, and every failure is
+// exported as sysinfo_collector_error.
+func WritePrometheus(w io.Writer, info *SysInfo, fields []string) error {
+	p := &promWriter{w: bufio.NewWriter(w)}
+	want := func(key, collector string) bool {
+		return (len(fields) == 0 || slices.Contains(fields, key)) && info.collected(collector)
+	}
+
+	if want("fd_count", "fd_count") {
+		p.gauge("sysinfo_fd_count", "Open file descriptors of the inspected process.", float64(info.FDCount))
+	}
+	if l := info.Rlimits["nofile"]; want("rlimits", "rlimits") && l.Soft != nil {
+		p.gauge("sysinfo_fd_limit", "Soft RLIMIT_NOFILE of the inspected process.", float64(*l.Soft))
+	}
+	if want("vmrss_bytes", "vmrss_bytes") {
+		// The vmrss_bytes JSON key has always carried kB.
+		p.gauge("sysinfo_vmrss_bytes", "Resident set size of the inspected process.", float64(info.VmRSS)*1024)
+	}
+	if want("cpu_cores", "cpu") {
+		p.gauge("sysinfo_cpu_cores", "Logical CPUs.", float64(info.CPUCores))
+	}
+	if want("uptime_seconds", "uptime") {
+		p.gauge("sysinfo_uptime_seconds", "Seconds since boot.", info.UptimeSeconds)
+	}
+	if want("boot_time", "uptime") {
+		if t, err := time.Parse(time.RFC3339, info.BootTime); err == nil {
+			p.gauge("sysinfo_boot_time_seconds", "Boot time as a Unix timestamp.", float64(t.Unix()))
+		}
+	}
+	if l := info.LoadAvg; l != nil && want("loadavg", "loadavg") {
+		p.gauge("sysinfo_load1", "1-minute load average.", l.One)
+		p.gauge("sysinfo_load5", "5-minute load average.", l.Five)
+		p.gauge("sysinfo_load15", "15-minute load average.", l.Fifteen)
+		p.gauge("sysinfo_procs_running", "Runnable processes.", float64(l.RunnableProcs))
+		p.gauge("sysinfo_procs_total", "Processes and threads.", float64(l.TotalProcs))
+	}
+	if m := info.Memory; m != nil && want("memory", "memory") {
+		p.gauge("sysinfo_memory_total_bytes", "MemTotal.", float64(m.TotalBytes))
+		p.gauge("sysinfo_memory_free_bytes", "MemFree.", float64(m.FreeBytes))
+		p.gauge("sysinfo_memory_available_bytes", "MemAvailable.", float64(m.AvailableBytes))
+		p.gauge("sysinfo_memory_used_bytes", "MemTotal minus MemAvailable.", float64(m.UsedBytes))
+		p.gauge("sysinfo_memory_buffers_bytes", "Buffers.", float64(m.BuffersBytes))
+		p.gauge("sysinfo_memory_cached_bytes", "Page cache.", float64(m.CachedBytes))
+		p.gauge("sysinfo_memory_dirty_bytes", "Dirty pages waiting for writeback.", float64(m.DirtyBytes))
+		p.gauge("sysinfo_memory_slab_bytes", "Kernel slab.", float64(m.SlabBytes))
+		p.gauge("sysinfo_swap_total_bytes", "SwapTotal.", float64(m.SwapTotalBytes))
+		p.gauge("sysinfo_swap_free_bytes", "SwapFree.", float64(m.SwapFreeBytes))
+	}
+	if want("mounts", "mounts") {
+		var size, free, avail, inodes, inodesFree, readOnly []promSample
+		for _, d := range info.Mounts {
+			if d.Error != "" {
+				continue
+			}
+			labels := []string{"mountpoint", d.Mountpoint, "fstype", d.FSType, "device", d.Device}
+			size = append(size, promSample{labels, float64(d.Total)})
+			free = append(free, promSample{labels, float64(d.Free)})
+			avail = append(avail, promSample{labels, float64(d.Avail)})
+			inodes = append(inodes, promSample{labels, float64(d.Inodes)})
+			inodesFree = append(inodesFree, promSample{labels, float64(d.InodesFree)})
+			ro := 0.0
+			if d.ReadOnly {
+				ro = 1
+			}
+			readOnly = append(readOnly, promSample{labels, ro})
+		}
+		p.family("sysinfo_disk_size_bytes", "gauge", "Filesystem size.", size...)
+		p.family("sysinfo_disk_free_bytes", "gauge", "Free bytes, including blocks reserved for root.", free...)
+		p.family("sysinfo_disk_avail_bytes", "gauge", "Bytes available to unprivileged users.", avail...)
+		p.family("sysinfo_disk_inodes", "gauge", "Inodes.", inodes...)
+		p.family("sysinfo_disk_inodes_free", "gauge", "Free inodes.", inodesFree...)
+		p.family("sysinfo_disk_read_only", "gauge", "1 if the filesystem is mounted read-only.", readOnly...)
+	}
+	if want("disk_io", "disk_io") {
+		var reads, readBytes, writes, writtenBytes []promSample
+		for _, d := range info.DiskIO {
+			c, ok := d.Counters()
+			if !ok {
+				continue
+			}
+			labels := []string{"device", d.Device}
+			reads = append(reads, promSample{labels, float64(c.ReadsCompleted)})
+			readBytes = append(readBytes, promSample{labels, float64(c.ReadBytes())})
+			writes = append(writes, promSample{labels, float64(c.WritesCompleted)})
+			writtenBytes = append(writtenBytes, promSample{labels, float64(c.WrittenBytes())})
+		}
+		p.family("sysinfo_disk_reads_completed_total", "counter", "Reads completed.", reads...)
+		p.family("sysinfo_disk_read_bytes_total", "counter", "Bytes read (512-byte sectors).", readBytes...)
+		p.family("sysinfo_disk_writes_completed_total", "counter", "Writes completed.", writes...)
+		p.family("sysinfo_disk_written_bytes_total", "counter", "Bytes written (512-byte sectors).", writtenBytes...)
+	}
+	if cg := info.CgroupV1; cg != nil && (len(fields) == 0 || slices.Contains(fields, "cgroup_v1")) {
+		if cg.MemoryLimitBytes != nil && info.collected("memory_limit") {
+			p.gauge("sysinfo_cgroup_memory_limit_bytes", "cgroup v1 memory limit.", float64(*cg.MemoryLimitBytes))
+		}
+		if cg.CPULimitCores != nil && info.collected("cpu_limit") {
+			p.gauge("sysinfo_cgroup_cpu_limit_cores", "cgroup v1 CPU quota in cores.", *cg.CPULimitCores)
+		}
+	}
+	if n := info.Network; n != nil && want("network", "interfaces") {
+		var rxBytes, rxPackets, txBytes, txPackets []promSample
+		for _, iface := range n.Interfaces {
+			labels := []string{"interface", iface.Name}
+			rxBytes = append(rxBytes, promSample{labels, float64(iface.RxBytes)})
+			rxPackets = append(rxPackets, promSample{labels, float64(iface.RxPackets)})
+			txBytes = append(txBytes, promSample{labels, float64(iface.TxBytes)})
+			txPackets = append(txPackets, promSample{labels, float64(iface.TxPackets)})
+		}
+		p.family("sysinfo_network_receive_bytes_total", "counter", "Bytes received.", rxBytes...)
+		p.family("sysinfo_network_receive_packets_total", "counter", "Packets received.", rxPackets...)
+		p.family("sysinfo_network_transmit_bytes_total", "counter", "Bytes transmitted.", txBytes...)
+		p.family("sysinfo_network_transmit_packets_total", "counter", "Packets transmitted.", txPackets...)
+	}
+	if want("hwmon", "hwmon") {
+		byType := make(map[string][]promSample)
+		for _, s := range info.Hwmon {
+			byType[s.Type] = append(byType[s.Type], promSample{
+				[]string{"chip", s.Chip, "sensor", s.Sensor, "label", s.Label}, s.Value})
+		}
+		p.family("sysinfo_hwmon_fan_rpm", "gauge", "Fan speed.", byType["fan"]...)
+		p.family("sysinfo_hwmon_voltage_volts", "gauge", "Voltage.", byType["voltage"]...)
+		p.family("sysinfo_hwmon_power_watts", "gauge", "Power.", byType["power"]...)
+		p.family("sysinfo_hwmon_current_amps", "gauge", "Current.", byType["current"]...)
+	}
+
+	var failed []promSample
+	for _, name := range slices.Sorted(maps.Keys(info.Errors)) {
+		failed = append(failed, promSample{[]string{"collector", name}, 1})
+	}
+	p.family("sysinfo_collector_error", "gauge", "Set for each collector that failed.", failed...)
+
+	if p.err == nil {
+		p.err = p.w.Flush()
+	}
+	return p.err
+}
@@ -0,0 +1,152 @@
+package sysinfo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// promInfo fills every section WritePrometheus exports.
+func promInfo() *SysInfo {
+	limit := uint64(512 << 20)
+	return &SysInfo{
+		FDCount:       42,
+		Rlimits:       map[string]Rlimit{"nofile": {Soft: ptr[uint64](1024), Hard: ptr[uint64](4096)}},
+		VmRSS:         2048,
+		CPUCores:      8,
+		UptimeSeconds: 3600.5,
+		BootTime:      "2026-01-02T03:04:05Z",
+		LoadAvg:       &LoadAvg{One: 0.5, Five: 0.25, Fifteen: 0.125, RunnableProcs: 2, TotalProcs: 431},
+		Memory: &MemInfo{
+			TotalBytes: 16 << 30, FreeBytes: 2 << 30, AvailableBytes: 8 << 30, UsedBytes: 8 << 30,
+			BuffersBytes: 1 << 20, CachedBytes: 4 << 30, DirtyBytes: 4096, SlabBytes: 256 << 20,
+			SwapTotalBytes: 2 << 30, SwapFreeBytes: 1 << 30,
+		},
+		Mounts: []DiskInfo{
+			{Mountpoint: "/", FSType: "ext4", Device: "/dev/sda1", Total: 100 << 30, Free: 40 << 30, Avail: 35 << 30, Inodes: 6553600, InodesFree: 6000000},
+			{Mountpoint: "/boot/efi", FSType: "vfat", Device: "/dev/sda15", Total: 100 << 20, Free: 90 << 20, Avail: 90 << 20, ReadOnly: true},
+			// Failed mounts have no sizes worth exporting.
+			{Mountpoint: "/mnt/nfs", FSType: "nfs4", Device: "nas:/export", Error: "stat timed out after 2s"},
+		},
+		DiskIO: []DiskIOStat{
+			{Device: "sda", counters: DiskCounters{ReadsCompleted: 100, SectorsRead: 8000, WritesCompleted: 50, SectorsWritten: 4000}, hasCounters: true},
+			{Device: "sdb"},
+		},
+		CgroupV1: &CgroupV1{MemoryLimitBytes: &limit, CPULimitCores: ptr(1.5)},
+		Network: &Network{Interfaces: []NetInterface{
+			{Name: "eth0", RxBytes: 1000, RxPackets: 10, TxBytes: 2000, TxPackets: 20},
+		}},
+		Hwmon: []HwmonSensor{
+			{Chip: "nct6775", Sensor: "fan1", Type: "fan", Value: 820, Unit: "RPM"},
+			{Chip: "nct6775", Sensor: "in0", Label: "Vcore", Type: "voltage", Value: 1.04, Unit: "V"},
+			{Chip: "pmbus", Sensor: "power1", Label: "pin", Type: "power", Value: 315.25, Unit: "W"},
+			{Chip: "pmbus", Sensor: "curr1", Label: "iin", Type: "current", Value: 1.375, Unit: "A"},
+		},
+	}
+}
+
+func TestWritePrometheusGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePrometheus(&buf, promInfo(), nil); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("testdata", "prometheus.txt")
+	if *update {
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -run TestWritePrometheusGolden -update)", err)
+	}
+	if buf.String() != string(golden) {
+		t.Errorf("exposition differs from %s:\n%s", path, buf.String())
+	}
+
+	// Every sample follows the HELP and TYPE lines of its own family.
+	var family string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+			family = strings.Fields(line)[2]
+		case strings.HasPrefix(line, "# TYPE "):
+			if f := strings.Fields(line); f[2] != family || (f[3] != "gauge" && f[3] != "counter") {
+				t.Errorf("%q after the HELP of %s", line, family)
+			}
+		default:
+			name, _, _ := strings.Cut(line, "{")
+			name, _, _ = strings.Cut(name, " ")
+			if name != family {
+				t.Errorf("sample %q under the HELP of %s", line, family)
+			}
+		}
+	}
+}
+
+func TestWritePrometheusLabelEscaping(t *testing.T) {
+	info := &SysInfo{Mounts: []DiskInfo{
+		{Mountpoint: "/mnt/a\\b \"c\"\nd", FSType: "ext4", Device: "/dev/sdb1", Total: 100},
+	}}
+	var buf bytes.Buffer
+	if err := WritePrometheus(&buf, info, []string{"mounts"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `sysinfo_disk_size_bytes{mountpoint="/mnt/a\\b \"c\"\nd",fstype="ext4",device="/dev/sdb1"} 100` + "\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("no %q in\n%s", want, buf.String())
+	}
+	// The raw newline must not split the sample over two lines.
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if !strings.HasPrefix(line, "# ") && !strings.HasPrefix(line, "sysinfo_disk_") {
+			t.Errorf("stray line %q", line)
+		}
+	}
+}
+
+func TestWritePrometheusFailedCollectors(t *testing.T) {
+	info := promInfo()
+	// The failed sections keep the partial values they had; none of them
+	// may be exported as if they were real.
+	info.Errors = map[string]string{
+		"mounts":     "open /proc/mounts: permission denied",
+		"memory":     "malformed /proc/meminfo",
+		"fd_count":   "open /proc/42/fd: permission denied",
+		"interfaces": "netlink: operation not permitted",
+	}
+	var buf bytes.Buffer
+	if err := WritePrometheus(&buf, info, nil); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, prefix := range []string{"sysinfo_disk_size_bytes", "sysinfo_memory_", "sysinfo_swap_", "sysinfo_fd_count", "sysinfo_network_"} {
+		if strings.Contains(out, "\n"+prefix) || strings.HasPrefix(out, prefix) {
+			t.Errorf("%s exported although its collector failed", prefix)
+		}
+	}
+	for _, want := range []string{"sysinfo_load1 0.5\n", "sysinfo_disk_reads_completed_total{device=\"sda\"} 100\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("no %q: a failed collector took others with it", want)
+		}
+	}
+	want := "# HELP sysinfo_collector_error Set for each collector that failed.\n" +
+		"# TYPE sysinfo_collector_error gauge\n" +
+		"sysinfo_collector_error{collector=\"fd_count\"} 1\n" +
+		"sysinfo_collector_error{collector=\"interfaces\"} 1\n" +
+		"sysinfo_collector_error{collector=\"memory\"} 1\n" +
+		"sysinfo_collector_error{collector=\"mounts\"} 1\n"
+	if !strings.HasSuffix(out, want) {
+		t.Errorf("output does not end with\n%s\ngot:\n%s", want, out)
+	}
+
+	// Without failures the family is left out entirely.
+	buf.Reset()
+	if err := WritePrometheus(&buf, promInfo(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "sysinfo_collector_error") {
+		t.Error("sysinfo_collector_error written without a failed collector")
+	}
+}
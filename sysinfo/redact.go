@@ -0,0 +1,30 @@
+package sysinfo
+
+import (
+	"net/url"
+	"strings"
+)
+
+const redacted = "<redacted>"
+
+var sensitiveKeyParts = []string{
+	"PASSWORD", "PASSWD", "SECRET", "TOKEN", "CREDENTIAL", "PRIVATE", "API_KEY", "APIKEY", "ACCESS_KEY", "AUTH", "COOKIE", "SESSION",
+}
+
+// RedactValue applies the standard redaction policy to an environment or
+// config value: values of keys that look like secrets are replaced
+// entirely, and credentials embedded in URLs (proxy settings, DSNs) are
+// masked while the rest of the URL is kept.
+func RedactValue(key, value string) string {
+	upper := strings.ToUpper(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(upper, part) {
+			return redacted
+		}
+	}
+	if u, err := url.Parse(value); err == nil && u.User != nil && u.Host != "" {
+		u.User = url.User("REDACTED")
+		return u.String()
+	}
+	return value
+}
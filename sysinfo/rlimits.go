@@ -0,0 +1,110 @@
+package sysinfo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Rlimit is one resource limit; nil means unlimited.
+type Rlimit struct {
+	Soft *uint64 `json:"soft"`
+	Hard *uint64 `json:"hard"`
+	Unit string  `json:"unit,omitempty"`
+}
+
+// rlimitNames maps the descriptions in /proc/<pid>/limits to the RLIMIT_*
+// suffixes used as keys.
+var rlimitNames = map[string]string{
+	"Max cpu time":          "cpu",
+	"Max file size":         "fsize",
+	"Max data size":         "data",
+	"Max stack size":        "stack",
+	"Max core file size":    "core",
+	"Max resident set":      "rss",
+	"Max processes":         "nproc",
+	"Max open files":        "nofile",
+	"Max locked memory":     "memlock",
+	"Max address space":     "as",
+	"Max file locks":        "locks",
+	"Max pending signals":   "sigpending",
+	"Max msgqueue size":     "msgqueue",
+	"Max nice priority":     "nice",
+	"Max realtime priority": "rtprio",
+	"Max realtime timeout":  "rttime",
+}
+
+// ReadRlimits parses /proc/<pid>/limits (self for 0), keyed by lower-case
+// RLIMIT name (nofile, nproc, as, ...). The file is used rather than
+// getrlimit so that other processes can be inspected the same way.
+func ReadRlimits(root string, pid int) (map[string]Rlimit, error) {
+	data, err := os.ReadFile(procDir(root, pid, "limits"))
+	if err != nil {
+		return nil, processError(pid, err)
+	}
+	lines := strings.Split(string(data), "\n")
+	// The columns are fixed-width and the descriptions contain spaces, so
+	// split at the header's column offsets.
+	soft := strings.Index(lines[0], "Soft Limit")
+	hard := strings.Index(lines[0], "Hard Limit")
+	units := strings.Index(lines[0], "Units")
+	if soft < 0 || hard < soft || units < hard {
+		return nil, fmt.Errorf("unexpected limits header %q", lines[0])
+	}
+	limits := make(map[string]Rlimit)
+	for _, line := range lines[1:] {
+		if len(line) < hard {
+			continue
+		}
+		name, ok := rlimitNames[strings.TrimSpace(line[:soft])]
+		if !ok {
+			continue
+		}
+		var l Rlimit
+		if l.Soft, err = parseRlimit(line[soft:hard]); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if l.Hard, err = parseRlimit(line[hard:min(units, len(line))]); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(line) > units {
+			l.Unit = strings.TrimSpace(line[units:])
+		}
+		limits[name] = l
+	}
+	return limits, nil
+}
+
+func parseRlimit(s string) (*uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "unlimited" {
+		return nil, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// fdLimitWarnPercent is the share of the soft NOFILE limit above which
+// running out of descriptors (EMFILE) is reported.
+const fdLimitWarnPercent = 80
+
+func rlimitFindings(info *SysInfo) []Finding {
+	nofile, ok := info.Rlimits["nofile"]
+	if !ok || nofile.Soft == nil || *nofile.Soft == 0 || !info.collected("fd_count") {
+		return nil
+	}
+	pct := float64(info.FDCount) / float64(*nofile.Soft) * 100
+	if pct <= fdLimitWarnPercent {
+		return nil
+	}
+	return []Finding{{
+		Code:     "fd_limit_near",
+		Severity: "warning",
+		Message: fmt.Sprintf("%d of %d file descriptors in use (%.0f%% of the soft RLIMIT_NOFILE): "+
+			"opening more will fail with EMFILE", info.FDCount, *nofile.Soft, pct),
+	}}
+}
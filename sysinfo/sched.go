@@ -0,0 +1,45 @@
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// CollectSchedFeatures reads the scheduler feature toggles from debugfs.
+// Each token is either FEATURE (enabled) or NO_FEATURE (disabled).
+func CollectSchedFeatures(root string) (map[string]bool, error) {
+	paths := []string{
+		"sys/kernel/debug/sched/features",
+		"sys/kernel/debug/sched_features",
+	}
+	var lastErr error
+	for _, p := range paths {
+		data, err := readTrim(rootPath(root, p))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return parseSchedFeatures(data), nil
+	}
+	if errors.Is(lastErr, fs.ErrPermission) {
+		return nil, fmt.Errorf("debugfs is not readable (requires root): %w", lastErr)
+	}
+	if errors.Is(lastErr, fs.ErrNotExist) {
+		return nil, fmt.Errorf("sched features not found: debugfs not mounted at /sys/kernel/debug")
+	}
+	return nil, lastErr
+}
+
+func parseSchedFeatures(data string) map[string]bool {
+	features := make(map[string]bool)
+	for _, token := range strings.Fields(data) {
+		if name, disabled := strings.CutPrefix(token, "NO_"); disabled {
+			features[name] = false
+		} else {
+			features[token] = true
+		}
+	}
+	return features
+}
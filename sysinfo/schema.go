@@ -0,0 +1,112 @@
+package sysinfo
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the schema_version of every report. Bump it whenever a
+// JSON key is renamed or removed or changes its type; adding keys is
+// compatible and does not need a bump.
+const SchemaVersion = 1
+
+// JSONSchema returns a JSON Schema (draft 2020-12) document describing the
+// JSON report, generated from the struct tags of SysInfo. Keys tagged
+// omitempty are optional; pointers, slices and maps without omitempty may
+// be null. It describes a full report: with Options.Fields a report has
+// only the selected keys.
+func JSONSchema() ([]byte, error) {
+	g := schemaGen{defs: make(map[string]any)}
+	root := g.object(reflect.TypeFor[SysInfo]())
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "sysinfo report"
+	root["properties"].(map[string]any)["schema_version"] = map[string]any{"type": "integer", "const": SchemaVersion}
+	root["$defs"] = g.defs
+	return json.MarshalIndent(root, "", "  ")
+}
+
+type schemaGen struct {
+	defs map[string]any
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// schema describes values of t. Named structs go to $defs once and are
+// referenced from then on.
+func (g *schemaGen) schema(t reflect.Type, nullable bool) map[string]any {
+	var s map[string]any
+	switch {
+	case t == timeType:
+		s = map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Pointer:
+		return g.schema(t.Elem(), nullable)
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // placeholder, for recursive types
+			g.defs[t.Name()] = g.object(t)
+		}
+		s = map[string]any{"$ref": "#/$defs/" + t.Name()}
+	case t.Kind() == reflect.Struct:
+		s = g.object(t)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		s = map[string]any{"type": "string", "contentEncoding": "base64"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s = map[string]any{"type": "array", "items": g.schema(t.Elem(), mayBeNil(t.Elem()))}
+	case t.Kind() == reflect.Map:
+		s = map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem(), mayBeNil(t.Elem()))}
+	case t.Kind() == reflect.Bool:
+		s = map[string]any{"type": "boolean"}
+	case t.Kind() == reflect.String:
+		s = map[string]any{"type": "string"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		s = map[string]any{"type": "integer"}
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr:
+		s = map[string]any{"type": "integer", "minimum": 0}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		s = map[string]any{"type": "number"}
+	default:
+		s = map[string]any{}
+	}
+	if !nullable {
+		return s
+	}
+	if typ, ok := s["type"].(string); ok {
+		s["type"] = []string{typ, "null"}
+		return s
+	}
+	return map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}}
+}
+
+// object describes a struct the way encoding/json marshals it.
+func (g *schemaGen) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		omitempty := strings.Contains(","+opts+",", ",omitempty,")
+		props[name] = g.schema(f.Type, !omitempty && mayBeNil(f.Type))
+		if !omitempty {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{"type": "object", "properties": props, "required": required}
+}
+
+// mayBeNil reports whether a value of t marshals as null when it is zero.
+func mayBeNil(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
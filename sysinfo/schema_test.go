@@ -0,0 +1,229 @@
+package sysinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// schemaGolden is the published schema of the current SchemaVersion. Keys
+// may be added to the report without a bump; renaming, removing or
+// retyping one needs SchemaVersion bumped and a new golden file.
+var schemaGolden = filepath.Join("testdata", fmt.Sprintf("schema_v%d.json", SchemaVersion))
+
+func TestSchemaCompatible(t *testing.T) {
+	current, err := JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile(schemaGolden, append(current, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	golden, err := os.ReadFile(schemaGolden)
+	if err != nil {
+		t.Fatalf("%v (bumped SchemaVersion? run go test -run TestSchemaCompatible -update)", err)
+	}
+	var old, cur map[string]any
+	if err := json.Unmarshal(golden, &old); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(current, &cur); err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range schemaBreaks(old, cur) {
+		t.Errorf("%s; bump SchemaVersion or restore the key", problem)
+	}
+	if !bytes.Equal(bytes.TrimSpace(golden), current) {
+		t.Logf("keys were added since %s; run go test -run TestSchemaCompatible -update to record them", schemaGolden)
+	}
+}
+
+// schemaBreaks lists the keys of old that cur drops, makes optional or
+// gives another type.
+func schemaBreaks(old, cur map[string]any) []string {
+	c := schemaCompare{oldDefs: defsOf(old), curDefs: defsOf(cur), seen: make(map[string]bool)}
+	c.compare("report", old, cur)
+	return c.problems
+}
+
+type schemaCompare struct {
+	oldDefs, curDefs map[string]any
+	seen             map[string]bool
+	problems         []string
+}
+
+func defsOf(schema map[string]any) map[string]any {
+	defs, _ := schema["$defs"].(map[string]any)
+	return defs
+}
+
+func (c *schemaCompare) resolve(s map[string]any, defs map[string]any) map[string]any {
+	for {
+		ref, ok := s["$ref"].(string)
+		if !ok {
+			return s
+		}
+		s, _ = defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+	}
+}
+
+func (c *schemaCompare) compare(path string, old, cur map[string]any) {
+	// A struct used in several places is compared once, at the first.
+	if ref, ok := old["$ref"].(string); ok {
+		if c.seen[ref] {
+			return
+		}
+		c.seen[ref] = true
+	}
+	old, cur = c.resolve(old, c.oldDefs), c.resolve(cur, c.curDefs)
+	if cur == nil {
+		c.problems = append(c.problems, path+": type definition removed")
+		return
+	}
+	if o, n := schemaType(old), schemaType(cur); o != n {
+		c.problems = append(c.problems, fmt.Sprintf("%s: type %s became %s", path, o, n))
+		return
+	}
+	if anyOf, ok := old["anyOf"].([]any); ok {
+		curAnyOf, _ := cur["anyOf"].([]any)
+		for i := range min(len(anyOf), len(curAnyOf)) {
+			c.compare(path, anyOf[i].(map[string]any), curAnyOf[i].(map[string]any))
+		}
+		return
+	}
+	if items, ok := old["items"].(map[string]any); ok {
+		curItems, _ := cur["items"].(map[string]any)
+		c.compare(path+"[]", items, curItems)
+	}
+	if values, ok := old["additionalProperties"].(map[string]any); ok {
+		curValues, _ := cur["additionalProperties"].(map[string]any)
+		c.compare(path+".*", values, curValues)
+	}
+	oldProps, _ := old["properties"].(map[string]any)
+	curProps, _ := cur["properties"].(map[string]any)
+	oldRequired, curRequired := stringList(old["required"]), stringList(cur["required"])
+	for _, name := range slices.Sorted(mapKeys(oldProps)) {
+		key := path + "." + name
+		p, ok := curProps[name].(map[string]any)
+		if !ok {
+			c.problems = append(c.problems, key+": removed")
+			continue
+		}
+		if slices.Contains(oldRequired, name) && !slices.Contains(curRequired, name) {
+			c.problems = append(c.problems, key+": no longer always present")
+		}
+		c.compare(key, oldProps[name].(map[string]any), p)
+	}
+}
+
+// schemaType is the JSON type of s, "object" for a struct and whatever
+// anyOf wraps for a nullable reference.
+func schemaType(s map[string]any) string {
+	if t, ok := s["type"]; ok {
+		return fmt.Sprint(t)
+	}
+	if anyOf, ok := s["anyOf"].([]any); ok {
+		var types []string
+		for _, a := range anyOf {
+			types = append(types, schemaType(a.(map[string]any)))
+		}
+		return strings.Join(types, "|")
+	}
+	if _, ok := s["$ref"]; ok {
+		return "object"
+	}
+	return "any"
+}
+
+func stringList(v any) []string {
+	var list []string
+	values, _ := v.([]any)
+	for _, s := range values {
+		list = append(list, s.(string))
+	}
+	return list
+}
+
+func mapKeys(m map[string]any) func(func(string) bool) {
+	return func(yield func(string) bool) {
+		for k := range m {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+func TestSchemaBreaks(t *testing.T) {
+	base := func() map[string]any {
+		var s map[string]any
+		json.Unmarshal([]byte(`{
+			"type": "object",
+			"required": ["pid", "mounts"],
+			"properties": {
+				"pid": {"type": "integer"},
+				"mounts": {"type": ["array", "null"], "items": {"$ref": "#/$defs/DiskInfo"}}
+			},
+			"$defs": {"DiskInfo": {"type": "object", "required": ["Mountpoint"], "properties": {"Mountpoint": {"type": "string"}}}}
+		}`), &s)
+		return s
+	}
+	props := func(s map[string]any) map[string]any { return s["properties"].(map[string]any) }
+	disk := func(s map[string]any) map[string]any {
+		return s["$defs"].(map[string]any)["DiskInfo"].(map[string]any)
+	}
+	tests := []struct {
+		name   string
+		change func(s map[string]any)
+		want   []string
+	}{
+		{"unchanged", func(map[string]any) {}, nil},
+		{"key added", func(s map[string]any) { props(s)["comm"] = map[string]any{"type": "string"} }, nil},
+		{"key removed", func(s map[string]any) { delete(props(s), "pid") }, []string{"report.pid: removed"}},
+		{"type changed", func(s map[string]any) { props(s)["pid"] = map[string]any{"type": "string"} },
+			[]string{"report.pid: type integer became string"}},
+		{"nested key removed", func(s map[string]any) { delete(props(disk(s)), "Mountpoint") },
+			[]string{"report.mounts[].Mountpoint: removed"}},
+		{"made optional", func(s map[string]any) { s["required"] = []any{"mounts"} },
+			[]string{"report.pid: no longer always present"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur := base()
+			tt.change(cur)
+			if got := schemaBreaks(base(), cur); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("schemaBreaks = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReportFixture decodes a report of the current schema version,
+// strictly: a key it has that SysInfo lost, or one whose type changed,
+// fails to decode.
+func TestReportFixture(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", fmt.Sprintf("report_v%d.json", SchemaVersion)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var info SysInfo
+	if err := dec.Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.SchemaVersion != SchemaVersion {
+		t.Errorf("fixture has schema_version %d, want %d", info.SchemaVersion, SchemaVersion)
+	}
+}
@@ -0,0 +1,186 @@
+package sysinfo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultPrivilegedDirs are the directories ScanPrivilegedFiles walks when
+// none are given.
+var DefaultPrivilegedDirs = []string{"/usr/bin", "/usr/sbin", "/usr/local/bin"}
+
+// maxPrivilegedFiles bounds the walk; a scan that stops there sets Truncated.
+const maxPrivilegedFiles = 100000
+
+// PrivilegedScan lists the files under Dirs that run with more privileges
+// than their caller: setuid/setgid binaries and files with capabilities.
+type PrivilegedScan struct {
+	Dirs       []string         `json:"dirs"`
+	Privileged []PrivilegedFile `json:"privileged_files"`
+	Scanned    int              `json:"scanned"`
+	Truncated  bool             `json:"truncated,omitempty"`
+}
+
+type PrivilegedFile struct {
+	Path         string    `json:"path"`
+	Setuid       bool      `json:"setuid,omitempty"`
+	Setgid       bool      `json:"setgid,omitempty"`
+	Capabilities *FileCaps `json:"capabilities,omitempty"`
+}
+
+// FileCaps is a decoded security.capability xattr, as shown by getcap.
+// RootUID is the owner of the user namespace the capabilities are valid
+// in; only version 3 records it.
+type FileCaps struct {
+	Version     int      `json:"version"`
+	Effective   bool     `json:"effective"`
+	Permitted   []string `json:"permitted,omitempty"`
+	Inheritable []string `json:"inheritable,omitempty"`
+	RootUID     *uint32  `json:"root_uid,omitempty"`
+}
+
+// ScanPrivilegedFiles walks dirs (DefaultPrivilegedDirs when empty) without
+// following symlinks and reports every regular file that is setuid,
+// setgid or carries file capabilities.
+func ScanPrivilegedFiles(root string, dirs []string) (*PrivilegedScan, error) {
+	if len(dirs) == 0 {
+		dirs = DefaultPrivilegedDirs
+	}
+	sec := &PrivilegedScan{Dirs: dirs, Privileged: []PrivilegedFile{}}
+	for _, dir := range dirs {
+		base := rootPath(root, dir)
+		err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable subdirectories are skipped; a missing
+				// top-level directory is not an error either.
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if sec.Scanned == maxPrivilegedFiles {
+				sec.Truncated = true
+				return fs.SkipAll
+			}
+			sec.Scanned++
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			f := PrivilegedFile{
+				Path:   filepath.Join(dir, strings.TrimPrefix(path, base)),
+				Setuid: info.Mode()&fs.ModeSetuid != 0,
+				Setgid: info.Mode()&fs.ModeSetgid != 0,
+			}
+			raw, err := readCapXattr(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if raw != nil {
+				if f.Capabilities, err = ParseFileCaps(raw); err != nil {
+					return fmt.Errorf("%s: %w", f.Path, err)
+				}
+			}
+			if f.Setuid || f.Setgid || f.Capabilities != nil {
+				sec.Privileged = append(sec.Privileged, f)
+			}
+			return nil
+		})
+		if err != nil {
+			return sec, err
+		}
+		if sec.Truncated {
+			break
+		}
+	}
+	slices.SortFunc(sec.Privileged, func(a, b PrivilegedFile) int { return strings.Compare(a.Path, b.Path) })
+	return sec, nil
+}
+
+// VFS capability xattr layout (linux/capability.h): a little-endian
+// magic_etc word holding the revision and the effective flag, then one
+// permitted/inheritable pair of 32-bit words per 32 capabilities, then in
+// revision 3 the namespace root uid.
+const (
+	vfsCapRevisionMask  = 0xff000000
+	vfsCapFlagEffective = 0x000001
+	vfsCapRevision1     = 0x01000000
+	vfsCapRevision2     = 0x02000000
+	vfsCapRevision3     = 0x03000000
+)
+
+// ParseFileCaps decodes a security.capability xattr value.
+func ParseFileCaps(data []byte) (*FileCaps, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("capability xattr too short (%d bytes)", len(data))
+	}
+	magic := binary.LittleEndian.Uint32(data)
+	caps := &FileCaps{Effective: magic&vfsCapFlagEffective != 0}
+	words, size := 0, 0
+	switch magic & vfsCapRevisionMask {
+	case vfsCapRevision1:
+		caps.Version, words, size = 1, 1, 12
+	case vfsCapRevision2:
+		caps.Version, words, size = 2, 2, 20
+	case vfsCapRevision3:
+		caps.Version, words, size = 3, 2, 24
+	default:
+		return nil, fmt.Errorf("unknown capability xattr revision %#x", magic&vfsCapRevisionMask)
+	}
+	if len(data) != size {
+		return nil, fmt.Errorf("capability xattr v%d has %d bytes, want %d", caps.Version, len(data), size)
+	}
+	var permitted, inheritable uint64
+	for i := 0; i < words; i++ {
+		off := 4 + i*8
+		permitted |= uint64(binary.LittleEndian.Uint32(data[off:])) << (32 * i)
+		inheritable |= uint64(binary.LittleEndian.Uint32(data[off+4:])) << (32 * i)
+	}
+	caps.Permitted = capNames(permitted)
+	caps.Inheritable = capNames(inheritable)
+	if caps.Version == 3 {
+		uid := binary.LittleEndian.Uint32(data[20:])
+		caps.RootUID = &uid
+	}
+	return caps, nil
+}
+
+// capabilityNames are indexed by capability number.
+var capabilityNames = []string{
+	"cap_chown", "cap_dac_override", "cap_dac_read_search", "cap_fowner",
+	"cap_fsetid", "cap_kill", "cap_setgid", "cap_setuid", "cap_setpcap",
+	"cap_linux_immutable", "cap_net_bind_service", "cap_net_broadcast",
+	"cap_net_admin", "cap_net_raw", "cap_ipc_lock", "cap_ipc_owner",
+	"cap_sys_module", "cap_sys_rawio", "cap_sys_chroot", "cap_sys_ptrace",
+	"cap_sys_pacct", "cap_sys_admin", "cap_sys_boot", "cap_sys_nice",
+	"cap_sys_resource", "cap_sys_time", "cap_sys_tty_config", "cap_mknod",
+	"cap_lease", "cap_audit_write", "cap_audit_control", "cap_setfcap",
+	"cap_mac_override", "cap_mac_admin", "cap_syslog", "cap_wake_alarm",
+	"cap_block_suspend", "cap_audit_read", "cap_perfmon", "cap_bpf",
+	"cap_checkpoint_restore",
+}
+
+// capNames lists the capabilities set in mask, in getcap's lower case;
+// bits newer than this table come out as "cap_<n>".
+func capNames(mask uint64) []string {
+	var names []string
+	for bit := 0; bit < 64; bit++ {
+		if mask&(1<<bit) == 0 {
+			continue
+		}
+		if bit < len(capabilityNames) {
+			names = append(names, capabilityNames[bit])
+		} else {
+			names = append(names, fmt.Sprintf("cap_%d", bit))
+		}
+	}
+	return names
+}
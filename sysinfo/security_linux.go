@@ -0,0 +1,21 @@
+package sysinfo
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// readCapXattr returns the raw security.capability xattr of path, or nil
+// when it has none.
+func readCapXattr(path string) ([]byte, error) {
+	buf := make([]byte, 64)
+	n, err := unix.Lgetxattr(path, "security.capability", buf)
+	if errors.Is(err, unix.ENODATA) || errors.Is(err, unix.ENOTSUP) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
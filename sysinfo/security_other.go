@@ -0,0 +1,7 @@
+//go:build !linux
+
+package sysinfo
+
+func readCapXattr(string) ([]byte, error) {
+	return nil, unsupported()
+}
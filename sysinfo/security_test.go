@@ -0,0 +1,105 @@
+package sysinfo
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// capXattr builds a security.capability value: magic_etc, the
+// permitted/inheritable word pairs, then the root uid words if any.
+func capXattr(magic uint32, words ...uint32) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, magic)
+	for _, w := range words {
+		data = binary.LittleEndian.AppendUint32(data, w)
+	}
+	return data
+}
+
+func TestParseFileCaps(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want *FileCaps
+	}{
+		{
+			// setcap cap_net_raw+ep /usr/bin/ping
+			name: "v2 ping",
+			data: []byte{0x01, 0x00, 0x00, 0x02, 0x00, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			want: &FileCaps{Version: 2, Effective: true, Permitted: []string{"cap_net_raw"}},
+		},
+		{
+			name: "v2 permitted only",
+			data: capXattr(vfsCapRevision2, 1<<10|1<<12, 0, 0, 0),
+			want: &FileCaps{Version: 2, Permitted: []string{"cap_net_bind_service", "cap_net_admin"}},
+		},
+		{
+			// Capabilities 32 and up live in the second word pair.
+			name: "v2 high word",
+			data: capXattr(vfsCapRevision2|vfsCapFlagEffective, 0, 1<<2, 1<<(38-32)|1<<(39-32), 1<<(34-32)),
+			want: &FileCaps{Version: 2, Effective: true, Permitted: []string{"cap_perfmon", "cap_bpf"}, Inheritable: []string{"cap_dac_read_search", "cap_syslog"}},
+		},
+		{
+			name: "v2 bit unknown to the table",
+			data: capXattr(vfsCapRevision2, 0, 0, 1<<(63-32), 0),
+			want: &FileCaps{Version: 2, Permitted: []string{"cap_63"}},
+		},
+		{
+			// setcap in a user namespace owned by uid 100000.
+			name: "v3 with rootid",
+			data: capXattr(vfsCapRevision3|vfsCapFlagEffective, 1<<13, 0, 0, 0, 100000),
+			want: &FileCaps{Version: 3, Effective: true, Permitted: []string{"cap_net_raw"}, RootUID: ptr[uint32](100000)},
+		},
+		{
+			name: "v3 rootid 0",
+			data: []byte{0x00, 0x00, 0x00, 0x03, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			want: &FileCaps{Version: 3, Permitted: []string{"cap_net_bind_service"}, RootUID: ptr[uint32](0)},
+		},
+		{
+			name: "v1",
+			data: capXattr(vfsCapRevision1|vfsCapFlagEffective, 1<<7, 0),
+			want: &FileCaps{Version: 1, Effective: true, Permitted: []string{"cap_setuid"}},
+		},
+		{
+			name: "no capabilities",
+			data: capXattr(vfsCapRevision2, 0, 0, 0, 0),
+			want: &FileCaps{Version: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caps, err := ParseFileCaps(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(caps, tt.want) {
+				t.Errorf("caps = %+v, want %+v", caps, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFileCapsMalformed(t *testing.T) {
+	v2 := capXattr(vfsCapRevision2|vfsCapFlagEffective, 1<<13, 0, 0, 0)
+	v3 := capXattr(vfsCapRevision3|vfsCapFlagEffective, 1<<13, 0, 0, 0, 1000)
+	tests := map[string][]byte{
+		"empty":               nil,
+		"magic cut short":     {0x01, 0x00, 0x00},
+		"magic only":          v2[:4],
+		"v2 truncated":        v2[:12],
+		"v2 one byte short":   v2[:19],
+		"v2 trailing byte":    append(append([]byte{}, v2...), 0),
+		"v3 without rootid":   v3[:20],
+		"v3 truncated rootid": v3[:22],
+		"v3 trailing bytes":   append(append([]byte{}, v3...), 0, 0, 0, 0),
+		"v1 truncated":        capXattr(vfsCapRevision1, 1<<7),
+		"v1 as long as v2":    capXattr(vfsCapRevision1, 1<<7, 0, 0, 0),
+		"revision 0":          capXattr(0, 1<<13, 0, 0, 0),
+		"revision 4":          capXattr(0x04000000, 1<<13, 0, 0, 0, 0),
+	}
+	for name, data := range tests {
+		if caps, err := ParseFileCaps(data); err == nil {
+			t.Errorf("%s: decoded %+v, want an error", name, caps)
+		}
+	}
+}
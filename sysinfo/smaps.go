@@ -0,0 +1,89 @@
+package sysinfo
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// MemoryDetail is the smaps view of a process's memory, in bytes. Pss
+// charges each shared page to the processes mapping it in equal parts, so
+// unlike Rss it adds up across processes.
+type MemoryDetail struct {
+	RssBytes          uint64 `json:"rss_bytes"`
+	PssBytes          uint64 `json:"pss_bytes"`
+	SharedCleanBytes  uint64 `json:"shared_clean_bytes"`
+	SharedDirtyBytes  uint64 `json:"shared_dirty_bytes"`
+	PrivateCleanBytes uint64 `json:"private_clean_bytes"`
+	PrivateDirtyBytes uint64 `json:"private_dirty_bytes"`
+	SwapBytes         uint64 `json:"swap_bytes"`
+	SwapPssBytes      uint64 `json:"swap_pss_bytes"`
+	// Source is "smaps_rollup", or "smaps" on kernels before 4.14, where
+	// the Mappings of the process are summed.
+	Source   string `json:"source"`
+	Mappings int    `json:"mappings,omitempty"`
+}
+
+// ReadMemoryDetail reads /proc/<pid>/smaps_rollup, falling back to
+// summing /proc/<pid>/smaps. Both need the same access as ptrace, so
+// foreign processes need root.
+func ReadMemoryDetail(root string, pid int) (*MemoryDetail, error) {
+	f, err := os.Open(procDir(root, pid, "smaps_rollup"))
+	source := "smaps_rollup"
+	if errors.Is(err, fs.ErrNotExist) {
+		f, err = os.Open(procDir(root, pid, "smaps"))
+		source = "smaps"
+	}
+	if err != nil {
+		return nil, processError(pid, err)
+	}
+	defer f.Close()
+
+	// smaps has a header line per mapping followed by "Key: <n> kB"
+	// lines; the rollup has one header for the whole address space. A
+	// process with many mappings has a smaps of several megabytes, hence
+	// the scanner.
+	d := &MemoryDetail{Source: source}
+	fields := map[string]*uint64{
+		"Rss:":           &d.RssBytes,
+		"Pss:":           &d.PssBytes,
+		"Shared_Clean:":  &d.SharedCleanBytes,
+		"Shared_Dirty:":  &d.SharedDirtyBytes,
+		"Private_Clean:": &d.PrivateCleanBytes,
+		"Private_Dirty:": &d.PrivateDirtyBytes,
+		"Swap:":          &d.SwapBytes,
+		"SwapPss:":       &d.SwapPssBytes,
+	}
+	headers := 0
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		key, rest, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		if !strings.HasSuffix(key, ":") {
+			headers++
+			continue
+		}
+		p, ok := fields[key]
+		if !ok {
+			continue
+		}
+		kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(rest), " kB"), 10, 64)
+		if err != nil {
+			continue
+		}
+		*p += kb * 1024
+	}
+	if err := sc.Err(); err != nil {
+		return nil, processError(pid, err)
+	}
+	if source == "smaps" {
+		d.Mappings = headers
+	}
+	return d, nil
+}
@@ -0,0 +1,73 @@
+package sysinfo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// smapsFixture is an smaps with n mappings of 8 kB resident, 4 kB
+// proportional, 4 kB private dirty and 4 kB swapped each.
+func smapsFixture(n int) string {
+	var b strings.Builder
+	for i := range n {
+		start := 0x7f0000000000 + uint64(i)*0x2000
+		fmt.Fprintf(&b, "%x-%x rw-p 00000000 00:00 0 \n", start, start+0x2000)
+		b.WriteString("Size:                  8 kB\nKernelPageSize:        4 kB\nMMUPageSize:           4 kB\n" +
+			"Rss:                   8 kB\nPss:                   4 kB\nPss_Dirty:             4 kB\n" +
+			"Shared_Clean:          4 kB\nShared_Dirty:          0 kB\nPrivate_Clean:         0 kB\n" +
+			"Private_Dirty:         4 kB\nReferenced:            8 kB\nAnonymous:             4 kB\n" +
+			"LazyFree:              0 kB\nAnonHugePages:         0 kB\nSwap:                  4 kB\n" +
+			"SwapPss:               2 kB\nLocked:                0 kB\nTHPeligible:    0\n" +
+			"VmFlags: rd wr mr mw me ac sd \n")
+	}
+	return b.String()
+}
+
+func TestReadMemoryDetailSmaps(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"proc/self/smaps": smapsFixture(10000)})
+	d, err := ReadMemoryDetail(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := MemoryDetail{
+		RssBytes: 10000 * 8 << 10, PssBytes: 10000 * 4 << 10, SharedCleanBytes: 10000 * 4 << 10,
+		PrivateDirtyBytes: 10000 * 4 << 10, SwapBytes: 10000 * 4 << 10, SwapPssBytes: 10000 * 2 << 10,
+		Source: "smaps", Mappings: 10000,
+	}
+	if *d != want {
+		t.Errorf("ReadMemoryDetail = %+v, want %+v", *d, want)
+	}
+}
+
+func TestReadMemoryDetailRollup(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"proc/self/smaps":        smapsFixture(3),
+		"proc/self/smaps_rollup": "00400000-7ffd6e5fe000 ---p 00000000 00:00 0 [rollup]\nRss:  100 kB\nPss:  60 kB\n",
+	})
+	d, err := ReadMemoryDetail(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Source != "smaps_rollup" || d.Mappings != 0 || d.RssBytes != 100<<10 || d.PssBytes != 60<<10 {
+		t.Errorf("ReadMemoryDetail = %+v, want the rollup's Rss and Pss", *d)
+	}
+}
+
+// BenchmarkReadMemoryDetailSmaps sums an smaps of 10k mappings, the
+// fallback path before kernel 4.14, about what a large JVM or browser has.
+func BenchmarkReadMemoryDetailSmaps(b *testing.B) {
+	root := b.TempDir()
+	data := smapsFixture(10000)
+	writeTree(b, root, map[string]string{"proc/self/smaps": data})
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := ReadMemoryDetail(root, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
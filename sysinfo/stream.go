@@ -0,0 +1,228 @@
+package sysinfo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+)
+
+type jsonStream struct {
+	w       *bufio.Writer
+	indent  string
+	fields  int
+	elems   int
+	err     error
+	inArray bool
+}
+
+func (s *jsonStream) raw(str string) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = s.w.WriteString(str)
+}
+
+func (s *jsonStream) marshal(v any, prefix string) {
+	if s.err != nil {
+		return
+	}
+	var out []byte
+	if s.indent == "" {
+		out, s.err = json.Marshal(v)
+	} else {
+		out, s.err = json.MarshalIndent(v, prefix, s.indent)
+	}
+	if s.err == nil {
+		_, s.err = s.w.Write(out)
+	}
+}
+
+func (s *jsonStream) newline(prefix string) {
+	if s.indent != "" {
+		s.raw("\n" + prefix)
+	}
+}
+
+func (s *jsonStream) key(k string) {
+	if s.fields > 0 {
+		s.raw(",")
+	}
+	s.fields++
+	s.newline(s.indent)
+	s.marshal(k, "")
+	if s.indent == "" {
+		s.raw(":")
+	} else {
+		s.raw(": ")
+	}
+}
+
+func (s *jsonStream) beginArray(k string) {
+	s.key(k)
+	s.elems = 0
+	s.inArray = true
+}
+
+func (s *jsonStream) elem(v any) {
+	if s.elems == 0 {
+		s.raw("[")
+	} else {
+		s.raw(",")
+	}
+	s.elems++
+	s.newline(s.indent + s.indent)
+	s.marshal(v, s.indent+s.indent)
+}
+
+func (s *jsonStream) endArray() {
+	if !s.inArray {
+		return
+	}
+	s.inArray = false
+	if s.elems == 0 {
+		s.raw("null")
+		return
+	}
+	s.newline(s.indent)
+	s.raw("]")
+}
+
+// field writes the top-level key of info, unless it is empty and tagged
+// omitempty. Only that field is encoded, however large the rest of info.
+func (s *jsonStream) field(info *SysInfo, key string) {
+	f, ok := reportFields[key]
+	if !ok || s.err != nil {
+		return
+	}
+	v := reflect.ValueOf(info).Elem().Field(f.index)
+	if f.omitEmpty && isEmptyValue(v) {
+		return
+	}
+	s.key(key)
+	s.marshal(v.Addr().Interface(), s.indent)
+}
+
+// flush hands what has been written to w, so each section reaches the
+// reader when it is done rather than when the buffer fills.
+func (s *jsonStream) flush() {
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+}
+
+type reportField struct {
+	index     int
+	omitEmpty bool
+}
+
+// reportFields maps the top-level JSON keys of SysInfo to their fields.
+var reportFields = func() map[string]reportField {
+	fields := make(map[string]reportField)
+	t := reflect.TypeFor[SysInfo]()
+	for i := range t.NumField() {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = reportField{index: i, omitEmpty: opts == "omitempty"}
+		}
+	}
+	return fields
+}()
+
+// isEmptyValue is encoding/json's test for omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// mounts writes each mount as soon as it is statted. Only those that
+// failed are kept in info, for the mount_unresponsive findings.
+func (s *jsonStream) mounts(ctx context.Context, info *SysInfo, opts Options) error {
+	s.beginArray("mounts")
+	err := WalkDisks(ctx, opts.Root, opts.Mounts, func(d DiskInfo) error {
+		s.elem(d)
+		if d.Error != "" {
+			info.Mounts = append(info.Mounts, d)
+		}
+		return s.err
+	})
+	s.endArray()
+	if err == s.err {
+		return nil
+	}
+	return err
+}
+
+// EncodeJSON collects the report and writes it to w section by section:
+// each top-level key is encoded and flushed once the last collector that
+// fills it is done, and mounts one by one as they are statted, so a slow
+// mount does not hold back the sections before it. The mounts, the one
+// section that grows with the host, are not kept; the other sections stay
+// in memory for the findings at the end. The output unmarshals into the
+// same SysInfo as MarshalReport of CollectWith's report for opts, except
+// that a collection cut short by ctx leaves it incomplete.
+func EncodeJSON(ctx context.Context, w io.Writer, opts Options) error {
+	s := &jsonStream{w: bufio.NewWriter(w), indent: opts.Indent}
+	var errs []error
+
+	info := &SysInfo{SchemaVersion: SchemaVersion}
+	ctx, reads := withReadLog(ctx, opts.clk(), nil)
+	s.raw("{")
+	s.field(info, "schema_version")
+	enabled := enabledCollectors(opts)
+	// Several collectors may fill the same key (e.g. "process"); a key is
+	// written once, after the last collector contributing to it.
+	last := make(map[string]int)
+	for i, c := range enabled {
+		for _, k := range c.keys {
+			last[k] = i
+		}
+	}
+	for i, c := range enabled {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		if c.name == "mounts" && opts.wants("mounts") {
+			err = s.mounts(ctx, info, opts)
+		} else {
+			err = c.collect(ctx, info, opts)
+			for _, k := range c.keys {
+				if last[k] == i && opts.wants(k) {
+					s.field(info, k)
+				}
+			}
+		}
+		s.flush()
+		if s.err != nil {
+			return s.err
+		}
+		if err != nil {
+			errs = append(errs, info.recordError(c.name, err))
+		}
+	}
+	info.ReadIssues = reads.list()
+	info.Findings = Analyze(info)
+	s.field(info, "read_issues")
+	if opts.wants("findings") {
+		s.field(info, "findings")
+	}
+	s.field(info, "errors")
+	s.newline("")
+	s.raw("}\n")
+	s.flush()
+	if s.err != nil {
+		return s.err
+	}
+	return errors.Join(errs...)
+}
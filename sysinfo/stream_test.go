@@ -0,0 +1,132 @@
+package sysinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+func streamTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"proc/meminfo":     testMeminfo,
+		"proc/stat":        "cpu  10 0 10 80 0 0 0 0 0 0\ncpu0 10 0 10 80 0 0 0 0 0 0\nbtime 1700000000\n",
+		"proc/uptime":      "1234.56 4321.00\n",
+		"proc/loadavg":     "0.50 0.40 0.30 2/300 4242\n",
+		"proc/self/stat":   "42 (app) S 1 42 42 0 -1 4194560 100 0 0 0 5 3 0 0 20 0 1 0 1000 10000000 500 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0\n",
+		"proc/self/status": procStatusWithRSS(2048),
+		"proc/mounts":      "/dev/sda1 / ext4 rw,relatime 0 0\n/dev/sdb1 /data xfs ro 0 0\n",
+	})
+	return root
+}
+
+// decodeTopLevel returns the top-level keys of a JSON object in order,
+// failing on a key that appears twice.
+func decodeTopLevel(t *testing.T, data []byte) []string {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		t.Fatalf("output does not start an object: %v %v", tok, err)
+	}
+	var keys []string
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := tok.(string)
+		if seen[key] {
+			t.Errorf("key %q written twice", key)
+		}
+		seen[key] = true
+		keys = append(keys, key)
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		t.Errorf("trailing data after the object: %v", err)
+	}
+	return keys
+}
+
+func TestEncodeJSONMatchesCollectWith(t *testing.T) {
+	root := streamTree(t)
+	for _, tt := range []struct {
+		name   string
+		fields []string
+		indent string
+	}{
+		{name: "everything", indent: "  "},
+		{name: "single line"},
+		{name: "fields", fields: []string{"memory", "mounts", "loadavg"}, indent: "  "},
+		{name: "fields with findings", fields: []string{"mounts", "findings"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Root: root, Fields: tt.fields, Indent: tt.indent}
+			opts.Clock = clock.NewManual(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+			info, collectErr := CollectWith(opts)
+			want, err := MarshalReport(info, tt.fields, tt.indent)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			opts.Clock = clock.NewManual(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+			streamErr := EncodeJSON(context.Background(), &buf, opts)
+			if (collectErr == nil) != (streamErr == nil) {
+				t.Errorf("EncodeJSON error %v, CollectWith error %v", streamErr, collectErr)
+			}
+			decodeTopLevel(t, buf.Bytes())
+			if tt.indent == "" && strings.Count(buf.String(), "\n") != 1 {
+				t.Errorf("single-line output has %d newlines", strings.Count(buf.String(), "\n"))
+			}
+
+			var got, wantDoc map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("streamed output is not JSON: %v\n%s", err, buf.String())
+			}
+			if err := json.Unmarshal(want, &wantDoc); err != nil {
+				t.Fatal(err)
+			}
+			dropFreeSpace(got)
+			dropFreeSpace(wantDoc)
+			if !reflect.DeepEqual(got, wantDoc) {
+				t.Errorf("streamed report differs from CollectWith's\nstreamed: %s\nbuffered: %s", buf.Bytes(), want)
+			}
+		})
+	}
+}
+
+// dropFreeSpace removes what statfs of the fake root's mounts reports
+// about free space, which other processes change between two collections.
+func dropFreeSpace(doc map[string]any) {
+	mounts, _ := doc["mounts"].([]any)
+	for _, m := range mounts {
+		for _, k := range []string{"Free", "Avail", "used_percent", "inodes_free", "inodes_used_percent"} {
+			delete(m.(map[string]any), k)
+		}
+	}
+}
+
+func TestEncodeJSONStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	if err := EncodeJSON(ctx, &buf, Options{Root: t.TempDir()}); err != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,201 @@
+package sysinfo
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Swap describes the active swap areas and whether RAM can be hibernated
+// to disk.
+type Swap struct {
+	Devices []SwapDevice `json:"devices"`
+	// Hibernation is set when /sys/power/state offers "disk".
+	Hibernation  bool   `json:"hibernation"`
+	ResumeDevice string `json:"resume_device,omitempty"`
+	// RootEncrypted is nil when the block device under / is unknown, e.g.
+	// for overlay or btrfs roots.
+	RootEncrypted *bool `json:"root_encrypted,omitempty"`
+	// Zram lists the initialized zram devices, swap or not; Zswap is nil
+	// without zswap support in the kernel.
+	Zram  []ZramDevice `json:"zram"`
+	Zswap *Zswap       `json:"zswap,omitempty"`
+}
+
+// SwapDevice is one line of /proc/swaps. Backing is the block device
+// chain from the swap device (or the device holding the swap file) down
+// to the disks, e.g. ["dm-1", "sda3"]; it is empty when it could not be
+// resolved, and Encrypted is then meaningless.
+type SwapDevice struct {
+	Path      string   `json:"path"`
+	Type      string   `json:"type"`
+	SizeBytes uint64   `json:"size_bytes"`
+	UsedBytes uint64   `json:"used_bytes"`
+	Priority  int      `json:"priority"`
+	Zram      bool     `json:"zram"`
+	Encrypted bool     `json:"encrypted"`
+	Backing   []string `json:"backing,omitempty"`
+}
+
+// CollectSwap reads /proc/swaps and follows each area's device-mapper
+// chain through /sys/class/block/*/slaves; a dm device whose uuid starts
+// with CRYPT- is dm-crypt. Swap files are resolved to the device of the
+// filesystem holding them via mountinfo.
+func CollectSwap(root string) (*Swap, error) {
+	data, err := os.ReadFile(rootPath(root, "proc/swaps"))
+	if err != nil {
+		return nil, err
+	}
+	devMounts, _, err := mountsByDevice(root)
+	if err != nil {
+		return nil, err
+	}
+	swap := &Swap{Devices: []SwapDevice{}}
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		size, errSize := strconv.ParseUint(fields[2], 10, 64)
+		used, errUsed := strconv.ParseUint(fields[3], 10, 64)
+		prio, errPrio := strconv.Atoi(fields[4])
+		if errSize != nil || errUsed != nil || errPrio != nil {
+			return nil, fmt.Errorf("malformed /proc/swaps line %q", line)
+		}
+		d := SwapDevice{
+			Path:      unescapeMountPath(fields[0]),
+			Type:      fields[1],
+			SizeBytes: size * 1024,
+			UsedBytes: used * 1024,
+			Priority:  prio,
+		}
+		var name string
+		if d.Type == "file" {
+			name = blockDeviceName(root, mountDevice(devMounts, d.Path))
+		} else {
+			name = swapPartitionName(root, d.Path)
+		}
+		if name != "" {
+			d.Backing = walkBlockChain(root, name, nil)
+			d.Encrypted = chainEncrypted(root, d.Backing)
+			d.Zram = strings.HasPrefix(name, "zram")
+		}
+		swap.Devices = append(swap.Devices, d)
+	}
+
+	if state, err := readTrim(rootPath(root, "sys/power/state")); err == nil {
+		swap.Hibernation = strings.Contains(" "+state+" ", " disk ")
+	}
+	if cmdline, err := readTrim(rootPath(root, "proc/cmdline")); err == nil {
+		for _, arg := range strings.Fields(cmdline) {
+			if v, ok := strings.CutPrefix(arg, "resume="); ok {
+				swap.ResumeDevice = v
+			}
+		}
+	}
+	if name := blockDeviceName(root, mountDevice(devMounts, "/")); name != "" {
+		encrypted := chainEncrypted(root, walkBlockChain(root, name, nil))
+		swap.RootEncrypted = &encrypted
+	}
+	swap.Zram = readZramDevices(root)
+	swap.Zswap = readZswap(root)
+	return swap, nil
+}
+
+// mountDevice returns the "maj:min" of the mount holding path, by longest
+// mountpoint prefix.
+func mountDevice(devMounts map[string][]string, path string) string {
+	var dev, best string
+	for d, mps := range devMounts {
+		for _, mp := range mps {
+			if (path == mp || strings.HasPrefix(path, strings.TrimSuffix(mp, "/")+"/")) && len(mp) > len(best) {
+				dev, best = d, mp
+			}
+		}
+	}
+	return dev
+}
+
+// blockDeviceName maps "maj:min" to its kernel name (sda3, dm-0) via
+// /sys/dev/block. Anonymous devices (overlay, btrfs subvolumes) have no
+// entry and yield "".
+func blockDeviceName(root, majMin string) string {
+	if majMin == "" {
+		return ""
+	}
+	target, err := os.Readlink(rootPath(root, "sys/dev/block", majMin))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+// swapPartitionName resolves /dev/mapper/cryptswap or /dev/dm-1 to dm-1.
+func swapPartitionName(root, path string) string {
+	if target, err := os.Readlink(rootPath(root, path)); err == nil {
+		path = target
+	}
+	name := filepath.Base(path)
+	if _, err := os.Stat(rootPath(root, "sys/class/block", name)); err != nil {
+		return ""
+	}
+	return name
+}
+
+// walkBlockChain appends name and, depth first, the devices it is built
+// on (its slaves).
+func walkBlockChain(root, name string, chain []string) []string {
+	chain = append(chain, name)
+	slaves, err := os.ReadDir(rootPath(root, "sys/class/block", name, "slaves"))
+	if err != nil {
+		return chain
+	}
+	for _, s := range slaves {
+		chain = walkBlockChain(root, s.Name(), chain)
+	}
+	return chain
+}
+
+func chainEncrypted(root string, chain []string) bool {
+	for _, name := range chain {
+		uuid, err := readTrim(rootPath(root, "sys/class/block", name, "dm/uuid"))
+		if err == nil && strings.HasPrefix(uuid, "CRYPT-") {
+			return true
+		}
+	}
+	return false
+}
+
+func swapFindings(sw *Swap) []Finding {
+	if sw == nil {
+		return nil
+	}
+	var findings []Finding
+	for _, d := range sw.Devices {
+		if d.Zram || d.Encrypted || len(d.Backing) == 0 {
+			continue
+		}
+		if sw.RootEncrypted != nil && *sw.RootEncrypted {
+			findings = append(findings, Finding{
+				Code:     "swap_unencrypted",
+				Severity: "warning",
+				Message: fmt.Sprintf("swap %s is not encrypted while / is: memory paged out (or a hibernation "+
+					"image) is written to disk in clear", d.Path),
+			})
+		}
+	}
+	for _, z := range sw.Zram {
+		if z.Ratio != nil && *z.Ratio < zramPoorRatio && z.OrigDataBytes >= zramMinData {
+			findings = append(findings, Finding{
+				Code:     "zram_poor_compression",
+				Severity: "warning",
+				Message: fmt.Sprintf("%s compresses its %d MiB only %.2f:1 with %s: the CPU spent compressing "+
+					"barely pays off", z.Name, z.OrigDataBytes>>20, *z.Ratio, cmp.Or(z.Algorithm, "its algorithm")),
+			})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,133 @@
+package sysinfo
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// sysctlNames are the sysctls reported, and read by the sysctlRules.
+var sysctlNames = []string{
+	"vm.overcommit_memory", "vm.overcommit_ratio", "vm.swappiness", "vm.panic_on_oom",
+	"net.ipv4.tcp_tw_recycle", "fs.file-max", "fs.file-nr",
+}
+
+// CollectSysctls reads sysctlNames from /proc/sys. Sysctls this kernel
+// does not have (tcp_tw_recycle is gone since Linux 4.12) are left out.
+func CollectSysctls(root string) (map[string]string, error) {
+	values := make(map[string]string)
+	var errs []error
+	for _, name := range sysctlNames {
+		v, err := readTrim(rootPath(root, "proc/sys", strings.ReplaceAll(name, ".", "/")))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		default:
+			values[name] = strings.Join(strings.Fields(v), " ")
+		}
+	}
+	return values, errors.Join(errs...)
+}
+
+// sysctlRule flags a setting, or combination of settings, that has caused
+// outages. check returns the explanation when the rule fires.
+type sysctlRule struct {
+	code     string
+	severity string
+	check    func(s sysctlValues, info *SysInfo) string
+}
+
+type sysctlValues map[string]string
+
+// int returns the sysctl as a number; ok is false when it is missing.
+func (s sysctlValues) int(name string) (n int64, ok bool) {
+	n, err := strconv.ParseInt(strings.Fields(s[name] + " x")[0], 10, 64)
+	return n, err == nil
+}
+
+var sysctlRules = []sysctlRule{
+	{
+		code:     "overcommit_strict_low_ratio",
+		severity: "warning",
+		check: func(s sysctlValues, _ *SysInfo) string {
+			mode, _ := s.int("vm.overcommit_memory")
+			ratio, ok := s.int("vm.overcommit_ratio")
+			if mode != 2 || !ok || ratio >= 80 {
+				return ""
+			}
+			return fmt.Sprintf("vm.overcommit_memory=2 with vm.overcommit_ratio=%d caps committed memory at swap plus %d%% of RAM; "+
+				"databases and JVMs that reserve large heaps get ENOMEM long before RAM is used up", ratio, ratio)
+		},
+	},
+	{
+		code:     "tcp_tw_recycle",
+		severity: "warning",
+		check: func(s sysctlValues, _ *SysInfo) string {
+			if v, _ := s.int("net.ipv4.tcp_tw_recycle"); v == 0 {
+				return ""
+			}
+			return "net.ipv4.tcp_tw_recycle=1 drops connections from clients behind NAT whose TCP timestamps " +
+				"are out of order; it was removed in Linux 4.12 for that reason"
+		},
+	},
+	{
+		code:     "swappiness_zero",
+		severity: "info",
+		check: func(s sysctlValues, info *SysInfo) string {
+			v, ok := s.int("vm.swappiness")
+			if !ok || v != 0 || !info.collected("memory") || info.SwapTotal == 0 {
+				return ""
+			}
+			return "vm.swappiness=0 keeps anonymous memory out of the configured swap until the system is about " +
+				"to run out of memory (Linux 3.5 and later), so swap rarely helps; use 1 to swap minimally"
+		},
+	},
+	{
+		code:     "file_max_low",
+		severity: "warning",
+		check: func(s sysctlValues, _ *SysInfo) string {
+			// fs.file-nr is "allocated unused max".
+			nr := strings.Fields(s["fs.file-nr"])
+			if len(nr) != 3 {
+				return ""
+			}
+			allocated, err1 := strconv.ParseInt(nr[0], 10, 64)
+			limit, err2 := strconv.ParseInt(nr[2], 10, 64)
+			// In floating point: systemd sets fs.file-max to LONG_MAX,
+			// and limit*80 would overflow.
+			if err1 != nil || err2 != nil || limit <= 0 || float64(allocated)*100 < float64(limit)*80 {
+				return ""
+			}
+			return fmt.Sprintf("%d of fs.file-max=%d file handles are allocated system-wide; at the limit every "+
+				"open() fails with ENFILE", allocated, limit)
+		},
+	},
+	{
+		code:     "panic_on_oom",
+		severity: "warning",
+		check: func(s sysctlValues, _ *SysInfo) string {
+			v, ok := s.int("vm.panic_on_oom")
+			if !ok || v == 0 {
+				return ""
+			}
+			return fmt.Sprintf("vm.panic_on_oom=%d panics the whole host on an out-of-memory condition instead of "+
+				"killing one process (with 2 even when the OOM is confined to a cgroup or cpuset)", v)
+		},
+	},
+}
+
+func sysctlFindings(info *SysInfo) []Finding {
+	if len(info.Sysctls) == 0 {
+		return nil
+	}
+	var findings []Finding
+	for _, r := range sysctlRules {
+		if msg := r.check(info.Sysctls, info); msg != "" {
+			findings = append(findings, Finding{Code: r.code, Severity: r.severity, Message: msg})
+		}
+	}
+	return findings
+}
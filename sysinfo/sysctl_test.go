@@ -0,0 +1,128 @@
+package sysinfo
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// healthySysctls trip no rule. fs.file-max is LONG_MAX, as systemd sets
+// it since v240.
+var healthySysctls = map[string]string{
+	"vm.overcommit_memory": "0",
+	"vm.overcommit_ratio":  "50",
+	"vm.swappiness":        "60",
+	"vm.panic_on_oom":      "0",
+	"fs.file-max":          "9223372036854775807",
+	"fs.file-nr":           "2080 0 9223372036854775807",
+}
+
+func TestSysctlRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		sysctls map[string]string
+		swap    int
+		errors  map[string]string
+		want    []string
+	}{
+		{name: "healthy", want: []string{}},
+		{name: "healthy with swap", swap: 1 << 20, want: []string{}},
+
+		{name: "strict overcommit, low ratio", sysctls: map[string]string{"vm.overcommit_memory": "2", "vm.overcommit_ratio": "50"}, want: []string{"overcommit_strict_low_ratio"}},
+		{name: "strict overcommit, ratio just below 80", sysctls: map[string]string{"vm.overcommit_memory": "2", "vm.overcommit_ratio": "79"}, want: []string{"overcommit_strict_low_ratio"}},
+		{name: "strict overcommit, ratio 80", sysctls: map[string]string{"vm.overcommit_memory": "2", "vm.overcommit_ratio": "80"}, want: []string{}},
+		{name: "heuristic overcommit, low ratio", sysctls: map[string]string{"vm.overcommit_memory": "0", "vm.overcommit_ratio": "10"}, want: []string{}},
+		{name: "always overcommit, low ratio", sysctls: map[string]string{"vm.overcommit_memory": "1", "vm.overcommit_ratio": "10"}, want: []string{}},
+		{name: "strict overcommit, ratio unreadable", sysctls: map[string]string{"vm.overcommit_memory": "2", "vm.overcommit_ratio": ""}, want: []string{}},
+
+		{name: "tcp_tw_recycle on", sysctls: map[string]string{"net.ipv4.tcp_tw_recycle": "1"}, want: []string{"tcp_tw_recycle"}},
+		{name: "tcp_tw_recycle off", sysctls: map[string]string{"net.ipv4.tcp_tw_recycle": "0"}, want: []string{}},
+		// healthySysctls has none: the sysctl is gone since Linux 4.12.
+
+		{name: "swappiness 0 with swap", sysctls: map[string]string{"vm.swappiness": "0"}, swap: 1 << 20, want: []string{"swappiness_zero"}},
+		{name: "swappiness 0 without swap", sysctls: map[string]string{"vm.swappiness": "0"}, want: []string{}},
+		{name: "swappiness 1 with swap", sysctls: map[string]string{"vm.swappiness": "1"}, swap: 1 << 20, want: []string{}},
+		{name: "swappiness 0, memory unreadable", sysctls: map[string]string{"vm.swappiness": "0"}, swap: 1 << 20, errors: map[string]string{"memory": "malformed /proc/meminfo"}, want: []string{}},
+
+		{name: "file handles at 80% of file-max", sysctls: map[string]string{"fs.file-max": "100000", "fs.file-nr": "80000 0 100000"}, want: []string{"file_max_low"}},
+		{name: "file handles exhausted", sysctls: map[string]string{"fs.file-max": "100000", "fs.file-nr": "100000 0 100000"}, want: []string{"file_max_low"}},
+		{name: "file handles below 80% of file-max", sysctls: map[string]string{"fs.file-max": "100000", "fs.file-nr": "79999 0 100000"}, want: []string{}},
+		{name: "LONG_MAX file-max, many handles", sysctls: map[string]string{"fs.file-nr": "4000000 0 9223372036854775807"}, want: []string{}},
+		{name: "file-nr malformed", sysctls: map[string]string{"fs.file-nr": "80000 0"}, want: []string{}},
+		{name: "file-nr zero max", sysctls: map[string]string{"fs.file-nr": "80000 0 0"}, want: []string{}},
+
+		{name: "panic_on_oom 1", sysctls: map[string]string{"vm.panic_on_oom": "1"}, want: []string{"panic_on_oom"}},
+		{name: "panic_on_oom 2", sysctls: map[string]string{"vm.panic_on_oom": "2"}, want: []string{"panic_on_oom"}},
+
+		{
+			name: "several at once",
+			sysctls: map[string]string{
+				"vm.overcommit_memory": "2", "vm.overcommit_ratio": "50",
+				"vm.panic_on_oom": "1", "net.ipv4.tcp_tw_recycle": "1",
+			},
+			want: []string{"overcommit_strict_low_ratio", "tcp_tw_recycle", "panic_on_oom"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sysctls := make(map[string]string)
+			for name, v := range healthySysctls {
+				sysctls[name] = v
+			}
+			for name, v := range tt.sysctls {
+				sysctls[name] = v
+			}
+			info := &SysInfo{Sysctls: sysctls, SwapTotal: tt.swap, Errors: tt.errors}
+			findings := sysctlFindings(info)
+			if got := findingCodes(findings); !slices.Equal(got, tt.want) {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+			for _, f := range findings {
+				if f.Message == "" || f.Severity == "" {
+					t.Errorf("%s: no severity or explanation: %+v", f.Code, f)
+				}
+			}
+		})
+	}
+}
+
+func TestSysctlFindingsWithoutSysctls(t *testing.T) {
+	// Not collected (or not readable): no rule may fire on missing values.
+	for _, sysctls := range []map[string]string{nil, {}, {"fs.file-max": "100"}} {
+		if findings := sysctlFindings(&SysInfo{Sysctls: sysctls, SwapTotal: 1 << 20}); len(findings) != 0 {
+			t.Errorf("sysctls %v: findings %+v", sysctls, findings)
+		}
+	}
+}
+
+func TestCollectSysctls(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"proc/sys/vm/overcommit_memory": "2\n",
+		"proc/sys/vm/overcommit_ratio":  "50\n",
+		"proc/sys/vm/swappiness":        "60\n",
+		"proc/sys/vm/panic_on_oom":      "0\n",
+		"proc/sys/fs/file-max":          "9223372036854775807\n",
+		"proc/sys/fs/file-nr":           "2080\t0\t9223372036854775807\n",
+	})
+	sysctls, err := CollectSysctls(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// tcp_tw_recycle is missing, as on Linux 4.12 and later; file-nr's
+	// tabs are normalized.
+	want := map[string]string{
+		"vm.overcommit_memory": "2",
+		"vm.overcommit_ratio":  "50",
+		"vm.swappiness":        "60",
+		"vm.panic_on_oom":      "0",
+		"fs.file-max":          "9223372036854775807",
+		"fs.file-nr":           "2080 0 9223372036854775807",
+	}
+	if !reflect.DeepEqual(sysctls, want) {
+		t.Errorf("sysctls = %v, want %v", sysctls, want)
+	}
+	if got := findingCodes(sysctlFindings(&SysInfo{Sysctls: sysctls})); !slices.Equal(got, []string{"overcommit_strict_low_ratio"}) {
+		t.Errorf("findings = %q, want overcommit_strict_low_ratio", got)
+	}
+}
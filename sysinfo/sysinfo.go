@@ -0,0 +1,215 @@
+// Package sysinfo collects process and host metrics from /proc, /sys and
+// cgroupfs. Every collector takes a root path so it can be pointed at a
+// fake tree; an empty root means "/". Those whose /proc reads are checked
+// and retried (see ReadIssue) also take a context, which carries the read
+// log of the collection they serve.
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+type SysInfo struct {
+	SchemaVersion    int                 `json:"schema_version"`
+	Timestamp        *time.Time          `json:"timestamp,omitempty"`
+	Host             *Host               `json:"host,omitempty"`
+	Hostname         string              `json:"hostname,omitempty"`
+	Kernel           string              `json:"kernel,omitempty"`
+	Arch             string              `json:"arch,omitempty"`
+	OSRelease        string              `json:"os_release,omitempty"`
+	PID              int                 `json:"pid"`
+	Comm             string              `json:"comm"`
+	FDCount          int                 `json:"fd_count"`
+	FDTypes          map[string]int      `json:"fd_types,omitempty"`
+	FDs              []FDInfo            `json:"fds,omitempty"`
+	Rlimits          map[string]Rlimit   `json:"rlimits,omitempty"`
+	VmRSS            int                 `json:"vmrss_bytes"`
+	RSS              *RSSBreakdown       `json:"rss,omitempty"`
+	RSSGrowth        *RSSGrowth          `json:"rss_growth,omitempty"`
+	MemoryDetail     *MemoryDetail       `json:"memory_detail,omitempty"`
+	ExePath          string              `json:"exe_path"`
+	Process          *ProcessInfo        `json:"process,omitempty"`
+	ProcessTree      []TreeProcess       `json:"process_tree,omitempty"`
+	ProcessTreeTotal *TreeTotal          `json:"process_tree_total,omitempty"`
+	Threads          *ThreadReport       `json:"threads,omitempty"`
+	CPUModel         string              `json:"cpu_model"`
+	CPUCores         int                 `json:"cpu_cores"`
+	Cores            []CoreInfo          `json:"cores,omitempty"`
+	CPU              *CPUInfo            `json:"cpu,omitempty"`
+	CPUFlags         []string            `json:"cpu_flags,omitempty"`
+	Virtualized      bool                `json:"virtualized"`
+	CPUUsagePercent  *float64            `json:"cpu_usage_percent,omitempty"`
+	CPUJiffies       *CPUJiffies         `json:"cpu_jiffies,omitempty"`
+	CPUFreq          *CPUFreq            `json:"cpufreq,omitempty"`
+	Throttle         *ThrottleAssessment `json:"throttle,omitempty"`
+	SchedFeatures    map[string]bool     `json:"sched_features,omitempty"`
+	UptimeSeconds    float64             `json:"uptime_seconds"`
+	IdleSeconds      float64             `json:"idle_seconds"`
+	BootTime         string              `json:"boot_time,omitempty"`
+	LoadAvg          *LoadAvg            `json:"loadavg,omitempty"`
+	Pressure         Pressure            `json:"pressure,omitempty"`
+	MemTotal         int                 `json:"mem_total_kb"`
+	MemAvailable     int                 `json:"mem_available_kb"`
+	MemUsedPct       float64             `json:"mem_used_percent"`
+	SwapTotal        int                 `json:"swap_total_kb"`
+	SwapFree         int                 `json:"swap_free_kb"`
+	Memory           *MemInfo            `json:"memory,omitempty"`
+	PageCache        *PageCache          `json:"page_cache,omitempty"`
+	Swap             *Swap               `json:"swap,omitempty"`
+	NUMANodes        []NUMANode          `json:"numa_nodes,omitempty"`
+	Sysctls          map[string]string   `json:"sysctl,omitempty"`
+	Mounts           []DiskInfo          `json:"mounts"`
+	DiskHealth       []DiskHealth        `json:"disk_health,omitempty"`
+	DiskIO           []DiskIOStat        `json:"disk_io,omitempty"`
+	BindFiles        []BindFile          `json:"bind_files,omitempty"`
+	ConfigFiles      []FileInfo          `json:"config_files,omitempty"`
+	CgroupV1         *CgroupV1           `json:"cgroup_v1,omitempty"`
+	CgroupV2         *CgroupV2           `json:"cgroup_v2,omitempty"`
+	ContainerRuntime string              `json:"container_runtime"`
+	Network          *Network            `json:"network,omitempty"`
+	Containers       []ContainerCPU      `json:"containers,omitempty"`
+	Agents           []Agent             `json:"agents,omitempty"`
+	IRQ              *IRQReport          `json:"irq,omitempty"`
+	Isolation        *Isolation          `json:"isolation,omitempty"`
+	Hwmon            []HwmonSensor       `json:"hwmon,omitempty"`
+	Sensors          []TempSensor        `json:"sensors,omitempty"`
+	Security         *Security           `json:"security,omitempty"`
+	ReadIssues       []ReadIssue         `json:"read_issues,omitempty"`
+	Findings         []Finding           `json:"findings,omitempty"`
+	Errors           map[string]string   `json:"errors,omitempty"`
+}
+
+type Options struct {
+	Root           string
+	PID            int
+	RSSSample      time.Duration
+	MemoryDetail   bool
+	Indent         string
+	IRQDetail      bool
+	CPUTime        bool
+	Sched          bool
+	Delta          time.Duration
+	SchedFeatures  bool
+	RawCounters    bool
+	Fields         []string
+	ContainerCPU   time.Duration
+	Isolation      bool
+	FDs            bool
+	ConfigFiles    bool
+	DiskHealth     bool
+	DiskLatency    time.Duration
+	DiskIOSample   time.Duration
+	DiskIOAll      bool
+	Children       bool
+	Threads        bool
+	CgroupCPU      time.Duration
+	PrivilegedScan bool
+	CPUSample      time.Duration
+	ThrottleSample time.Duration
+	Sensors        bool
+	Agents         bool
+	PrivilegedDirs []string
+	Mounts         MountOptions
+	Clock          clock.Clock
+}
+
+// FieldError reports which part of the report a collector failed to fill.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string { return e.Field + ": " + e.Err.Error() }
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// Collect gathers a full report from the live system.
+func Collect() (*SysInfo, error) {
+	return CollectWith(Options{})
+}
+
+// CollectWith gathers a full report using opts. Every collector runs even
+// if earlier ones failed: failures leave their fields zeroed, are recorded
+// in SysInfo.Errors, and are joined into the returned error as *FieldError.
+func CollectWith(opts Options) (*SysInfo, error) {
+	return collectWith(context.Background(), opts)
+}
+
+// collectWith stops before the next collector once ctx is done, so an
+// abandoned collection does not keep running to the end.
+func collectWith(ctx context.Context, opts Options) (*SysInfo, error) {
+	info := &SysInfo{SchemaVersion: SchemaVersion}
+	ctx, reads := withReadLog(ctx, opts.clk(), nil)
+	var errs []error
+	for _, c := range enabledCollectors(opts) {
+		if ctx.Err() != nil {
+			return info, ctx.Err()
+		}
+		if err := c.collect(ctx, info, opts); err != nil {
+			errs = append(errs, info.recordError(c.name, err))
+		}
+	}
+	info.ReadIssues = reads.list()
+	info.Findings = Analyze(info)
+	return info, errors.Join(errs...)
+}
+
+// CollectContext is CollectWith bounded by ctx. Reads from /proc and
+// statfs cannot be interrupted, so when ctx is done first the collection
+// runs in the background until the current collector returns, and its
+// result is dropped.
+func CollectContext(ctx context.Context, opts Options) (*SysInfo, error) {
+	type result struct {
+		info *SysInfo
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		info, err := collectWith(ctx, opts)
+		done <- result{info, err}
+	}()
+	select {
+	case r := <-done:
+		return r.info, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (info *SysInfo) recordError(field string, err error) error {
+	if info.Errors == nil {
+		info.Errors = make(map[string]string)
+	}
+	info.Errors[field] = err.Error()
+	return &FieldError{Field: field, Err: err}
+}
+
+// clk returns opts.Clock, defaulting to the wall clock.
+func (opts Options) clk() clock.Clock {
+	if opts.Clock == nil {
+		return clock.Real
+	}
+	return opts.Clock
+}
+
+func rootPath(root string, elem ...string) string {
+	if root == "" {
+		root = "/"
+	}
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
+func readTrim(path string) (string, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bytes)), nil
+}
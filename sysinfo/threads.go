@@ -0,0 +1,181 @@
+package sysinfo
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ThreadReport is the threads of the inspected process grouped by name,
+// with their total against the limits that cap it. Threads is the full
+// per-thread list; the text report shows only the groups.
+type ThreadReport struct {
+	Total int `json:"total"`
+	// NprocLimit is the soft RLIMIT_NPROC, which counts every thread of
+	// the process's real user, not just this process's.
+	NprocLimit  *uint64       `json:"nproc_limit,omitempty"`
+	PidsMax     *uint64       `json:"pids_max,omitempty"`
+	PidsCurrent *uint64       `json:"pids_current,omitempty"`
+	Groups      []ThreadGroup `json:"groups"`
+	Threads     []ThreadInfo  `json:"threads"`
+}
+
+// ThreadGroup is the threads sharing a name once a trailing number is
+// replaced by "*" (pool-1-thread-7 and pool-1-thread-12 are both
+// pool-1-thread-*).
+type ThreadGroup struct {
+	Name       string  `json:"name"`
+	Count      int     `json:"count"`
+	CPUTicks   uint64  `json:"cpu_ticks"`
+	CPUSeconds float64 `json:"cpu_seconds"`
+}
+
+type ThreadInfo struct {
+	TID      int    `json:"tid"`
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	CPUTicks uint64 `json:"cpu_ticks"` // utime + stime
+}
+
+var threadNumberSuffix = regexp.MustCompile(`\d+$`)
+
+// walkTasks calls fn with the stat of every thread of pid. Threads that
+// exit during the walk are skipped; if the process itself exits, or its
+// pid is reused by another process meanwhile (a different start time),
+// the walk fails with ErrNoProcess rather than reporting a mix.
+func walkTasks(ctx context.Context, root string, pid int, fn func(tid int, stat *procStat)) error {
+	self, err := readProcStat(ctx, root, pid)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(procDir(root, pid, "task"))
+	if err != nil {
+		return processError(pid, err)
+	}
+	for _, e := range entries {
+		tid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(procDir(root, pid, "task", e.Name(), "stat"))
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ESRCH) {
+			continue
+		}
+		if err != nil {
+			return processError(pid, err)
+		}
+		stat, err := parseProcStat(string(data))
+		if err != nil {
+			continue
+		}
+		fn(tid, stat)
+	}
+	// The task directory of a process that exited during the walk reads
+	// as empty or partial; do not pass that off as its threads.
+	after, err := readProcStat(ctx, root, pid)
+	if err != nil {
+		return err
+	}
+	if after.uintField(22) != self.uintField(22) {
+		return processError(pid, fs.ErrNotExist)
+	}
+	return nil
+}
+
+// threadStateNames are the proc(5) names of the stat state letters.
+var threadStateNames = map[string]string{
+	"R": "running",
+	"S": "sleeping",
+	"D": "disk_sleep",
+	"Z": "zombie",
+	"T": "stopped",
+	"t": "tracing_stop",
+	"X": "dead",
+	"I": "idle",
+	"P": "parked",
+	"W": "waking",
+}
+
+// ReadThreadStates returns the Threads count of /proc/<pid>/status and
+// how many threads are in each state (running, sleeping, disk_sleep, ...;
+// an unknown letter is kept as is).
+func ReadThreadStates(ctx context.Context, root string, pid int) (total int, states map[string]int, err error) {
+	status, err := readProcStatus(ctx, root, pid)
+	if err != nil {
+		return 0, nil, err
+	}
+	if total, err = strconv.Atoi(status["Threads"]); err != nil {
+		return 0, nil, fmt.Errorf("Threads: %w", err)
+	}
+	states = make(map[string]int)
+	err = walkTasks(ctx, root, pid, func(_ int, stat *procStat) {
+		states[cmp.Or(threadStateNames[stat.State()], stat.State())]++
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, states, nil
+}
+
+// ReadThreads lists the threads of pid from /proc/<pid>/task and groups
+// them by name. The limits come from /proc/<pid>/limits and the pids
+// controller of the cgroup mounted at /sys/fs/cgroup (its pids/
+// hierarchy on cgroup v1); a missing one is left out.
+func ReadThreads(ctx context.Context, root string, pid int) (*ThreadReport, error) {
+	t := &ThreadReport{}
+	err := walkTasks(ctx, root, pid, func(tid int, stat *procStat) {
+		t.Threads = append(t.Threads, ThreadInfo{
+			TID:      tid,
+			Name:     stat.Comm,
+			State:    stat.State(),
+			CPUTicks: stat.uintField(14) + stat.uintField(15),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.Total = len(t.Threads)
+
+	hz := clockTicks(root)
+	groups := make(map[string]*ThreadGroup)
+	for _, th := range t.Threads {
+		name := threadNumberSuffix.ReplaceAllString(th.Name, "*")
+		g, ok := groups[name]
+		if !ok {
+			g = &ThreadGroup{Name: name}
+			groups[name] = g
+		}
+		g.Count++
+		g.CPUTicks += th.CPUTicks
+	}
+	for _, g := range groups {
+		g.CPUSeconds = float64(g.CPUTicks) / hz
+		t.Groups = append(t.Groups, *g)
+	}
+	slices.SortFunc(t.Groups, func(a, b ThreadGroup) int {
+		return cmp.Or(b.Count-a.Count, cmp.Compare(b.CPUTicks, a.CPUTicks), strings.Compare(a.Name, b.Name))
+	})
+
+	if limits, err := ReadRlimits(root, pid); err == nil {
+		t.NprocLimit = limits["nproc"].Soft
+	}
+	base := rootPath(root, "sys/fs/cgroup")
+	if _, err := os.Stat(base + "/cgroup.controllers"); err != nil {
+		base += "/pids"
+	}
+	if v, err := readTrim(base + "/pids.max"); err == nil && v != "max" {
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			t.PidsMax = &n
+		}
+	}
+	t.PidsCurrent, _ = readOptionalUint(base + "/pids.current")
+	return t, nil
+}
@@ -0,0 +1,191 @@
+package sysinfo
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+// ThrottleAssessment tells a CPU slowed down by heat or a frequency cap
+// from one that is merely busy. Frequencies are sampled over the window;
+// the thermal_throttle counters (Intel only) are read at both ends.
+type ThrottleAssessment struct {
+	WindowSeconds float64 `json:"window_seconds"`
+	Samples       int     `json:"samples"`
+	// AvgFreqPercent is the mean over CPUs of their average
+	// scaling_cur_freq relative to scaling_max_freq.
+	AvgFreqPercent float64 `json:"avg_freq_percent"`
+	// LimitPercent is the tightest cap below the hardware maximum
+	// (cpuinfo_max_freq): scaling_max_freq, acpi-cpufreq's bios_limit or
+	// intel_pstate's max_perf_pct, named by LimitSource. 100 when nothing
+	// caps the frequency.
+	LimitPercent float64 `json:"limit_percent"`
+	LimitSource  string  `json:"limit_source,omitempty"`
+	// CoreThrottleEvents sums the core_throttle_count increase of every
+	// CPU; PackageThrottleEvents is the largest package_throttle_count
+	// increase, as each CPU of a package reports the same counter. Both are
+	// nil without thermal_throttle in sysfs.
+	CoreThrottleEvents    *uint64 `json:"core_throttle_events,omitempty"`
+	PackageThrottleEvents *uint64 `json:"package_throttle_events,omitempty"`
+	// Capped is set when a limit holds the CPUs below their hardware
+	// maximum, or when they ran under 70% of scaling_max_freq while the
+	// throttle counters rose.
+	Capped bool          `json:"capped"`
+	CPUs   []CPUThrottle `json:"cpus"`
+}
+
+// CPUThrottle is one CPU of a ThrottleAssessment, frequencies in kHz.
+type CPUThrottle struct {
+	CPU            int     `json:"cpu"`
+	AvgFreqKHz     uint64  `json:"avg_freq_khz"`
+	MaxFreqKHz     uint64  `json:"max_freq_khz"`
+	HWMaxFreqKHz   uint64  `json:"hw_max_freq_khz"`
+	BIOSLimitKHz   *uint64 `json:"bios_limit_khz,omitempty"`
+	AvgFreqPercent float64 `json:"avg_freq_percent"`
+}
+
+// throttleLowPercent is the sustained share of scaling_max_freq below
+// which rising throttle counters mean heat is holding the CPUs back.
+const throttleLowPercent = 70
+
+// throttleSamples is how many times scaling_cur_freq is read, evenly
+// spread over the window.
+const throttleSamples = 5
+
+type cpuThrottleCounters struct{ core, pkg *uint64 }
+
+// SampleThrottle returns nil without cpufreq (most VMs), where there is no
+// frequency to compare.
+func SampleThrottle(clk clock.Clock, root string, window time.Duration) (*ThrottleAssessment, error) {
+	dirs, _ := filepath.Glob(rootPath(root, "sys/devices/system/cpu/cpu[0-9]*"))
+	type cpuState struct {
+		dir    string
+		t      CPUThrottle
+		sum    uint64
+		before cpuThrottleCounters
+	}
+	var cpus []*cpuState
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "cpu"))
+		if err != nil {
+			continue
+		}
+		maxFreq, err := readOptionalUint(dir + "/cpufreq/scaling_max_freq")
+		if err != nil || maxFreq == nil || *maxFreq == 0 {
+			continue
+		}
+		c := &cpuState{dir: dir, t: CPUThrottle{CPU: id, MaxFreqKHz: *maxFreq, HWMaxFreqKHz: *maxFreq}}
+		if hw, err := readOptionalUint(dir + "/cpufreq/cpuinfo_max_freq"); err == nil && hw != nil {
+			c.t.HWMaxFreqKHz = *hw
+		}
+		c.t.BIOSLimitKHz, _ = readOptionalUint(dir + "/cpufreq/bios_limit")
+		c.before = readThrottleCounters(dir)
+		cpus = append(cpus, c)
+	}
+	if len(cpus) == 0 {
+		return nil, nil
+	}
+	slices.SortFunc(cpus, func(a, b *cpuState) int { return cmp.Compare(a.t.CPU, b.t.CPU) })
+
+	t := &ThrottleAssessment{WindowSeconds: window.Seconds(), Samples: throttleSamples + 1}
+	for i := 0; i <= throttleSamples; i++ {
+		if i > 0 {
+			clk.Sleep(window / throttleSamples)
+		}
+		for _, c := range cpus {
+			cur, err := readOptionalUint(c.dir + "/cpufreq/scaling_cur_freq")
+			if err != nil {
+				return nil, fmt.Errorf("cpu%d: %w", c.t.CPU, err)
+			}
+			if cur != nil {
+				c.sum += *cur
+			}
+		}
+	}
+
+	t.LimitPercent = 100
+	limit := func(pct float64, source string) {
+		if pct < t.LimitPercent {
+			t.LimitPercent, t.LimitSource = pct, source
+		}
+	}
+	var freqSum float64
+	for _, c := range cpus {
+		c.t.AvgFreqKHz = c.sum / uint64(t.Samples)
+		c.t.AvgFreqPercent = float64(c.t.AvgFreqKHz) / float64(c.t.MaxFreqKHz) * 100
+		freqSum += c.t.AvgFreqPercent
+		hw := float64(c.t.HWMaxFreqKHz)
+		limit(float64(c.t.MaxFreqKHz)/hw*100, "scaling_max_freq")
+		if b := c.t.BIOSLimitKHz; b != nil && *b > 0 {
+			limit(float64(*b)/hw*100, "bios_limit")
+		}
+
+		after := readThrottleCounters(c.dir)
+		if d, ok := counterDelta(c.before.core, after.core); ok {
+			t.CoreThrottleEvents = addCount(t.CoreThrottleEvents, d)
+		}
+		if d, ok := counterDelta(c.before.pkg, after.pkg); ok {
+			if t.PackageThrottleEvents == nil || d > *t.PackageThrottleEvents {
+				t.PackageThrottleEvents = &d
+			}
+		}
+		t.CPUs = append(t.CPUs, c.t)
+	}
+	t.AvgFreqPercent = freqSum / float64(len(cpus))
+	if pct, err := readOptionalUint(rootPath(root, "sys/devices/system/cpu/intel_pstate/max_perf_pct")); err == nil && pct != nil {
+		limit(float64(*pct), "intel_pstate max_perf_pct")
+	}
+	t.Capped = t.LimitPercent < 100 || (t.AvgFreqPercent < throttleLowPercent && t.Events() > 0)
+	return t, nil
+}
+
+// Events is the core and package throttle events together.
+func (t *ThrottleAssessment) Events() uint64 {
+	var n uint64
+	for _, c := range []*uint64{t.CoreThrottleEvents, t.PackageThrottleEvents} {
+		if c != nil {
+			n += *c
+		}
+	}
+	return n
+}
+
+func readThrottleCounters(dir string) cpuThrottleCounters {
+	core, _ := readOptionalUint(dir + "/thermal_throttle/core_throttle_count")
+	pkg, _ := readOptionalUint(dir + "/thermal_throttle/package_throttle_count")
+	return cpuThrottleCounters{core, pkg}
+}
+
+func counterDelta(before, after *uint64) (uint64, bool) {
+	if before == nil || after == nil || *after < *before {
+		return 0, false
+	}
+	return *after - *before, true
+}
+
+func addCount(total *uint64, n uint64) *uint64 {
+	if total == nil {
+		return &n
+	}
+	sum := *total + n
+	return &sum
+}
+
+func throttleFindings(t *ThrottleAssessment) []Finding {
+	if t == nil || t.AvgFreqPercent >= throttleLowPercent || t.Events() == 0 {
+		return nil
+	}
+	return []Finding{{
+		Code:     "cpu_thermal_throttling",
+		Severity: "warning",
+		Message: fmt.Sprintf("CPUs ran at %.0f%% of their maximum frequency over %gs while %d thermal throttle "+
+			"events were counted: the CPU is slow because it is hot, not because of scheduling",
+			t.AvgFreqPercent, t.WindowSeconds, t.Events()),
+	}}
+}
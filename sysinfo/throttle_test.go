@@ -0,0 +1,74 @@
+package sysinfo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func throttleTree(t *testing.T, root string, cur uint64, coreEvents uint64) {
+	t.Helper()
+	files := make(map[string]string)
+	for cpu := range 2 {
+		dir := fmt.Sprintf("sys/devices/system/cpu/cpu%d/", cpu)
+		files[dir+"cpufreq/scaling_max_freq"] = "3000000\n"
+		files[dir+"cpufreq/cpuinfo_max_freq"] = "3000000\n"
+		files[dir+"cpufreq/scaling_cur_freq"] = fmt.Sprintf("%d\n", cur)
+		files[dir+"thermal_throttle/core_throttle_count"] = fmt.Sprintf("%d\n", coreEvents)
+		files[dir+"thermal_throttle/package_throttle_count"] = "7\n"
+	}
+	writeTree(t, root, files)
+}
+
+func TestSampleThrottleWindow(t *testing.T) {
+	root := t.TempDir()
+	throttleTree(t, root, 3000000, 10)
+	// The CPUs drop to a third of their maximum after the first sample and
+	// every core counts 5 throttle events over the window.
+	clk := newSampleClock(func(n int) {
+		if n == 1 {
+			throttleTree(t, root, 1000000, 15)
+		}
+	})
+	start := clk.Now()
+
+	ta, err := SampleThrottle(clk, root, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clk.sleeps != throttleSamples {
+		t.Errorf("slept %d times, want %d", clk.sleeps, throttleSamples)
+	}
+	if got := clk.Now().Sub(start); got != 10*time.Second {
+		t.Errorf("clock advanced %v, want the 10s window", got)
+	}
+	if ta.WindowSeconds != 10 || ta.Samples != throttleSamples+1 {
+		t.Errorf("window %vs over %d samples, want 10s over %d", ta.WindowSeconds, ta.Samples, throttleSamples+1)
+	}
+	// (3 GHz + 5 × 1 GHz) / 6 samples.
+	wantKHz := uint64((3000000 + 5*1000000) / 6)
+	for _, c := range ta.CPUs {
+		if c.AvgFreqKHz != wantKHz {
+			t.Errorf("cpu%d averaged %d kHz, want %d", c.CPU, c.AvgFreqKHz, wantKHz)
+		}
+	}
+	if ta.CoreThrottleEvents == nil || *ta.CoreThrottleEvents != 10 {
+		t.Errorf("CoreThrottleEvents = %v, want 10", ta.CoreThrottleEvents)
+	}
+	if ta.PackageThrottleEvents == nil || *ta.PackageThrottleEvents != 0 {
+		t.Errorf("PackageThrottleEvents = %v, want 0", ta.PackageThrottleEvents)
+	}
+	if !ta.Capped || ta.LimitPercent != 100 {
+		t.Errorf("Capped = %v with limit %v%%, want capped by heat with no limit", ta.Capped, ta.LimitPercent)
+	}
+	if f := throttleFindings(ta); len(f) != 1 || f[0].Code != "cpu_thermal_throttling" {
+		t.Errorf("findings = %+v, want cpu_thermal_throttling", f)
+	}
+}
+
+func TestSampleThrottleNoCPUFreq(t *testing.T) {
+	ta, err := SampleThrottle(newSampleClock(nil), t.TempDir(), time.Second)
+	if ta != nil || err != nil {
+		t.Errorf("SampleThrottle = %+v, %v; want nil, nil without cpufreq", ta, err)
+	}
+}
@@ -0,0 +1,169 @@
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+// ReadIssue is a /proc file whose first read failed its sanity check:
+// truncated, missing a line every kernel writes, or a transient EIO. It
+// was read Attempts times; Partial is set when the last attempt still
+// failed the check and its content was used anyway.
+type ReadIssue struct {
+	Path     string `json:"path"`
+	Attempts int    `json:"attempts"`
+	Partial  bool   `json:"partial,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+const verifiedReadAttempts = 3
+
+// readLog is where the verified reads of one collection record their
+// issues. It travels in the collection's context, so concurrent
+// collections keep their issues apart; files is what they read, the real
+// filesystem unless a test injects one, and clk the clock their backoff
+// sleeps on.
+type readLog struct {
+	files  fileReader
+	clk    clock.Clock
+	mu     sync.Mutex
+	issues []ReadIssue
+}
+
+// fileReader is the part of the filesystem verified reads need.
+type fileReader interface {
+	ReadFile(path string) ([]byte, error)
+}
+
+type readLogKey struct{}
+
+// withReadLog returns ctx carrying a new log for reads from files, or from
+// the real filesystem when files is nil.
+func withReadLog(ctx context.Context, clk clock.Clock, files fileReader) (context.Context, *readLog) {
+	l := &readLog{files: files, clk: clk}
+	return context.WithValue(ctx, readLogKey{}, l), l
+}
+
+// readLogFrom returns the log in ctx. Reads outside a collection have none:
+// they read the real filesystem, sleep on the wall clock and their issues
+// are dropped.
+func readLogFrom(ctx context.Context) *readLog {
+	l, _ := ctx.Value(readLogKey{}).(*readLog)
+	return l
+}
+
+func (l *readLog) readFile(path string) ([]byte, error) {
+	if l == nil || l.files == nil {
+		return os.ReadFile(path)
+	}
+	return l.files.ReadFile(path)
+}
+
+func (l *readLog) sleep(d time.Duration) {
+	if l == nil || l.clk == nil {
+		clock.Real.Sleep(d)
+		return
+	}
+	l.clk.Sleep(d)
+}
+
+func (l *readLog) add(issue ReadIssue) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.issues = append(l.issues, issue)
+}
+
+// list returns what has been logged so far.
+func (l *readLog) list() []ReadIssue {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.issues)
+}
+
+// readVerified reads path until check accepts the content, at most
+// verifiedReadAttempts times with a short backoff. After the last attempt
+// it returns the content that failed the check rather than nothing: a
+// partial meminfo still has MemTotal. EIO is retried like a failed check;
+// other errors are returned at once.
+func readVerified(ctx context.Context, path string, check func(string) error) (string, error) {
+	reads := readLogFrom(ctx)
+	var reason, first error
+	for attempt := 1; ; attempt++ {
+		data, err := reads.readFile(path)
+		if err != nil && !errors.Is(err, syscall.EIO) {
+			return "", err
+		}
+		if err == nil {
+			reason = check(string(data))
+		} else {
+			reason = err
+		}
+		if reason == nil {
+			if attempt > 1 {
+				reads.add(ReadIssue{Path: path, Attempts: attempt, Reason: first.Error()})
+			}
+			return string(data), nil
+		}
+		if first == nil {
+			first = reason
+		}
+		if attempt == verifiedReadAttempts {
+			if err != nil {
+				return "", err
+			}
+			reads.add(ReadIssue{Path: path, Attempts: attempt, Partial: true, Reason: reason.Error()})
+			return string(data), nil
+		}
+		reads.sleep(time.Duration(attempt) * time.Millisecond)
+	}
+}
+
+// hasLines returns a check that the content is newline-terminated (a
+// truncated read is not) and has a line starting with each of prefixes.
+func hasLines(prefixes ...string) func(string) error {
+	return func(data string) error {
+		if !strings.HasSuffix(data, "\n") {
+			return errors.New("truncated: no final newline")
+		}
+		for _, p := range prefixes {
+			if !strings.HasPrefix(data, p) && !strings.Contains(data, "\n"+p) {
+				return fmt.Errorf("no %s line", strings.TrimSpace(p))
+			}
+		}
+		return nil
+	}
+}
+
+func readIssueFindings(issues []ReadIssue) []Finding {
+	var findings []Finding
+	for _, ri := range issues {
+		if ri.Partial {
+			findings = append(findings, Finding{
+				Code:     "proc_read_partial",
+				Severity: "warning",
+				Message: fmt.Sprintf("%s was still incomplete after %d reads (%s): values from it may be wrong",
+					ri.Path, ri.Attempts, ri.Reason),
+			})
+		} else {
+			findings = append(findings, Finding{
+				Code:     "proc_read_retried",
+				Severity: "info",
+				Message: fmt.Sprintf("%s had to be read %d times to get complete content (%s)",
+					ri.Path, ri.Attempts, ri.Reason),
+			})
+		}
+	}
+	return findings
+}
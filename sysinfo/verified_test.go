@@ -0,0 +1,187 @@
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/OkiMol/sysinfo-lab/internal/clock"
+)
+
+// flakyFiles serves each path's reads in turn: the nth read of a path gets
+// its nth result, the last one repeating.
+type flakyFiles struct {
+	mu    sync.Mutex
+	reads map[string][]flakyRead
+	count map[string]int
+}
+
+type flakyRead struct {
+	data string
+	err  error
+}
+
+func newFlakyFiles(reads map[string][]flakyRead) *flakyFiles {
+	return &flakyFiles{reads: reads, count: make(map[string]int)}
+}
+
+func (f *flakyFiles) ReadFile(path string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	results, ok := f.reads[path]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+	r := results[min(f.count[path], len(results)-1)]
+	f.count[path]++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []byte(r.data), nil
+}
+
+const testMeminfo = "MemTotal:       16318412 kB\nMemFree:         1203388 kB\nSwapFree:        2097148 kB\n"
+
+func TestReadVerified(t *testing.T) {
+	eio := &fs.PathError{Op: "read", Path: "/proc/meminfo", Err: syscall.EIO}
+	tests := []struct {
+		name     string
+		reads    []flakyRead
+		want     string
+		wantErr  bool
+		issue    *ReadIssue
+		attempts int
+	}{
+		{
+			name:     "complete",
+			reads:    []flakyRead{{data: testMeminfo}},
+			want:     testMeminfo,
+			attempts: 1,
+		},
+		{
+			name:     "truncated once",
+			reads:    []flakyRead{{data: testMeminfo[:20]}, {data: testMeminfo}},
+			want:     testMeminfo,
+			issue:    &ReadIssue{Attempts: 2, Reason: "truncated: no final newline"},
+			attempts: 2,
+		},
+		{
+			name:     "missing line once",
+			reads:    []flakyRead{{data: "MemTotal: 1 kB\n"}, {data: "MemTotal: 1 kB\nMemFree: 1 kB\n"}, {data: testMeminfo}},
+			want:     testMeminfo,
+			issue:    &ReadIssue{Attempts: 3, Reason: "no MemFree: line"},
+			attempts: 3,
+		},
+		{
+			name:     "always truncated",
+			reads:    []flakyRead{{data: testMeminfo[:30]}},
+			want:     testMeminfo[:30],
+			issue:    &ReadIssue{Attempts: 3, Partial: true, Reason: "truncated: no final newline"},
+			attempts: 3,
+		},
+		{
+			name:     "EIO then complete",
+			reads:    []flakyRead{{err: eio}, {data: testMeminfo}},
+			want:     testMeminfo,
+			issue:    &ReadIssue{Attempts: 2, Reason: eio.Error()},
+			attempts: 2,
+		},
+		{
+			name:     "always EIO",
+			reads:    []flakyRead{{err: eio}},
+			wantErr:  true,
+			attempts: 3,
+		},
+		{
+			name:     "permission denied",
+			reads:    []flakyRead{{err: &fs.PathError{Op: "open", Path: "/proc/meminfo", Err: fs.ErrPermission}}},
+			wantErr:  true,
+			attempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const path = "/proc/meminfo"
+			files := newFlakyFiles(map[string][]flakyRead{path: tt.reads})
+			clk := clock.NewManual(time.Unix(0, 0))
+			ctx, reads := withReadLog(context.Background(), clk, files)
+			got, err := readVerified(ctx, path, hasLines("MemTotal:", "MemFree:", "SwapFree:"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("data = %q, want %q", got, tt.want)
+			}
+			if files.count[path] != tt.attempts {
+				t.Errorf("read %d times, want %d", files.count[path], tt.attempts)
+			}
+			// The backoff grows by a millisecond per attempt and sleeps on
+			// the collection's clock.
+			var backoff time.Duration
+			for a := 1; a < tt.attempts; a++ {
+				backoff += time.Duration(a) * time.Millisecond
+			}
+			if got := clk.Now().Sub(time.Unix(0, 0)); got != backoff {
+				t.Errorf("backoff slept %v, want %v", got, backoff)
+			}
+			issues := reads.list()
+			if tt.issue == nil {
+				if len(issues) != 0 {
+					t.Errorf("issues = %+v, want none", issues)
+				}
+				return
+			}
+			want := *tt.issue
+			want.Path = path
+			if len(issues) != 1 || issues[0] != want {
+				t.Errorf("issues = %+v, want [%+v]", issues, want)
+			}
+		})
+	}
+}
+
+func TestReadLogPerCollection(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "proc/meminfo")
+	files := newFlakyFiles(map[string][]flakyRead{path: {{data: testMeminfo[:10]}, {data: testMeminfo}}})
+
+	ctx, reads := withReadLog(context.Background(), clock.NewManual(time.Unix(0, 0)), files)
+	_, other := withReadLog(context.Background(), clock.NewManual(time.Unix(0, 0)), files)
+	meminfo, err := readMeminfo(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meminfo["MemTotal"] != 16318412 || meminfo["SwapFree"] != 2097148 {
+		t.Errorf("meminfo = %v, want the complete read's values", meminfo)
+	}
+	if issues := reads.list(); len(issues) != 1 || issues[0].Path != path || issues[0].Attempts != 2 {
+		t.Errorf("issues = %+v, want one retried read of %s", issues, path)
+	}
+	if issues := other.list(); len(issues) != 0 {
+		t.Errorf("another collection logged %+v", issues)
+	}
+}
+
+func TestReadVerifiedWithoutLog(t *testing.T) {
+	// Reads outside a collection use the real filesystem.
+	_, err := readVerified(context.Background(), filepath.Join(t.TempDir(), "missing"), hasLines())
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("err = %v, want ErrNotExist", err)
+	}
+}
+
+func TestCollectReadIssues(t *testing.T) {
+	// A collection starts a log of its own: a concurrent one's issues do
+	// not end up in its report.
+	busy, reads := withReadLog(context.Background(), nil, nil)
+	reads.add(ReadIssue{Path: "/proc/meminfo", Attempts: 2, Reason: "truncated: no final newline"})
+	info, _ := collectWith(busy, Options{Root: t.TempDir(), Fields: []string{"pid"}})
+	if len(info.ReadIssues) != 0 {
+		t.Errorf("ReadIssues = %+v, want none", info.ReadIssues)
+	}
+}
@@ -0,0 +1,193 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ZramDevice is the compression state of an initialized zram device, from
+// its mm_stat. SavedBytes is what the device spares against keeping its
+// pages uncompressed: OrigDataBytes minus the memory the allocator really
+// uses, which can be negative.
+type ZramDevice struct {
+	Name           string `json:"name"`
+	Algorithm      string `json:"algorithm,omitempty"`
+	DiskSizeBytes  uint64 `json:"disk_size_bytes"`
+	OrigDataBytes  uint64 `json:"orig_data_bytes"`
+	ComprDataBytes uint64 `json:"compr_data_bytes"`
+	MemUsedBytes   uint64 `json:"mem_used_bytes"`
+	// Ratio is OrigDataBytes over ComprDataBytes; nil while the device
+	// holds no compressed data.
+	Ratio      *float64 `json:"ratio,omitempty"`
+	SavedBytes int64    `json:"saved_bytes"`
+	// SamePages are pages filled with one repeated value, stored without
+	// compressing them; HugePages are pages that did not compress and are
+	// stored whole (nil before kernel 4.19).
+	SamePages uint64  `json:"same_pages"`
+	HugePages *uint64 `json:"huge_pages,omitempty"`
+	// Writeback is set when a backing device is configured.
+	Writeback *ZramWriteback `json:"writeback,omitempty"`
+}
+
+// ZramWriteback is the bd_stat of a zram device with a backing device, in
+// bytes.
+type ZramWriteback struct {
+	BackingDevice string `json:"backing_device"`
+	StoredBytes   uint64 `json:"stored_bytes"`
+	ReadBytes     uint64 `json:"read_bytes"`
+	WrittenBytes  uint64 `json:"written_bytes"`
+}
+
+// Zswap is the compressed swap cache. The pool counters come from debugfs
+// and are nil unless /sys/kernel/debug/zswap is readable (root and debugfs
+// mounted).
+type Zswap struct {
+	Enabled        bool    `json:"enabled"`
+	Compressor     string  `json:"compressor,omitempty"`
+	MaxPoolPercent *uint64 `json:"max_pool_percent,omitempty"`
+	StoredPages    *uint64 `json:"stored_pages,omitempty"`
+	PoolTotalBytes *uint64 `json:"pool_total_bytes,omitempty"`
+	// Ratio is the stored pages over the pool size.
+	Ratio            *float64 `json:"ratio,omitempty"`
+	SameFilledPages  *uint64  `json:"same_filled_pages,omitempty"`
+	WrittenBackPages *uint64  `json:"written_back_pages,omitempty"`
+	PoolLimitHit     *uint64  `json:"pool_limit_hit,omitempty"`
+	// Rejects are the reject_* counters without the prefix
+	// (compress_poor, alloc_fail, reclaim_fail, ...); the set differs
+	// between kernels.
+	Rejects map[string]uint64 `json:"rejects,omitempty"`
+}
+
+// zramPoorRatio is the compression ratio below which a zram device costs
+// more CPU than the memory it saves is worth; zramMinData keeps devices
+// that hold next to nothing out of the finding.
+const (
+	zramPoorRatio = 1.2
+	zramMinData   = 16 << 20
+)
+
+// readZramDevices reads every zram device in /sys/block that has a disk
+// size set, whether it is used for swap or as a filesystem.
+func readZramDevices(root string) []ZramDevice {
+	dirs, _ := filepath.Glob(rootPath(root, "sys/block/zram*"))
+	devices := []ZramDevice{}
+	for _, dir := range dirs {
+		d, ok := readZram(dir)
+		if ok {
+			devices = append(devices, d)
+		}
+	}
+	return devices
+}
+
+func readZram(dir string) (ZramDevice, bool) {
+	d := ZramDevice{Name: filepath.Base(dir)}
+	size, err := readOptionalUint(dir + "/disksize")
+	if err != nil || size == nil || *size == 0 {
+		return d, false
+	}
+	d.DiskSizeBytes = *size
+	// comp_algorithm lists every algorithm with the selected one in
+	// brackets: "lzo lzo-rle [zstd]".
+	if algs, err := readTrim(dir + "/comp_algorithm"); err == nil {
+		for _, a := range strings.Fields(algs) {
+			if strings.HasPrefix(a, "[") {
+				d.Algorithm = strings.Trim(a, "[]")
+			}
+		}
+	}
+
+	// mm_stat is positional and has grown over time: orig_data_size
+	// compr_data_size mem_used_total mem_limit mem_used_max same_pages
+	// pages_compacted, then huge_pages since 4.19 and huge_pages_since
+	// since 5.16.
+	data, err := readTrim(dir + "/mm_stat")
+	if err != nil {
+		return d, false
+	}
+	var mm []uint64
+	for _, f := range strings.Fields(data) {
+		n, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return d, false
+		}
+		mm = append(mm, n)
+	}
+	if len(mm) < 6 {
+		return d, false
+	}
+	d.OrigDataBytes, d.ComprDataBytes, d.MemUsedBytes, d.SamePages = mm[0], mm[1], mm[2], mm[5]
+	if len(mm) > 7 {
+		d.HugePages = &mm[7]
+	}
+	if d.ComprDataBytes > 0 {
+		ratio := float64(d.OrigDataBytes) / float64(d.ComprDataBytes)
+		d.Ratio = &ratio
+	}
+	d.SavedBytes = int64(d.OrigDataBytes) - int64(d.MemUsedBytes)
+
+	// bd_stat counts pages: stored on the backing device, read back and
+	// written to it.
+	if backing, err := readTrim(dir + "/backing_dev"); err == nil && backing != "none" {
+		wb := &ZramWriteback{BackingDevice: backing}
+		pageSize := uint64(os.Getpagesize())
+		if bd, err := readTrim(dir + "/bd_stat"); err == nil {
+			if f := strings.Fields(bd); len(f) >= 3 {
+				count, _ := strconv.ParseUint(f[0], 10, 64)
+				reads, _ := strconv.ParseUint(f[1], 10, 64)
+				writes, _ := strconv.ParseUint(f[2], 10, 64)
+				wb.StoredBytes, wb.ReadBytes, wb.WrittenBytes = count*pageSize, reads*pageSize, writes*pageSize
+			}
+		}
+		d.Writeback = wb
+	}
+	return d, true
+}
+
+// readZswap returns nil when the kernel has no zswap.
+func readZswap(root string) *Zswap {
+	params := rootPath(root, "sys/module/zswap/parameters")
+	enabled, err := readTrim(params + "/enabled")
+	if err != nil {
+		return nil
+	}
+	z := &Zswap{Enabled: enabled == "Y"}
+	z.Compressor, _ = readTrim(params + "/compressor")
+	z.MaxPoolPercent, _ = readOptionalUint(params + "/max_pool_percent")
+
+	debug := rootPath(root, "sys/kernel/debug/zswap")
+	entries, err := os.ReadDir(debug)
+	if err != nil {
+		return z
+	}
+	for _, e := range entries {
+		v, err := readOptionalUint(filepath.Join(debug, e.Name()))
+		if err != nil || v == nil {
+			continue
+		}
+		switch name := e.Name(); {
+		case name == "stored_pages":
+			z.StoredPages = v
+		case name == "pool_total_size":
+			z.PoolTotalBytes = v
+		case name == "same_filled_pages":
+			z.SameFilledPages = v
+		case name == "written_back_pages":
+			z.WrittenBackPages = v
+		case name == "pool_limit_hit":
+			z.PoolLimitHit = v
+		case strings.HasPrefix(name, "reject_"):
+			if z.Rejects == nil {
+				z.Rejects = make(map[string]uint64)
+			}
+			z.Rejects[strings.TrimPrefix(name, "reject_")] = *v
+		}
+	}
+	if z.StoredPages != nil && z.PoolTotalBytes != nil && *z.PoolTotalBytes > 0 {
+		ratio := float64(*z.StoredPages*uint64(os.Getpagesize())) / float64(*z.PoolTotalBytes)
+		z.Ratio = &ratio
+	}
+	return z
+}
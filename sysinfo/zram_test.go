@@ -0,0 +1,93 @@
+package sysinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadZramMMStat(t *testing.T) {
+	tests := []struct {
+		name      string
+		mmStat    string
+		ok        bool
+		hugePages *uint64
+	}{
+		// Before 4.19: no huge_pages.
+		{name: "7 columns", mmStat: "8388608 2097152 3145728 0 3145728 12 0\n", ok: true},
+		// 4.19 to 5.15: huge_pages.
+		{name: "8 columns", mmStat: "8388608 2097152 3145728 0 3145728 12 0 5\n", ok: true, hugePages: ptr(uint64(5))},
+		// Since 5.16: huge_pages_since as well.
+		{name: "9 columns", mmStat: "  8388608  2097152  3145728        0  3145728       12        0        5       40\n", ok: true, hugePages: ptr(uint64(5))},
+		{name: "too short", mmStat: "8388608 2097152 3145728 0 3145728\n"},
+		{name: "not a number", mmStat: "8388608 2097152 3145728 0 3145728 12 x\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "zram0")
+			writeTree(t, dir, map[string]string{
+				"disksize":       "4294967296\n",
+				"comp_algorithm": "lzo lzo-rle [zstd] lz4\n",
+				"mm_stat":        tt.mmStat,
+			})
+			d, ok := readZram(dir)
+			if ok != tt.ok {
+				t.Fatalf("readZram ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if d.Name != "zram0" || d.Algorithm != "zstd" || d.DiskSizeBytes != 4<<30 {
+				t.Errorf("device = %q %q %d, want zram0 zstd %d", d.Name, d.Algorithm, d.DiskSizeBytes, 4<<30)
+			}
+			if d.OrigDataBytes != 8<<20 || d.ComprDataBytes != 2<<20 || d.MemUsedBytes != 3<<20 || d.SamePages != 12 {
+				t.Errorf("mm_stat read as orig %d compr %d used %d same %d", d.OrigDataBytes, d.ComprDataBytes, d.MemUsedBytes, d.SamePages)
+			}
+			if d.Ratio == nil || *d.Ratio != 4 {
+				t.Errorf("Ratio = %v, want 4", d.Ratio)
+			}
+			if d.SavedBytes != 5<<20 {
+				t.Errorf("SavedBytes = %d, want %d", d.SavedBytes, 5<<20)
+			}
+			switch {
+			case tt.hugePages == nil && d.HugePages != nil:
+				t.Errorf("HugePages = %d, want nil", *d.HugePages)
+			case tt.hugePages != nil && (d.HugePages == nil || *d.HugePages != *tt.hugePages):
+				t.Errorf("HugePages = %v, want %d", d.HugePages, *tt.hugePages)
+			}
+			if d.Writeback != nil {
+				t.Errorf("Writeback = %+v without a backing device", d.Writeback)
+			}
+		})
+	}
+}
+
+func TestReadZramEmptyAndWriteback(t *testing.T) {
+	root := t.TempDir()
+	page := uint64(os.Getpagesize())
+	writeTree(t, root, map[string]string{
+		// Reset: no disk size, not reported.
+		"sys/block/zram0/disksize": "0\n",
+		"sys/block/zram0/mm_stat":  "0 0 0 0 0 0 0 0 0\n",
+		// Holds nothing compressed yet, and costs its allocator overhead.
+		"sys/block/zram1/disksize":    "1073741824\n",
+		"sys/block/zram1/mm_stat":     "0 0 4096 0 4096 0 0 0 0\n",
+		"sys/block/zram1/backing_dev": "/dev/sdb2\n",
+		"sys/block/zram1/bd_stat":     "3 2 10\n",
+	})
+	devices := readZramDevices(root)
+	if len(devices) != 1 || devices[0].Name != "zram1" {
+		t.Fatalf("devices = %+v, want only zram1", devices)
+	}
+	d := devices[0]
+	if d.Ratio != nil {
+		t.Errorf("Ratio = %v, want nil with no compressed data", *d.Ratio)
+	}
+	if d.SavedBytes != -4096 {
+		t.Errorf("SavedBytes = %d, want -4096", d.SavedBytes)
+	}
+	want := ZramWriteback{BackingDevice: "/dev/sdb2", StoredBytes: 3 * page, ReadBytes: 2 * page, WrittenBytes: 10 * page}
+	if d.Writeback == nil || *d.Writeback != want {
+		t.Errorf("Writeback = %+v, want %+v", d.Writeback, want)
+	}
+}